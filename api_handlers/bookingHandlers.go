@@ -0,0 +1,625 @@
+package api_handlers
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	availabilityMaxDays   = 14 // ค้นหาเวลาว่างได้ครั้งละไม่เกินกี่วัน
+	reservationCodeLength = 8
+)
+
+// BookingDetail การจองที่ลูกค้าดูได้ด้วยรหัสยืนยันและเบอร์โทรศัพท์
+type BookingDetail struct {
+	ConfirmationCode string    `json:"confirmation_code"`
+	Status           string    `json:"status"`
+	CustomerName     string    `json:"customer_name"`
+	GuestCount       int       `json:"guest_count"`
+	ReservedFor      time.Time `json:"reserved_for"`
+	GracePeriodUntil time.Time `json:"grace_period_until"`
+	TableName        string    `json:"table_name"`
+	Note             string    `json:"note"`
+	DepositAmount    float64   `json:"deposit_amount"`
+	DepositStatus    string    `json:"deposit_status"`
+	NoShowFee        float64   `json:"no_show_fee"`
+	ModifiableUntil  time.Time `json:"modifiable_until"` // แก้ไข/ยกเลิกเองได้ถึงเวลานี้ หลังจากนั้นต้องติดต่อร้าน
+	CanModify        bool      `json:"can_modify"`
+}
+
+// AvailabilitySlot เวลาที่ยังจองได้
+type AvailabilitySlot struct {
+	Time            time.Time `json:"time"`
+	Label           string    `json:"label"`            // HH:MM
+	AvailableTables int       `json:"available_tables"` // จำนวนโต๊ะ (หรือโต๊ะรวม) ที่จองได้ในเวลานี้
+}
+
+// AvailabilityDay เวลาที่ยังจองได้ของวันหนึ่ง
+type AvailabilityDay struct {
+	Date  string             `json:"date"` // YYYY-MM-DD
+	Slots []AvailabilitySlot `json:"slots"`
+}
+
+// AvailabilityResponse ผลการค้นหาเวลาว่าง
+type AvailabilityResponse struct {
+	PartySize       int               `json:"party_size"`
+	DiningMinutes   int               `json:"dining_minutes"` // เวลาที่ใช้โต๊ะที่ใช้คำนวณ
+	IntervalMinutes int               `json:"interval_minutes"`
+	Days            []AvailabilityDay `json:"days"`
+}
+
+// parseAvailabilityDate อ่านวันที่ YYYY-MM-DD ตามเวลาท้องถิ่นของร้าน ค่าว่างคือวันนี้
+func parseAvailabilityDate(value string) (time.Time, bool) {
+	if value == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local), true
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	return t, err == nil
+}
+
+// @Summary ค้นหาเวลาที่จองโต๊ะได้
+// @Description เวลาที่ยังมีโต๊ะว่างพอสำหรับจำนวนลูกค้าในช่วงวันที่ระบุ สำหรับหน้าจองโต๊ะของลูกค้า ไม่ต้องเข้าสู่ระบบ
+// @Description ใช้ช่วงเวลารับจอง เวลากั้นโต๊ะ และเวลาที่ใช้โต๊ะจากกฎการจอง (ถ้าไม่กำหนดใช้เวลานั่งเฉลี่ยจากการใช้โต๊ะที่ผ่านมา) และความจุของโต๊ะ
+// @Produce json
+// @Param party_size query integer true "จำนวนลูกค้า"
+// @Param from query string false "ตั้งแต่วันที่ (YYYY-MM-DD) ค่าเริ่มต้นวันนี้"
+// @Param to query string false "ถึงวันที่ (YYYY-MM-DD รวมทั้งวัน) ค่าเริ่มต้นเท่ากับ from ไม่เกิน 14 วัน"
+// @Param zone_id query integer false "เฉพาะโต๊ะในโซนนี้"
+// @Success 200 {object} AvailabilityResponse "เวลาที่จองได้"
+// @Failure 400 {object} map[string]interface{} "พารามิเตอร์ไม่ถูกต้อง"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการค้นหา"
+// @Router /api/booking/availability [get]
+// @Tags Booking
+func GetReservationAvailability(c *fiber.Ctx) error {
+	partySize := c.QueryInt("party_size", 0)
+	if partySize <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "จำนวนลูกค้าต้องมากกว่า 0",
+		})
+	}
+	from, ok := parseAvailabilityDate(c.Query("from"))
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid from, use YYYY-MM-DD",
+		})
+	}
+	to := from
+	if value := c.Query("to"); value != "" {
+		if to, ok = parseAvailabilityDate(value); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid to, use YYYY-MM-DD",
+			})
+		}
+	}
+	if to.Before(from) || to.After(from.AddDate(0, 0, availabilityMaxDays-1)) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ช่วงวันที่ต้องไม่เกิน 14 วัน และ to ต้องไม่ก่อน from",
+		})
+	}
+
+	rules, err := activeReservationRules(db.DB)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ไม่สามารถดึงกฎการจองได้",
+		})
+	}
+	first, errFirst := time.Parse("15:04", rules.FirstSlotTime)
+	last, errLast := time.Parse("15:04", rules.LastSlotTime)
+	if errFirst != nil || errLast != nil || rules.SlotIntervalMinutes <= 0 {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "กฎการจองมีช่วงเวลารับจองไม่ถูกต้อง",
+		})
+	}
+	dining, err := reservationDiningDuration(db.DB, rules)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ไม่สามารถคำนวณเวลาที่ใช้โต๊ะได้",
+		})
+	}
+
+	lead := time.Duration(rules.PreReservationMinutes) * time.Minute
+	interval := time.Duration(rules.SlotIntervalMinutes) * time.Minute
+	slotAt := func(day, clock time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, time.Local)
+	}
+
+	state, err := service.LoadTableState(db.DB, slotAt(from, first).Add(-lead), slotAt(to, last).Add(dining))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ไม่สามารถดึงข้อมูลโต๊ะได้",
+		})
+	}
+
+	zoneID := queryZoneID(c)
+	now := time.Now()
+	response := AvailabilityResponse{
+		PartySize:       partySize,
+		DiningMinutes:   int(dining.Minutes()),
+		IntervalMinutes: rules.SlotIntervalMinutes,
+		Days:            []AvailabilityDay{},
+	}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		result := AvailabilityDay{Date: day.Format("2006-01-02"), Slots: []AvailabilitySlot{}}
+		for slot := slotAt(day, first); !slot.After(slotAt(day, last)); slot = slot.Add(interval) {
+			if !slot.After(now) {
+				continue
+			}
+			allocations := state.Allocate(service.AllocationRequest{
+				PartySize: partySize,
+				At:        slot,
+				Duration:  dining,
+				Lead:      lead,
+				ZoneID:    zoneID,
+			})
+			count := 0
+			for _, allocation := range allocations {
+				inZone := zoneID == nil || (allocation.ZoneID != nil && *allocation.ZoneID == *zoneID)
+				if allocation.Bookable() && inZone {
+					count++
+				}
+			}
+			if count > 0 {
+				result.Slots = append(result.Slots, AvailabilitySlot{
+					Time:            slot,
+					Label:           slot.Format("15:04"),
+					AvailableTables: count,
+				})
+			}
+		}
+		response.Days = append(response.Days, result)
+	}
+	return c.JSON(response)
+}
+
+// newReservationCode สุ่มรหัสยืนยันการจองที่ยังไม่ซ้ำ
+// รหัสคู่กับเบอร์โทรใช้ดู แก้ไข และยกเลิกการจองได้ จึงสุ่มด้วย crypto/rand ให้เดารหัสไม่ได้
+func newReservationCode(tx *gorm.DB) (string, error) {
+	charCount := big.NewInt(int64(len(ticketCodeChars)))
+	for attempt := 0; attempt < 10; attempt++ {
+		code := make([]byte, reservationCodeLength)
+		for i := range code {
+			n, err := rand.Int(rand.Reader, charCount)
+			if err != nil {
+				return "", err
+			}
+			code[i] = ticketCodeChars[n.Int64()]
+		}
+
+		var count int64
+		if err := tx.Unscoped().Model(&models.TableReservation{}).Where("confirmation_code = ?", string(code)).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return string(code), nil
+		}
+	}
+	return "", errors.New("failed to generate unique confirmation code")
+}
+
+// normalizePhoneNumber เหลือแต่ตัวเลข และแปลง +66 เป็น 0 เพื่อเทียบเบอร์ที่พิมพ์ต่างรูปแบบกัน
+func normalizePhoneNumber(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+	if strings.HasPrefix(digits, "66") && len(digits) == 11 {
+		return "0" + digits[2:]
+	}
+	return digits
+}
+
+// findBooking การจองจากรหัสยืนยันใน path ที่เบอร์โทรศัพท์ตรงกับที่จองไว้
+// เบอร์ไม่ตรงถือว่าไม่พบ เพื่อไม่บอกว่ารหัสนั้นมีอยู่จริง
+func findBooking(tx *gorm.DB, c *fiber.Ctx, phone string) (*models.TableReservation, error) {
+	code := strings.ToUpper(strings.TrimSpace(c.Params("code")))
+	var reservation models.TableReservation
+	if err := tx.Preload("Table").Where("confirmation_code = ?", code).First(&reservation).Error; err != nil {
+		return nil, err
+	}
+	if phone == "" || normalizePhoneNumber(phone) != normalizePhoneNumber(reservation.PhoneNumber) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &reservation, nil
+}
+
+// bookingModifiableUntil เวลาสุดท้ายที่ลูกค้าแก้ไขหรือยกเลิกการจองเองได้
+func bookingModifiableUntil(reservation models.TableReservation, rule models.ReservationRules) time.Time {
+	return reservation.ReservedFor.Add(-time.Duration(rule.CancelCutoffMinutes) * time.Minute)
+}
+
+func newBookingDetail(reservation models.TableReservation, rule models.ReservationRules) BookingDetail {
+	until := bookingModifiableUntil(reservation, rule)
+	detail := BookingDetail{
+		Status:           reservation.Status,
+		CustomerName:     reservation.CustomerName,
+		GuestCount:       reservation.GuestCount,
+		ReservedFor:      reservation.ReservedFor,
+		GracePeriodUntil: reservation.GracePeriodUntil,
+		TableName:        reservation.Table.Name,
+		Note:             reservation.Note,
+		DepositAmount:    reservation.DepositAmount,
+		DepositStatus:    reservation.DepositStatus,
+		NoShowFee:        reservation.NoShowFee,
+		ModifiableUntil:  until,
+		CanModify:        reservation.Status == "active" && time.Now().Before(until),
+	}
+	if reservation.ConfirmationCode != nil {
+		detail.ConfirmationCode = *reservation.ConfirmationCode
+	}
+	return detail
+}
+
+// isBookingSlot เวลาจองต้องอยู่ในช่วงเวลารับจองของวันนั้นและตรงกับช่วงห่างของเวลาจองตามกฎการจอง
+func isBookingSlot(rule models.ReservationRules, at time.Time) bool {
+	first, errFirst := time.Parse("15:04", rule.FirstSlotTime)
+	last, errLast := time.Parse("15:04", rule.LastSlotTime)
+	if errFirst != nil || errLast != nil || rule.SlotIntervalMinutes <= 0 {
+		return false
+	}
+	at = at.In(time.Local)
+	minutes := at.Hour()*60 + at.Minute()
+	firstMinutes := first.Hour()*60 + first.Minute()
+	lastMinutes := last.Hour()*60 + last.Minute()
+	return at.Second() == 0 && at.Nanosecond() == 0 &&
+		minutes >= firstMinutes && minutes <= lastMinutes &&
+		(minutes-firstMinutes)%rule.SlotIntervalMinutes == 0
+}
+
+// findBookableTable โต๊ะที่จองได้ทันทีซึ่งเหมาะที่สุดสำหรับลูกค้า ถ้าระบุโซนต้องอยู่ในโซนนั้น คืน nil ถ้าไม่มี
+func findBookableTable(tx *gorm.DB, rule models.ReservationRules, dining time.Duration, guestCount int, reservedFor time.Time, zoneID *uint) (*service.TableAllocation, error) {
+	allocations, err := service.AllocateTables(tx, service.AllocationRequest{
+		PartySize: guestCount,
+		At:        reservedFor,
+		Duration:  dining,
+		Lead:      time.Duration(rule.PreReservationMinutes) * time.Minute,
+		ZoneID:    zoneID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range allocations {
+		inZone := zoneID == nil || (allocations[i].ZoneID != nil && *allocations[i].ZoneID == *zoneID)
+		if allocations[i].Bookable() && inZone {
+			return &allocations[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// refreshReservedTable ปรับสถานะโต๊ะให้ตรงกับการจอง ณ ตอนนี้ หลังการจองถูกแก้ไขหรือยกเลิก
+// โต๊ะว่างที่มีการจองกั้นอยู่เป็น reserved และโต๊ะ reserved ที่ไม่มีการจองกั้นแล้วกลับเป็น available
+func refreshReservedTable(tx *gorm.DB, tableID uint, note string) (bool, error) {
+	held, err := service.TableHeldByReservation(tx, tableID, time.Now())
+	if err != nil {
+		return false, err
+	}
+	action, fromStatus, toStatus := models.TableActionRelease, "reserved", "available"
+	if held {
+		action, fromStatus, toStatus = models.TableActionReserve, "available", "reserved"
+	}
+
+	change, err := service.BeginTableChange(tx, action, tableID)
+	if err != nil {
+		return false, err
+	}
+	result := tx.Model(&models.Table{}).Where("id = ? AND status = ?", tableID, fromStatus).Update("status", toStatus)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	change.Entry.Note = note
+	return true, change.Record(tx)
+}
+
+// @Summary ลูกค้าจองโต๊ะเอง
+// @Description จองโต๊ะโดยไม่ต้องเข้าสู่ระบบ ระบบเลือกโต๊ะที่เหมาะที่สุดให้ เวลาจองต้องเป็นเวลาที่ /api/booking/availability เสนอ
+// @Description ได้รหัสยืนยัน (confirmation_code) สำหรับดู แก้ไข หรือยกเลิกการจองคู่กับเบอร์โทรศัพท์ มีการจำกัดจำนวนครั้งต่อ IP
+// @Accept json
+// @Produce json
+// @Param request body models.BookingRequest true "ข้อมูลการจอง"
+// @Success 200 {object} map[string]interface{} "จองสำเร็จ พร้อมรหัสยืนยัน เงินมัดจำ และค่าปรับเมื่อไม่มา"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้องหรือไม่ใช่เวลาที่รับจอง"
+// @Failure 409 {object} map[string]interface{} "ไม่มีโต๊ะว่างในเวลานี้"
+// @Failure 429 {object} map[string]interface{} "เรียกใช้บ่อยเกินไป"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดภายในระบบ"
+// @Router /api/booking [post]
+// @Tags Booking
+func CreateBooking(c *fiber.Ctx) error {
+	var req models.BookingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ข้อมูลการจองไม่ถูกต้อง",
+		})
+	}
+	reservation := ReservationRequest{
+		CustomerName: strings.TrimSpace(req.CustomerName),
+		PhoneNumber:  strings.TrimSpace(req.PhoneNumber),
+		GuestCount:   req.GuestCount,
+		ReservedFor:  req.ReservedFor,
+		Note:         strings.TrimSpace(req.Note),
+		Email:        strings.TrimSpace(req.Email),
+		LineUserID:   strings.TrimSpace(req.LineUserID),
+		Language:     req.Language,
+		Source:       models.ReservationSourceOnline,
+	}
+	if msg := validateReservationRequest(reservation); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	rules, err := activeReservationRules(db.DB)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ไม่สามารถดึงกฎการจองได้",
+		})
+	}
+	if !isBookingSlot(rules, req.ReservedFor) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "เวลานี้ไม่เปิดรับจอง กรุณาเลือกเวลาจากเวลาที่ว่าง",
+		})
+	}
+	dining, err := reservationDiningDuration(db.DB, rules)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ไม่สามารถคำนวณเวลาที่ใช้โต๊ะได้",
+		})
+	}
+
+	allocation, err := findBookableTable(db.DB, rules, dining, req.GuestCount, req.ReservedFor, req.ZoneID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ไม่สามารถค้นหาโต๊ะได้",
+		})
+	}
+	if allocation == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "ไม่มีโต๊ะว่างสำหรับจำนวนลูกค้าในเวลานี้",
+		})
+	}
+	return reserveTable(c, fmt.Sprint(allocation.TableID), reservation)
+}
+
+// @Summary ลูกค้าดูการจองของตัวเอง
+// @Description ดูการจองด้วยรหัสยืนยันและเบอร์โทรศัพท์ที่ใช้จอง ไม่ต้องเข้าสู่ระบบ
+// @Produce json
+// @Param code path string true "รหัสยืนยันการจอง"
+// @Param phone query string true "เบอร์โทรศัพท์ที่ใช้จอง"
+// @Success 200 {object} BookingDetail "การจอง"
+// @Failure 404 {object} map[string]interface{} "ไม่พบการจอง"
+// @Failure 429 {object} map[string]interface{} "เรียกใช้บ่อยเกินไป"
+// @Router /api/booking/{code} [get]
+// @Tags Booking
+func GetBooking(c *fiber.Ctx) error {
+	reservation, err := findBooking(db.DB, c, c.Query("phone"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "ไม่พบการจอง กรุณาตรวจสอบรหัสยืนยันและเบอร์โทรศัพท์",
+		})
+	}
+	rules, err := activeReservationRules(db.DB)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ไม่สามารถดึงกฎการจองได้",
+		})
+	}
+	return c.JSON(newBookingDetail(*reservation, rules))
+}
+
+// @Summary ลูกค้าแก้ไขการจองของตัวเอง
+// @Description เปลี่ยนเวลา จำนวนลูกค้า หรือหมายเหตุ ได้จนถึงเวลาปิดการแก้ไข (cancel_cutoff_minutes ก่อนเวลาจอง)
+// @Description ใช้โต๊ะเดิมถ้ายังรับได้ ไม่เช่นนั้นระบบเลือกโต๊ะใหม่ให้ เงินมัดจำที่ยังไม่ชำระคำนวณใหม่ตามจำนวนลูกค้า
+// @Accept json
+// @Produce json
+// @Param code path string true "รหัสยืนยันการจอง"
+// @Param request body models.UpdateBookingRequest true "ค่าที่ต้องการเปลี่ยน"
+// @Success 200 {object} BookingDetail "การจองหลังแก้ไข"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้องหรือเลยเวลาที่แก้ไขได้"
+// @Failure 404 {object} map[string]interface{} "ไม่พบการจอง"
+// @Failure 409 {object} map[string]interface{} "ไม่มีโต๊ะว่างในเวลาใหม่"
+// @Failure 429 {object} map[string]interface{} "เรียกใช้บ่อยเกินไป"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดภายในระบบ"
+// @Router /api/booking/{code} [put]
+// @Tags Booking
+func UpdateBooking(c *fiber.Ctx) error {
+	var req models.UpdateBookingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ข้อมูลไม่ถูกต้อง",
+		})
+	}
+
+	tx := db.DB.Begin()
+	reservation, err := findBooking(tx, c, req.PhoneNumber)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "ไม่พบการจอง กรุณาตรวจสอบรหัสยืนยันและเบอร์โทรศัพท์",
+		})
+	}
+	rules, err := activeReservationRules(tx)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ไม่สามารถดึงกฎการจองได้",
+		})
+	}
+	if reservation.Status != "active" || !time.Now().Before(bookingModifiableUntil(*reservation, rules)) {
+		tx.Rollback()
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "การจองนี้แก้ไขเองไม่ได้แล้ว กรุณาติดต่อร้าน",
+		})
+	}
+
+	guestCount, reservedFor, note := reservation.GuestCount, reservation.ReservedFor, reservation.Note
+	if req.GuestCount != nil {
+		guestCount = *req.GuestCount
+	}
+	if req.ReservedFor != nil {
+		reservedFor = *req.ReservedFor
+	}
+	if req.Note != nil {
+		note = strings.TrimSpace(*req.Note)
+	}
+	if guestCount <= 0 {
+		tx.Rollback()
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "จำนวนลูกค้าต้องมากกว่า 0"})
+	}
+	if req.ReservedFor != nil && (!reservedFor.After(time.Now()) || !isBookingSlot(rules, reservedFor)) {
+		tx.Rollback()
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "เวลานี้ไม่เปิดรับจอง กรุณาเลือกเวลาจากเวลาที่ว่าง",
+		})
+	}
+
+	dining, err := reservationDiningDuration(tx, rules)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ไม่สามารถคำนวณเวลาที่ใช้โต๊ะได้",
+		})
+	}
+
+	// ใช้โต๊ะเดิมถ้ายังรับได้ ไม่เช่นนั้นหาโต๊ะใหม่ในโซนเดียวกับโต๊ะเดิม
+	oldTableID := reservation.TableID
+	tableID := oldTableID
+	status, _ := checkTableReservable(tx, reservation.Table, guestCount, reservedFor, dining, reservation.ID, rules)
+	if status == fiber.StatusInternalServerError {
+		tx.Rollback()
+		return c.Status(status).JSON(fiber.Map{"error": "ไม่สามารถตรวจสอบการจองได้"})
+	}
+	if status != 0 {
+		allocation, err := findBookableTable(tx, rules, dining, guestCount, reservedFor, reservation.Table.ZoneID)
+		if err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "ไม่สามารถค้นหาโต๊ะได้"})
+		}
+		if allocation == nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "ไม่มีโต๊ะว่างสำหรับจำนวนลูกค้าในเวลานี้",
+			})
+		}
+		tableID = allocation.TableID
+	}
+
+	reservation.GuestCount = guestCount
+	applyReservationDeposit(reservation, rules)
+	blockedFrom, gracePeriodUntil := reservationWindow(rules, reservedFor)
+	if err := tx.Model(&models.TableReservation{}).Where("id = ?", reservation.ID).Updates(map[string]interface{}{
+		"table_id":           tableID,
+		"guest_count":        guestCount,
+		"reserved_for":       reservedFor,
+		"table_blocked_from": blockedFrom,
+		"grace_period_until": gracePeriodUntil,
+		"note":               note,
+		"deposit_amount":     reservation.DepositAmount,
+		"deposit_status":     reservation.DepositStatus,
+	}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "ไม่สามารถแก้ไขการจองได้"})
+	}
+
+	// เวลาหรือโต๊ะเปลี่ยน สถานะ reserved ของโต๊ะเดิมและโต๊ะใหม่อาจต้องเปลี่ยนตาม
+	tableIDs := []uint{oldTableID}
+	if tableID != oldTableID {
+		tableIDs = append(tableIDs, tableID)
+	}
+	for _, id := range tableIDs {
+		if _, err := refreshReservedTable(tx, id, fmt.Sprintf("reservation #%d updated by customer", reservation.ID)); err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "ไม่สามารถอัพเดทสถานะโต๊ะได้"})
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "เกิดข้อผิดพลาดในการบันทึกข้อมูล"})
+	}
+	service.PublishTableChange(service.TableEventReservation, tableIDs...)
+
+	var updated models.TableReservation
+	db.DB.Preload("Table").First(&updated, reservation.ID)
+	return c.JSON(newBookingDetail(updated, rules))
+}
+
+// @Summary ลูกค้ายกเลิกการจองของตัวเอง
+// @Description ยกเลิกการจองด้วยรหัสยืนยันและเบอร์โทรศัพท์ ได้จนถึงเวลาปิดการยกเลิก (cancel_cutoff_minutes ก่อนเวลาจอง)
+// @Description เงินมัดจำที่ชำระแล้วคงสถานะ paid ไว้ให้พนักงานคืนเงิน
+// @Accept json
+// @Produce json
+// @Param code path string true "รหัสยืนยันการจอง"
+// @Param request body models.CancelBookingRequest true "เบอร์โทรศัพท์ที่ใช้จอง"
+// @Success 200 {object} BookingDetail "การจองที่ยกเลิกแล้ว"
+// @Failure 400 {object} map[string]interface{} "เลยเวลาที่ยกเลิกได้"
+// @Failure 404 {object} map[string]interface{} "ไม่พบการจอง"
+// @Failure 429 {object} map[string]interface{} "เรียกใช้บ่อยเกินไป"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดภายในระบบ"
+// @Router /api/booking/{code}/cancel [post]
+// @Tags Booking
+func CancelBooking(c *fiber.Ctx) error {
+	var req models.CancelBookingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ข้อมูลไม่ถูกต้อง",
+		})
+	}
+
+	tx := db.DB.Begin()
+	reservation, err := findBooking(tx, c, req.PhoneNumber)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "ไม่พบการจอง กรุณาตรวจสอบรหัสยืนยันและเบอร์โทรศัพท์",
+		})
+	}
+	rules, err := activeReservationRules(tx)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ไม่สามารถดึงกฎการจองได้",
+		})
+	}
+	if reservation.Status != "active" || !time.Now().Before(bookingModifiableUntil(*reservation, rules)) {
+		tx.Rollback()
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "การจองนี้ยกเลิกเองไม่ได้แล้ว กรุณาติดต่อร้าน",
+		})
+	}
+
+	now := time.Now()
+	reservation.Status = "cancelled"
+	reservation.CancelledAt = &now
+	if reservation.DepositStatus == models.DepositPending {
+		reservation.DepositStatus = models.DepositNone
+	}
+	if err := tx.Model(&models.TableReservation{}).Where("id = ?", reservation.ID).Updates(map[string]interface{}{
+		"status":         reservation.Status,
+		"cancelled_at":   now,
+		"deposit_status": reservation.DepositStatus,
+	}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "ไม่สามารถยกเลิกการจองได้"})
+	}
+
+	if _, err := refreshReservedTable(tx, reservation.TableID, fmt.Sprintf("reservation #%d cancelled by customer", reservation.ID)); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "ไม่สามารถอัพเดทสถานะโต๊ะได้"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "เกิดข้อผิดพลาดในการบันทึกข้อมูล"})
+	}
+	service.PublishTableChange(service.TableEventReservation, reservation.TableID)
+
+	return c.JSON(newBookingDetail(*reservation, rules))
+}
@@ -0,0 +1,264 @@
+package api_handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	utils "food-ordering-api/utility"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservationAvailability(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.Table{}, &models.TableSession{}, &models.TableReservation{}, &models.ReservationRules{})
+
+	app.Get("/api/booking/availability", GetReservationAvailability)
+
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	date := tomorrow.Format("2006-01-02")
+	at := func(hour int) time.Time {
+		return time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), hour, 0, 0, 0, time.Local)
+	}
+	availability := func(query string) AvailabilityResponse {
+		req := httptest.NewRequest("GET", "/api/booking/availability?"+query, nil)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var result AvailabilityResponse
+		json.NewDecoder(resp.Body).Decode(&result)
+		return result
+	}
+	labels := func(day AvailabilityDay) []string {
+		result := []string{}
+		for _, slot := range day.Slots {
+			result = append(result, slot.Label)
+		}
+		return result
+	}
+
+	// รับจอง 11:00-13:00 ทุกชั่วโมง ไม่กั้นโต๊ะล่วงหน้า มีโต๊ะ 4 ที่ตัวเดียวที่จองไว้แล้วตอน 11:00
+	db.DB.Create(&models.ReservationRules{GracePeriodMinutes: 15, FirstSlotTime: "11:00", LastSlotTime: "13:00", SlotIntervalMinutes: 60, IsActive: true})
+	table := models.Table{Name: "T1", Capacity: 4, Status: "available"}
+	db.DB.Create(&table)
+	db.DB.Create(&models.TableReservation{
+		TableID: table.ID, CustomerName: "สมชาย", PhoneNumber: "0812345678", GuestCount: 4, Status: "active",
+		ReservedFor: at(11), TableBlockedFrom: at(11), GracePeriodUntil: at(11).Add(15 * time.Minute),
+	})
+
+	// กรณีทดสอบที่ 1: ยังไม่มีประวัติการใช้โต๊ะ ใช้เวลานั่ง 90 นาที ลูกค้าที่จอง 11:00 ใช้โต๊ะถึง 12:30
+	t.Run("Success - Default dining duration", func(t *testing.T) {
+		result := availability("party_size=4&from=" + date)
+		assert.Equal(t, 90, result.DiningMinutes)
+		assert.Len(t, result.Days, 1)
+		assert.Equal(t, date, result.Days[0].Date)
+		assert.Equal(t, []string{"13:00"}, labels(result.Days[0]))
+		assert.Equal(t, 1, result.Days[0].Slots[0].AvailableTables)
+	})
+
+	// กรณีทดสอบที่ 2: ลูกค้านั่งเฉลี่ย 60 นาที โต๊ะว่างตั้งแต่ 12:00
+	t.Run("Success - Average dining duration from sessions", func(t *testing.T) {
+		opened := time.Now().AddDate(0, 0, -1)
+		for i := 0; i < 10; i++ {
+			closed := opened.Add(time.Hour)
+			db.DB.Create(&models.TableSession{
+				Token: fmt.Sprintf("closed-%d", i), TableID: table.ID, Status: models.SessionClosed,
+				OpenedAt: opened, ClosedAt: &closed,
+			})
+		}
+
+		result := availability("party_size=4&from=" + date)
+		assert.Equal(t, 60, result.DiningMinutes)
+		assert.Equal(t, []string{"12:00", "13:00"}, labels(result.Days[0]))
+	})
+
+	// กรณีทดสอบที่ 3: ลูกค้ามากกว่าที่นั่งไม่มีเวลาว่าง และช่วงวันที่ที่ไม่ถูกต้อง
+	t.Run("Failure - No capacity and invalid range", func(t *testing.T) {
+		result := availability(fmt.Sprintf("party_size=10&from=%s&to=%s", date, tomorrow.AddDate(0, 0, 1).Format("2006-01-02")))
+		assert.Len(t, result.Days, 2)
+		for _, day := range result.Days {
+			assert.Empty(t, day.Slots)
+		}
+
+		for _, query := range []string{
+			"party_size=0",
+			"party_size=2&from=" + date + "&to=" + tomorrow.AddDate(0, 0, 20).Format("2006-01-02"),
+			"party_size=2&from=tomorrow",
+		} {
+			resp, err := app.Test(httptest.NewRequest("GET", "/api/booking/availability?"+query, nil))
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+		}
+	})
+}
+
+func TestSelfBooking(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.Table{}, &models.TableZone{}, &models.TableSession{}, &models.TableReservation{},
+		&models.ReservationRules{}, &models.TableHistory{}, &models.ReservationNotification{})
+
+	app.Post("/api/booking", CreateBooking)
+	app.Get("/api/booking/:code", GetBooking)
+	app.Put("/api/booking/:code", UpdateBooking)
+	app.Post("/api/booking/:code/cancel", CancelBooking)
+
+	decode := func(resp *http.Response) BookingDetail {
+		var detail BookingDetail
+		json.NewDecoder(resp.Body).Decode(&detail)
+		return detail
+	}
+
+	db.DB.Create(&models.ReservationRules{
+		GracePeriodMinutes: 15, PreReservationMinutes: 30, FirstSlotTime: "11:00", LastSlotTime: "21:00",
+		SlotIntervalMinutes: 30, DiningDurationMinutes: 90, DepositPerGuest: 100, NoShowFee: 200,
+		CancelCutoffMinutes: 120, IsActive: true,
+	})
+	small := models.Table{Name: "A1", Capacity: 2, Status: "available"}
+	large := models.Table{Name: "B1", Capacity: 4, Status: "available"}
+	db.DB.Create(&small)
+	db.DB.Create(&large)
+
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	at := func(hour, minute int) time.Time {
+		return time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), hour, minute, 0, 0, time.Local)
+	}
+	var code string
+
+	// กรณีทดสอบที่ 1: จองเองได้รหัสยืนยัน โต๊ะที่พอดี เงินมัดจำ และค่าปรับตามกฎการจอง
+	t.Run("Success - Create booking", func(t *testing.T) {
+		resp := sendJSON(t, app, "POST", "/api/booking", models.BookingRequest{
+			CustomerName: "สมชาย", PhoneNumber: "081-234-5678", GuestCount: 2, ReservedFor: at(18, 0), Note: "ขอโต๊ะริมหน้าต่าง",
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		code = result["confirmation_code"].(string)
+		assert.Len(t, code, reservationCodeLength)
+		assert.Equal(t, float64(small.ID), result["table_id"])
+		assert.Equal(t, float64(200), result["deposit_amount"])
+		assert.Equal(t, models.DepositPending, result["deposit_status"])
+		assert.Equal(t, float64(200), result["no_show_fee"])
+
+		var reservation models.TableReservation
+		db.DB.Where("confirmation_code = ?", code).First(&reservation)
+		assert.Equal(t, models.ReservationSourceOnline, reservation.Source)
+
+		// เวลาที่ไม่ตรงช่วงรับจอง เวลาที่ผ่านมาแล้ว และจำนวนลูกค้าที่ไม่มีโต๊ะรับได้
+		for _, req := range []models.BookingRequest{
+			{CustomerName: "สมหญิง", PhoneNumber: "0899999999", GuestCount: 2, ReservedFor: at(18, 10)},
+			{CustomerName: "สมหญิง", PhoneNumber: "0899999999", GuestCount: 2, ReservedFor: at(23, 0)},
+			{CustomerName: "สมหญิง", PhoneNumber: "0899999999", GuestCount: 2, ReservedFor: time.Now().AddDate(0, 0, -1)},
+		} {
+			assert.Equal(t, http.StatusBadRequest, sendJSON(t, app, "POST", "/api/booking", req).StatusCode)
+		}
+		resp = sendJSON(t, app, "POST", "/api/booking", models.BookingRequest{CustomerName: "สมหญิง", PhoneNumber: "0899999999", GuestCount: 8, ReservedFor: at(18, 0)})
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 2: ดูการจองด้วยรหัสและเบอร์โทรที่พิมพ์ต่างรูปแบบได้ เบอร์ผิดถือว่าไม่พบ
+	t.Run("Success - View booking with phone number", func(t *testing.T) {
+		resp := sendJSON(t, app, "GET", "/api/booking/"+code+"?phone="+url.QueryEscape("+66812345678"), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		detail := decode(resp)
+		assert.Equal(t, "A1", detail.TableName)
+		assert.Equal(t, "ขอโต๊ะริมหน้าต่าง", detail.Note)
+		assert.True(t, detail.CanModify)
+		assert.True(t, detail.ModifiableUntil.Equal(at(16, 0)))
+
+		resp = sendJSON(t, app, "GET", "/api/booking/"+code+"?phone=0800000000", nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 3: เพิ่มจำนวนลูกค้าจนโต๊ะเดิมไม่พอ ย้ายไปโต๊ะใหม่และคำนวณมัดจำใหม่
+	t.Run("Success - Modify booking", func(t *testing.T) {
+		guests := 4
+		resp := sendJSON(t, app, "PUT", "/api/booking/"+code, models.UpdateBookingRequest{PhoneNumber: "0812345678", GuestCount: &guests})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		detail := decode(resp)
+		assert.Equal(t, "B1", detail.TableName)
+		assert.Equal(t, float64(400), detail.DepositAmount)
+
+		later := at(19, 30)
+		resp = sendJSON(t, app, "PUT", "/api/booking/"+code, models.UpdateBookingRequest{PhoneNumber: "0812345678", ReservedFor: &later})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.True(t, decode(resp).ReservedFor.Equal(later))
+
+		var reservation models.TableReservation
+		db.DB.Where("confirmation_code = ?", code).First(&reservation)
+		assert.Equal(t, large.ID, reservation.TableID)
+		assert.True(t, reservation.TableBlockedFrom.Equal(at(19, 0)))
+	})
+
+	// กรณีทดสอบที่ 4: ยกเลิกเองได้ก่อนเวลาปิดการยกเลิก ยกเลิกซ้ำหรือใกล้เวลาจองเกินไปไม่ได้
+	t.Run("Success - Cancel booking", func(t *testing.T) {
+		resp := sendJSON(t, app, "POST", "/api/booking/"+code+"/cancel", models.CancelBookingRequest{PhoneNumber: "0812345678"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		detail := decode(resp)
+		assert.Equal(t, "cancelled", detail.Status)
+		assert.Equal(t, models.DepositNone, detail.DepositStatus)
+		assert.False(t, detail.CanModify)
+
+		resp = sendJSON(t, app, "POST", "/api/booking/"+code+"/cancel", models.CancelBookingRequest{PhoneNumber: "0812345678"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		soon := "SOONCODE"
+		reservedFor := time.Now().Add(time.Hour)
+		db.DB.Create(&models.TableReservation{
+			TableID: small.ID, ConfirmationCode: &soon, CustomerName: "สมศรี", PhoneNumber: "0877777777", GuestCount: 2,
+			Status: "active", ReservedFor: reservedFor, TableBlockedFrom: reservedFor.Add(-30 * time.Minute), GracePeriodUntil: reservedFor.Add(15 * time.Minute),
+		})
+		resp = sendJSON(t, app, "POST", "/api/booking/soonCode/cancel", models.CancelBookingRequest{PhoneNumber: "0877777777"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 5: จองในโซนที่เลือก แล้วเพิ่มจำนวนลูกค้าจนต้องย้ายโต๊ะ ยังได้โต๊ะในโซนเดิม
+	t.Run("Success - Modify booking keeps zone", func(t *testing.T) {
+		terrace := models.TableZone{Name: "ระเบียง"}
+		db.DB.Create(&terrace)
+		terraceSmall := models.Table{Name: "P1", Capacity: 2, Status: "available", ZoneID: &terrace.ID}
+		terraceLarge := models.Table{Name: "P2", Capacity: 4, Status: "available", ZoneID: &terrace.ID}
+		db.DB.Create(&terraceSmall)
+		db.DB.Create(&terraceLarge)
+
+		resp := sendJSON(t, app, "POST", "/api/booking", models.BookingRequest{
+			CustomerName: "สมปอง", PhoneNumber: "0866666666", GuestCount: 2, ReservedFor: at(12, 0), ZoneID: &terrace.ID,
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		assert.Equal(t, float64(terraceSmall.ID), result["table_id"])
+
+		guests := 4
+		resp = sendJSON(t, app, "PUT", "/api/booking/"+result["confirmation_code"].(string), models.UpdateBookingRequest{PhoneNumber: "0866666666", GuestCount: &guests})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "P2", decode(resp).TableName)
+	})
+
+	// กรณีทดสอบที่ 6: จำกัดจำนวนครั้งต่อ IP เกินแล้วได้ 429
+	t.Run("Failure - Rate limited", func(t *testing.T) {
+		limited := fiber.New()
+		limited.Get("/api/booking/:code", limiter.New(utils.BookingActionRateLimit), GetBooking)
+		for i := 0; i < utils.BookingActionRateLimit.Max; i++ {
+			resp, err := limited.Test(httptest.NewRequest("GET", "/api/booking/NOPE?phone=0800000000", nil))
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusNotFound, resp.StatusCode, i)
+		}
+
+		resp, err := limited.Test(httptest.NewRequest("GET", "/api/booking/NOPE?phone=0800000000", nil))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		assert.NotEmpty(t, result["error"])
+	})
+}
@@ -7,10 +7,11 @@ import (
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 // @Summary สร้างหมวดหมู่ใหม่
-// @Description ฟังก์ชันนี้ใช้สำหรับสร้างหมวดหมู่ใหม่ โดยต้องระบุข้อมูลชื่อหมวดหมู่
+// @Description ฟังก์ชันนี้ใช้สำหรับสร้างหมวดหมู่ใหม่ โดยต้องระบุข้อมูลชื่อหมวดหมู่ ระบุ parent_id เพื่อสร้างเป็นหมวดหมู่ย่อย
 // @Accept json
 // @Produce json
 // @Security BearerAuth
@@ -23,31 +24,66 @@ import (
 // @Router /api/categories [post]
 // @Tags categories
 func CreateCategoryHandler(c *fiber.Ctx) error {
-	var category models.Category
+	var req models.CreateCategoryRequest
 
-	if err := c.BodyParser(&category); err != nil {
+	if err := c.BodyParser(&req); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(map[string]interface{}{
 			"error": "Invalid input",
 		})
 	}
 
-	if category.Name == "" { //if menuItem.Name == "" || menuItem.CategoryID == 0 {
+	if req.Name == "" { //if menuItem.Name == "" || menuItem.CategoryID == 0 {
 		return c.Status(http.StatusBadRequest).JSON(map[string]interface{}{
 			"error": "Name  are required",
 		})
 	}
 
+	var category models.Category
 	// db.DB.First(&category, "Name = ?", category.Name)
-	if err := db.DB.First(&category, "Name = ?", category.Name).Error; err == nil {
+	if err := db.DB.First(&category, "Name = ?", req.Name).Error; err == nil {
 		return c.Status(http.StatusConflict).JSON(fiber.Map{
 			"error": "Category name already exists",
 		})
 	}
+
+	if req.ParentID != nil && *req.ParentID != 0 {
+		if err := validateCategoryParent(db.DB, 0, *req.ParentID); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	} else {
+		req.ParentID = nil
+	}
+
+	showOnQR := req.ShowOnQR == nil || *req.ShowOnQR
+	showOnPOS := req.ShowOnPOS == nil || *req.ShowOnPOS
+	category = models.Category{
+		Name:      req.Name,
+		NameEn:    req.NameEn,
+		NameCh:    req.NameCh,
+		ParentID:  req.ParentID,
+		SortOrder: req.SortOrder,
+		Icon:      req.Icon,
+		ShowOnQR:  showOnQR,
+		ShowOnPOS: showOnPOS,
+	}
 	if err := db.DB.Create(&category).Error; err != nil {
 		return c.Status(500).JSON(map[string]interface{}{
 			"error": fmt.Sprintf("Error creating category: %v", err),
 		})
 	}
+	// default:true ทำให้ GORM ข้ามค่า false ตอน Create จึงต้องบันทึกซ้ำ
+	if !showOnQR || !showOnPOS {
+		if err := db.DB.Model(&category).Updates(map[string]interface{}{
+			"show_on_qr":  showOnQR,
+			"show_on_pos": showOnPOS,
+		}).Error; err != nil {
+			return c.Status(500).JSON(map[string]interface{}{
+				"error": fmt.Sprintf("Error creating category: %v", err),
+			})
+		}
+	}
 
 	return c.Status(http.StatusOK).JSON(category)
 }
@@ -100,6 +136,15 @@ func Delete_categoryHandler(c *fiber.Ctx) error {
 		})
 	}
 
+	// หมวดหมู่ย่อยย้ายขึ้นไปอยู่ใต้หมวดหมู่แม่ของหมวดหมู่ที่ลบ
+	if err := tx.Model(&models.Category{}).Where("parent_id = ?", existingCategory.ID).
+		Update("parent_id", existingCategory.ParentID).Error; err != nil {
+		tx.Rollback()
+		return c.Status(http.StatusInternalServerError).JSON(map[string]interface{}{
+			"error": "Failed to move subcategories",
+		})
+	}
+
 	// ลบหมวดหมู่ (soft delete)
 	if err := tx.Delete(&existingCategory).Error; err != nil {
 		tx.Rollback()
@@ -120,13 +165,24 @@ func Delete_categoryHandler(c *fiber.Ctx) error {
 }
 
 type updateCat struct {
-	Name   string `json:"name,omitempty"`
-	NameEn string `json:"nameEn,omitempty"`
-	NameCh string `json:"nameCh,omitempty"`
+	Name      string  `json:"name,omitempty"`
+	NameEn    string  `json:"nameEn,omitempty"`
+	NameCh    string  `json:"nameCh,omitempty"`
+	ParentID  *uint   `json:"parent_id,omitempty"` // 0 = ย้ายไประดับบนสุด
+	SortOrder *int    `json:"sort_order,omitempty"`
+	Icon      *string `json:"icon,omitempty"`
+	ShowOnQR  *bool   `json:"show_on_qr,omitempty"`
+	ShowOnPOS *bool   `json:"show_on_pos,omitempty"`
+}
+
+// hasChanges ตรวจว่ามีฟิลด์ที่จะอัปเดตอย่างน้อย 1 ฟิลด์
+func (u updateCat) hasChanges() bool {
+	return u.Name != "" || u.NameEn != "" || u.NameCh != "" || u.ParentID != nil ||
+		u.SortOrder != nil || u.Icon != nil || u.ShowOnQR != nil || u.ShowOnPOS != nil
 }
 
 // @Summary อัพเดตชื่อหมวดหมู่
-// @Description ฟังก์ชันนี้ใช้สำหรับแก้ไขชื่อของหมวดหมู่ที่มีอยู่แล้ว โดยระบุ ID และชื่อใหม่
+// @Description ฟังก์ชันนี้ใช้สำหรับแก้ไขชื่อ หมวดหมู่แม่ ลำดับ ไอคอน และการแสดงในแต่ละช่องทางของหมวดหมู่ที่มีอยู่แล้ว ส่ง parent_id = 0 เพื่อย้ายไประดับบนสุด
 // @Accept json
 // @Produce json
 // @Security BearerAuth
@@ -159,9 +215,9 @@ func UpdateCategoryHandler(c *fiber.Ctx) error {
 	}
 
 	// ตรวจสอบว่ามีการส่งข้อมูลที่จะอัปเดตมาอย่างน้อย 1 ฟิลด์
-	if updatedCategory.Name == "" && updatedCategory.NameEn == "" && updatedCategory.NameCh == "" {
+	if !updatedCategory.hasChanges() {
 		return c.Status(http.StatusBadRequest).JSON(map[string]interface{}{
-			"error": "At least one field (name, nameEn, or nameCh) is required for update",
+			"error": "At least one field (name, nameEn, nameCh, parent_id, sort_order, icon, show_on_qr or show_on_pos) is required for update",
 		})
 	}
 
@@ -204,6 +260,31 @@ func UpdateCategoryHandler(c *fiber.Ctx) error {
 		existingCategory.NameCh = updatedCategory.NameCh
 	}
 
+	if updatedCategory.ParentID != nil {
+		if *updatedCategory.ParentID == 0 {
+			existingCategory.ParentID = nil
+		} else {
+			if err := validateCategoryParent(db.DB, existingCategory.ID, *updatedCategory.ParentID); err != nil {
+				return c.Status(http.StatusBadRequest).JSON(map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+			existingCategory.ParentID = updatedCategory.ParentID
+		}
+	}
+	if updatedCategory.SortOrder != nil {
+		existingCategory.SortOrder = *updatedCategory.SortOrder
+	}
+	if updatedCategory.Icon != nil {
+		existingCategory.Icon = *updatedCategory.Icon
+	}
+	if updatedCategory.ShowOnQR != nil {
+		existingCategory.ShowOnQR = *updatedCategory.ShowOnQR
+	}
+	if updatedCategory.ShowOnPOS != nil {
+		existingCategory.ShowOnPOS = *updatedCategory.ShowOnPOS
+	}
+
 	// อัปเดตข้อมูลในฐานข้อมูล
 	if err := db.DB.Save(&existingCategory).Error; err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(map[string]interface{}{
@@ -215,23 +296,54 @@ func UpdateCategoryHandler(c *fiber.Ctx) error {
 }
 
 // @Summary เรียกรายการหมวดหมู่ทั้งหมด
-// @Description ฟังก์ชันนี้ใช้สำหรับเรียกข้อมูลหมวดหมู่ทั้งหมดที่มีอยู่ในระบบ
+// @Description ฟังก์ชันนี้ใช้สำหรับเรียกข้อมูลหมวดหมู่ทั้งหมดที่มีอยู่ในระบบ เรียงตาม sort_order โดยหมวดหมู่ย่อยอยู่ต่อจากหมวดหมู่แม่
+// @Description ส่ง tree=true เพื่อรับเป็นต้นไม้ (หมวดหมู่ย่อยอยู่ใน children) และ channel เพื่อตัดหมวดหมู่ที่ซ่อนในช่องทางนั้น
 // @Produce json
 // @Security BearerAuth
+// @Param channel query string false "ช่องทางที่แสดง" Enums(qr, pos)
+// @Param tree query boolean false "ตอบเป็นต้นไม้"
 // @Success 200 {array} models.Category "รายการหมวดหมู่ทั้งหมด"
+// @Failure 400 {object} map[string]interface{} "channel ไม่ถูกต้อง"
 // @Failure 401 {object} map[string]interface{} "ไม่ได้รับอนุญาต (Unauthorized)"
 // @Failure 403 {object} map[string]interface{} "ไม่มีสิทธิ์เข้าถึง (Forbidden)"
 // @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูลหมวดหมู่"
 // @Router /api/categories [get]
 // @Tags categories
 func GetCategoriesHandler(c *fiber.Ctx) error {
-	var categories []models.Category
+	channel := c.Query("channel")
+	if channel != "" && channel != models.ChannelQR && channel != models.ChannelPOS {
+		return c.Status(http.StatusBadRequest).JSON(map[string]interface{}{
+			"error": "channel must be qr or pos",
+		})
+	}
+
 	// ค้นหาทุก Category
-	if err := db.DB.Find(&categories).Error; err != nil {
+	tree, err := loadCategoryTree(db.DB)
+	if err != nil {
 		return c.Status(500).JSON(map[string]interface{}{
 			"error": fmt.Sprintf("Error fetching categories: %v", err),
 		})
 	}
+
+	var hidden map[uint]bool
+	if channel != "" {
+		hidden = tree.hidden(channel)
+	}
+
+	l := newLocalizer(c)
+	categories := []models.Category{}
+	tree.walk(0, func(id uint) {
+		if hidden[id] {
+			return
+		}
+		category := tree.byID[id]
+		l.category(&category)
+		categories = append(categories, category)
+	})
+
+	if c.QueryBool("tree") {
+		return c.JSON(tree.nest(categories))
+	}
 	// ส่งรายการ categories กลับในรูปแบบ JSON
 	return c.JSON(categories)
 }
@@ -355,3 +467,168 @@ func Get_Delete_Cat(c *fiber.Ctx) error {
 
 	return c.JSON(deleteCat)
 }
+
+// categoryTree หมวดหมู่ทั้งหมดในรูปต้นไม้ ใช้หาลำดับการแสดง การซ่อนตามช่องทาง และเครื่องพิมพ์ที่สืบทอดจากหมวดหมู่แม่
+type categoryTree struct {
+	byID     map[uint]models.Category
+	children map[uint][]uint // key 0 = หมวดหมู่ระดับบนสุด
+	printers map[uint][]uint // เครื่องพิมพ์ที่กำหนดให้หมวดหมู่โดยตรง
+}
+
+func loadCategoryTree(tx *gorm.DB) (*categoryTree, error) {
+	var categories []models.Category
+	if err := tx.Order("sort_order, id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	tree := &categoryTree{
+		byID:     make(map[uint]models.Category, len(categories)),
+		children: map[uint][]uint{},
+		printers: map[uint][]uint{},
+	}
+	for _, category := range categories {
+		tree.byID[category.ID] = category
+	}
+	for _, category := range categories {
+		parent := uint(0)
+		// หมวดหมู่แม่ที่ถูกลบหรือไม่มีอยู่ ให้ถือเป็นระดับบนสุด
+		if category.ParentID != nil && *category.ParentID != category.ID {
+			if _, ok := tree.byID[*category.ParentID]; ok {
+				parent = *category.ParentID
+			}
+		}
+		tree.children[parent] = append(tree.children[parent], category.ID)
+	}
+
+	var links []struct {
+		PrinterID  uint
+		CategoryID uint
+	}
+	if err := tx.Table("printer_categories").Select("printer_id, category_id").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		tree.printers[link.CategoryID] = append(tree.printers[link.CategoryID], link.PrinterID)
+	}
+	return tree, nil
+}
+
+// walk ไล่หมวดหมู่แบบ depth-first ตามลำดับการแสดง (หมวดหมู่แม่ก่อนหมวดหมู่ย่อย)
+func (t *categoryTree) walk(from uint, visit func(id uint)) {
+	visited := map[uint]bool{}
+	var walk func(id uint)
+	walk = func(id uint) {
+		for _, child := range t.children[id] {
+			if visited[child] {
+				continue
+			}
+			visited[child] = true
+			visit(child)
+			walk(child)
+		}
+	}
+	walk(from)
+}
+
+// rank ลำดับการแสดงของทุกหมวดหมู่ (หมวดหมู่ย่อยอยู่ต่อจากหมวดหมู่แม่)
+func (t *categoryTree) rank() map[uint]int {
+	ranks := make(map[uint]int, len(t.byID))
+	t.walk(0, func(id uint) { ranks[id] = len(ranks) })
+	return ranks
+}
+
+// descendants หมวดหมู่นั้นและหมวดหมู่ย่อยทุกชั้น
+func (t *categoryTree) descendants(id uint) []uint {
+	ids := []uint{id}
+	t.walk(id, func(child uint) { ids = append(ids, child) })
+	return ids
+}
+
+// hidden หมวดหมู่ที่ไม่แสดงในช่องทางนั้น หมวดหมู่ที่ซ่อนจะซ่อนหมวดหมู่ย่อยทั้งหมดด้วย
+func (t *categoryTree) hidden(channel string) map[uint]bool {
+	hidden := map[uint]bool{}
+	for id, category := range t.byID {
+		visible := category.ShowOnQR
+		if channel == models.ChannelPOS {
+			visible = category.ShowOnPOS
+		}
+		if !visible {
+			for _, child := range t.descendants(id) {
+				hidden[child] = true
+			}
+		}
+	}
+	return hidden
+}
+
+// printerIDs เครื่องพิมพ์ของหมวดหมู่ ถ้าหมวดหมู่ไม่ได้กำหนดเครื่องพิมพ์เองจะใช้ของหมวดหมู่แม่ที่ใกล้ที่สุด
+func (t *categoryTree) printerIDs(categoryID uint) []uint {
+	seen := map[uint]bool{}
+	for id := categoryID; id != 0 && !seen[id]; {
+		seen[id] = true
+		if printers := t.printers[id]; len(printers) > 0 {
+			return printers
+		}
+		category, ok := t.byID[id]
+		if !ok || category.ParentID == nil {
+			break
+		}
+		id = *category.ParentID
+	}
+	return nil
+}
+
+// printerCategoryIDs หมวดหมู่ทั้งหมดที่พิมพ์ไปเครื่องพิมพ์นี้ รวมหมวดหมู่ย่อยที่สืบทอดเครื่องพิมพ์มา
+func (t *categoryTree) printerCategoryIDs(printerID uint) map[uint]bool {
+	ids := map[uint]bool{}
+	for id := range t.byID {
+		for _, p := range t.printerIDs(id) {
+			if p == printerID {
+				ids[id] = true
+			}
+		}
+	}
+	return ids
+}
+
+// nest สร้างต้นไม้ของหมวดหมู่ที่ระบุ (ใช้ตอบ ?tree=true)
+func (t *categoryTree) nest(categories []models.Category) []models.Category {
+	byID := make(map[uint]models.Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	var build func(parent uint) []models.Category
+	build = func(parent uint) []models.Category {
+		var result []models.Category
+		for _, id := range t.children[parent] {
+			category, ok := byID[id]
+			if !ok {
+				continue
+			}
+			category.Children = build(id)
+			result = append(result, category)
+		}
+		return result
+	}
+	return build(0)
+}
+
+// validateCategoryParent ตรวจว่าหมวดหมู่แม่มีอยู่จริงและไม่ทำให้เกิดวงวน (เป็นตัวเองหรือหมวดหมู่ย่อยของตัวเอง)
+func validateCategoryParent(tx *gorm.DB, categoryID uint, parentID uint) error {
+	tree, err := loadCategoryTree(tx)
+	if err != nil {
+		return err
+	}
+	if _, ok := tree.byID[parentID]; !ok {
+		return fmt.Errorf("parent category ID %d not found", parentID)
+	}
+	if categoryID != 0 {
+		for _, id := range tree.descendants(categoryID) {
+			if id == parentID {
+				return fmt.Errorf("category cannot be moved under itself or its subcategory")
+			}
+		}
+	}
+	return nil
+}
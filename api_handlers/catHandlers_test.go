@@ -0,0 +1,136 @@
+package api_handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategoryHierarchy(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.Translation{}, &models.Printer{})
+
+	app.Post("/api/categories", CreateCategoryHandler)
+	app.Get("/api/categories", GetCategoriesHandler)
+	app.Put("/api/categories/:id", UpdateCategoryHandler)
+	app.Get("/api/menu/ActiveMenu", GetActiveMenu)
+	app.Get("/api/menu", GetMenuByCategory)
+
+	create := func(req models.CreateCategoryRequest) models.Category {
+		resp := sendJSON(t, app, "POST", "/api/categories", req)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var category models.Category
+		json.NewDecoder(resp.Body).Decode(&category)
+		return category
+	}
+
+	hidden := false
+	drinks := create(models.CreateCategoryRequest{Name: "เครื่องดื่ม", SortOrder: 1, Icon: "cup"})
+	food := create(models.CreateCategoryRequest{Name: "อาหาร", SortOrder: 0})
+	coffee := create(models.CreateCategoryRequest{Name: "กาแฟ", ParentID: &drinks.ID, SortOrder: 1})
+	tea := create(models.CreateCategoryRequest{Name: "ชา", ParentID: &drinks.ID, SortOrder: 0})
+	staff := create(models.CreateCategoryRequest{Name: "อาหารพนักงาน", ParentID: &food.ID, ShowOnQR: &hidden})
+
+	latte := models.MenuItem{Name: "ลาเต้", CategoryID: coffee.ID, Price: 60}
+	greenTea := models.MenuItem{Name: "ชาเขียว", CategoryID: tea.ID, Price: 50}
+	staffMeal := models.MenuItem{Name: "ข้าวพนักงาน", CategoryID: staff.ID, Price: 0}
+	soup := models.MenuItem{Name: "ต้มยำ", CategoryID: food.ID, Price: 120}
+	for _, item := range []*models.MenuItem{&latte, &greenTea, &staffMeal, &soup} {
+		db.DB.Create(item)
+	}
+
+	menuNames := func(url string) []string {
+		resp := sendJSON(t, app, "GET", url, nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var items []models.MenuItem
+		json.NewDecoder(resp.Body).Decode(&items)
+		names := []string{}
+		for _, item := range items {
+			names = append(names, item.Name)
+		}
+		return names
+	}
+
+	// กรณีทดสอบที่ 1: หมวดหมู่เรียงตาม sort_order และหมวดหมู่ย่อยอยู่ต่อจากหมวดหมู่แม่
+	t.Run("Success - Ordered categories and tree", func(t *testing.T) {
+		assert.False(t, staff.ShowOnQR)
+		assert.True(t, staff.ShowOnPOS)
+		assert.Equal(t, "cup", drinks.Icon)
+
+		resp := sendJSON(t, app, "GET", "/api/categories", nil)
+		var categories []models.Category
+		json.NewDecoder(resp.Body).Decode(&categories)
+		names := []string{}
+		for _, category := range categories {
+			names = append(names, category.Name)
+		}
+		assert.Equal(t, []string{"Default Category", "อาหาร", "อาหารพนักงาน", "เครื่องดื่ม", "ชา", "กาแฟ"}, names)
+
+		resp = sendJSON(t, app, "GET", "/api/categories?tree=true&channel=qr", nil)
+		var tree []models.Category
+		json.NewDecoder(resp.Body).Decode(&tree)
+		assert.Len(t, tree, 3)
+		assert.Empty(t, tree[1].Children) // อาหารพนักงานซ่อนในเมนูลูกค้า
+		assert.Len(t, tree[2].Children, 2)
+		assert.Equal(t, "ชา", tree[2].Children[0].Name)
+	})
+
+	// กรณีทดสอบที่ 2: เมนูลูกค้าไม่แสดงหมวดหมู่ที่ซ่อน แต่ POS แสดง และเรียงตามหมวดหมู่
+	t.Run("Success - Channel visibility in active menu", func(t *testing.T) {
+		assert.Equal(t, []string{"ต้มยำ", "ชาเขียว", "ลาเต้"}, menuNames("/api/menu/ActiveMenu"))
+		assert.Equal(t, []string{"ต้มยำ", "ข้าวพนักงาน", "ชาเขียว", "ลาเต้"}, menuNames("/api/menu/ActiveMenu?channel=pos"))
+
+		resp := sendJSON(t, app, "GET", "/api/menu/ActiveMenu?channel=kiosk", nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 3: เมนูตามหมวดหมู่รวมหมวดหมู่ย่อย และซ่อนหมวดหมู่แม่จะซ่อนหมวดหมู่ย่อยด้วย
+	t.Run("Success - Menu by category includes subcategories", func(t *testing.T) {
+		assert.Equal(t, []string{"ชาเขียว", "ลาเต้"}, menuNames(fmt.Sprintf("/api/menu?category_id=%d", drinks.ID)))
+
+		resp := sendJSON(t, app, "PUT", fmt.Sprintf("/api/categories/%d", drinks.ID), map[string]interface{}{"show_on_qr": false})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, menuNames(fmt.Sprintf("/api/menu?category_id=%d&channel=qr", drinks.ID)))
+		assert.Equal(t, []string{"ต้มยำ"}, menuNames("/api/menu/ActiveMenu"))
+	})
+
+	// กรณีทดสอบที่ 4: ย้ายหมวดหมู่ไปอยู่ใต้หมวดหมู่ย่อยของตัวเองไม่ได้
+	t.Run("Failure - Category cycle", func(t *testing.T) {
+		resp := sendJSON(t, app, "PUT", fmt.Sprintf("/api/categories/%d", drinks.ID), map[string]interface{}{"parent_id": coffee.ID})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = sendJSON(t, app, "PUT", fmt.Sprintf("/api/categories/%d", coffee.ID), map[string]interface{}{"parent_id": 0})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var moved models.Category
+		json.NewDecoder(resp.Body).Decode(&moved)
+		assert.Nil(t, moved.ParentID)
+	})
+
+	// กรณีทดสอบที่ 5: หมวดหมู่ย่อยใช้เครื่องพิมพ์ของหมวดหมู่แม่ เว้นแต่กำหนดเอง
+	t.Run("Success - Printer inheritance", func(t *testing.T) {
+		bar := models.Printer{Name: "bar", Type: "network"}
+		kitchen := models.Printer{Name: "kitchen", Type: "network"}
+		db.DB.Create(&bar)
+		db.DB.Create(&kitchen)
+		db.DB.Exec("INSERT INTO printer_categories (printer_id, category_id) VALUES (?, ?)", bar.ID, drinks.ID)
+		db.DB.Exec("INSERT INTO printer_categories (printer_id, category_id) VALUES (?, ?)", kitchen.ID, food.ID)
+		db.DB.Exec("INSERT INTO printer_categories (printer_id, category_id) VALUES (?, ?)", kitchen.ID, staff.ID)
+
+		tree, err := loadCategoryTree(db.DB)
+		assert.Nil(t, err)
+		assert.Equal(t, []uint{bar.ID}, tree.printerIDs(tea.ID))
+		assert.Equal(t, []uint{kitchen.ID}, tree.printerIDs(staff.ID))
+		assert.Nil(t, tree.printerIDs(coffee.ID)) // ย้ายไประดับบนสุดแล้ว
+
+		barCategories := tree.printerCategoryIDs(bar.ID)
+		assert.True(t, barCategories[tea.ID])
+		assert.False(t, barCategories[coffee.ID])
+	})
+}
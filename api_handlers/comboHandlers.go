@@ -0,0 +1,513 @@
+package api_handlers
+
+import (
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// preloadCombo ดึงชุดพร้อมช่อง (เรียงตาม sort_order) และเมนูของแต่ละตัวเลือก
+func preloadCombo(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("Slots", func(tx *gorm.DB) *gorm.DB { return tx.Order("sort_order, id") }).
+		Preload("Slots.Choices", func(tx *gorm.DB) *gorm.DB { return tx.Order("is_default DESC, id") }).
+		Preload("Slots.Choices.MenuItem")
+}
+
+// getComboFromParam ดึงชุดจาก :id
+func getComboFromParam(c *fiber.Ctx) (*models.Combo, error) {
+	comboID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return nil, c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid combo ID format",
+		})
+	}
+
+	var combo models.Combo
+	if err := preloadCombo(db.DB).First(&combo, comboID).Error; err != nil {
+		return nil, c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Combo not found",
+		})
+	}
+	return &combo, nil
+}
+
+// buildComboSlots ตรวจและแปลงช่องจาก request เป็นโมเดล (ยังไม่บันทึก)
+func buildComboSlots(tx *gorm.DB, req models.ComboRequest) ([]models.ComboSlot, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("combo name is required")
+	}
+	if req.Price < 0 {
+		return nil, fmt.Errorf("combo price cannot be negative")
+	}
+	if len(req.Slots) == 0 {
+		return nil, fmt.Errorf("combo must have at least one slot")
+	}
+
+	menuItemIDs := []uint{}
+	slots := make([]models.ComboSlot, 0, len(req.Slots))
+	for i, slotReq := range req.Slots {
+		if slotReq.Name == "" {
+			return nil, fmt.Errorf("slot %d: name is required", i+1)
+		}
+		minSelections := 1
+		if slotReq.MinSelections != nil {
+			minSelections = *slotReq.MinSelections
+		}
+		maxSelections := max(minSelections, 1)
+		if slotReq.MaxSelections != nil {
+			maxSelections = *slotReq.MaxSelections
+		}
+		if minSelections < 0 || maxSelections < 1 || minSelections > maxSelections {
+			return nil, fmt.Errorf("slot %q: invalid min/max selections", slotReq.Name)
+		}
+		if len(slotReq.Choices) == 0 {
+			return nil, fmt.Errorf("slot %q must have at least one choice", slotReq.Name)
+		}
+
+		slot := models.ComboSlot{
+			Name:          slotReq.Name,
+			NameEn:        slotReq.NameEn,
+			NameCh:        slotReq.NameCh,
+			SortOrder:     i,
+			MinSelections: minSelections,
+			MaxSelections: maxSelections,
+		}
+		seen := map[uint]bool{}
+		for _, choiceReq := range slotReq.Choices {
+			if seen[choiceReq.MenuItemID] {
+				return nil, fmt.Errorf("slot %q: menu item ID %d is listed twice", slotReq.Name, choiceReq.MenuItemID)
+			}
+			if choiceReq.Upcharge < 0 {
+				return nil, fmt.Errorf("slot %q: upcharge cannot be negative", slotReq.Name)
+			}
+			seen[choiceReq.MenuItemID] = true
+			menuItemIDs = append(menuItemIDs, choiceReq.MenuItemID)
+			slot.Choices = append(slot.Choices, models.ComboSlotChoice{
+				MenuItemID: choiceReq.MenuItemID,
+				Upcharge:   choiceReq.Upcharge,
+				IsDefault:  choiceReq.IsDefault,
+			})
+		}
+		slots = append(slots, slot)
+	}
+
+	var found []uint
+	if err := tx.Model(&models.MenuItem{}).Where("id IN ?", menuItemIDs).Pluck("id", &found).Error; err != nil {
+		return nil, err
+	}
+	exists := map[uint]bool{}
+	for _, id := range found {
+		exists[id] = true
+	}
+	for _, id := range menuItemIDs {
+		if !exists[id] {
+			return nil, fmt.Errorf("menu item ID %d not found", id)
+		}
+	}
+	return slots, nil
+}
+
+// allocateComboPrice แบ่งราคาชุดให้แต่ละรายการตามสัดส่วนของน้ำหนัก (ราคาเมนู + ค่าอัปเกรด)
+// คิดเป็นสตางค์: ปัดลงทุกรายการแล้วแจกสตางค์ที่เหลือให้รายการที่มีเศษมากที่สุดก่อน ผลรวมจึงเท่าราคาชุดพอดี
+// ถ้าน้ำหนักรวมเป็น 0 จะแบ่งเท่าๆ กัน
+func allocateComboPrice(total float64, weights []float64) []float64 {
+	n := len(weights)
+	shares := make([]float64, n)
+	if n == 0 {
+		return shares
+	}
+
+	totalSatang := int64(math.Round(total * 100))
+	sum := 0.0
+	for _, w := range weights {
+		sum += math.Max(w, 0)
+	}
+
+	satang := make([]int64, n)
+	remainders := make([]float64, n)
+	var allocated int64
+	for i, w := range weights {
+		exact := float64(totalSatang) / float64(n)
+		if sum > 0 {
+			exact = float64(totalSatang) * math.Max(w, 0) / sum
+		}
+		satang[i] = int64(math.Floor(exact))
+		remainders[i] = exact - float64(satang[i])
+		allocated += satang[i]
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for k := 0; allocated < totalSatang; k++ {
+		satang[order[k%n]]++
+		allocated++
+	}
+
+	for i := range shares {
+		shares[i] = float64(satang[i]) / 100
+	}
+	return shares
+}
+
+// comboSelection รายการที่เลือกในชุดหลังตรวจแล้ว
+type comboSelection struct {
+	MenuItem models.MenuItem
+	Upcharge float64
+	Options  []OrderItemOptionRequest
+}
+
+// resolveComboOrder ตรวจรายการที่เลือกของชุดหนึ่งชุด คืนชุด รายการที่เลือก (เรียงตามช่อง) และราคาต่อชุด
+func resolveComboOrder(tx *gorm.DB, req OrderComboRequest) (*models.Combo, []comboSelection, float64, error) {
+	var combo models.Combo
+	if err := preloadCombo(tx).Where("is_active = ?", true).First(&combo, req.ComboID).Error; err != nil {
+		return nil, nil, 0, fmt.Errorf("combo ID %d not found or inactive", req.ComboID)
+	}
+
+	bySlot := map[uint][]ComboSelectionRequest{}
+	for _, sel := range req.Selections {
+		bySlot[sel.SlotID] = append(bySlot[sel.SlotID], sel)
+	}
+
+	unitPrice := combo.Price
+	var selections []comboSelection
+	for _, slot := range combo.Slots {
+		chosen := bySlot[slot.ID]
+		delete(bySlot, slot.ID)
+		if len(chosen) < slot.MinSelections || len(chosen) > slot.MaxSelections {
+			return nil, nil, 0, fmt.Errorf("slot %q requires %d-%d selections", slot.Name, slot.MinSelections, slot.MaxSelections)
+		}
+
+		for _, sel := range chosen {
+			var choice *models.ComboSlotChoice
+			for i := range slot.Choices {
+				if slot.Choices[i].MenuItemID == sel.MenuItemID {
+					choice = &slot.Choices[i]
+					break
+				}
+			}
+			if choice == nil {
+				return nil, nil, 0, fmt.Errorf("menu item ID %d is not a choice of slot %q", sel.MenuItemID, slot.Name)
+			}
+			if choice.MenuItem.ID == 0 || !choice.MenuItem.Is_available {
+				return nil, nil, 0, fmt.Errorf("menu item ID %d is not available", sel.MenuItemID)
+			}
+
+			optionIDs := make([]uint, 0, len(sel.Options))
+			for _, opt := range sel.Options {
+				optionIDs = append(optionIDs, opt.MenuOptionID)
+			}
+			if err := validateOrderItemOptions(tx, sel.MenuItemID, optionIDs); err != nil {
+				return nil, nil, 0, err
+			}
+
+			unitPrice += choice.Upcharge
+			selections = append(selections, comboSelection{MenuItem: choice.MenuItem, Upcharge: choice.Upcharge, Options: sel.Options})
+		}
+	}
+	for slotID := range bySlot {
+		return nil, nil, 0, fmt.Errorf("slot ID %d is not in combo ID %d", slotID, combo.ID)
+	}
+	// ทุกช่องไม่บังคับเลือกก็ต้องเลือกอย่างน้อยหนึ่งรายการ ไม่งั้นจะคิดเงินชุดที่ไม่มีอาหาร
+	if len(selections) == 0 {
+		return nil, nil, 0, fmt.Errorf("combo ID %d requires at least one selection", combo.ID)
+	}
+	return &combo, selections, unitPrice, nil
+}
+
+// createComboOrderItems บันทึกชุดที่สั่งเป็น ComboUsage และ OrderItem ของแต่ละรายการ (ราคาเป็นส่วนแบ่งของราคาชุด)
+// คืนยอดรวมของชุดทั้งหมดรวมตัวเลือกเสริม
+func createComboOrderItems(tx *gorm.DB, orderID uint, req OrderComboRequest, combo *models.Combo, selections []comboSelection, unitPrice float64) (float64, error) {
+	quantity := max(req.Quantity, 1)
+
+	usage := models.ComboUsage{
+		ComboID:   combo.ID,
+		OrderID:   orderID,
+		Quantity:  quantity,
+		UnitPrice: unitPrice,
+	}
+	if err := tx.Create(&usage).Error; err != nil {
+		return 0, err
+	}
+
+	weights := make([]float64, len(selections))
+	for i, sel := range selections {
+		weights[i] = float64(sel.MenuItem.Price) + sel.Upcharge
+	}
+	shares := allocateComboPrice(unitPrice, weights)
+
+	total := unitPrice * float64(quantity)
+	for i, sel := range selections {
+		orderItem := models.OrderItem{
+			OrderID:      orderID,
+			MenuItemID:   sel.MenuItem.ID,
+			Quantity:     quantity,
+			Price:        shares[i],
+			Notes:        req.Notes,
+			Status:       "pending",
+			ComboUsageID: &usage.ID,
+		}
+		if err := tx.Create(&orderItem).Error; err != nil {
+			return 0, err
+		}
+
+		for _, opt := range sel.Options {
+			var menuOption models.MenuOption
+			if err := tx.First(&menuOption, opt.MenuOptionID).Error; err != nil {
+				return 0, err
+			}
+			price, err := menuOptionPrice(tx, sel.MenuItem.ID, menuOption)
+			if err != nil {
+				return 0, err
+			}
+			if err := tx.Create(&models.OrderItemOption{
+				OrderItemID:  orderItem.ID,
+				MenuOptionID: menuOption.ID,
+				Price:        price,
+				Quantity:     quantity,
+			}).Error; err != nil {
+				return 0, err
+			}
+			total += price * float64(quantity)
+		}
+	}
+	return total, nil
+}
+
+// @Summary ดูชุดอาหารที่เปิดขาย
+// @Description ดึงชุดที่เปิดใช้งานพร้อมช่องและเมนูที่เลือกได้ ตัดเมนูที่ปิดขาย และไม่แสดงชุดที่มีช่องบังคับเลือกแต่ไม่เหลือเมนูให้เลือก
+// @Produce json
+// @Param lang query string false "รหัสภาษา เช่น th, en, zh"
+// @Success 200 {array} models.Combo "รายการชุดอาหาร"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
+// @Router /api/combos/Active [get]
+// @Tags combos
+func GetActiveCombos(c *fiber.Ctx) error {
+	var combos []models.Combo
+	if err := preloadCombo(db.DB).Where("is_active = ?", true).Order("id").Find(&combos).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch combos",
+		})
+	}
+
+	l := newLocalizer(c)
+	result := make([]models.Combo, 0, len(combos))
+	for _, combo := range combos {
+		orderable := true
+		for si := range combo.Slots {
+			slot := &combo.Slots[si]
+			choices := make([]models.ComboSlotChoice, 0, len(slot.Choices))
+			for _, choice := range slot.Choices {
+				if choice.MenuItem.ID != 0 && choice.MenuItem.Is_available {
+					choices = append(choices, choice)
+				}
+			}
+			slot.Choices = choices
+			if slot.MinSelections > 0 && len(choices) == 0 {
+				orderable = false
+			}
+		}
+		if orderable {
+			l.combo(&combo)
+			result = append(result, combo)
+		}
+	}
+	return c.JSON(result)
+}
+
+// @Summary ดูชุดอาหารทั้งหมด
+// @Description ดึงชุดทั้งหมดรวมที่ปิดใช้งาน สำหรับหน้าจัดการ
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Combo "รายการชุดอาหาร"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
+// @Router /api/combos [get]
+// @Tags combos
+func GetCombos(c *fiber.Ctx) error {
+	var combos []models.Combo
+	if err := preloadCombo(db.DB).Order("id").Find(&combos).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch combos",
+		})
+	}
+	return c.JSON(combos)
+}
+
+// @Summary ดูรายละเอียดชุดอาหาร
+// @Produce json
+// @Param id path integer true "ID ของชุด"
+// @Param lang query string false "รหัสภาษา เช่น th, en, zh"
+// @Success 200 {object} models.Combo "รายละเอียดชุด"
+// @Failure 404 {object} map[string]interface{} "ไม่พบชุด"
+// @Router /api/combos/{id} [get]
+// @Tags combos
+func GetComboByID(c *fiber.Ctx) error {
+	combo, err := getComboFromParam(c)
+	if combo == nil {
+		return err
+	}
+	newLocalizer(c).combo(combo)
+	return c.JSON(combo)
+}
+
+// @Summary สร้างชุดอาหาร
+// @Description สร้างชุดพร้อมช่องและเมนูที่เลือกได้ เช่น จานหลัก 1 + เครื่องเคียง 1 + เครื่องดื่ม 1 (เปลี่ยนเป็นชาไทย +20)
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.ComboRequest true "ข้อมูลชุด"
+// @Success 201 {object} models.Combo "ชุดที่สร้าง"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการสร้างชุด"
+// @Router /api/combos [post]
+// @Tags combos
+func CreateCombo(c *fiber.Ctx) error {
+	var req models.ComboRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+
+	slots, err := buildComboSlots(db.DB, req)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	combo := models.Combo{
+		Name:          req.Name,
+		NameEn:        req.NameEn,
+		NameCh:        req.NameCh,
+		Description:   req.Description,
+		DescriptionEn: req.DescriptionEn,
+		DescriptionCh: req.DescriptionCh,
+		Price:         req.Price,
+		IsActive:      true,
+		Slots:         slots,
+	}
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&combo).Error; err != nil {
+			return err
+		}
+		// default:true ทำให้ GORM ข้ามค่า false ตอน Create จึงต้องอัปเดตแยก
+		if req.IsActive != nil && !*req.IsActive {
+			return tx.Model(&combo).Update("is_active", false).Error
+		}
+		return nil
+	}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create combo",
+		})
+	}
+
+	var created models.Combo
+	preloadCombo(db.DB).First(&created, combo.ID)
+	return c.Status(http.StatusCreated).JSON(created)
+}
+
+// @Summary แก้ไขชุดอาหาร
+// @Description แก้ไขข้อมูลชุดและแทนที่ช่องทั้งหมดด้วย slots ที่ส่งมา ออเดอร์เก่าไม่เปลี่ยนเพราะเก็บราคาไว้ที่ OrderItem แล้ว
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของชุด"
+// @Param request body models.ComboRequest true "ข้อมูลชุด"
+// @Success 200 {object} models.Combo "ชุดหลังแก้ไข"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 404 {object} map[string]interface{} "ไม่พบชุด"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการแก้ไขชุด"
+// @Router /api/combos/{id} [put]
+// @Tags combos
+func UpdateCombo(c *fiber.Ctx) error {
+	combo, err := getComboFromParam(c)
+	if combo == nil {
+		return err
+	}
+
+	var req models.ComboRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+
+	slots, err := buildComboSlots(db.DB, req)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	updates := map[string]interface{}{
+		"name":           req.Name,
+		"name_en":        req.NameEn,
+		"name_ch":        req.NameCh,
+		"description":    req.Description,
+		"description_en": req.DescriptionEn,
+		"description_ch": req.DescriptionCh,
+		"price":          req.Price,
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Combo{}).Where("id = ?", combo.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("slot_id IN (?)", tx.Model(&models.ComboSlot{}).Select("id").Where("combo_id = ?", combo.ID)).
+			Delete(&models.ComboSlotChoice{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("combo_id = ?", combo.ID).Delete(&models.ComboSlot{}).Error; err != nil {
+			return err
+		}
+		for i := range slots {
+			slots[i].ComboID = combo.ID
+		}
+		return tx.Create(&slots).Error
+	}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update combo",
+		})
+	}
+
+	var updated models.Combo
+	preloadCombo(db.DB).First(&updated, combo.ID)
+	return c.JSON(updated)
+}
+
+// @Summary ลบชุดอาหาร
+// @Description soft delete ชุด ออเดอร์เก่าที่สั่งชุดนี้ยังแสดงได้ตามเดิม
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของชุด"
+// @Success 200 {object} map[string]interface{} "ลบสำเร็จ"
+// @Failure 404 {object} map[string]interface{} "ไม่พบชุด"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการลบชุด"
+// @Router /api/combos/{id} [delete]
+// @Tags combos
+func DeleteCombo(c *fiber.Ctx) error {
+	combo, err := getComboFromParam(c)
+	if combo == nil {
+		return err
+	}
+
+	if err := db.DB.Delete(combo).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete combo",
+		})
+	}
+	return c.JSON(fiber.Map{"message": "Combo deleted successfully"})
+}
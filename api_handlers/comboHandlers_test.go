@@ -0,0 +1,244 @@
+package api_handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"food-ordering-api/api_v2"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestAllocateComboPrice(t *testing.T) {
+	sum := func(shares []float64) float64 {
+		total := 0.0
+		for _, s := range shares {
+			total += s
+		}
+		return total
+	}
+
+	// แบ่ง 100 บาทตามราคา 1:1:1 เศษสตางค์ต้องไปอยู่ที่รายการแรก
+	shares := allocateComboPrice(100, []float64{50, 50, 50})
+	assert.Equal(t, []float64{33.34, 33.33, 33.33}, shares)
+
+	// แบ่งตามสัดส่วนราคาเมนู ผลรวมต้องเท่าราคาชุดพอดี
+	shares = allocateComboPrice(199, []float64{120, 45, 35})
+	assert.InDelta(t, 199, sum(shares), 0.001)
+	assert.Equal(t, []float64{119.4, 44.78, 34.82}, shares)
+
+	// น้ำหนักเป็น 0 ทั้งหมด แบ่งเท่าๆ กัน
+	shares = allocateComboPrice(10, []float64{0, 0, 0})
+	assert.Equal(t, []float64{3.34, 3.33, 3.33}, shares)
+
+	assert.Empty(t, allocateComboPrice(100, nil))
+}
+
+func TestComboWorkflow(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.Translation{}, &models.Combo{}, &models.ComboSlot{}, &models.ComboSlotChoice{},
+		&models.ComboUsage{}, &models.Order{}, &models.OrderItem{}, &models.OrderItemOption{})
+
+	app.Get("/api/combos", GetCombos)
+	app.Get("/api/combos/Active", GetActiveCombos)
+	app.Post("/api/combos", CreateCombo)
+	app.Put("/api/combos/:id", UpdateCombo)
+	app.Delete("/api/combos/:id", DeleteCombo)
+
+	burger := models.MenuItem{Name: "เบอร์เกอร์", CategoryID: 1, Price: 120, Is_available: true,
+		OptionGroups: []models.OptionGroup{{
+			Name: "ชีส", MaxSelections: 1,
+			Options: []models.MenuOption{{Name: "เพิ่มชีส", Price: 15}},
+		}},
+	}
+	fries := models.MenuItem{Name: "เฟรนช์ฟรายส์", CategoryID: 1, Price: 45, Is_available: true}
+	water := models.MenuItem{Name: "น้ำเปล่า", CategoryID: 1, Price: 15, Is_available: true}
+	thaiTea := models.MenuItem{Name: "ชาไทย", CategoryID: 1, Price: 35, Is_available: true}
+	for _, item := range []*models.MenuItem{&burger, &fries, &water, &thaiTea} {
+		db.DB.Create(item)
+	}
+
+	one := 1
+	comboReq := models.ComboRequest{
+		Name:  "ชุดเบอร์เกอร์",
+		Price: 150,
+		Slots: []models.ComboSlotRequest{
+			{Name: "จานหลัก", Choices: []models.ComboChoiceRequest{{MenuItemID: burger.ID, IsDefault: true}}},
+			{Name: "เครื่องเคียง", MinSelections: &one, Choices: []models.ComboChoiceRequest{{MenuItemID: fries.ID}}},
+			{Name: "เครื่องดื่ม", Choices: []models.ComboChoiceRequest{
+				{MenuItemID: water.ID, IsDefault: true},
+				{MenuItemID: thaiTea.ID, Upcharge: 20},
+			}},
+		},
+	}
+
+	var combo models.Combo
+
+	// กรณีทดสอบที่ 1: สร้างชุดพร้อมช่องและค่าอัปเกรด
+	t.Run("Success - Create combo", func(t *testing.T) {
+		resp := sendJSON(t, app, "POST", "/api/combos", comboReq)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		json.NewDecoder(resp.Body).Decode(&combo)
+
+		assert.True(t, combo.IsActive)
+		assert.Len(t, combo.Slots, 3)
+		assert.Equal(t, "จานหลัก", combo.Slots[0].Name)
+		assert.Equal(t, 1, combo.Slots[2].MaxSelections)
+		assert.Len(t, combo.Slots[2].Choices, 2)
+	})
+
+	// กรณีทดสอบที่ 2: ข้อมูลไม่ถูกต้อง
+	t.Run("Failure - Invalid combo", func(t *testing.T) {
+		invalid := comboReq
+		invalid.Slots = []models.ComboSlotRequest{{Name: "จานหลัก", Choices: []models.ComboChoiceRequest{{MenuItemID: 9999}}}}
+		resp := sendJSON(t, app, "POST", "/api/combos", invalid)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		invalid.Slots = []models.ComboSlotRequest{{Name: "จานหลัก", Choices: []models.ComboChoiceRequest{
+			{MenuItemID: burger.ID}, {MenuItemID: burger.ID},
+		}}}
+		resp = sendJSON(t, app, "POST", "/api/combos", invalid)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		invalid.Slots = nil
+		resp = sendJSON(t, app, "POST", "/api/combos", invalid)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 3: สั่งชุดโดยเปลี่ยนเครื่องดื่มเป็นชาไทย ราคาแบ่งให้รายการในชุดครบทุกสตางค์
+	t.Run("Success - Order combo with upcharge", func(t *testing.T) {
+		var withOptions models.MenuItem
+		db.DB.Preload("OptionGroups.Options").First(&withOptions, burger.ID)
+
+		order := models.Order{UUID: "combo-test", TableID: 1, Status: "pending"}
+		db.DB.Create(&order)
+
+		req := OrderComboRequest{
+			ComboID:  combo.ID,
+			Quantity: 2,
+			Selections: []ComboSelectionRequest{
+				{SlotID: combo.Slots[0].ID, MenuItemID: burger.ID, Options: []OrderItemOptionRequest{{MenuOptionID: withOptions.OptionGroups[0].Options[0].ID}}},
+				{SlotID: combo.Slots[1].ID, MenuItemID: fries.ID},
+				{SlotID: combo.Slots[2].ID, MenuItemID: thaiTea.ID},
+			},
+		}
+
+		var total float64
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			resolved, selections, unitPrice, err := resolveComboOrder(tx, req)
+			if err != nil {
+				return err
+			}
+			assert.Equal(t, 170.0, unitPrice)
+			total, err = createComboOrderItems(tx, order.ID, req, resolved, selections, unitPrice)
+			return err
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 370.0, total) // (150 + 20) x 2 + ชีส 15 x 2
+
+		var items []models.OrderItem
+		db.DB.Preload("MenuItem").Preload("Options").Preload("ComboUsage.Combo").
+			Where("order_id = ?", order.ID).Order("id").Find(&items)
+		assert.Len(t, items, 3)
+
+		perCombo := 0.0
+		for _, item := range items {
+			assert.NotNil(t, item.ComboUsageID)
+			assert.Equal(t, 2, item.Quantity)
+			perCombo += item.Price
+		}
+		assert.InDelta(t, 170, perCombo, 0.001)
+
+		regular, lines := api_v2.GroupComboItems(items)
+		assert.Empty(t, regular)
+		assert.Len(t, lines, 1)
+		assert.Equal(t, "ชุดเบอร์เกอร์", lines[0].Name)
+		assert.InDelta(t, 370, lines[0].Price, 0.001)
+	})
+
+	// กรณีทดสอบที่ 4: เลือกเมนูที่ไม่อยู่ในช่อง หรือเลือกไม่ครบ
+	t.Run("Failure - Invalid selections", func(t *testing.T) {
+		cases := []OrderComboRequest{
+			{ComboID: combo.ID, Selections: []ComboSelectionRequest{
+				{SlotID: combo.Slots[0].ID, MenuItemID: fries.ID},
+				{SlotID: combo.Slots[1].ID, MenuItemID: fries.ID},
+				{SlotID: combo.Slots[2].ID, MenuItemID: water.ID},
+			}},
+			{ComboID: combo.ID, Selections: []ComboSelectionRequest{
+				{SlotID: combo.Slots[0].ID, MenuItemID: burger.ID},
+				{SlotID: combo.Slots[2].ID, MenuItemID: water.ID},
+			}},
+			{ComboID: combo.ID, Selections: []ComboSelectionRequest{
+				{SlotID: combo.Slots[0].ID, MenuItemID: burger.ID},
+				{SlotID: combo.Slots[1].ID, MenuItemID: fries.ID},
+				{SlotID: combo.Slots[2].ID, MenuItemID: water.ID},
+				{SlotID: 9999, MenuItemID: water.ID},
+			}},
+		}
+		for i, req := range cases {
+			_, _, _, err := resolveComboOrder(db.DB, req)
+			assert.NotNil(t, err, fmt.Sprintf("case %d", i))
+		}
+
+		// ชุดที่ทุกช่องไม่บังคับเลือก ต้องเลือกอย่างน้อยหนึ่งรายการ
+		zero := 0
+		resp := sendJSON(t, app, "POST", "/api/combos", models.ComboRequest{
+			Name: "ชุดเลือกเอง", Price: 50,
+			Slots: []models.ComboSlotRequest{{Name: "ของหวาน", MinSelections: &zero, Choices: []models.ComboChoiceRequest{{MenuItemID: fries.ID}}}},
+		})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		var optional models.Combo
+		json.NewDecoder(resp.Body).Decode(&optional)
+		_, _, _, err := resolveComboOrder(db.DB, OrderComboRequest{ComboID: optional.ID})
+		assert.NotNil(t, err)
+		db.DB.Delete(&optional)
+	})
+
+	// กรณีทดสอบที่ 5: แก้ไขช่อง และปิดขายเมนูที่เป็นตัวเลือกเดียวของช่องบังคับ ชุดต้องหายจากหน้าลูกค้า
+	t.Run("Success - Update and hide unorderable combo", func(t *testing.T) {
+		update := comboReq
+		update.Price = 159
+		update.Slots = comboReq.Slots[:2]
+		resp := sendJSON(t, app, "PUT", fmt.Sprintf("/api/combos/%d", combo.ID), update)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var updated models.Combo
+		json.NewDecoder(resp.Body).Decode(&updated)
+		assert.Equal(t, 159.0, updated.Price)
+		assert.Len(t, updated.Slots, 2)
+
+		var active []models.Combo
+		resp = sendJSON(t, app, "GET", "/api/combos/Active", nil)
+		json.NewDecoder(resp.Body).Decode(&active)
+		assert.Len(t, active, 1)
+
+		db.DB.Model(&fries).Update("is_available", false)
+		resp = sendJSON(t, app, "GET", "/api/combos/Active", nil)
+		active = nil
+		json.NewDecoder(resp.Body).Decode(&active)
+		assert.Empty(t, active)
+		db.DB.Model(&fries).Update("is_available", true)
+	})
+
+	// กรณีทดสอบที่ 6: ลบชุด
+	t.Run("Success - Delete combo", func(t *testing.T) {
+		resp := sendJSON(t, app, "DELETE", fmt.Sprintf("/api/combos/%d", combo.ID), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = sendJSON(t, app, "DELETE", fmt.Sprintf("/api/combos/%d", combo.ID), nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		// ออเดอร์เก่ายังแสดงชื่อชุดที่ถูกลบไปแล้ว
+		var items []models.OrderItem
+		db.DB.Preload("ComboUsage.Combo", models.WithDeletedCombo).Where("combo_usage_id IS NOT NULL").Find(&items)
+		if assert.NotEmpty(t, items) {
+			assert.Equal(t, "ชุดเบอร์เกอร์", items[0].ComboUsage.Combo.Name)
+		}
+	})
+}
@@ -3,15 +3,19 @@ package api_handlers
 import (
 	"bytes"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"food-ordering-api/db"
 	"food-ordering-api/models"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
 )
 
 type MenuImportRow struct {
@@ -24,6 +28,10 @@ type MenuImportRow struct {
 	CategoryName  string              `json:"category_name"`
 	Price         float64             `json:"price"`
 	OptionGroups  []OptionGroupImport `json:"option_groups,omitempty"`
+	IsAvailable   bool                `json:"is_available"`
+	SKU           string              `json:"sku,omitempty"`
+	Tags          []string            `json:"tags,omitempty"`        // nil = ไฟล์ไม่มีคอลัมน์ Tags (ไม่แก้ไขแท็กเดิม)
+	SpicyLevel    *int                `json:"spicy_level,omitempty"` // nil = ไฟล์ไม่มีคอลัมน์ SpicyLevel
 }
 
 type OptionGroupImport struct {
@@ -33,13 +41,15 @@ type OptionGroupImport struct {
 	MaxSelections int            `json:"max_selections"`
 	IsRequired    bool           `json:"is_required"`
 	Options       []OptionImport `json:"options"`
+	ParentOption  string         `json:"parent_option,omitempty"` // ชื่อตัวเลือกในกลุ่มก่อนหน้าที่เปิดกลุ่มย่อยนี้
 }
 
 type OptionImport struct {
-	Name   string  `json:"name"`
-	NameEn string  `json:"name_en"`
-	NameCh string  `json:"name_ch"`
-	Price  float64 `json:"price"`
+	Name   string   `json:"name"`
+	NameEn string   `json:"name_en"`
+	NameCh string   `json:"name_ch"`
+	Price  float64  `json:"price"`
+	Tags   []string `json:"tags,omitempty"`
 }
 
 type MenuImportResponse struct {
@@ -61,10 +71,37 @@ type ImportError struct {
 }
 
 type ImportResponse struct {
+	Mode         string          `json:"mode"`
+	DryRun       bool            `json:"dry_run"`
 	Success      int             `json:"success"`
 	Failed       int             `json:"failed"`
 	FailedItems  []ImportError   `json:"failed_items"`
 	SuccessItems []MenuImportRow `json:"success_items"`
+	Changes      []ImportChange  `json:"changes"`
+}
+
+// โหมดการนำเข้าเมนู
+const (
+	ImportModeCreate = "create" // สร้างใหม่อย่างเดียว เมนูที่ชื่อซ้ำจะ error (ค่าเริ่มต้น)
+	ImportModeUpsert = "upsert" // อัปเดตเมนูที่ตรงกัน (SKU หรือชื่อ+หมวดหมู่) และสร้างที่ยังไม่มี
+	ImportModeSync   = "sync"   // เหมือน upsert และ soft delete เมนูที่ไม่อยู่ในไฟล์
+)
+
+// การเปลี่ยนแปลงของแต่ละเมนูใน ImportChange
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionDelete    = "delete"
+	ImportActionUnchanged = "unchanged"
+)
+
+// ImportChange การเปลี่ยนแปลงที่การนำเข้าทำกับเมนูหนึ่งรายการ (หรือจะทำ ถ้าเป็น dry run)
+type ImportChange struct {
+	Row        int      `json:"row"` // 0 = เมนูที่ไม่อยู่ในไฟล์
+	Action     string   `json:"action"`
+	MenuItemID uint     `json:"menu_item_id,omitempty"` // ว่างเมื่อเป็นเมนูใหม่ใน dry run
+	Name       string   `json:"name"`
+	Fields     []string `json:"fields,omitempty"` // ฟิลด์ที่เปลี่ยนเมื่อ action เป็น update
 }
 
 // parseOptionString แปลงข้อความที่คั่นด้วย | เป็นโครงสร้างข้อมูลกลุ่มตัวเลือก
@@ -72,7 +109,11 @@ func parseOptionString(optionStr string) ([]OptionGroupImport, error) {
 	var groups []OptionGroupImport
 	// รูปแบบ: ชื่อกลุ่ม|ชื่อกลุ่มEN|ชื่อกลุ่มCH|จำนวนเลือก|บังคับ?|ตัวเลือก1:ราคา,ตัวเลือก2:ราคา
 	// ตัวอย่าง: ความเผ็ด|Spiciness|辣度|1|true|ไม่เผ็ด:0,เผ็ดน้อย:0,เผ็ดมาก:0
+	// ตัวเลือกใส่แท็กต่อท้ายได้ (คั่นด้วย ;) เช่น กุ้ง:Shrimp:虾:20:shellfish
+	// กลุ่มย่อยใส่ชื่อตัวเลือกแม่ (จากกลุ่มก่อนหน้าในช่องเดียวกัน) เป็นส่วนที่ 7
+	// เช่น ประเภท|Type|类型|1|true|น้ำ:Soup:汤:0||เส้น|Noodle|面|1|true|เส้นเล็ก:Thin:细面:0|น้ำ
 
+	optionNames := map[string]bool{}
 	groupStrings := strings.Split(optionStr, "||")
 	for _, groupStr := range groupStrings {
 		if strings.TrimSpace(groupStr) == "" {
@@ -98,6 +139,12 @@ func parseOptionString(optionStr string) ([]OptionGroupImport, error) {
 			MaxSelections: maxSel,
 			IsRequired:    isReq,
 		}
+		if len(parts) >= 7 {
+			group.ParentOption = strings.TrimSpace(parts[6])
+			if group.ParentOption != "" && !optionNames[group.ParentOption] {
+				return nil, fmt.Errorf("parent option %q not found in earlier option groups", group.ParentOption)
+			}
+		}
 
 		// แยกตัวเลือก
 		optionParts := strings.Split(parts[5], ",")
@@ -108,7 +155,7 @@ func parseOptionString(optionStr string) ([]OptionGroupImport, error) {
 
 			// แยกชื่อและราคา
 			optDetails := strings.Split(optPart, ":")
-			if len(optDetails) != 4 {
+			if len(optDetails) != 4 && len(optDetails) != 5 {
 				continue
 			}
 
@@ -123,7 +170,11 @@ func parseOptionString(optionStr string) ([]OptionGroupImport, error) {
 				NameCh: strings.TrimSpace(optDetails[2]),
 				Price:  price,
 			}
+			if len(optDetails) == 5 {
+				option.Tags = parseTagCodes(optDetails[4], ";")
+			}
 			group.Options = append(group.Options, option)
+			optionNames[option.Name] = true
 		}
 
 		groups = append(groups, group)
@@ -132,15 +183,192 @@ func parseOptionString(optionStr string) ([]OptionGroupImport, error) {
 	return groups, nil
 }
 
+// menuSheetHeaders หัวตารางของไฟล์เมนู ลำดับคอลัมน์ต้องตรงกับที่ ImportMenuFromExcel อ่าน
+var menuSheetHeaders = []string{
+	"Name", "NameEn", "NameCh",
+	"Description", "DescriptionEn", "DescriptionCh",
+	"Category", "Price", "OptionGroups", "Available", "SKU",
+	"Tags", "SpicyLevel",
+}
+
+// optionFieldReplacer ตัดอักขระที่ใช้เป็นตัวคั่นใน grammar ของ parseOptionString ออกจากชื่อ
+var optionFieldReplacer = strings.NewReplacer("|", " ", ",", " ", ":", " ")
+
+// formatOptionString แปลงกลุ่มตัวเลือกกลับเป็นข้อความรูปแบบเดียวกับที่ parseOptionString อ่านได้
+func formatOptionString(groups []models.OptionGroup) string {
+	optionNames := map[uint]string{}
+	for _, group := range groups {
+		for _, opt := range group.Options {
+			optionNames[opt.ID] = opt.Name
+		}
+	}
+
+	groupStrings := make([]string, 0, len(groups))
+	for _, group := range groups {
+		parentName := ""
+		if group.ParentOptionID != nil {
+			parentName = optionNames[*group.ParentOptionID]
+		}
+		groupStrings = append(groupStrings, formatOptionGroup(group, parentName))
+	}
+	return strings.Join(groupStrings, "||")
+}
+
+// formatImportedOptionString แปลงกลุ่มตัวเลือกจากไฟล์กลับเป็นข้อความ ใช้เทียบกับของเดิมว่าเปลี่ยนหรือไม่
+func formatImportedOptionString(groups []OptionGroupImport) string {
+	groupStrings := make([]string, 0, len(groups))
+	for i, group := range importOptionGroups(groups) {
+		groupStrings = append(groupStrings, formatOptionGroup(group, groups[i].ParentOption))
+	}
+	return strings.Join(groupStrings, "||")
+}
+
+// formatOptionGroup แปลงกลุ่มตัวเลือกหนึ่งกลุ่ม ใส่ชื่อตัวเลือกแม่ต่อท้ายเฉพาะกลุ่มย่อย
+func formatOptionGroup(group models.OptionGroup, parentName string) string {
+	options := make([]string, 0, len(group.Options))
+	for _, opt := range group.Options {
+		fields := []string{
+			optionFieldReplacer.Replace(opt.Name),
+			optionFieldReplacer.Replace(opt.NameEn),
+			optionFieldReplacer.Replace(opt.NameCh),
+			strconv.FormatFloat(opt.Price, 'f', -1, 64),
+		}
+		// ใส่ช่องแท็กเฉพาะตัวเลือกที่มีแท็ก ไฟล์เดิมที่ไม่มีแท็กจึงยังเหมือนเดิม
+		if len(opt.Tags) > 0 {
+			fields = append(fields, strings.Join(tagCodes(opt.Tags), ";"))
+		}
+		options = append(options, strings.Join(fields, ":"))
+	}
+
+	parts := []string{
+		optionFieldReplacer.Replace(group.Name),
+		optionFieldReplacer.Replace(group.NameEn),
+		optionFieldReplacer.Replace(group.NameCh),
+		strconv.Itoa(group.MaxSelections),
+		strconv.FormatBool(group.IsRequired),
+		strings.Join(options, ","),
+	}
+	if parentName != "" {
+		parts = append(parts, optionFieldReplacer.Replace(parentName))
+	}
+	return strings.Join(parts, "|")
+}
+
+// menuExportRows สร้างแถวข้อมูลเมนูทั้งหมด (รวมหัวตาราง) ในรูปแบบที่นำเข้ากลับได้
+func menuExportRows(menuItems []models.MenuItem) [][]string {
+	rows := [][]string{menuSheetHeaders}
+	for _, item := range menuItems {
+		sku := ""
+		if item.SKU != nil {
+			sku = *item.SKU
+		}
+		rows = append(rows, []string{
+			item.Name,
+			item.NameEn,
+			item.NameCh,
+			item.Description,
+			item.DescriptionEn,
+			item.DescriptionCh,
+			item.Category.Name,
+			strconv.Itoa(int(item.Price)),
+			formatOptionString(item.OptionGroups),
+			strconv.FormatBool(item.Is_available),
+			sku,
+			strings.Join(tagCodes(item.Tags), ","),
+			strconv.Itoa(item.SpicyLevel),
+		})
+	}
+	return rows
+}
+
+// @Summary ส่งออกเมนูเป็นไฟล์ Excel/CSV
+// @Description ส่งออกเมนูทั้งหมดพร้อมกลุ่มตัวเลือกในรูปแบบเดียวกับไฟล์นำเข้า เพื่อแก้ไขแล้วนำเข้ากลับได้
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce text/csv
+// @Security BearerAuth
+// @Param format query string false "รูปแบบไฟล์" Enums(xlsx, csv)
+// @Success 200 {file} file "ไฟล์เมนู"
+// @Failure 400 {object} map[string]interface{} "รูปแบบไฟล์ไม่ถูกต้อง"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการส่งออก"
+// @Router /api/menu/export [get]
+// @Tags menu
+func ExportMenuToExcel(c *fiber.Ctx) error {
+	format := strings.ToLower(c.Query("format", "xlsx"))
+	if format != "xlsx" && format != "csv" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid format. Only xlsx and csv are supported",
+		})
+	}
+
+	var menuItems []models.MenuItem
+	if err := db.DB.Preload("Category").Preload("Tags").
+		Preload("OptionGroups", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+		Preload("OptionGroups.Options", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+		Preload("OptionGroups.Options.Tags").
+		Order("category_id, id").
+		Find(&menuItems).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch menu items",
+		})
+	}
+
+	rows := menuExportRows(menuItems)
+	fileName := fmt.Sprintf("menu_%s.%s", time.Now().Format("20060102_150405"), format)
+
+	var buf bytes.Buffer
+	if format == "csv" {
+		// ใส่ BOM เพื่อให้ Excel เปิดภาษาไทย/จีนได้ถูกต้อง
+		buf.WriteString("\uFEFF")
+		writer := csv.NewWriter(&buf)
+		if err := writer.WriteAll(rows); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to write CSV file",
+			})
+		}
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	} else {
+		workbook := excelize.NewFile()
+		defer workbook.Close()
+
+		sheetName := workbook.GetSheetName(0)
+		for i, row := range rows {
+			cell, _ := excelize.CoordinatesToCellName(1, i+1)
+			values := make([]interface{}, len(row))
+			for j, v := range row {
+				values[j] = v
+			}
+			if err := workbook.SetSheetRow(sheetName, cell, &values); err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to write Excel file",
+				})
+			}
+		}
+
+		if err := workbook.Write(&buf); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to write Excel file",
+			})
+		}
+		c.Set(fiber.HeaderContentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	}
+
+	c.Attachment(fileName)
+	return c.Send(buf.Bytes())
+}
+
 // เพิ่มค่าคงที่สำหรับขนาดไฟล์สูงสุด
 const maxFileSize = 5 * 1024 * 1024 // 5MB
 
 // @Summary นำเข้าเมนูจากไฟล์ Excel
 // @Description นำเข้าเมนูพร้อมกลุ่มตัวเลือกและตัวเลือกเสริมจากไฟล์ Excel (.xlsx, .xls)
+// @Description โหมด upsert จับคู่เมนูเดิมด้วย SKU หรือชื่อ+หมวดหมู่แล้วอัปเดต, sync ทำเหมือน upsert และ soft delete เมนูที่ไม่อยู่ในไฟล์
+// @Description dry_run=true จะคืนรายการเปลี่ยนแปลง (changes) โดยไม่บันทึกลงฐานข้อมูล
 // @Accept multipart/form-data
 // @Produce json
 // @Security BearerAuth
 // @Param file formData file true "ไฟล์ Excel สำหรับนำเข้าเมนู"
+// @Param mode query string false "โหมดการนำเข้า" Enums(create, upsert, sync)
+// @Param dry_run query boolean false "แสดงผลการเปลี่ยนแปลงโดยไม่บันทึก"
 // @Success 200 {object} ImportResponse "ผลการนำเข้าเมนู"
 // @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
 // @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการนำเข้า"
@@ -161,6 +389,14 @@ func ImportMenuFromExcel(c *fiber.Ctx) error {
 		})
 	}
 
+	mode := strings.ToLower(c.Query("mode", ImportModeCreate))
+	if mode != ImportModeCreate && mode != ImportModeUpsert && mode != ImportModeSync {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid mode. Only create, upsert and sync are supported",
+		})
+	}
+	dryRun := c.QueryBool("dry_run", false)
+
 	// ตรวจสอบนามสกุลไฟล์
 	fileName := strings.ToLower(file.Filename)
 	isCSV := strings.HasSuffix(fileName, ".csv")
@@ -186,6 +422,8 @@ func ImportMenuFromExcel(c *fiber.Ctx) error {
 	if isCSV {
 		// อ่านไฟล์ CSV
 		reader := csv.NewReader(src)
+		// คอลัมน์ท้ายตาราง (Available, SKU, Tags, SpicyLevel) เป็นตัวเลือก แต่ละแถวจึงยาวไม่เท่ากันได้
+		reader.FieldsPerRecord = -1
 		rows, err = reader.ReadAll()
 		if err != nil {
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
@@ -223,6 +461,9 @@ func ImportMenuFromExcel(c *fiber.Ctx) error {
 		case strings.Contains(errMsg, "Menu item already exists"):
 			return "เมนูนี้มีอยู่ในระบบแล้ว กรุณาตรวจสอบชื่อเมนู"
 
+		case strings.Contains(errMsg, "Duplicate row in file"):
+			return "มีเมนูเดียวกันมากกว่าหนึ่งแถวในไฟล์ กรุณาเหลือไว้แถวเดียว"
+
 		case strings.Contains(errMsg, "Invalid option groups format"):
 			if strings.Contains(errMsg, "parsing") {
 				return fmt.Sprintf("รูปแบบตัวเลือกไม่ถูกต้อง ต้องใช้รูปแบบ: ชื่อกลุ่ม|ชื่อEN|ชื่อCN|จำนวนที่เลือกได้|บังคับ?|ตัวเลือก1:ตัวเลือกEN:ตัวเลือกCN:ราคา\nข้อมูลที่รับมา: %s", inputData)
@@ -236,22 +477,27 @@ func ImportMenuFromExcel(c *fiber.Ctx) error {
 
 	failedItems := []ImportError{}
 	successItems := []MenuImportRow{}
+	changes := []ImportChange{}
 	successCount := 0
 
+	// เมนูในระบบที่ถูกอ้างถึงจากไฟล์แล้ว ใช้กันแถวซ้ำและหาเมนูที่ต้องลบในโหมด sync
+	matchedIDs := map[uint]int{}
+	newNames := map[string]int{}
+
 	// ในลูปการนำเข้าข้อมูล
 	for i, row := range rows[1:] {
-		// เริ่ม transaction ใหม่สำหรับแต่ละรายการ
-		tx := db.DB.Begin()
+		// excelize ตัดเซลล์ว่างท้ายแถวทิ้ง เติมให้ครบก่อนเพื่อให้แถวที่ไม่มีตัวเลือกยังอ่านได้
+		if len(row) == 8 {
+			row = append(row, "")
+		}
 
 		if len(row) < 9 {
 			failedItems = append(failedItems, ImportError{
 				Row:        i + 2,
 				Error:      "Required fields missing or invalid",
-				InputData:  row[8],
-				Suggestion: getErrorSuggestion("Required fields missing or invalid", row[8]),
+				InputData:  strings.Join(row, ","),
+				Suggestion: getErrorSuggestion("Required fields missing or invalid", strings.Join(row, ",")),
 			})
-
-			tx.Rollback()
 			continue
 		}
 
@@ -263,6 +509,45 @@ func ImportMenuFromExcel(c *fiber.Ctx) error {
 			DescriptionEn: strings.TrimSpace(row[4]),
 			DescriptionCh: strings.TrimSpace(row[5]),
 			CategoryName:  strings.TrimSpace(row[6]),
+			IsAvailable:   true,
+		}
+
+		// คอลัมน์ Available เป็นตัวเลือก (ไฟล์ที่ส่งออกจากระบบจะมีเสมอ)
+		if len(row) > 9 && strings.TrimSpace(row[9]) != "" {
+			available, err := strconv.ParseBool(strings.TrimSpace(row[9]))
+			if err != nil {
+				failedItems = append(failedItems, ImportError{
+					Row:        i + 2,
+					Error:      "Invalid availability format",
+					InputData:  row[9],
+					Suggestion: "คอลัมน์ Available ต้องเป็น true หรือ false",
+				})
+				continue
+			}
+			menuRow.IsAvailable = available
+		}
+
+		// คอลัมน์ SKU เป็นตัวเลือก ใช้เป็น key หลักในการจับคู่เมนูเดิม
+		if len(row) > 10 {
+			menuRow.SKU = strings.TrimSpace(row[10])
+		}
+
+		// คอลัมน์ Tags และ SpicyLevel เป็นตัวเลือก
+		if len(row) > 11 {
+			menuRow.Tags = parseTagCodes(row[11], ",")
+		}
+		if len(row) > 12 && strings.TrimSpace(row[12]) != "" {
+			level, err := strconv.Atoi(strings.TrimSpace(row[12]))
+			if err != nil || level < 0 || level > models.MaxSpicyLevel {
+				failedItems = append(failedItems, ImportError{
+					Row:        i + 2,
+					Error:      "Invalid spicy level",
+					InputData:  row[12],
+					Suggestion: fmt.Sprintf("คอลัมน์ SpicyLevel ต้องเป็นตัวเลข 0-%d", models.MaxSpicyLevel),
+				})
+				continue
+			}
+			menuRow.SpicyLevel = &level
 		}
 
 		// แปลงราคาเป็นตัวเลข
@@ -274,7 +559,6 @@ func ImportMenuFromExcel(c *fiber.Ctx) error {
 				InputData:  row[7],
 				Suggestion: getErrorSuggestion("Invalid price format", row[7]),
 			})
-			tx.Rollback()
 			continue
 		}
 		menuRow.Price = price
@@ -289,12 +573,22 @@ func ImportMenuFromExcel(c *fiber.Ctx) error {
 					InputData:  row[8],
 					Suggestion: getErrorSuggestion("Invalid option groups format", row[8]),
 				})
-				tx.Rollback()
 				continue
 			}
 			menuRow.OptionGroups = optionGroups
 		}
 
+		// code ของแท็กทั้งเมนูและตัวเลือกต้องมีอยู่ในระบบ
+		if _, err := resolveDietaryTags(db.DB, importTagCodes(menuRow)); err != nil {
+			failedItems = append(failedItems, ImportError{
+				Row:        i + 2,
+				Error:      err.Error(),
+				InputData:  strings.Join(importTagCodes(menuRow), ","),
+				Suggestion: "ใช้ code ของแท็กที่มีในระบบ (ดูได้จาก /api/dietary-tags) คั่นหลายแท็กด้วย , (ในตัวเลือกคั่นด้วย ;)",
+			})
+			continue
+		}
+
 		// ตรวจสอบข้อมูลที่จำเป็น
 		if menuRow.Name == "" || menuRow.CategoryName == "" || menuRow.Price <= 0 {
 			failedItems = append(failedItems, ImportError{
@@ -303,128 +597,399 @@ func ImportMenuFromExcel(c *fiber.Ctx) error {
 				InputData:  row[8],
 				Suggestion: getErrorSuggestion("Required fields missing or invalid", row[8]),
 			})
-			tx.Rollback()
 			continue
 		}
 
 		// ค้นหาหมวดหมู่ (จะไม่สร้างใหม่ถ้าไม่มีอยู่)
 		var category models.Category
-		if err := tx.Where("name = ?", menuRow.CategoryName).First(&category).Error; err != nil {
+		if err := db.DB.Where("name = ?", menuRow.CategoryName).First(&category).Error; err != nil {
 			failedItems = append(failedItems, ImportError{
 				Row:        i + 2,
 				Error:      "Category does not exist: " + menuRow.CategoryName,
 				InputData:  menuRow.CategoryName,
 				Suggestion: getErrorSuggestion("Category does not exist", menuRow.CategoryName),
 			})
-			tx.Rollback()
 			continue // ข้ามเมนูนี้เพราะหมวดหมู่ไม่มีอยู่จริง
 		}
 
-		// แก้ไขส่วนการตรวจสอบเมนูซ้ำ
-		var existingMenu models.MenuItem
-		// ตรวจสอบเฉพาะเมนูที่ไม่ได้ถูก soft delete
-		if err := db.DB.Where("name = ? AND deleted_at IS NULL", menuRow.Name).First(&existingMenu).Error; err == nil {
-			failedItems = append(failedItems, ImportError{
-				Row:        i + 2,
-				Error:      "Menu item already exists",
-				InputData:  menuRow.Name,
-				Suggestion: getErrorSuggestion("Menu item already exists", menuRow.Name),
-			})
-			tx.Rollback()
-			continue
-		}
-
-		// สร้างเมนูใหม่เสมอถ้าไม่มีชื่อซ้ำในระบบ (ไม่สนใจเมนูที่ถูก soft delete)
-		menuItem := models.MenuItem{
-			Name:          menuRow.Name,
-			NameEn:        menuRow.NameEn,
-			NameCh:        menuRow.NameCh,
-			Description:   menuRow.Description,
-			DescriptionEn: menuRow.DescriptionEn,
-			DescriptionCh: menuRow.DescriptionCh,
-			CategoryID:    category.ID,
-			Price:         int16(menuRow.Price),
-			Is_available:  true,
+		// โหมด create จะไม่จับคู่เมนูเดิมเลย ส่วน upsert/sync จับคู่ด้วย SKU หรือชื่อ+หมวดหมู่
+		var existingMenu *models.MenuItem
+		if mode != ImportModeCreate {
+			existingMenu, err = findImportMatch(menuRow, category.ID)
+			if err != nil {
+				failedItems = append(failedItems, ImportError{
+					Row:        i + 2,
+					Error:      "Failed to look up existing menu item",
+					InputData:  menuRow.Name,
+					Suggestion: "เกิดข้อผิดพลาดในการค้นหาเมนูเดิม กรุณาลองใหม่อีกครั้ง",
+				})
+				continue
+			}
 		}
 
-		if err := tx.Create(&menuItem).Error; err != nil {
+		if existingMenu == nil {
+			// ตรวจสอบเฉพาะเมนูที่ไม่ได้ถูก soft delete (ชื่อเมนูต้องไม่ซ้ำทั้งระบบ)
+			var duplicate models.MenuItem
+			if err := db.DB.Where("name = ?", menuRow.Name).First(&duplicate).Error; err == nil {
+				failedItems = append(failedItems, ImportError{
+					Row:        i + 2,
+					Error:      "Menu item already exists",
+					InputData:  menuRow.Name,
+					Suggestion: getErrorSuggestion("Menu item already exists", menuRow.Name),
+				})
+				continue
+			}
+			if firstRow, ok := newNames[menuRow.Name]; ok {
+				failedItems = append(failedItems, ImportError{
+					Row:        i + 2,
+					Error:      fmt.Sprintf("Duplicate row in file (same as row %d)", firstRow),
+					InputData:  menuRow.Name,
+					Suggestion: getErrorSuggestion("Duplicate row in file", menuRow.Name),
+				})
+				continue
+			}
+		} else if firstRow, ok := matchedIDs[existingMenu.ID]; ok {
 			failedItems = append(failedItems, ImportError{
 				Row:        i + 2,
-				Error:      "Failed to create menu item",
-				InputData:  row[8],
-				Suggestion: getErrorSuggestion("Failed to create menu item", row[8]),
+				Error:      fmt.Sprintf("Duplicate row in file (same as row %d)", firstRow),
+				InputData:  menuRow.Name,
+				Suggestion: getErrorSuggestion("Duplicate row in file", menuRow.Name),
 			})
-			tx.Rollback()
 			continue
 		}
 
-		// สร้างกลุ่มตัวเลือกและตัวเลือก
-		for _, group := range menuRow.OptionGroups {
-			optionGroup := models.OptionGroup{
-				MenuItemID:    menuItem.ID,
-				Name:          group.Name,
-				NameEn:        group.NameEn,
-				NameCh:        group.NameCh,
-				MaxSelections: group.MaxSelections,
-				IsRequired:    group.IsRequired,
+		change := ImportChange{Row: i + 2, Name: menuRow.Name}
+		if existingMenu == nil {
+			change.Action = ImportActionCreate
+		} else {
+			change.MenuItemID = existingMenu.ID
+			change.Fields = menuImportDiff(*existingMenu, menuRow, category.ID)
+			change.Action = ImportActionUpdate
+			if len(change.Fields) == 0 {
+				change.Action = ImportActionUnchanged
 			}
+		}
 
-			if err := tx.Create(&optionGroup).Error; err != nil {
+		if !dryRun && change.Action != ImportActionUnchanged {
+			// แต่ละแถวมี transaction ของตัวเอง แถวที่ผิดพลาดจะไม่กระทบแถวอื่น
+			err := db.DB.Transaction(func(tx *gorm.DB) error {
+				if existingMenu == nil {
+					menuItem, err := createImportedMenu(tx, menuRow, category.ID)
+					if err != nil {
+						return err
+					}
+					change.MenuItemID = menuItem.ID
+					return nil
+				}
+				return updateImportedMenu(tx, existingMenu, menuRow, category.ID, change.Fields)
+			})
+			if err != nil {
 				failedItems = append(failedItems, ImportError{
 					Row:        i + 2,
-					Error:      "Failed to create option group",
-					InputData:  row[8],
-					Suggestion: getErrorSuggestion("Failed to create option group", row[8]),
+					Error:      "Failed to save menu item: " + err.Error(),
+					InputData:  menuRow.Name,
+					Suggestion: "เกิดข้อผิดพลาดในการบันทึกข้อมูล กรุณาลองใหม่อีกครั้ง",
 				})
-				tx.Rollback()
 				continue
 			}
-
-			for _, opt := range group.Options {
-				option := models.MenuOption{
-					GroupID: optionGroup.ID,
-					Name:    opt.Name,
-					NameEn:  opt.NameEn,
-					NameCh:  opt.NameCh,
-					Price:   opt.Price,
-				}
-
-				if err := tx.Create(&option).Error; err != nil {
-					failedItems = append(failedItems, ImportError{
-						Row:        i + 2,
-						Error:      "Failed to create option",
-						InputData:  row[8],
-						Suggestion: getErrorSuggestion("Failed to create option", row[8]),
-					})
-					tx.Rollback()
-					continue
-				}
-			}
 		}
 
-		// ถ้าสำเร็จให้ commit transaction ของรายการนี้
-		if err := tx.Commit().Error; err != nil {
-			tx.Rollback()
-			failedItems = append(failedItems, ImportError{
-				Row:        i + 2,
-				Error:      "Failed to commit transaction",
-				InputData:  row[8],
-				Suggestion: "เกิดข้อผิดพลาดในการบันทึกข้อมูล กรุณาลองใหม่อีกครั้ง",
-			})
-			continue
+		if change.MenuItemID != 0 {
+			matchedIDs[change.MenuItemID] = i + 2
+		} else {
+			newNames[menuRow.Name] = i + 2
 		}
 
 		// เพิ่มรายการที่สำเร็จ
+		changes = append(changes, change)
 		successItems = append(successItems, menuRow)
 		successCount++
 	}
 
+	// โหมด sync ลบ (soft delete) เมนูที่ไม่อยู่ในไฟล์
+	if mode == ImportModeSync {
+		if len(failedItems) > 0 {
+			// แถวที่ผิดพลาดอาจเป็นเมนูที่ยังใช้อยู่ ลบไปจะหายโดยไม่ตั้งใจ
+			failedItems = append(failedItems, ImportError{
+				Row:        0,
+				Error:      "Skipped deleting menu items missing from file",
+				Suggestion: "มีแถวที่นำเข้าไม่สำเร็จ ระบบจึงยังไม่ลบเมนูที่ไม่อยู่ในไฟล์ กรุณาแก้ไขแล้วนำเข้าใหม่",
+			})
+		} else {
+			var missingMenus []models.MenuItem
+			if err := db.DB.Order("id").Find(&missingMenus).Error; err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to fetch menu items",
+				})
+			}
+
+			for _, menu := range missingMenus {
+				if _, ok := matchedIDs[menu.ID]; ok {
+					continue
+				}
+
+				if !dryRun {
+					if err := db.DB.Delete(&menu).Error; err != nil {
+						failedItems = append(failedItems, ImportError{
+							Row:        0,
+							Error:      "Failed to delete menu item",
+							InputData:  menu.Name,
+							Suggestion: "เกิดข้อผิดพลาดในการลบเมนู กรุณาลองใหม่อีกครั้ง",
+						})
+						continue
+					}
+				}
+
+				changes = append(changes, ImportChange{
+					Action:     ImportActionDelete,
+					MenuItemID: menu.ID,
+					Name:       menu.Name,
+				})
+			}
+		}
+	}
+
 	response := ImportResponse{
+		Mode:         mode,
+		DryRun:       dryRun,
 		Success:      successCount,
 		Failed:       len(failedItems),
 		FailedItems:  failedItems,
 		SuccessItems: successItems,
+		Changes:      changes,
 	}
 
 	return c.JSON(response)
 }
+
+// findImportMatch หาเมนูเดิมที่ตรงกับแถว โดยใช้ SKU ก่อน ถ้าไม่มีใช้ชื่อ+หมวดหมู่ (nil = ไม่พบ)
+func findImportMatch(row MenuImportRow, categoryID uint) (*models.MenuItem, error) {
+	query := func(where string, args ...interface{}) (*models.MenuItem, error) {
+		var menu models.MenuItem
+		err := db.DB.Preload("Tags").
+			Preload("OptionGroups", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+			Preload("OptionGroups.Options", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+			Preload("OptionGroups.Options.Tags").
+			Where(where, args...).
+			First(&menu).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &menu, nil
+	}
+
+	if row.SKU != "" {
+		menu, err := query("sku = ?", row.SKU)
+		if menu != nil || err != nil {
+			return menu, err
+		}
+	}
+	return query("name = ? AND category_id = ?", row.Name, categoryID)
+}
+
+// importOptionGroups แปลงกลุ่มตัวเลือกจากไฟล์เป็นโมเดล (ยังไม่บันทึก)
+func importOptionGroups(groups []OptionGroupImport) []models.OptionGroup {
+	result := make([]models.OptionGroup, 0, len(groups))
+	for _, group := range groups {
+		optionGroup := models.OptionGroup{
+			Name:          group.Name,
+			NameEn:        group.NameEn,
+			NameCh:        group.NameCh,
+			MaxSelections: group.MaxSelections,
+			IsRequired:    group.IsRequired,
+		}
+		for _, opt := range group.Options {
+			option := models.MenuOption{
+				Name:   opt.Name,
+				NameEn: opt.NameEn,
+				NameCh: opt.NameCh,
+				Price:  opt.Price,
+			}
+			// ใส่แค่ code ไว้เทียบกับของเดิม ตอนบันทึกจะดึงแท็กจริงจากฐานข้อมูล
+			for _, code := range opt.Tags {
+				option.Tags = append(option.Tags, models.DietaryTag{Code: code})
+			}
+			optionGroup.Options = append(optionGroup.Options, option)
+		}
+		result = append(result, optionGroup)
+	}
+	return result
+}
+
+// menuImportDiff คืนชื่อฟิลด์ที่แถวในไฟล์ต่างจากเมนูเดิม
+func menuImportDiff(existing models.MenuItem, row MenuImportRow, categoryID uint) []string {
+	fields := []string{}
+	compare := func(field string, changed bool) {
+		if changed {
+			fields = append(fields, field)
+		}
+	}
+
+	compare("Name", existing.Name != row.Name)
+	compare("NameEn", existing.NameEn != row.NameEn)
+	compare("NameCh", existing.NameCh != row.NameCh)
+	compare("Description", existing.Description != row.Description)
+	compare("DescriptionEn", existing.DescriptionEn != row.DescriptionEn)
+	compare("DescriptionCh", existing.DescriptionCh != row.DescriptionCh)
+	compare("Category", existing.CategoryID != categoryID)
+	compare("Price", existing.Price != int16(row.Price))
+	compare("Available", existing.Is_available != row.IsAvailable)
+	// SKU ว่างในไฟล์หมายถึงไม่แก้ไข ไม่ใช่ล้างค่า
+	compare("SKU", row.SKU != "" && (existing.SKU == nil || *existing.SKU != row.SKU))
+	compare("OptionGroups", formatOptionString(existing.OptionGroups) != formatImportedOptionString(row.OptionGroups))
+	compare("Tags", row.Tags != nil && !slices.Equal(tagCodes(existing.Tags), sortedCodes(row.Tags)))
+	compare("SpicyLevel", row.SpicyLevel != nil && existing.SpicyLevel != *row.SpicyLevel)
+
+	return fields
+}
+
+// createImportedMenu สร้างเมนูใหม่พร้อมกลุ่มตัวเลือกจากแถวในไฟล์
+func createImportedMenu(tx *gorm.DB, row MenuImportRow, categoryID uint) (*models.MenuItem, error) {
+	menuItem := models.MenuItem{
+		Name:          row.Name,
+		NameEn:        row.NameEn,
+		NameCh:        row.NameCh,
+		Description:   row.Description,
+		DescriptionEn: row.DescriptionEn,
+		DescriptionCh: row.DescriptionCh,
+		CategoryID:    categoryID,
+		Price:         int16(row.Price),
+		Is_available:  row.IsAvailable,
+		DisplayOrder:  nextMenuDisplayOrder(tx, categoryID),
+	}
+	if row.SKU != "" {
+		menuItem.SKU = &row.SKU
+	}
+	if row.SpicyLevel != nil {
+		menuItem.SpicyLevel = *row.SpicyLevel
+	}
+
+	if err := tx.Create(&menuItem).Error; err != nil {
+		return nil, err
+	}
+
+	if len(row.Tags) > 0 {
+		if err := replaceImportedTags(tx, &menuItem, row.Tags); err != nil {
+			return nil, err
+		}
+	}
+
+	// default:true ทำให้ GORM ข้ามค่า false ตอน Create จึงต้องอัปเดตแยก
+	if !row.IsAvailable {
+		if err := tx.Model(&menuItem).Update("is_available", false).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if err := createImportedOptionGroups(tx, menuItem.ID, row.OptionGroups); err != nil {
+		return nil, err
+	}
+
+	return &menuItem, nil
+}
+
+// createImportedOptionGroups สร้างกลุ่มตัวเลือกและตัวเลือกของเมนู
+func createImportedOptionGroups(tx *gorm.DB, menuItemID uint, groups []OptionGroupImport) error {
+	optionIDs := map[string]uint{} // ชื่อตัวเลือกที่สร้างแล้ว -> ID ใช้หาตัวเลือกแม่ของกลุ่มย่อย
+	for i, group := range importOptionGroups(groups) {
+		options := group.Options
+		group.Options = nil
+		group.MenuItemID = &menuItemID
+		if parentName := groups[i].ParentOption; parentName != "" {
+			parentID, ok := optionIDs[parentName]
+			if !ok {
+				return fmt.Errorf("parent option %q not found in earlier option groups", parentName)
+			}
+			group.ParentOptionID = &parentID
+		}
+		if err := tx.Create(&group).Error; err != nil {
+			return err
+		}
+
+		for _, option := range options {
+			tags := option.Tags
+			option.Tags = nil
+			option.GroupID = group.ID
+			if err := tx.Create(&option).Error; err != nil {
+				return err
+			}
+			optionIDs[option.Name] = option.ID
+			if len(tags) > 0 {
+				if err := replaceImportedTags(tx, &option, tagCodes(tags)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// updateImportedMenu อัปเดตเมนูเดิมตามแถวในไฟล์ ถ้ากลุ่มตัวเลือกเปลี่ยนจะเอากลุ่มเดิมออกแล้วสร้างใหม่
+// (กลุ่มเดิมถูก soft delete ไม่ลบจริงเพราะ OrderItemOption เก่ายังอ้างถึงตัวเลือกเดิม)
+func updateImportedMenu(tx *gorm.DB, existing *models.MenuItem, row MenuImportRow, categoryID uint, fields []string) error {
+	updates := map[string]interface{}{
+		"name":           row.Name,
+		"name_en":        row.NameEn,
+		"name_ch":        row.NameCh,
+		"description":    row.Description,
+		"description_en": row.DescriptionEn,
+		"description_ch": row.DescriptionCh,
+		"category_id":    categoryID,
+		"price":          int16(row.Price),
+		"is_available":   row.IsAvailable,
+	}
+	if row.SKU != "" {
+		updates["sku"] = row.SKU
+	}
+	if row.SpicyLevel != nil {
+		updates["spicy_level"] = *row.SpicyLevel
+	}
+
+	if err := tx.Model(existing).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	if slices.Contains(fields, "Tags") {
+		if err := replaceImportedTags(tx, existing, row.Tags); err != nil {
+			return err
+		}
+	}
+
+	if !slices.Contains(fields, "OptionGroups") {
+		return nil
+	}
+
+	// กลุ่มที่ใช้ร่วมกับเมนูอื่นจะแค่ถูกเอาออกจากเมนูนี้ ส่วนกลุ่มที่ไม่มีเมนูอื่นใช้แล้วจะถูก soft delete
+	for _, group := range existing.OptionGroups {
+		if err := unlinkOptionGroup(tx, existing.ID, group, true); err != nil {
+			return err
+		}
+	}
+	return createImportedOptionGroups(tx, existing.ID, row.OptionGroups)
+}
+
+// importTagCodes รวม code ของแท็กทั้งหมดในแถว (ทั้งเมนูและตัวเลือก) ไม่ซ้ำกัน
+func importTagCodes(row MenuImportRow) []string {
+	codes := append([]string{}, row.Tags...)
+	for _, group := range row.OptionGroups {
+		for _, opt := range group.Options {
+			codes = append(codes, opt.Tags...)
+		}
+	}
+	return parseTagCodes(strings.Join(codes, ","), ",")
+}
+
+// replaceImportedTags แทนที่แท็กของเมนูหรือตัวเลือกด้วยแท็กตาม code (ไม่มี code = ล้างแท็ก)
+func replaceImportedTags(tx *gorm.DB, owner interface{}, codes []string) error {
+	if len(codes) == 0 {
+		return tx.Model(owner).Association("Tags").Clear()
+	}
+	tags, err := resolveDietaryTags(tx, codes)
+	if err != nil {
+		return err
+	}
+	return tx.Model(owner).Association("Tags").Replace(tags)
+}
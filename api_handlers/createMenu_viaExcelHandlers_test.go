@@ -0,0 +1,287 @@
+package api_handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestFormatOptionStringRoundTrip(t *testing.T) {
+	groups := []models.OptionGroup{
+		{
+			Name: "ความเผ็ด", NameEn: "Spiciness", NameCh: "辣度", MaxSelections: 1, IsRequired: true,
+			Options: []models.MenuOption{
+				{Name: "ไม่เผ็ด", NameEn: "Mild", NameCh: "不辣", Price: 0},
+				{Name: "เผ็ดมาก", NameEn: "Hot", NameCh: "很辣", Price: 5.5},
+			},
+		},
+		{
+			Name: "ท็อปปิ้ง", NameEn: "Topping", NameCh: "配料", MaxSelections: 2, IsRequired: false,
+			Options: []models.MenuOption{
+				{Name: "ไข่ดาว", NameEn: "Fried egg", NameCh: "煎蛋", Price: 10},
+			},
+		},
+	}
+
+	parsed, err := parseOptionString(formatOptionString(groups))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(parsed))
+	assert.Equal(t, "Spiciness", parsed[0].NameEn)
+	assert.True(t, parsed[0].IsRequired)
+	assert.Equal(t, 2, len(parsed[0].Options))
+	assert.Equal(t, 5.5, parsed[0].Options[1].Price)
+	assert.Equal(t, 2, parsed[1].MaxSelections)
+	assert.Equal(t, "Fried egg", parsed[1].Options[0].NameEn)
+}
+
+func TestFormatOptionStringNestedGroups(t *testing.T) {
+	soupID := uint(7)
+	groups := []models.OptionGroup{
+		{
+			Name: "แบบ", NameEn: "Style", NameCh: "做法", MaxSelections: 1, IsRequired: true,
+			Options: []models.MenuOption{{ID: soupID, Name: "น้ำ", NameEn: "Soup", NameCh: "汤"}},
+		},
+		{
+			Name: "เส้น", NameEn: "Noodle", NameCh: "面", MaxSelections: 1, IsRequired: true, ParentOptionID: &soupID,
+			Options: []models.MenuOption{{Name: "เส้นเล็ก", NameEn: "Thin", NameCh: "细面"}},
+		},
+	}
+
+	formatted := formatOptionString(groups)
+	parsed, err := parseOptionString(formatted)
+	assert.Nil(t, err)
+	assert.Equal(t, "", parsed[0].ParentOption)
+	assert.Equal(t, "น้ำ", parsed[1].ParentOption)
+	assert.Equal(t, formatted, formatImportedOptionString(parsed))
+
+	// ตัวเลือกแม่ต้องอยู่ในกลุ่มก่อนหน้า
+	_, err = parseOptionString("เส้น|Noodle|面|1|true|เส้นเล็ก:Thin:细面:0|น้ำ")
+	assert.NotNil(t, err)
+}
+
+func TestExportMenuToExcel(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+
+	app.Get("/api/menu/export", ExportMenuToExcel)
+	app.Post("/api/menu/import", ImportMenuFromExcel)
+
+	menu := models.MenuItem{
+		Name: "ต้มยำกุ้ง", NameEn: "Tom Yum", NameCh: "冬阴功", CategoryID: 1, Price: 120, Is_available: false,
+		OptionGroups: []models.OptionGroup{{
+			Name: "ขนาด", NameEn: "Size", NameCh: "大小", MaxSelections: 1, IsRequired: true,
+			Options: []models.MenuOption{{Name: "ใหญ่", NameEn: "Large", NameCh: "大", Price: 30}},
+		}},
+	}
+	db.DB.Create(&menu)
+	db.DB.Model(&menu).Update("is_available", false)
+
+	// กรณีทดสอบที่ 1: ส่งออก CSV ได้ครบทุกคอลัมน์
+	t.Run("Success - Export CSV", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/menu/export?format=csv", nil)
+		resp, err := app.Test(req)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Disposition"), ".csv")
+
+		rows, err := csv.NewReader(resp.Body).ReadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(rows))
+		assert.Equal(t, "Default Category", rows[1][6])
+		assert.Equal(t, "120", rows[1][7])
+		assert.Equal(t, "ขนาด|Size|大小|1|true|ใหญ่:Large:大:30", rows[1][8])
+		assert.Equal(t, "false", rows[1][9])
+	})
+
+	// กรณีทดสอบที่ 2: ไฟล์ xlsx ที่ส่งออกนำเข้ากลับได้
+	t.Run("Success - Export xlsx and re-import", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/menu/export?format=xlsx", nil)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		workbook, err := excelize.OpenReader(resp.Body)
+		assert.Nil(t, err)
+		var file bytes.Buffer
+		workbook.Write(&file)
+
+		// ลบเมนูเดิมเพื่อให้นำเข้ากลับได้โดยไม่ชนชื่อซ้ำ
+		db.DB.Unscoped().Delete(&models.MenuOption{}, "1 = 1")
+		db.DB.Unscoped().Delete(&models.OptionGroup{}, "1 = 1")
+		db.DB.Unscoped().Delete(&models.MenuItem{}, "1 = 1")
+
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, _ := writer.CreateFormFile("file", "menu.xlsx")
+		part.Write(file.Bytes())
+		writer.Close()
+
+		req = httptest.NewRequest("POST", "/api/menu/import", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var imported models.MenuItem
+		assert.Nil(t, db.DB.Preload("OptionGroups.Options").Where("name = ?", "ต้มยำกุ้ง").First(&imported).Error)
+		assert.False(t, imported.Is_available)
+		assert.Equal(t, int16(120), imported.Price)
+		assert.Equal(t, 1, len(imported.OptionGroups))
+		assert.Equal(t, 30.0, imported.OptionGroups[0].Options[0].Price)
+	})
+
+	// กรณีทดสอบที่ 3: รูปแบบไฟล์ไม่รองรับ
+	t.Run("Failure - Invalid format", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/menu/export?format=pdf", nil)
+		resp, err := app.Test(req)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+// newImportRequest สร้าง request อัปโหลดไฟล์ CSV สำหรับทดสอบการนำเข้า
+func newImportRequest(t *testing.T, query string, rows [][]string) *http.Request {
+	var file bytes.Buffer
+	csvWriter := csv.NewWriter(&file)
+	assert.Nil(t, csvWriter.WriteAll(append([][]string{menuSheetHeaders}, rows...)))
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", "menu.csv")
+	part.Write(file.Bytes())
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/api/menu/import"+query, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestImportMenuModes(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+
+	app.Post("/api/menu/import", ImportMenuFromExcel)
+
+	sku := "TY-01"
+	db.DB.Create(&models.MenuItem{Name: "ต้มยำกุ้ง", NameEn: "Tom Yum", NameCh: "冬阴功", CategoryID: 1, Price: 120, SKU: &sku})
+	db.DB.Create(&models.MenuItem{Name: "ผัดไทย", NameEn: "Pad Thai", NameCh: "泰式炒河粉", CategoryID: 1, Price: 80})
+	db.DB.Create(&models.MenuItem{Name: "ส้มตำ", NameEn: "Papaya Salad", NameCh: "青木瓜沙拉", CategoryID: 1, Price: 60})
+
+	rows := [][]string{
+		{"ต้มยำกุ้งน้ำข้น", "Tom Yum", "冬阴功", "", "", "", "Default Category", "150", "", "true", "TY-01"},
+		{"ผัดไทย", "Pad Thai", "泰式炒河粉", "", "", "", "Default Category", "80", "", "true", ""},
+		{"ข้าวผัด", "Fried Rice", "炒饭", "", "", "", "Default Category", "70", "ขนาด|Size|大小|1|true|ใหญ่:Large:大:10", "true", ""},
+	}
+
+	decode := func(resp *http.Response) ImportResponse {
+		var result ImportResponse
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(&result))
+		return result
+	}
+
+	// กรณีทดสอบที่ 1: dry run คืน diff ครบโดยไม่แก้ฐานข้อมูล
+	t.Run("Success - Dry run sync", func(t *testing.T) {
+		resp, err := app.Test(newImportRequest(t, "?mode=sync&dry_run=true", rows))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		result := decode(resp)
+		assert.True(t, result.DryRun)
+		assert.Equal(t, 4, len(result.Changes))
+		assert.Equal(t, ImportActionUpdate, result.Changes[0].Action)
+		assert.Equal(t, []string{"Name", "Price"}, result.Changes[0].Fields)
+		assert.Equal(t, ImportActionUnchanged, result.Changes[1].Action)
+		assert.Equal(t, ImportActionCreate, result.Changes[2].Action)
+		assert.Equal(t, ImportActionDelete, result.Changes[3].Action)
+		assert.Equal(t, "ส้มตำ", result.Changes[3].Name)
+
+		var count int64
+		db.DB.Model(&models.MenuItem{}).Count(&count)
+		assert.Equal(t, int64(3), count)
+		assert.Nil(t, db.DB.Where("name = ?", "ต้มยำกุ้ง").First(&models.MenuItem{}).Error)
+	})
+
+	// กรณีทดสอบที่ 2: create mode เดิมยังปฏิเสธเมนูที่ชื่อซ้ำ
+	t.Run("Failure - Create mode duplicate", func(t *testing.T) {
+		resp, err := app.Test(newImportRequest(t, "?dry_run=true", rows[1:2]))
+		assert.Nil(t, err)
+
+		result := decode(resp)
+		assert.Equal(t, 1, result.Failed)
+		assert.Equal(t, "Menu item already exists", result.FailedItems[0].Error)
+	})
+
+	// กรณีทดสอบที่ 3: upsert อัปเดตเมนูเดิมและไม่ลบเมนูที่ไม่อยู่ในไฟล์
+	t.Run("Success - Upsert", func(t *testing.T) {
+		resp, err := app.Test(newImportRequest(t, "?mode=upsert", rows))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		result := decode(resp)
+		assert.Equal(t, 3, result.Success)
+
+		var updated models.MenuItem
+		assert.Nil(t, db.DB.Where("sku = ?", "TY-01").First(&updated).Error)
+		assert.Equal(t, "ต้มยำกุ้งน้ำข้น", updated.Name)
+		assert.Equal(t, int16(150), updated.Price)
+
+		var created models.MenuItem
+		assert.Nil(t, db.DB.Preload("OptionGroups.Options").Where("name = ?", "ข้าวผัด").First(&created).Error)
+		assert.Equal(t, 1, len(created.OptionGroups))
+
+		assert.Nil(t, db.DB.Where("name = ?", "ส้มตำ").First(&models.MenuItem{}).Error)
+	})
+
+	// กรณีทดสอบที่ 4: sync soft delete เมนูที่ไม่อยู่ในไฟล์ และแทนที่กลุ่มตัวเลือกที่เปลี่ยน
+	t.Run("Success - Sync", func(t *testing.T) {
+		rows[2][8] = "ขนาด|Size|大小|1|true|ใหญ่:Large:大:15"
+		resp, err := app.Test(newImportRequest(t, "?mode=sync", rows))
+		assert.Nil(t, err)
+
+		result := decode(resp)
+		assert.Equal(t, 0, result.Failed)
+		assert.Equal(t, ImportActionUpdate, result.Changes[2].Action)
+		assert.Equal(t, []string{"OptionGroups"}, result.Changes[2].Fields)
+
+		assert.NotNil(t, db.DB.Where("name = ?", "ส้มตำ").First(&models.MenuItem{}).Error)
+		assert.Nil(t, db.DB.Unscoped().Where("name = ?", "ส้มตำ").First(&models.MenuItem{}).Error)
+
+		var menu models.MenuItem
+		assert.Nil(t, db.DB.Preload("OptionGroups.Options").Where("name = ?", "ข้าวผัด").First(&menu).Error)
+		assert.Equal(t, 1, len(menu.OptionGroups))
+		assert.Equal(t, 15.0, menu.OptionGroups[0].Options[0].Price)
+	})
+
+	// กรณีทดสอบที่ 5: sync ไม่ลบอะไรถ้ามีแถวผิดพลาด
+	t.Run("Success - Sync skips delete on failed rows", func(t *testing.T) {
+		resp, err := app.Test(newImportRequest(t, "?mode=sync", [][]string{
+			{"ผัดไทย", "Pad Thai", "泰式炒河粉", "", "", "", "Default Category", "abc", "", "true", ""},
+		}))
+		assert.Nil(t, err)
+
+		result := decode(resp)
+		assert.Equal(t, 2, result.Failed)
+		assert.Equal(t, 0, len(result.Changes))
+		assert.Nil(t, db.DB.Where("name = ?", "ข้าวผัด").First(&models.MenuItem{}).Error)
+	})
+
+	// กรณีทดสอบที่ 6: โหมดไม่ถูกต้อง
+	t.Run("Failure - Invalid mode", func(t *testing.T) {
+		resp, err := app.Test(newImportRequest(t, "?mode=replace", rows))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
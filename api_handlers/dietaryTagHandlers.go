@@ -0,0 +1,388 @@
+package api_handlers
+
+import (
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// parseTagCodes แยกรายการ code ที่คั่นด้วย sep (ตัดช่องว่าง, ตัวพิมพ์เล็ก, ตัดค่าว่าง/ซ้ำ)
+func parseTagCodes(value string, sep string) []string {
+	codes := []string{}
+	seen := map[string]bool{}
+	for _, code := range strings.Split(value, sep) {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	return codes
+}
+
+// resolveDietaryTags แปลง code เป็นแท็ก คืน error ถ้ามี code ที่ไม่รู้จัก
+func resolveDietaryTags(tx *gorm.DB, codes []string) ([]models.DietaryTag, error) {
+	tags := []models.DietaryTag{}
+	if len(codes) == 0 {
+		return tags, nil
+	}
+
+	if err := tx.Where("code IN ?", codes).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	if len(tags) != len(codes) {
+		found := map[string]bool{}
+		for _, tag := range tags {
+			found[tag.Code] = true
+		}
+		unknown := []string{}
+		for _, code := range codes {
+			if !found[code] {
+				unknown = append(unknown, code)
+			}
+		}
+		return nil, fmt.Errorf("unknown dietary tag: %s", strings.Join(unknown, ", "))
+	}
+	return tags, nil
+}
+
+// tagCodes คืน code ของแท็กทั้งหมดเรียงตามตัวอักษร (ใช้ส่งออกและเทียบความต่าง)
+func tagCodes(tags []models.DietaryTag) []string {
+	codes := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		codes = append(codes, tag.Code)
+	}
+	return sortedCodes(codes)
+}
+
+// sortedCodes คืนสำเนาของ code ที่เรียงแล้ว
+func sortedCodes(codes []string) []string {
+	sorted := append([]string{}, codes...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// validateDietaryTagRequest ตรวจข้อมูลแท็กและจัดรูปแบบ code
+func validateDietaryTagRequest(req *models.DietaryTagRequest) string {
+	req.Code = strings.ToLower(strings.TrimSpace(req.Code))
+	if req.Code == "" || strings.ContainsAny(req.Code, ",;:| ") {
+		return "Tag code is required and cannot contain spaces or , ; : |"
+	}
+	if req.Type != models.TagTypeAllergen && req.Type != models.TagTypeDietary {
+		return "Tag type must be allergen or dietary"
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return "Tag name is required"
+	}
+	return ""
+}
+
+// @Summary ดูแท็กสารก่อภูมิแพ้/โภชนาการ
+// @Description ดึงแท็กทั้งหมด กรองตามประเภทได้ ชื่อตามภาษาอยู่ใน localized
+// @Produce json
+// @Param type query string false "ประเภทแท็ก" Enums(allergen, dietary)
+// @Param lang query string false "รหัสภาษา เช่น th, en, zh"
+// @Success 200 {array} models.DietaryTag "รายการแท็ก"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
+// @Router /api/dietary-tags [get]
+// @Tags dietary-tags
+func GetDietaryTags(c *fiber.Ctx) error {
+	query := db.DB.Order("type, id")
+	if tagType := c.Query("type"); tagType != "" {
+		query = query.Where("type = ?", tagType)
+	}
+
+	var tags []models.DietaryTag
+	if err := query.Find(&tags).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch dietary tags",
+		})
+	}
+
+	l := newLocalizer(c)
+	for i := range tags {
+		l.dietaryTag(&tags[i])
+	}
+
+	return c.JSON(tags)
+}
+
+// @Summary สร้างแท็กสารก่อภูมิแพ้/โภชนาการ
+// @Description สร้างแท็กใหม่ code ต้องไม่ซ้ำและใช้อ้างอิงในไฟล์นำเข้าและตัวกรองเมนู
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.DietaryTagRequest true "ข้อมูลแท็ก"
+// @Success 201 {object} models.DietaryTag "แท็กที่สร้าง"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 409 {object} map[string]interface{} "code ซ้ำ"
+// @Router /api/dietary-tags [post]
+// @Tags dietary-tags
+func CreateDietaryTag(c *fiber.Ctx) error {
+	var req models.DietaryTagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input format",
+		})
+	}
+
+	if msg := validateDietaryTagRequest(&req); msg != "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	if err := db.DB.Where("code = ?", req.Code).First(&models.DietaryTag{}).Error; err == nil {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error": "Tag code already exists",
+		})
+	}
+
+	tag := models.DietaryTag{
+		Code:   req.Code,
+		Type:   req.Type,
+		Name:   req.Name,
+		NameEn: req.NameEn,
+		NameCh: req.NameCh,
+	}
+	if err := db.DB.Create(&tag).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create dietary tag",
+		})
+	}
+
+	return c.Status(http.StatusCreated).JSON(tag)
+}
+
+// @Summary แก้ไขแท็กสารก่อภูมิแพ้/โภชนาการ
+// @Description แก้ไขชื่อ ประเภท หรือ code ของแท็ก
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของแท็ก"
+// @Param request body models.DietaryTagRequest true "ข้อมูลแท็ก"
+// @Success 200 {object} models.DietaryTag "แท็กที่แก้ไขแล้ว"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 404 {object} map[string]interface{} "ไม่พบแท็ก"
+// @Failure 409 {object} map[string]interface{} "code ซ้ำ"
+// @Router /api/dietary-tags/{id} [put]
+// @Tags dietary-tags
+func UpdateDietaryTag(c *fiber.Ctx) error {
+	tagID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid tag ID format",
+		})
+	}
+
+	var tag models.DietaryTag
+	if err := db.DB.First(&tag, tagID).Error; err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Dietary tag not found",
+		})
+	}
+
+	var req models.DietaryTagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input format",
+		})
+	}
+
+	if msg := validateDietaryTagRequest(&req); msg != "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	if err := db.DB.Where("code = ? AND id != ?", req.Code, tag.ID).First(&models.DietaryTag{}).Error; err == nil {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error": "Tag code already exists",
+		})
+	}
+
+	if err := db.DB.Model(&tag).Updates(map[string]interface{}{
+		"code":    req.Code,
+		"type":    req.Type,
+		"name":    req.Name,
+		"name_en": req.NameEn,
+		"name_ch": req.NameCh,
+	}).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update dietary tag",
+		})
+	}
+
+	return c.JSON(tag)
+}
+
+// @Summary ลบแท็กสารก่อภูมิแพ้/โภชนาการ
+// @Description ลบแท็กและเอาออกจากเมนู/ตัวเลือกทั้งหมดที่ใช้อยู่
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของแท็ก"
+// @Success 200 {object} map[string]interface{} "ลบสำเร็จ"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 404 {object} map[string]interface{} "ไม่พบแท็ก"
+// @Router /api/dietary-tags/{id} [delete]
+// @Tags dietary-tags
+func DeleteDietaryTag(c *fiber.Ctx) error {
+	tagID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid tag ID format",
+		})
+	}
+
+	var tag models.DietaryTag
+	if err := db.DB.First(&tag, tagID).Error; err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Dietary tag not found",
+		})
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM menu_item_tags WHERE dietary_tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM menu_option_tags WHERE dietary_tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&tag).Error
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete dietary tag",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Dietary tag deleted successfully",
+	})
+}
+
+// @Summary กำหนดแท็กและระดับความเผ็ดของเมนู
+// @Description แทนที่แท็กทั้งหมดของเมนูด้วยรายการที่ส่งมา
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของเมนู"
+// @Param request body models.SetMenuTagsRequest true "code ของแท็กและระดับความเผ็ด"
+// @Success 200 {object} models.MenuItem "เมนูพร้อมแท็ก"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 404 {object} map[string]interface{} "ไม่พบเมนู"
+// @Router /api/menu/{id}/tags [put]
+// @Tags dietary-tags
+func SetMenuItemTags(c *fiber.Ctx) error {
+	menuID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid menu ID format",
+		})
+	}
+
+	var req models.SetMenuTagsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input format",
+		})
+	}
+
+	if req.SpicyLevel != nil && (*req.SpicyLevel < 0 || *req.SpicyLevel > models.MaxSpicyLevel) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Spicy level must be between 0 and %d", models.MaxSpicyLevel),
+		})
+	}
+
+	var menu models.MenuItem
+	if err := db.DB.First(&menu, menuID).Error; err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Menu not found",
+		})
+	}
+
+	tags, err := resolveDietaryTags(db.DB, parseTagCodes(strings.Join(req.Tags, ","), ","))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&menu).Association("Tags").Replace(tags); err != nil {
+			return err
+		}
+		if req.SpicyLevel != nil {
+			return tx.Model(&menu).Update("spicy_level", *req.SpicyLevel).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update menu tags",
+		})
+	}
+
+	db.DB.Preload("Tags").First(&menu, menu.ID)
+	return c.JSON(menu)
+}
+
+// @Summary กำหนดแท็กของตัวเลือกเสริม
+// @Description แทนที่แท็กทั้งหมดของตัวเลือก เช่น ท็อปปิ้งกุ้งใส่ shellfish
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของตัวเลือก"
+// @Param request body models.SetMenuTagsRequest true "code ของแท็ก (spicy_level ไม่ใช้)"
+// @Success 200 {object} models.MenuOption "ตัวเลือกพร้อมแท็ก"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 404 {object} map[string]interface{} "ไม่พบตัวเลือก"
+// @Router /api/menu/options/{id}/tags [put]
+// @Tags dietary-tags
+func SetMenuOptionTags(c *fiber.Ctx) error {
+	optionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid option ID format",
+		})
+	}
+
+	var req models.SetMenuTagsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input format",
+		})
+	}
+
+	var option models.MenuOption
+	if err := db.DB.First(&option, optionID).Error; err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Option not found",
+		})
+	}
+
+	tags, err := resolveDietaryTags(db.DB, parseTagCodes(strings.Join(req.Tags, ","), ","))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := db.DB.Model(&option).Association("Tags").Replace(tags); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update option tags",
+		})
+	}
+
+	db.DB.Preload("Tags").First(&option, option.ID)
+	return c.JSON(option)
+}
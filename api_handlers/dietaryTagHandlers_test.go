@@ -0,0 +1,152 @@
+package api_handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupDietaryTags(t *testing.T) {
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.DietaryTag{})
+	for _, tag := range models.DefaultDietaryTags {
+		db.DB.Create(&tag)
+	}
+}
+
+func TestDietaryTagFilters(t *testing.T) {
+	app := fiber.New()
+	setupDietaryTags(t)
+
+	app.Get("/api/menu/ActiveMenu", GetActiveMenu)
+	app.Put("/api/menu/:id/tags", SetMenuItemTags)
+	app.Put("/api/menu/options/:id/tags", SetMenuOptionTags)
+
+	satay := models.MenuItem{Name: "สะเต๊ะ", NameEn: "Satay", NameCh: "沙爹", CategoryID: 1, Price: 90}
+	salad := models.MenuItem{
+		Name: "สลัดผัก", NameEn: "Salad", NameCh: "沙拉", CategoryID: 1, Price: 70,
+		OptionGroups: []models.OptionGroup{{
+			Name: "น้ำสลัด", NameEn: "Dressing", NameCh: "酱汁", MaxSelections: 1, IsRequired: true,
+			Options: []models.MenuOption{
+				{Name: "งาญี่ปุ่น", NameEn: "Sesame", NameCh: "芝麻酱"},
+				{Name: "น้ำใส", NameEn: "Vinaigrette", NameCh: "油醋汁"},
+			},
+		}},
+	}
+	db.DB.Create(&satay)
+	db.DB.Create(&salad)
+	sesameOption := salad.OptionGroups[0].Options[0]
+
+	getMenu := func(query string) []models.MenuItem {
+		req := httptest.NewRequest("GET", "/api/menu/ActiveMenu"+query, nil)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var items []models.MenuItem
+		json.NewDecoder(resp.Body).Decode(&items)
+		return items
+	}
+
+	// กรณีทดสอบที่ 1: กำหนดแท็กและระดับความเผ็ด
+	t.Run("Success - Set tags", func(t *testing.T) {
+		spicy := 2
+		resp := sendJSON(t, app, "PUT", fmt.Sprintf("/api/menu/%d/tags", satay.ID), models.SetMenuTagsRequest{Tags: []string{"peanut", "Pork"}, SpicyLevel: &spicy})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var menu models.MenuItem
+		json.NewDecoder(resp.Body).Decode(&menu)
+		assert.Equal(t, []string{"peanut", "pork"}, tagCodes(menu.Tags))
+		assert.Equal(t, 2, menu.SpicyLevel)
+
+		resp = sendJSON(t, app, "PUT", fmt.Sprintf("/api/menu/%d/tags", salad.ID), models.SetMenuTagsRequest{Tags: []string{"vegan"}})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp = sendJSON(t, app, "PUT", fmt.Sprintf("/api/menu/options/%d/tags", sesameOption.ID), models.SetMenuTagsRequest{Tags: []string{"sesame"}})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 2: code ที่ไม่มีในระบบหรือความเผ็ดเกินช่วงถูกปฏิเสธ
+	t.Run("Failure - Invalid tags", func(t *testing.T) {
+		resp := sendJSON(t, app, "PUT", fmt.Sprintf("/api/menu/%d/tags", satay.ID), models.SetMenuTagsRequest{Tags: []string{"durian"}})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		spicy := models.MaxSpicyLevel + 1
+		resp = sendJSON(t, app, "PUT", fmt.Sprintf("/api/menu/%d/tags", satay.ID), models.SetMenuTagsRequest{SpicyLevel: &spicy})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 3: exclude_allergens ตัดเมนูและซ่อนตัวเลือกที่มีสารก่อภูมิแพ้
+	t.Run("Success - Exclude allergens", func(t *testing.T) {
+		items := getMenu("?exclude_allergens=peanut,sesame")
+		assert.Equal(t, 1, len(items))
+		assert.Equal(t, salad.ID, items[0].ID)
+		assert.Equal(t, 1, len(items[0].OptionGroups[0].Options))
+		assert.Equal(t, "น้ำใส", items[0].OptionGroups[0].Options[0].Name)
+	})
+
+	// กรณีทดสอบที่ 4: กลุ่มบังคับเลือกไม่เหลือตัวเลือก เมนูจึงถูกตัดออก
+	t.Run("Success - Drop unorderable item", func(t *testing.T) {
+		sendJSON(t, app, "PUT", fmt.Sprintf("/api/menu/options/%d/tags", salad.OptionGroups[0].Options[1].ID), models.SetMenuTagsRequest{Tags: []string{"sesame"}})
+		items := getMenu("?exclude_allergens=sesame")
+		assert.Equal(t, 1, len(items))
+		assert.Equal(t, satay.ID, items[0].ID)
+	})
+
+	// กรณีทดสอบที่ 5: tags ต้องมีครบ และ max_spicy
+	t.Run("Success - Required tags and spicy level", func(t *testing.T) {
+		items := getMenu("?tags=vegan")
+		assert.Equal(t, 1, len(items))
+		assert.Equal(t, salad.ID, items[0].ID)
+
+		assert.Equal(t, 0, len(getMenu("?tags=vegan,halal")))
+		assert.Equal(t, 1, len(getMenu("?max_spicy=1")))
+	})
+}
+
+func TestImportMenuTags(t *testing.T) {
+	app := fiber.New()
+	setupDietaryTags(t)
+
+	app.Post("/api/menu/import", ImportMenuFromExcel)
+
+	rows := [][]string{
+		{"ผัดกะเพรากุ้ง", "Basil Shrimp", "罗勒虾", "", "", "", "Default Category", "80", "ท็อปปิ้ง|Topping|配料|1|false|ไข่ดาว:Fried egg:煎蛋:10:egg", "true", "", "shellfish", "3"},
+		{"ข้าวผัด", "Fried Rice", "炒饭", "", "", "", "Default Category", "60", "", "true", "", "durian", ""},
+	}
+
+	// กรณีทดสอบที่ 1: นำเข้าแท็กของเมนูและตัวเลือก code ที่ไม่รู้จักเป็น error ของแถวนั้น
+	t.Run("Success - Import tags", func(t *testing.T) {
+		resp, err := app.Test(newImportRequest(t, "", rows))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var result ImportResponse
+		json.NewDecoder(resp.Body).Decode(&result)
+		assert.Equal(t, 1, result.Success)
+		assert.Equal(t, 1, result.Failed)
+		assert.Equal(t, 3, result.FailedItems[0].Row)
+
+		var menu models.MenuItem
+		db.DB.Preload("Tags").Preload("OptionGroups.Options.Tags").Where("name = ?", "ผัดกะเพรากุ้ง").First(&menu)
+		assert.Equal(t, []string{"shellfish"}, tagCodes(menu.Tags))
+		assert.Equal(t, 3, menu.SpicyLevel)
+		assert.Equal(t, []string{"egg"}, tagCodes(menu.OptionGroups[0].Options[0].Tags))
+	})
+
+	// กรณีทดสอบที่ 2: ส่งออกแล้วได้แท็กกลับมาในรูปแบบเดียวกัน
+	t.Run("Success - Export tags", func(t *testing.T) {
+		var items []models.MenuItem
+		db.DB.Preload("Category").Preload("Tags").Preload("OptionGroups.Options.Tags").Find(&items)
+		exported := menuExportRows(items)
+		assert.Equal(t, 2, len(exported))
+		assert.Equal(t, rows[0][8], exported[1][8])
+		assert.Equal(t, "shellfish", exported[1][11])
+		assert.Equal(t, "3", exported[1][12])
+	})
+}
@@ -0,0 +1,72 @@
+package api_handlers
+
+import (
+	"errors"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary ดึงรูปภาพเมนู/โปรโมชั่น
+// @Description ดึงไฟล์รูปจาก image store รองรับขนาด original, medium, thumb พร้อม ETag และ Cache-Control
+// @Produce image/jpeg
+// @Produce image/png
+// @Param id path integer true "ID ของรูป"
+// @Param size query string false "ขนาดรูป" Enums(original, medium, thumb)
+// @Success 200 {file} file "ไฟล์รูปภาพ"
+// @Success 304 "รูปไม่มีการเปลี่ยนแปลง"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 404 {object} map[string]interface{} "ไม่พบรูป"
+// @Router /images/{id} [get]
+// @Tags images
+func GetImage(c *fiber.Ctx) error {
+	imageID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid image ID format",
+		})
+	}
+
+	size := c.Query("size", "original")
+	if _, ok := service.ImageVariants[size]; !ok {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid image size",
+		})
+	}
+
+	var img models.StoredImage
+	if err := db.DB.First(&img, imageID).Error; err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Image not found",
+		})
+	}
+
+	// รูปแต่ละ ID ไม่เปลี่ยนเนื้อหา (อัปโหลดใหม่จะได้ ID ใหม่) จึง cache ได้ยาว
+	etag := fmt.Sprintf(`"%s-%s"`, img.Checksum[:16], size)
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, "public, max-age=31536000, immutable")
+
+	if c.Get(fiber.HeaderIfNoneMatch) == etag {
+		return c.SendStatus(http.StatusNotModified)
+	}
+
+	data, err := service.Images.Get(service.ImageVariantKey(img, size))
+	if err != nil {
+		if errors.Is(err, service.ErrImageNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"error": "Image file not found",
+			})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to read image",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, img.ContentType)
+	return c.Send(data)
+}
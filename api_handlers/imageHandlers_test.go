@@ -0,0 +1,181 @@
+package api_handlers
+
+import (
+	"bytes"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestPNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestGetImage(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.StoredImage{})
+	service.Images = &service.LocalImageStore{BaseDir: t.TempDir()}
+
+	app.Get("/images/:id", GetImage)
+
+	stored, err := service.SaveImage(db.DB, createTestPNG(t, 1000, 500))
+	assert.Nil(t, err)
+	assert.Equal(t, 1000, stored.Width)
+
+	// กรณีทดสอบที่ 1: ได้ thumbnail ที่ย่อขนาดแล้วพร้อม header สำหรับ cache
+	t.Run("Success - Thumbnail with cache headers", func(t *testing.T) {
+		req := httptest.NewRequest("GET", models.ImageURL(&stored.ID)+"?size=thumb", nil)
+		resp, err := app.Test(req)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+		assert.NotEmpty(t, resp.Header.Get("ETag"))
+		assert.Contains(t, resp.Header.Get("Cache-Control"), "max-age")
+
+		thumb, _, err := image.Decode(resp.Body)
+		assert.Nil(t, err)
+		assert.Equal(t, service.ImageVariants["thumb"].Width, thumb.Bounds().Dx())
+	})
+
+	// กรณีทดสอบที่ 2: ส่ง If-None-Match ที่ตรงกันได้ 304
+	t.Run("Success - Not modified", func(t *testing.T) {
+		req := httptest.NewRequest("GET", models.ImageURL(&stored.ID), nil)
+		resp, _ := app.Test(req)
+		etag := resp.Header.Get("ETag")
+
+		req = httptest.NewRequest("GET", models.ImageURL(&stored.ID), nil)
+		req.Header.Set("If-None-Match", etag)
+		resp, err := app.Test(req)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 3: ขนาดไม่ถูกต้อง
+	t.Run("Failure - Invalid size", func(t *testing.T) {
+		req := httptest.NewRequest("GET", models.ImageURL(&stored.ID)+"?size=huge", nil)
+		resp, err := app.Test(req)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 4: ไม่พบรูป
+	t.Run("Failure - Image not found", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/images/9999", nil)
+		resp, err := app.Test(req)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 5: transaction ถูก rollback ไม่เหลือไฟล์ค้างใน store
+	t.Run("Success - Discard files on rollback", func(t *testing.T) {
+		tx := db.DB.Begin()
+		img, err := service.SaveImage(tx, createTestPNG(t, 300, 200))
+		assert.Nil(t, err)
+		_, err = service.Images.Get(service.ImageVariantKey(*img, "original"))
+		assert.Nil(t, err)
+
+		tx.Rollback()
+		service.DiscardImageFiles(*img)
+
+		for name := range service.ImageVariants {
+			_, err := service.Images.Get(service.ImageVariantKey(*img, name))
+			assert.ErrorIs(t, err, service.ErrImageNotFound)
+		}
+	})
+}
+
+func TestReplaceImage(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.StoredImage{}, &models.Promotion{}, &models.PromotionItem{})
+	service.Images = &service.LocalImageStore{BaseDir: t.TempDir()}
+
+	app.Get("/images/:id", GetImage)
+	app.Put("/api/menu/image/:id", UpdateMenuImage)
+	app.Put("/api/promotions/image/:id", UpdatePromotionImage)
+
+	upload := func(url string, width int) *http.Response {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, _ := writer.CreateFormFile("image", "photo.png")
+		part.Write(createTestPNG(t, width, 100))
+		writer.Close()
+
+		req := httptest.NewRequest("PUT", url, &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		return resp
+	}
+	imageStatus := func(id uint) int {
+		resp, err := app.Test(httptest.NewRequest("GET", models.ImageURL(&id), nil))
+		assert.Nil(t, err)
+		return resp.StatusCode
+	}
+
+	// กรณีทดสอบที่ 1: เปลี่ยนรูปเมนูซ้ำ รูปใหม่ยังเปิดได้ และรูปเดิมถูกลบ
+	t.Run("Success - Replace menu image", func(t *testing.T) {
+		menu := models.MenuItem{Name: "ผัดไทย", CategoryID: 1, Price: 80}
+		db.DB.Create(&menu)
+		url := fmt.Sprintf("/api/menu/image/%d", menu.ID)
+
+		upload(url, 200)
+		db.DB.First(&menu, menu.ID)
+		firstID := *menu.ImageID
+
+		upload(url, 300)
+		db.DB.First(&menu, menu.ID)
+		if assert.NotNil(t, menu.ImageID) {
+			assert.NotEqual(t, firstID, *menu.ImageID)
+			assert.Equal(t, http.StatusOK, imageStatus(*menu.ImageID))
+		}
+		assert.Equal(t, http.StatusNotFound, imageStatus(firstID))
+	})
+
+	// กรณีทดสอบที่ 2: เปลี่ยนรูปโปรโมชั่นซ้ำ รูปใหม่ยังเปิดได้ และรูปเดิมถูกลบ
+	t.Run("Success - Replace promotion image", func(t *testing.T) {
+		promotion := models.Promotion{Name: "ชุดคู่", StartDate: time.Now(), EndDate: time.Now().AddDate(0, 1, 0), Price: 199}
+		db.DB.Create(&promotion)
+		url := fmt.Sprintf("/api/promotions/image/%d", promotion.ID)
+
+		upload(url, 200)
+		db.DB.First(&promotion, promotion.ID)
+		firstID := *promotion.ImageID
+
+		upload(url, 300)
+		db.DB.First(&promotion, promotion.ID)
+		if assert.NotNil(t, promotion.ImageID) {
+			assert.NotEqual(t, firstID, *promotion.ImageID)
+			assert.Equal(t, http.StatusOK, imageStatus(*promotion.ImageID))
+		}
+		assert.Equal(t, http.StatusNotFound, imageStatus(firstID))
+	})
+}
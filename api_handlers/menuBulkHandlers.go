@@ -0,0 +1,293 @@
+package api_handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// bulkMenuResult ผลการแก้ไขเมนูแบบกลุ่ม changes มีเฉพาะเมนูที่ค่าเปลี่ยนจริง
+type bulkMenuResult struct {
+	Preview bool                       `json:"preview"`
+	Action  string                     `json:"action"`
+	Matched int                        `json:"matched"`
+	Changed int                        `json:"changed"`
+	Changes []models.MenuBulkAuditItem `json:"changes"`
+	AuditID *uint                      `json:"audit_id,omitempty"`
+}
+
+// validateBulkMenuRequest ตรวจว่าส่งค่าที่ action ต้องใช้มาครบ
+func validateBulkMenuRequest(tx *gorm.DB, req models.BulkMenuRequest) error {
+	f := req.Filter
+	if !f.All && len(f.CategoryIDs) == 0 && len(f.MenuItemIDs) == 0 && len(f.Tags) == 0 {
+		return fmt.Errorf("filter is required (set all to true to change every menu item)")
+	}
+
+	for _, id := range f.CategoryIDs {
+		var category models.Category
+		if err := tx.First(&category, id).Error; err != nil {
+			return fmt.Errorf("category ID %d not found", id)
+		}
+	}
+
+	switch req.Action {
+	case models.BulkSetPrice:
+		if req.Amount == nil {
+			return fmt.Errorf("amount is required for %s", req.Action)
+		}
+	case models.BulkAdjustPrice:
+		if (req.Amount == nil) == (req.Percent == nil) {
+			return fmt.Errorf("either amount or percent is required for %s", req.Action)
+		}
+	case models.BulkMoveCategory:
+		if req.CategoryID == nil {
+			return fmt.Errorf("category_id is required for %s", req.Action)
+		}
+		var category models.Category
+		if err := tx.First(&category, *req.CategoryID).Error; err != nil {
+			return fmt.Errorf("category ID %d not found", *req.CategoryID)
+		}
+	case models.BulkAvailability, models.BulkRecommendation:
+	default:
+		return fmt.Errorf("unknown action %q", req.Action)
+	}
+	return nil
+}
+
+// findBulkMenuItems เมนูที่ตรงกับตัวกรองทุกข้อ หมวดหมู่รวมหมวดหมู่ย่อยด้วย
+func findBulkMenuItems(tx *gorm.DB, f models.BulkMenuFilter) ([]models.MenuItem, error) {
+	query := tx.Model(&models.MenuItem{})
+	if len(f.CategoryIDs) > 0 {
+		tree, err := loadCategoryTree(tx)
+		if err != nil {
+			return nil, err
+		}
+		var categoryIDs []uint
+		for _, id := range f.CategoryIDs {
+			categoryIDs = append(categoryIDs, tree.descendants(id)...)
+		}
+		query = query.Where("category_id IN ?", categoryIDs)
+	}
+	if len(f.MenuItemIDs) > 0 {
+		query = query.Where("id IN ?", f.MenuItemIDs)
+	}
+	if len(f.Tags) > 0 {
+		query = query.Where("id IN (?)", tx.Table("menu_item_tags").
+			Select("menu_item_tags.menu_item_id").
+			Joins("JOIN dietary_tags ON dietary_tags.id = menu_item_tags.dietary_tag_id").
+			Where("dietary_tags.code IN ?", f.Tags))
+	}
+
+	var items []models.MenuItem
+	if err := query.Order("id").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// bulkMenuPrice ราคาใหม่ของเมนูตาม action ปัดเป็นบาท
+func bulkMenuPrice(req models.BulkMenuRequest, price int16) (int16, error) {
+	var newPrice float64
+	switch {
+	case req.Action == models.BulkSetPrice:
+		newPrice = *req.Amount
+	case req.Percent != nil:
+		newPrice = float64(price) * (1 + *req.Percent/100)
+	default:
+		newPrice = float64(price) + *req.Amount
+	}
+	newPrice = math.Round(newPrice)
+	if newPrice < 0 || newPrice > math.MaxInt16 {
+		return 0, fmt.Errorf("price %.0f is out of range", newPrice)
+	}
+	return int16(newPrice), nil
+}
+
+// bulkMenuBool ค่าใหม่ของสถานะ ถ้าไม่ได้ส่ง value มาจะสลับสถานะเดิม
+func bulkMenuBool(value *bool, current bool) bool {
+	if value == nil {
+		return !current
+	}
+	return *value
+}
+
+// planBulkMenuChange คำนวณค่าใหม่ของเมนูตาม action คืน nil ถ้าค่าไม่เปลี่ยน
+func planBulkMenuChange(req models.BulkMenuRequest, item models.MenuItem) (*models.MenuBulkAuditItem, map[string]interface{}, error) {
+	change := models.MenuBulkAuditItem{MenuItemID: item.ID, Name: item.Name}
+	updates := map[string]interface{}{}
+
+	switch req.Action {
+	case models.BulkSetPrice, models.BulkAdjustPrice:
+		price, err := bulkMenuPrice(req, item.Price)
+		if err != nil {
+			return nil, nil, fmt.Errorf("menu item %q: %v", item.Name, err)
+		}
+		if price == item.Price {
+			return nil, nil, nil
+		}
+		change.Field = "price"
+		change.OldValue = strconv.Itoa(int(item.Price))
+		change.NewValue = strconv.Itoa(int(price))
+		updates["price"] = price
+	case models.BulkAvailability:
+		available := bulkMenuBool(req.Value, item.Is_available)
+		if available == item.Is_available {
+			return nil, nil, nil
+		}
+		change.Field = "is_available"
+		change.OldValue = strconv.FormatBool(item.Is_available)
+		change.NewValue = strconv.FormatBool(available)
+		updates["is_available"] = available
+	case models.BulkRecommendation:
+		recommended := bulkMenuBool(req.Value, item.IsRecommended)
+		if recommended == item.IsRecommended {
+			return nil, nil, nil
+		}
+		change.Field = "is_recommended"
+		change.OldValue = strconv.FormatBool(item.IsRecommended)
+		change.NewValue = strconv.FormatBool(recommended)
+		updates["is_recommended"] = recommended
+	case models.BulkMoveCategory:
+		if item.CategoryID == *req.CategoryID {
+			return nil, nil, nil
+		}
+		change.Field = "category_id"
+		change.OldValue = strconv.FormatUint(uint64(item.CategoryID), 10)
+		change.NewValue = strconv.FormatUint(uint64(*req.CategoryID), 10)
+		updates["category_id"] = *req.CategoryID
+	}
+	return &change, updates, nil
+}
+
+// @Summary แก้ไขเมนูแบบกลุ่ม
+// @Description เลือกเมนูด้วยตัวกรอง (หมวดหมู่รวมหมวดหมู่ย่อย, ID เมนู, แท็ก) แล้วแก้ไขทั้งหมดใน transaction เดียว
+// @Description action: set_price, adjust_price (amount หรือ percent), availability, move_category, recommended
+// @Description ส่ง preview = true เพื่อดูค่าก่อน/หลังโดยไม่บันทึก การแก้ไขจริงจะถูกบันทึกลง audit log
+// @Description ถ้าราคาของเมนูใดติดลบหรือเกินช่วง จะไม่มีเมนูใดถูกแก้ไข
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.BulkMenuRequest true "ตัวกรองและการแก้ไข"
+// @Success 200 {object} bulkMenuResult "ค่าก่อน/หลังของเมนูที่เปลี่ยน"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการแก้ไขเมนู"
+// @Router /api/menu/bulk [post]
+// @Tags menu
+func BulkUpdateMenu(c *fiber.Ctx) error {
+	var req models.BulkMenuRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+	if err := validateBulkMenuRequest(db.DB, req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	result := bulkMenuResult{Preview: req.Preview, Action: req.Action, Changes: []models.MenuBulkAuditItem{}}
+	var reqErr error
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		items, err := findBulkMenuItems(tx, req.Filter)
+		if err != nil {
+			return err
+		}
+		result.Matched = len(items)
+
+		var planned []map[string]interface{}
+		oldPrices := map[uint]int16{}
+		for _, item := range items {
+			oldPrices[item.ID] = item.Price
+			change, updates, err := planBulkMenuChange(req, item)
+			if err != nil {
+				reqErr = err
+				return err
+			}
+			if change != nil {
+				result.Changes = append(result.Changes, *change)
+				planned = append(planned, updates)
+			}
+		}
+		result.Changed = len(result.Changes)
+		if req.Preview || result.Changed == 0 {
+			return nil
+		}
+
+		now := time.Now()
+		for i, updates := range planned {
+			menuItemID := result.Changes[i].MenuItemID
+			// เมนูที่ย้ายหมวดหมู่ต่อท้ายหมวดหมู่ใหม่ตามลำดับ ID
+			if req.Action == models.BulkMoveCategory {
+				updates["display_order"] = nextMenuDisplayOrder(tx, *req.CategoryID)
+			}
+			if err := tx.Model(&models.MenuItem{}).Where("id = ?", menuItemID).Updates(updates).Error; err != nil {
+				return err
+			}
+			if price, ok := updates["price"].(int16); ok {
+				if err := recordMenuPriceChange(tx, menuItemID, oldPrices[menuItemID], price, nil, now); err != nil {
+					return err
+				}
+			}
+		}
+
+		request, _ := json.Marshal(req)
+		audit := models.MenuBulkAudit{
+			Action:    req.Action,
+			Request:   string(request),
+			Note:      req.Note,
+			ItemCount: result.Changed,
+			CreatedBy: currentUserID(c),
+			Items:     result.Changes,
+		}
+		if err := tx.Create(&audit).Error; err != nil {
+			return err
+		}
+		result.Changes = audit.Items
+		result.AuditID = &audit.ID
+		return nil
+	})
+	if reqErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": reqErr.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update menu items",
+		})
+	}
+	return c.JSON(result)
+}
+
+// @Summary ดูประวัติการแก้ไขเมนูแบบกลุ่ม
+// @Description เรียงจากล่าสุด พร้อมค่าก่อน/หลังของแต่ละเมนู
+// @Produce json
+// @Security BearerAuth
+// @Param limit query integer false "จำนวนรายการ (ค่าเริ่มต้น 50)"
+// @Success 200 {array} models.MenuBulkAudit "ประวัติการแก้ไข"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
+// @Router /api/menu/bulk/audits [get]
+// @Tags menu
+func GetMenuBulkAudits(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var audits []models.MenuBulkAudit
+	if err := db.DB.Preload("Items").Order("created_at DESC, id DESC").Limit(limit).Find(&audits).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch bulk menu audits",
+		})
+	}
+	return c.JSON(audits)
+}
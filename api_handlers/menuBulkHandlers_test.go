@@ -0,0 +1,151 @@
+package api_handlers
+
+import (
+	"encoding/json"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkUpdateMenu(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.DietaryTag{}, &models.MenuPriceHistory{}, &models.MenuBulkAudit{}, &models.MenuBulkAuditItem{})
+
+	app.Post("/api/menu/bulk", BulkUpdateMenu)
+	app.Get("/api/menu/bulk/audits", GetMenuBulkAudits)
+
+	send := func(body interface{}) (*http.Response, bulkMenuResult) {
+		resp := sendJSON(t, app, "POST", "/api/menu/bulk", body)
+		var result bulkMenuResult
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp, result
+	}
+
+	drinks := models.Category{Name: "เครื่องดื่ม"}
+	db.DB.Create(&drinks)
+	coffee := models.Category{Name: "กาแฟ", ParentID: &drinks.ID}
+	db.DB.Create(&coffee)
+
+	vegan := models.DietaryTag{Code: "vegan", Type: models.TagTypeDietary, Name: "วีแกน"}
+	db.DB.Create(&vegan)
+
+	latte := models.MenuItem{Name: "ลาเต้", CategoryID: coffee.ID, Price: 60, Is_available: true}
+	tea := models.MenuItem{Name: "ชาไทย", CategoryID: drinks.ID, Price: 45, Is_available: true}
+	salad := models.MenuItem{Name: "สลัด", CategoryID: 1, Price: 90, Is_available: true, Tags: []models.DietaryTag{vegan}}
+	for _, item := range []*models.MenuItem{&latte, &tea, &salad} {
+		db.DB.Create(item)
+	}
+
+	price := func(id uint) int16 {
+		var item models.MenuItem
+		db.DB.First(&item, id)
+		return item.Price
+	}
+
+	// กรณีทดสอบที่ 1: preview แสดงค่าก่อน/หลังแต่ไม่บันทึก หมวดหมู่รวมหมวดหมู่ย่อย
+	t.Run("Success - Preview percent adjustment", func(t *testing.T) {
+		percent := 10.0
+		resp, result := send(models.BulkMenuRequest{
+			Filter:  models.BulkMenuFilter{CategoryIDs: []uint{drinks.ID}},
+			Action:  models.BulkAdjustPrice,
+			Percent: &percent,
+			Preview: true,
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 2, result.Changed)
+		assert.Equal(t, "60", result.Changes[0].OldValue)
+		assert.Equal(t, "66", result.Changes[0].NewValue)
+		assert.Equal(t, "50", result.Changes[1].NewValue) // 49.5 ปัดเป็น 50
+		assert.Nil(t, result.AuditID)
+		assert.Equal(t, int16(60), price(latte.ID))
+	})
+
+	// กรณีทดสอบที่ 2: ปรับราคาจริง เก็บประวัติราคาและ audit log
+	t.Run("Success - Apply price change", func(t *testing.T) {
+		amount := -5.0
+		resp, result := send(models.BulkMenuRequest{
+			Filter: models.BulkMenuFilter{CategoryIDs: []uint{drinks.ID}, MenuItemIDs: []uint{tea.ID, salad.ID}},
+			Action: models.BulkAdjustPrice,
+			Amount: &amount,
+			Note:   "ลดราคาชา",
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 1, result.Matched)
+		assert.NotNil(t, result.AuditID)
+		assert.Equal(t, int16(40), price(tea.ID))
+		assert.Equal(t, int16(90), price(salad.ID))
+
+		var history []models.MenuPriceHistory
+		db.DB.Where("menu_item_id = ?", tea.ID).Order("effective_from").Find(&history)
+		assert.Len(t, history, 2)
+		assert.Equal(t, int16(45), history[0].Price)
+		assert.NotNil(t, history[0].EffectiveTo)
+		assert.Equal(t, int16(40), history[1].Price)
+
+		req := httptest.NewRequest("GET", "/api/menu/bulk/audits", nil)
+		resp, _ = app.Test(req)
+		var audits []models.MenuBulkAudit
+		json.NewDecoder(resp.Body).Decode(&audits)
+		assert.Len(t, audits, 1)
+		assert.Equal(t, "ลดราคาชา", audits[0].Note)
+		assert.Len(t, audits[0].Items, 1)
+	})
+
+	// กรณีทดสอบที่ 3: ถ้าราคาเมนูใดติดลบ ต้องไม่มีเมนูใดถูกแก้ไข
+	t.Run("Failure - Negative price rolls back", func(t *testing.T) {
+		amount := -50.0
+		resp, _ := send(models.BulkMenuRequest{
+			Filter: models.BulkMenuFilter{All: true},
+			Action: models.BulkAdjustPrice,
+			Amount: &amount,
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, int16(60), price(latte.ID))
+
+		resp, _ = send(models.BulkMenuRequest{Action: models.BulkSetPrice, Amount: &amount})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode) // ไม่มีตัวกรอง
+
+		resp, _ = send(models.BulkMenuRequest{Filter: models.BulkMenuFilter{All: true}, Action: "delete"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 4: ปิดขายตามแท็ก สลับเมนูแนะนำ และย้ายหมวดหมู่
+	t.Run("Success - Availability, recommended and category", func(t *testing.T) {
+		off := false
+		resp, result := send(models.BulkMenuRequest{
+			Filter: models.BulkMenuFilter{Tags: []string{"vegan"}},
+			Action: models.BulkAvailability,
+			Value:  &off,
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 1, result.Changed)
+		var updated models.MenuItem
+		db.DB.First(&updated, salad.ID)
+		assert.False(t, updated.Is_available)
+
+		resp, result = send(models.BulkMenuRequest{
+			Filter: models.BulkMenuFilter{MenuItemIDs: []uint{latte.ID, tea.ID}},
+			Action: models.BulkRecommendation,
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 2, result.Changed)
+		assert.Equal(t, "true", result.Changes[0].NewValue)
+
+		resp, result = send(models.BulkMenuRequest{
+			Filter:     models.BulkMenuFilter{CategoryIDs: []uint{coffee.ID}},
+			Action:     models.BulkMoveCategory,
+			CategoryID: &drinks.ID,
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var moved models.MenuItem
+		db.DB.First(&moved, latte.ID)
+		assert.Equal(t, drinks.ID, moved.CategoryID)
+		assert.Equal(t, 1, moved.DisplayOrder)
+	})
+}
@@ -0,0 +1,292 @@
+package api_handlers
+
+import (
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	bestSellerDays  = 30 // ช่วงเวลาที่ใช้คิดเมนูขายดีเริ่มต้น (วัน)
+	bestSellerLimit = 10 // จำนวนเมนูขายดีที่แสดงเริ่มต้น
+)
+
+// menuDisplayLess ลำดับเมนูในหมวดหมู่เดียวกัน: ปักหมุดก่อน แล้วตาม display_order และ ID
+func menuDisplayLess(a, b models.MenuItem) bool {
+	if a.IsPinned != b.IsPinned {
+		return a.IsPinned
+	}
+	if a.DisplayOrder != b.DisplayOrder {
+		return a.DisplayOrder < b.DisplayOrder
+	}
+	return a.ID < b.ID
+}
+
+// nextMenuDisplayOrder ลำดับถัดไปของหมวดหมู่ ใช้ให้เมนูใหม่ต่อท้ายหมวดหมู่
+func nextMenuDisplayOrder(tx *gorm.DB, categoryID uint) int {
+	var maxOrder *int
+	tx.Model(&models.MenuItem{}).Where("category_id = ?", categoryID).Select("MAX(display_order)").Scan(&maxOrder)
+	if maxOrder == nil {
+		return 0
+	}
+	return *maxOrder + 1
+}
+
+// bestSellerMenuIDs ID เมนูที่ขายได้มากที่สุดตั้งแต่ since นับจำนวนจาก OrderItem ที่ไม่ถูกยกเลิก
+// เฉพาะเมนูที่ยังเปิดขาย limit <= 0 คือไม่จำกัด
+func bestSellerMenuIDs(tx *gorm.DB, since time.Time, limit int) ([]uint, error) {
+	var rows []struct {
+		MenuItemID uint
+		Sold       int
+	}
+	query := tx.Model(&models.OrderItem{}).
+		Select("order_items.menu_item_id, SUM(order_items.quantity) AS sold").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Joins("JOIN menu_items ON menu_items.id = order_items.menu_item_id").
+		Where("order_items.status <> ? AND orders.status <> ?", "cancelled", "cancelled").
+		Where("order_items.created_at >= ?", since).
+		Where("menu_items.is_available = ? AND menu_items.deleted_at IS NULL", true).
+		Group("order_items.menu_item_id").
+		Order("sold DESC, order_items.menu_item_id")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.MenuItemID)
+	}
+	return ids, nil
+}
+
+// pickMenuItems เลือกเมนูจาก items ตามลำดับ ID ที่ให้มา ID ที่ไม่อยู่ใน items (เช่น ถูกกรองออก) จะถูกข้าม
+func pickMenuItems(items []models.MenuItem, ids []uint, limit int) []models.MenuItem {
+	byID := make(map[uint]models.MenuItem, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+	picked := []models.MenuItem{}
+	for _, id := range ids {
+		if item, ok := byID[id]; ok && (limit <= 0 || len(picked) < limit) {
+			picked = append(picked, item)
+		}
+	}
+	return picked
+}
+
+// activeMenuSections เมนูที่เปิดขายพร้อมส่วนเมนูแนะนำและเมนูขายดี (GetActiveMenu?sections=true)
+type activeMenuSections struct {
+	Recommended []models.MenuItem `json:"recommended"`
+	BestSellers []models.MenuItem `json:"best_sellers"`
+	Items       []models.MenuItem `json:"items"`
+}
+
+// buildActiveMenuSections แยกเมนูแนะนำและเมนูขายดีจากเมนูที่ผ่านตัวกรองแล้ว
+func buildActiveMenuSections(items []models.MenuItem) (activeMenuSections, error) {
+	sections := activeMenuSections{Recommended: []models.MenuItem{}, Items: items}
+	for _, item := range items {
+		if item.IsRecommended {
+			sections.Recommended = append(sections.Recommended, item)
+		}
+	}
+
+	ids, err := bestSellerMenuIDs(db.DB, time.Now().AddDate(0, 0, -bestSellerDays), 0)
+	if err != nil {
+		return sections, err
+	}
+	sections.BestSellers = pickMenuItems(items, ids, bestSellerLimit)
+	return sections, nil
+}
+
+// @Summary ดึงเมนูขายดี
+// @Description จัดอันดับเมนูที่เปิดขายตามจำนวนที่ขายได้จริงจากออเดอร์ (ไม่นับรายการที่ยกเลิก)
+// @Produce json
+// @Param days query integer false "ย้อนหลังกี่วัน (ค่าเริ่มต้น 30)"
+// @Param limit query integer false "จำนวนเมนู (ค่าเริ่มต้น 10)"
+// @Param channel query string false "ช่องทางที่แสดง (ค่าเริ่มต้น qr)" Enums(qr, pos)
+// @Param lang query string false "รหัสภาษา เช่น th, en, zh"
+// @Success 200 {array} models.MenuItem "เมนูขายดีเรียงจากขายได้มากไปน้อย"
+// @Failure 400 {object} map[string]interface{} "พารามิเตอร์ไม่ถูกต้อง"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
+// @Router /api/menu/best-sellers [get]
+// @Tags menu
+func GetBestSellers(c *fiber.Ctx) error {
+	days := c.QueryInt("days", bestSellerDays)
+	limit := c.QueryInt("limit", bestSellerLimit)
+	if days <= 0 || limit <= 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "days and limit must be positive numbers",
+		})
+	}
+	channel := c.Query("channel", models.ChannelQR)
+	if channel != models.ChannelQR && channel != models.ChannelPOS {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "channel must be qr or pos",
+		})
+	}
+
+	ids, err := bestSellerMenuIDs(db.DB, time.Now().AddDate(0, 0, -days), 0)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch best sellers",
+		})
+	}
+
+	var items []models.MenuItem
+	if len(ids) > 0 {
+		if err := db.DB.Preload("Category").Where("id IN ?", ids).Find(&items).Error; err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch best sellers",
+			})
+		}
+	}
+
+	tree, err := loadCategoryTree(db.DB)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch best sellers",
+		})
+	}
+	hidden := tree.hidden(channel)
+	visible := make([]models.MenuItem, 0, len(items))
+	for _, item := range items {
+		if !hidden[item.CategoryID] {
+			visible = append(visible, item)
+		}
+	}
+
+	bestSellers := pickMenuItems(visible, ids, limit)
+	l := newLocalizer(c)
+	for i := range bestSellers {
+		l.menuItem(&bestSellers[i])
+	}
+	return c.JSON(bestSellers)
+}
+
+// @Summary จัดลำดับเมนูในหมวดหมู่
+// @Description ส่งรายการ ID เมนูเรียงตามลำดับที่ต้องการของแต่ละหมวดหมู่ เมนูในหมวดหมู่ที่ไม่ได้ส่งมาจะต่อท้ายตามลำดับเดิม
+// @Description เมนูที่ปักหมุดยังแสดงก่อนเมนูอื่นในหมวดหมู่เสมอ
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.ReorderMenuRequest true "ลำดับเมนูของแต่ละหมวดหมู่"
+// @Success 200 {array} models.MenuItem "เมนูของหมวดหมู่ที่จัดลำดับเรียงตามลำดับใหม่"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการจัดลำดับ"
+// @Router /api/menu/reorder [put]
+// @Tags menu
+func ReorderMenuItems(c *fiber.Ctx) error {
+	var req models.ReorderMenuRequest
+	if err := c.BodyParser(&req); err != nil || len(req.Categories) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+
+	var result []models.MenuItem
+	var reqErr error
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		for _, category := range req.Categories {
+			var items []models.MenuItem
+			if err := tx.Where("category_id = ?", category.CategoryID).Order("display_order, id").Find(&items).Error; err != nil {
+				return err
+			}
+			inCategory := make(map[uint]bool, len(items))
+			for _, item := range items {
+				inCategory[item.ID] = true
+			}
+
+			order := make([]uint, 0, len(items))
+			listed := map[uint]bool{}
+			for _, id := range category.MenuItemIDs {
+				if !inCategory[id] {
+					reqErr = fmt.Errorf("menu item ID %d is not in category ID %d", id, category.CategoryID)
+					return reqErr
+				}
+				if listed[id] {
+					reqErr = fmt.Errorf("menu item ID %d is listed twice", id)
+					return reqErr
+				}
+				listed[id] = true
+				order = append(order, id)
+			}
+			for _, item := range items {
+				if !listed[item.ID] {
+					order = append(order, item.ID)
+				}
+			}
+
+			for i, id := range order {
+				if err := tx.Model(&models.MenuItem{}).Where("id = ?", id).Update("display_order", i).Error; err != nil {
+					return err
+				}
+			}
+
+			var updated []models.MenuItem
+			if err := tx.Where("category_id = ?", category.CategoryID).Find(&updated).Error; err != nil {
+				return err
+			}
+			sort.SliceStable(updated, func(i, j int) bool { return menuDisplayLess(updated[i], updated[j]) })
+			result = append(result, updated...)
+		}
+		return nil
+	})
+	if reqErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": reqErr.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to reorder menu items",
+		})
+	}
+	return c.JSON(result)
+}
+
+// @Summary สลับสถานะปักหมุดเมนู
+// @Description เมนูที่ปักหมุดจะแสดงก่อนเมนูอื่นในหมวดหมู่
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของเมนู"
+// @Success 200 {object} models.MenuItem "รายละเอียดของเมนูที่อัพเดทสถานะปักหมุด"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 404 {object} map[string]interface{} "ไม่พบเมนู"
+// @Router /api/menu/{id}/pin [put]
+// @Tags menu
+func ToggleMenuItemPin(c *fiber.Ctx) error {
+	menuItemID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid menu item ID",
+		})
+	}
+
+	var menuItem models.MenuItem
+	if err := db.DB.First(&menuItem, menuItemID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Menu item not found",
+		})
+	}
+
+	menuItem.IsPinned = !menuItem.IsPinned
+	if err := db.DB.Model(&menuItem).Update("is_pinned", menuItem.IsPinned).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update menu item pin status",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":   "Pin status updated successfully",
+		"menu_item": menuItem,
+	})
+}
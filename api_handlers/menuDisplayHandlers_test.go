@@ -0,0 +1,102 @@
+package api_handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMenuDisplayOrder(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.Translation{}, &models.Order{}, &models.OrderItem{})
+
+	app.Get("/api/menu/ActiveMenu", GetActiveMenu)
+	app.Get("/api/menu/best-sellers", GetBestSellers)
+	app.Put("/api/menu/reorder", ReorderMenuItems)
+	app.Put("/api/menu/:id/pin", ToggleMenuItemPin)
+
+	names := func(items []models.MenuItem) []string {
+		result := []string{}
+		for _, item := range items {
+			result = append(result, item.Name)
+		}
+		return result
+	}
+
+	activeMenu := func() []models.MenuItem {
+		resp := sendJSON(t, app, "GET", "/api/menu/ActiveMenu", nil)
+		var items []models.MenuItem
+		json.NewDecoder(resp.Body).Decode(&items)
+		return items
+	}
+
+	padThai := models.MenuItem{Name: "ผัดไทย", CategoryID: 1, Price: 80, Is_available: true, IsRecommended: true}
+	somTam := models.MenuItem{Name: "ส้มตำ", CategoryID: 1, Price: 60, Is_available: true, DisplayOrder: 1}
+	larb := models.MenuItem{Name: "ลาบ", CategoryID: 1, Price: 70, Is_available: true, DisplayOrder: 2}
+	for _, item := range []*models.MenuItem{&padThai, &somTam, &larb} {
+		db.DB.Create(item)
+	}
+
+	// กรณีทดสอบที่ 1: จัดลำดับใหม่ เมนูที่ไม่ได้ส่งต่อท้ายตามลำดับเดิม
+	t.Run("Success - Reorder menu items", func(t *testing.T) {
+		resp := sendJSON(t, app, "PUT", "/api/menu/reorder", models.ReorderMenuRequest{
+			Categories: []models.CategoryMenuOrder{{CategoryID: 1, MenuItemIDs: []uint{larb.ID}}},
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"ลาบ", "ผัดไทย", "ส้มตำ"}, names(activeMenu()))
+
+		resp = sendJSON(t, app, "PUT", "/api/menu/reorder", models.ReorderMenuRequest{
+			Categories: []models.CategoryMenuOrder{{CategoryID: 1, MenuItemIDs: []uint{larb.ID, 9999}}},
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 2: เมนูที่ปักหมุดขึ้นก่อนเสมอ
+	t.Run("Success - Pinned item first", func(t *testing.T) {
+		resp := sendJSON(t, app, "PUT", fmt.Sprintf("/api/menu/%d/pin", somTam.ID), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"ส้มตำ", "ลาบ", "ผัดไทย"}, names(activeMenu()))
+	})
+
+	// กรณีทดสอบที่ 3: เมนูขายดีคิดจากจำนวนที่ขายจริง ไม่นับรายการที่ยกเลิกและออเดอร์ที่เก่าเกินช่วง
+	t.Run("Success - Best sellers from order items", func(t *testing.T) {
+		order := models.Order{UUID: "best-seller", TableID: 1, Status: "served"}
+		db.DB.Create(&order)
+		db.DB.Create(&models.OrderItem{OrderID: order.ID, MenuItemID: padThai.ID, Quantity: 3, Price: 80, Status: "served"})
+		db.DB.Create(&models.OrderItem{OrderID: order.ID, MenuItemID: larb.ID, Quantity: 2, Price: 70, Status: "served"})
+		db.DB.Create(&models.OrderItem{OrderID: order.ID, MenuItemID: somTam.ID, Quantity: 10, Price: 60, Status: "cancelled"})
+		old := models.OrderItem{OrderID: order.ID, MenuItemID: larb.ID, Quantity: 5, Price: 70, Status: "served"}
+		db.DB.Create(&old)
+		db.DB.Model(&old).UpdateColumn("created_at", time.Now().AddDate(0, 0, -60))
+
+		resp := sendJSON(t, app, "GET", "/api/menu/best-sellers", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var bestSellers []models.MenuItem
+		json.NewDecoder(resp.Body).Decode(&bestSellers)
+		assert.Equal(t, []string{"ผัดไทย", "ลาบ"}, names(bestSellers))
+
+		resp = sendJSON(t, app, "GET", "/api/menu/best-sellers?days=90&limit=1", nil)
+		bestSellers = nil
+		json.NewDecoder(resp.Body).Decode(&bestSellers)
+		assert.Equal(t, []string{"ลาบ"}, names(bestSellers))
+	})
+
+	// กรณีทดสอบที่ 4: GetActiveMenu แบบมีส่วนเมนูแนะนำและขายดี
+	t.Run("Success - Active menu sections", func(t *testing.T) {
+		resp := sendJSON(t, app, "GET", "/api/menu/ActiveMenu?sections=true", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var sections activeMenuSections
+		json.NewDecoder(resp.Body).Decode(&sections)
+		assert.Equal(t, []string{"ผัดไทย"}, names(sections.Recommended))
+		assert.Equal(t, []string{"ผัดไทย", "ลาบ"}, names(sections.BestSellers))
+		assert.Len(t, sections.Items, 3)
+	})
+}
@@ -4,12 +4,17 @@ import (
 	"fmt"
 	"food-ordering-api/db"
 	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	"log"
 	"net/http"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 // @Summary เรียกรายการเมนูทั้งหมด
@@ -30,15 +35,30 @@ func GetMenuAll(c *fiber.Ctx) error {
 			"error": fmt.Sprintf("Error fetching menuItem: %v", err),
 		})
 	}
+	if err := applyOptionPriceOverrides(db.DB, menuItem); err != nil {
+		return c.Status(500).JSON(map[string]interface{}{
+			"error": fmt.Sprintf("Error fetching option prices: %v", err),
+		})
+	}
 	// ส่งรายการ categories กลับในรูปแบบ JSON
 	return c.JSON(menuItem)
 }
 
 // @Summary เรียกรายการเมนูที่พร้อมใช้งาน
 // @Description ฟังก์ชันนี้ใช้สำหรับเรียกรายการเมนูทั้งหมดที่มีอยู่ในระบบ
+// @Description ชื่อ/คำอธิบายตามภาษาอยู่ใน localized (เลือกจาก lang หรือ Accept-Language ไม่พบใช้ภาษาไทย)
+// @Description กรองเมนูได้ด้วย exclude_allergens (ตัดเมนูที่มีแท็กนั้น และซ่อนตัวเลือกที่มีแท็กนั้น), tags (ต้องมีครบทุกแท็ก) และ max_spicy
 // @Produce json
 // @Security BearerAuth
+// @Param lang query string false "รหัสภาษา เช่น th, en, zh"
+// @Param Accept-Language header string false "ภาษาที่ต้องการ"
+// @Param exclude_allergens query string false "code ของแท็กที่ต้องไม่มี คั่นด้วย , เช่น peanut,shellfish"
+// @Param tags query string false "code ของแท็กที่ต้องมีทุกตัว คั่นด้วย , เช่น vegan,halal"
+// @Param max_spicy query integer false "ระดับความเผ็ดสูงสุด (0-5)"
+// @Param channel query string false "ช่องทางที่แสดง ตัดเมนูในหมวดหมู่ที่ซ่อนในช่องทางนั้น (ค่าเริ่มต้น qr)" Enums(qr, pos)
+// @Param sections query boolean false "ตอบเป็น object ที่มี recommended, best_sellers (30 วันล่าสุด) และ items แทน array"
 // @Success 200 {array} models.MenuItem "รายการเมนูทั้งหมด"
+// @Failure 400 {object} map[string]interface{} "พารามิเตอร์ไม่ถูกต้อง"
 // @Failure 401 {object} map[string]interface{} "ไม่ได้รับอนุญาต"
 // @Failure 403 {object} map[string]interface{} "ไม่มีสิทธิ์เข้าถึง"
 // @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูลเมนู"
@@ -46,16 +66,162 @@ func GetMenuAll(c *fiber.Ctx) error {
 // @Tags menu
 func GetActiveMenu(c *fiber.Ctx) error {
 	var menuItem []models.MenuItem
+	query := db.DB.Preload("Category").Preload("Tags").Preload("OptionGroups").Preload("OptionGroups.Options").Preload("OptionGroups.Options.Tags").Where("is_available = ?", true)
+
+	excluded := parseTagCodes(c.Query("exclude_allergens"), ",")
+	if len(excluded) > 0 {
+		query = query.Where("id NOT IN (?)", db.DB.Table("menu_item_tags").
+			Select("menu_item_tags.menu_item_id").
+			Joins("JOIN dietary_tags ON dietary_tags.id = menu_item_tags.dietary_tag_id").
+			Where("dietary_tags.code IN ?", excluded))
+	}
+
+	required := parseTagCodes(c.Query("tags"), ",")
+	if len(required) > 0 {
+		query = query.Where("id IN (?)", db.DB.Table("menu_item_tags").
+			Select("menu_item_tags.menu_item_id").
+			Joins("JOIN dietary_tags ON dietary_tags.id = menu_item_tags.dietary_tag_id").
+			Where("dietary_tags.code IN ?", required).
+			Group("menu_item_tags.menu_item_id").
+			Having("COUNT(DISTINCT dietary_tags.code) = ?", len(required)))
+	}
+
+	if maxSpicy := c.Query("max_spicy"); maxSpicy != "" {
+		level, err := strconv.Atoi(maxSpicy)
+		if err != nil || level < 0 {
+			return c.Status(http.StatusBadRequest).JSON(map[string]interface{}{
+				"error": "max_spicy must be a non-negative number",
+			})
+		}
+		query = query.Where("spicy_level <= ?", level)
+	}
+
 	// ค้นหาทุก Category
-	if err := db.DB.Preload("Category").Preload("OptionGroups").Preload("OptionGroups.Options").Where("is_available = ?", true).Find(&menuItem).Error; err != nil {
+	if err := query.Find(&menuItem).Error; err != nil {
 		return c.Status(500).JSON(map[string]interface{}{
 			"error": fmt.Sprintf("Error fetching menuItem: %v", err),
 		})
 	}
+
+	if err := applyOptionPriceOverrides(db.DB, menuItem); err != nil {
+		return c.Status(500).JSON(map[string]interface{}{
+			"error": fmt.Sprintf("Error fetching option prices: %v", err),
+		})
+	}
+
+	if len(excluded) > 0 {
+		menuItem = excludeAllergenOptions(menuItem, excluded)
+	}
+
+	channel := c.Query("channel", models.ChannelQR)
+	if channel != models.ChannelQR && channel != models.ChannelPOS {
+		return c.Status(http.StatusBadRequest).JSON(map[string]interface{}{
+			"error": "channel must be qr or pos",
+		})
+	}
+	tree, err := loadCategoryTree(db.DB)
+	if err != nil {
+		return c.Status(500).JSON(map[string]interface{}{
+			"error": fmt.Sprintf("Error fetching categories: %v", err),
+		})
+	}
+	menuItem = sortMenuByCategory(tree, menuItem, tree.hidden(channel))
+
+	l := newLocalizer(c)
+	for i := range menuItem {
+		l.menuItem(&menuItem[i])
+	}
+
+	if c.QueryBool("sections") {
+		sections, err := buildActiveMenuSections(menuItem)
+		if err != nil {
+			return c.Status(500).JSON(map[string]interface{}{
+				"error": fmt.Sprintf("Error fetching best sellers: %v", err),
+			})
+		}
+		return c.JSON(sections)
+	}
 	// ส่งรายการ categories กลับในรูปแบบ JSON
 	return c.JSON(menuItem)
 }
 
+// sortMenuByCategory ตัดเมนูในหมวดหมู่ที่ซ่อนออก แล้วเรียงตามลำดับหมวดหมู่ (หมวดหมู่ย่อยต่อจากหมวดหมู่แม่)
+// ในหมวดหมู่เดียวกัน เมนูที่ปักหมุดขึ้นก่อน แล้วเรียงตาม display_order
+func sortMenuByCategory(tree *categoryTree, items []models.MenuItem, hidden map[uint]bool) []models.MenuItem {
+	ranks := tree.rank()
+	rankOf := func(categoryID uint) int {
+		if rank, ok := ranks[categoryID]; ok {
+			return rank
+		}
+		return len(ranks)
+	}
+
+	filtered := make([]models.MenuItem, 0, len(items))
+	for _, item := range items {
+		if !hidden[item.CategoryID] {
+			filtered = append(filtered, item)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		ri, rj := rankOf(filtered[i].CategoryID), rankOf(filtered[j].CategoryID)
+		if ri != rj {
+			return ri < rj
+		}
+		return menuDisplayLess(filtered[i], filtered[j])
+	})
+	return filtered
+}
+
+// excludeAllergenOptions ซ่อนตัวเลือกที่มีแท็กต้องห้าม
+// ถ้ากลุ่มย่อยที่บังคับเลือกไม่เหลือตัวเลือก ตัวเลือกแม่ของกลุ่มนั้นจะถูกซ่อนด้วย
+// ถ้ากลุ่มหลักที่บังคับเลือกไม่เหลือตัวเลือกเลย เมนูนั้นสั่งไม่ได้จึงตัดออกทั้งเมนู
+func excludeAllergenOptions(items []models.MenuItem, excluded []string) []models.MenuItem {
+	filtered := make([]models.MenuItem, 0, len(items))
+	for _, item := range items {
+		hidden := map[uint]bool{}
+		for changed := true; changed; {
+			changed = false
+			for gi := range item.OptionGroups {
+				group := &item.OptionGroups[gi]
+				options := make([]models.MenuOption, 0, len(group.Options))
+				for _, option := range group.Options {
+					if !hidden[option.ID] && !hasAnyTag(option.Tags, excluded) {
+						options = append(options, option)
+					}
+				}
+				group.Options = options
+				if group.ParentOptionID != nil && group.IsRequired && len(options) == 0 && !hidden[*group.ParentOptionID] {
+					hidden[*group.ParentOptionID] = true
+					changed = true
+				}
+			}
+		}
+
+		orderable := true
+		for _, group := range item.OptionGroups {
+			if group.ParentOptionID == nil && group.IsRequired && len(group.Options) == 0 {
+				orderable = false
+			}
+		}
+		if orderable {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// hasAnyTag ตรวจว่ามีแท็กใด code หนึ่งในรายการหรือไม่
+func hasAnyTag(tags []models.DietaryTag, codes []string) bool {
+	for _, tag := range tags {
+		for _, code := range codes {
+			if tag.Code == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // @Summary ดึงข้อมูลเมนูตาม ID
 // @Description ฟังก์ชันนี้ใช้สำหรับดึงข้อมูลของเมนูโดยการระบุ ID ของเมนูนั้น
 // @Produce json
@@ -86,6 +252,13 @@ func GetMenuByID(c *fiber.Ctx) error {
 			"error": fmt.Sprintf("Error fetching menuItem: %v", err),
 		})
 	}
+	items := []models.MenuItem{menuItem}
+	if err := applyOptionPriceOverrides(db.DB, items); err != nil {
+		return c.Status(500).JSON(map[string]interface{}{
+			"error": fmt.Sprintf("Error fetching option prices: %v", err),
+		})
+	}
+	menuItem = items[0]
 	// ส่งรายการ categories กลับในรูปแบบ JSON
 	return c.JSON(menuItem)
 }
@@ -96,7 +269,8 @@ func GetMenuByID(c *fiber.Ctx) error {
 // @Security BearerAuth
 // @Param action query string true "รูปแบบการค้นหาเมนู: getByID, getByCategory หรือ getAll" Enums(getByID, getByCategory, getAll)
 // @Param id query integer false "ID ของเมนู (ใช้กับ action=getByID)"
-// @Param category_id query integer false "ID ของหมวดหมู่ (ใช้กับ action=getByCategory)"
+// @Param category_id query integer false "ID ของหมวดหมู่ (ใช้กับ action=getByCategory) รวมเมนูในหมวดหมู่ย่อยด้วย"
+// @Param channel query string false "ตัดเมนูในหมวดหมู่ที่ซ่อนในช่องทางนั้น" Enums(qr, pos)
 // @Success 200 {array} models.MenuItem "รายการเมนูที่ค้นพบ"
 // @Failure 400 {object} map[string]interface{} "เกิดข้อผิดพลาดจากการระบุพารามิเตอร์"
 // @Failure 401 {object} map[string]interface{} "ไม่ได้รับอนุญาต"
@@ -116,12 +290,40 @@ func GetMenuByCategory(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := db.DB.Preload("Category").Where("category_id = ?", categoryID, true).Find(&menuItems).Error; err != nil {
+	id, err := strconv.Atoi(categoryID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(map[string]interface{}{
+			"error": "CategoryID must be a number",
+		})
+	}
+
+	channel := c.Query("channel")
+	if channel != "" && channel != models.ChannelQR && channel != models.ChannelPOS {
+		return c.Status(fiber.StatusBadRequest).JSON(map[string]interface{}{
+			"error": "channel must be qr or pos",
+		})
+	}
+
+	tree, err := loadCategoryTree(db.DB)
+	if err != nil {
+		return c.Status(500).JSON(map[string]interface{}{
+			"error": fmt.Sprintf("Error fetching categories: %v", err),
+		})
+	}
+
+	// รวมเมนูของหมวดหมู่ย่อยทุกชั้น
+	if err := db.DB.Preload("Category").Where("category_id IN ?", tree.descendants(uint(id))).Find(&menuItems).Error; err != nil {
 		return c.Status(500).JSON(map[string]interface{}{
 			"error": fmt.Sprintf("Error fetching menu items: %v", err),
 		})
 	}
 
+	var hidden map[uint]bool
+	if channel != "" {
+		hidden = tree.hidden(channel)
+	}
+	menuItems = sortMenuByCategory(tree, menuItems, hidden)
+
 	// ส่งรายการ MenuItems ที่ค้นพบกลับในรูปแบบ JSON
 	return c.JSON(menuItems)
 }
@@ -203,7 +405,7 @@ func UpdateMenuStatus(c *fiber.Ctx) error {
 }
 
 // @Summary สร้างเมนูใหม่พร้อม options
-// @Description สร้างเมนูอาหารใหม่พร้อมกับตัวเลือกเพิ่มเติม (options) ของเมนูนั้นๆ
+// @Description สร้างเมนูอาหารใหม่พร้อมกับตัวเลือกเพิ่มเติม (options) ของเมนูนั้นๆ ตัวเลือกมี child_groups ได้ (เช่น เลือก "ก๋วยเตี๋ยวน้ำ" แล้วต้องเลือกเส้น)
 // @Accept json
 // @Produce json
 // @Security BearerAuth
@@ -273,6 +475,7 @@ func CreateMenuItemHandler(c *fiber.Ctx) error {
 		CategoryID:    req.MenuItem.CategoryID,
 		Is_available:  true,
 		Price:         req.MenuItem.Price,
+		DisplayOrder:  nextMenuDisplayOrder(tx, req.MenuItem.CategoryID),
 	}
 
 	if err := tx.Create(&menuItem).Error; err != nil {
@@ -286,36 +489,11 @@ func CreateMenuItemHandler(c *fiber.Ctx) error {
 				continue
 			}
 
-			optionGroup := models.OptionGroup{
-				MenuItemID:    menuItem.ID,
-				Name:          group.Name,
-				NameEn:        group.NameEn,
-				NameCh:        group.NameCh,
-				MaxSelections: group.MaxSelections,
-				IsRequired:    group.IsRequired,
-			}
-
-			if err := tx.Create(&optionGroup).Error; err != nil {
+			// สร้างกลุ่มพร้อมตัวเลือกและกลุ่มย่อย (child_groups) ของแต่ละตัวเลือก
+			if _, err := createOptionGroupTree(tx, &menuItem.ID, nil, group); err != nil {
 				tx.Rollback()
 				return c.Status(500).JSON(fiber.Map{"error": "Error creating option group"})
 			}
-
-			if len(group.Options) > 0 {
-				for _, opt := range group.Options {
-					groupOption := models.MenuOption{
-						GroupID: optionGroup.ID,
-						Name:    opt.Name,
-						NameEn:  opt.NameEn,
-						NameCh:  opt.NameCh,
-						Price:   opt.Price,
-					}
-
-					if err := tx.Create(&groupOption).Error; err != nil {
-						tx.Rollback()
-						return c.Status(500).JSON(fiber.Map{"error": "Error creating group option"})
-					}
-				}
-			}
 		}
 	}
 	if err := tx.Commit().Error; err != nil {
@@ -329,7 +507,7 @@ func CreateMenuItemHandler(c *fiber.Ctx) error {
 }
 
 // @Summary เพิ่ม group options แยกถ้าสร้างเมนูไปแล้ว
-// @Description สร้าง (group options) ของเมนูนั้นๆ
+// @Description สร้าง (group options) ของเมนูนั้นๆ ใส่ parent_option_id เพื่อให้เป็นกลุ่มย่อยที่ต้องเลือกเมื่อเลือกตัวเลือกนั้น
 // @Accept json
 // @Produce json
 // @Security BearerAuth
@@ -367,8 +545,7 @@ func AddMoreGroup(c *fiber.Ctx) error {
 
 	tx := db.DB.Begin()
 
-	var opt models.OptionGroup
-	if err := tx.Where("name = ? AND menu_item_id = ?", req.Name, menuId).First(&opt).Error; err == nil {
+	if taken, _ := optionGroupNameTaken(tx, []int{menuId}, req.Name, 0); taken {
 		tx.Rollback()
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
 			"error": "ชื่อ optgroup ซ้ำกับที่มีอยู่ในเมนูนี้",
@@ -376,43 +553,28 @@ func AddMoreGroup(c *fiber.Ctx) error {
 	}
 
 	var jjjjjj models.MenuItem
-	menu := db.DB.First(&jjjjjj, menuId)
+	menu := tx.First(&jjjjjj, menuId)
 	if menu.Error != nil {
+		tx.Rollback()
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
 			"error": "หา id อาหารไม่เจอ",
 		})
 	}
 
-	optionGroup := models.OptionGroup{
-		MenuItemID:    jjjjjj.ID,
-		Name:          req.Name,
-		NameEn:        req.NameEn,
-		NameCh:        req.NameCh,
-		MaxSelections: req.MaxSelections,
-		IsRequired:    req.IsRequired,
+	// กลุ่มย่อยของตัวเลือกที่มีอยู่ ตัวเลือกแม่ต้องอยู่ในเมนูนี้
+	if req.ParentOptionID != nil {
+		if err := validateParentOption(tx, &jjjjjj.ID, *req.ParentOptionID); err != nil {
+			tx.Rollback()
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 	}
 
-	if err := tx.Create(&optionGroup).Error; err != nil {
+	if _, err := createOptionGroupTree(tx, &jjjjjj.ID, req.ParentOptionID, req); err != nil {
 		tx.Rollback()
 		return c.Status(500).JSON(fiber.Map{"error": "Error creating option group"})
 	}
-
-	if len(req.Options) > 0 {
-		for _, opt := range req.Options {
-			groupOption := models.MenuOption{
-				GroupID: optionGroup.ID,
-				Name:    opt.Name,
-				NameEn:  opt.NameEn,
-				NameCh:  opt.NameCh,
-				Price:   opt.Price,
-			}
-
-			if err := tx.Create(&groupOption).Error; err != nil {
-				tx.Rollback()
-				return c.Status(500).JSON(fiber.Map{"error": "Error creating group option"})
-			}
-		}
-	}
 	if err := tx.Commit().Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Error committing transaction"})
 	}
@@ -640,7 +802,19 @@ func UpdateMenuItem(c *fiber.Ctx) error {
 		Price:         req.Price,
 	}
 
-	if err := db.DB.Model(&existingMenu).Updates(updates).Error; err != nil {
+	// Updates จะเขียนค่าใหม่ทับ struct จึงต้องเก็บราคาเดิมไว้ก่อน
+	oldPrice := existingMenu.Price
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&existingMenu).Updates(updates).Error; err != nil {
+			return err
+		}
+		// เก็บประวัติราคาไว้ทำรายงาน (ราคา 0 ไม่ถูกอัพเดทเพราะ Updates ข้ามค่า zero)
+		if req.Price != 0 && req.Price != oldPrice {
+			return recordMenuPriceChange(tx, existingMenu.ID, oldPrice, req.Price, nil, time.Now())
+		}
+		return nil
+	})
+	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update menu",
 		})
@@ -710,10 +884,8 @@ func UpdateOptionGroup(c *fiber.Ctx) error {
 		})
 	}
 
-	// ตรวจสอบว่าชื่อไม่ซ้ำกับ Option Group อื่นในเมนูเดียวกัน
-	var duplicateGroup models.OptionGroup
-	if err := db.DB.Where("menu_item_id = ? AND name = ? AND id != ?",
-		existingGroup.MenuItemID, existingGroup.Name, groupID).First(&duplicateGroup).Error; err == nil {
+	// ตรวจสอบว่าชื่อไม่ซ้ำกับ Option Group อื่นในทุกเมนูที่ใช้กลุ่มนี้
+	if taken, _ := optionGroupNameTaken(db.DB, optionGroupMenuIDs(db.DB, existingGroup.ID), req.Name, existingGroup.ID); taken {
 		return c.Status(http.StatusConflict).JSON(fiber.Map{
 			"error": "Option group name already exists in this menu",
 		})
@@ -902,13 +1074,48 @@ func UpdateMenuImage(c *fiber.Ctx) error {
 		})
 	}
 
-	// อัพเดทรูปภาพในฐานข้อมูล
-	if err := db.DB.Model(&existingMenu).Update("image", buffer).Error; err != nil {
+	// เก็บรูปพร้อม thumbnail ลง image store แล้วผูก image_id กับเมนู
+	tx := db.DB.Begin()
+	storedImage, err := service.SaveImage(tx, buffer)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Failed to process menu image",
+			"details": err.Error(),
+		})
+	}
+
+	// Updates เขียนค่าใหม่กลับเข้า existingMenu (ผ่าน pointer เดิม) จึงต้องคัดลอก ID รูปเดิมไว้ก่อน
+	var oldImageID *uint
+	if existingMenu.ImageID != nil {
+		id := *existingMenu.ImageID
+		oldImageID = &id
+	}
+	if err := tx.Model(&existingMenu).Updates(map[string]interface{}{
+		"image_id": storedImage.ID,
+		"image":    nil,
+	}).Error; err != nil {
+		tx.Rollback()
+		service.DiscardImageFiles(*storedImage)
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update menu image",
 		})
 	}
 
+	if err := tx.Commit().Error; err != nil {
+		service.DiscardImageFiles(*storedImage)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to commit transaction",
+		})
+	}
+
+	// ลบรูปเดิมออกจาก store หลัง commit แล้วเท่านั้น
+	if oldImageID != nil {
+		if err := service.DeleteImage(db.DB, *oldImageID); err != nil {
+			log.Printf("Failed to delete old menu image %d: %v", *oldImageID, err)
+		}
+	}
+
 	// ดึงข้อมูลที่อัพเดทแล้วมาแสดง
 	var updatedMenu models.MenuItem
 	if err := db.DB.Preload("Category").Preload("OptionGroups.Options").First(&updatedMenu, menuID).Error; err != nil {
@@ -998,9 +1205,9 @@ func RestoreMenu(c *fiber.Ctx) error {
 		})
 	}
 
-	// กู้คืน option groups ที่เกี่ยวข้อง
+	// กู้คืน option groups ที่ผูกกับเมนูนี้
 	var deletedGroups []models.OptionGroup
-	if err := tx.Unscoped().Where("menu_item_id = ? AND deleted_at IS NOT NULL", menuID).
+	if err := tx.Unscoped().Where("id IN (?) AND deleted_at IS NOT NULL", menuOptionGroupIDs(tx, uint(menuID))).
 		Find(&deletedGroups).Error; err != nil {
 		tx.Rollback()
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
@@ -1083,10 +1290,26 @@ func RestoreOptionGroup(c *fiber.Ctx) error {
 		})
 	}
 
-	// ตรวจสอบว่าชื่อไม่ซ้ำกับ option group อื่นในเมนูเดียวกัน
-	var existingGroup models.OptionGroup
-	if err := tx.Where("menu_item_id = ? AND name = ? AND deleted_at IS NULL",
-		deletedGroup.MenuItemID, deletedGroup.Name).First(&existingGroup).Error; err == nil {
+	// กลุ่มที่ถูกลบเพราะเมนูสุดท้ายเอาออก จะไม่มีการผูกเหลือ กู้คืนแล้วผูกกลับกับเมนูที่สร้างกลุ่ม (ถ้ายังอยู่)
+	var links int64
+	if err := tx.Table("menu_item_option_groups").Where("option_group_id = ?", deletedGroup.ID).Count(&links).Error; err != nil {
+		tx.Rollback()
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch option group links",
+		})
+	}
+	var menuIDs interface{} = optionGroupMenuIDs(tx, deletedGroup.ID)
+	relink := false
+	if links == 0 && deletedGroup.MenuItemID != nil {
+		var owner models.MenuItem
+		if err := tx.First(&owner, *deletedGroup.MenuItemID).Error; err == nil {
+			relink = true
+			menuIDs = []uint{owner.ID}
+		}
+	}
+
+	// ตรวจสอบว่าชื่อไม่ซ้ำกับ option group อื่นในเมนูที่ใช้กลุ่มนี้
+	if taken, _ := optionGroupNameTaken(tx, menuIDs, deletedGroup.Name, deletedGroup.ID); taken {
 		tx.Rollback()
 		return c.Status(http.StatusConflict).JSON(fiber.Map{
 			"error": "An option group with this name already exists in this menu",
@@ -1111,6 +1334,16 @@ func RestoreOptionGroup(c *fiber.Ctx) error {
 		})
 	}
 
+	if relink {
+		if err := tx.Table("menu_item_option_groups").
+			Create(map[string]interface{}{"menu_item_id": *deletedGroup.MenuItemID, "option_group_id": deletedGroup.ID}).Error; err != nil {
+			tx.Rollback()
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to link restored option group",
+			})
+		}
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
@@ -1208,7 +1441,10 @@ func GetOptionByid(c *fiber.Ctx) error {
 	}
 
 	var optiongroup []models.OptionGroup
-	if err := db.DB.Preload("Options").Where("menu_item_id = ?", foodid).Find(&optiongroup).Error; err != nil {
+	if err := db.DB.Preload("Options").
+		Joins("JOIN menu_item_option_groups ON menu_item_option_groups.option_group_id = option_groups.id").
+		Where("menu_item_option_groups.menu_item_id = ?", foodid).
+		Find(&optiongroup).Error; err != nil {
 		return c.Status(http.StatusNotFound).JSON(fiber.Map{
 			"error": "Group option not found",
 		})
@@ -1264,8 +1500,8 @@ func UpdateOptionByMenuID(c *fiber.Ctx) error {
 
 	// หา option ที่ต้องการอัพเดท และตรวจสอบว่าเป็นของเมนูนี้จริงๆ
 	var option models.MenuOption
-	if err := db.DB.Joins("JOIN option_groups ON menu_options.group_id = option_groups.id").
-		Where("option_groups.menu_item_id = ? AND menu_options.id = ?", menuID, optionID).
+	if err := db.DB.Joins("JOIN menu_item_option_groups ON menu_options.group_id = menu_item_option_groups.option_group_id").
+		Where("menu_item_option_groups.menu_item_id = ? AND menu_options.id = ?", menuID, optionID).
 		First(&option).Error; err != nil {
 		return c.Status(http.StatusNotFound).JSON(fiber.Map{
 			"error": "Option not found for this menu",
@@ -1307,15 +1543,19 @@ func UpdateOptionByMenuID(c *fiber.Ctx) error {
 }
 
 // @Summary ลบกลุ่มตัวเลือกอาหาร
-// @Description ฟังก์ชันนี้ใช้สำหรับลบกลุ่มตัวเลือกโดยจะเป็นการ soft delete
+// @Description เอากลุ่มตัวเลือกออกจากเมนู (menu_id หรือเมนูที่สร้างกลุ่มถ้าไม่ระบุ) เมนูอื่นที่ใช้กลุ่มเดียวกันไม่ได้รับผลกระทบ
+// @Description กลุ่มจะถูก soft delete พร้อม options เมื่อไม่มีเมนูใดผูกอยู่แล้วเท่านั้น
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path integer true "ID ของกลุ่มตัวเลือกนั้นๆ"
+// @Param menu_id query integer false "ID ของเมนูที่ต้องการเอากลุ่มออก"
 // @Success 200 "ลบกลุ่มตัวเลือกสำเร็จ"
 // @Failure 400 {object} map[string]interface{} "เกิดข้อผิดพลาดจากข้อมูลที่ไม่ถูกต้อง"
 // @Failure 401 {object} map[string]interface{} "ไม่ได้รับอนุญาต"
 // @Failure 403 {object} map[string]interface{} "ไม่มีสิทธิ์เข้าถึง"
+// @Failure 404 {object} map[string]interface{} "ไม่พบกลุ่ม หรือกลุ่มไม่ได้ผูกกับเมนูนี้"
+// @Failure 409 {object} map[string]interface{} "กลุ่มในคลังยังมีเมนูใช้อยู่"
 // @Router /api/menu/option-groups/{id} [delete]
 // @Tags menu
 func SoftDelete_OptionGroup(c *fiber.Ctx) error {
@@ -1339,20 +1579,65 @@ func SoftDelete_OptionGroup(c *fiber.Ctx) error {
 		})
 	}
 
-	// ลบ options ที่อยู่ในกลุ่มก่อน
-	if err := tx.Where("group_id = ?", id).Delete(&models.MenuOption{}).Error; err != nil {
-		tx.Rollback()
-		return c.Status(http.StatusInternalServerError).JSON(map[string]interface{}{
-			"error": "Failed to delete options in group",
-		})
+	menuID := existingOptionGroup.MenuItemID
+	if c.Query("menu_id") != "" {
+		queryID, err := strconv.Atoi(c.Query("menu_id"))
+		if err != nil || queryID <= 0 {
+			tx.Rollback()
+			return c.Status(http.StatusBadRequest).JSON(map[string]interface{}{
+				"error": "Invalid menu ID",
+			})
+		}
+		menuItemID := uint(queryID)
+		menuID = &menuItemID
 	}
 
-	// ลบกลุ่มตัวเลือก
-	if err := tx.Delete(&existingOptionGroup).Error; err != nil {
-		tx.Rollback()
-		return c.Status(http.StatusInternalServerError).JSON(map[string]interface{}{
-			"error": "Failed to delete Option Group",
-		})
+	if menuID != nil {
+		// เอาออกจากเมนูนี้เท่านั้น กลุ่มถูกลบเมื่อไม่มีเมนูอื่นใช้แล้ว
+		linked, err := menuItemHasOptionGroup(tx, *menuID, existingOptionGroup.ID)
+		if err != nil || !linked {
+			tx.Rollback()
+			return c.Status(http.StatusNotFound).JSON(map[string]interface{}{
+				"error": "Option group is not linked to this menu",
+			})
+		}
+		if err := unlinkOptionGroup(tx, *menuID, existingOptionGroup, true); err != nil {
+			tx.Rollback()
+			return c.Status(http.StatusInternalServerError).JSON(map[string]interface{}{
+				"error": "Failed to delete Option Group",
+			})
+		}
+	} else {
+		// กลุ่มในคลัง ลบได้เมื่อไม่มีเมนูใดผูกอยู่
+		var links int64
+		if err := tx.Table("menu_item_option_groups").Where("option_group_id = ?", existingOptionGroup.ID).Count(&links).Error; err != nil {
+			tx.Rollback()
+			return c.Status(http.StatusInternalServerError).JSON(map[string]interface{}{
+				"error": "Failed to delete Option Group",
+			})
+		}
+		if links > 0 {
+			tx.Rollback()
+			return c.Status(http.StatusConflict).JSON(map[string]interface{}{
+				"error": "Option group is still linked to menus",
+			})
+		}
+
+		// ลบ options ที่อยู่ในกลุ่มก่อน
+		if err := tx.Where("group_id = ?", existingOptionGroup.ID).Delete(&models.MenuOption{}).Error; err != nil {
+			tx.Rollback()
+			return c.Status(http.StatusInternalServerError).JSON(map[string]interface{}{
+				"error": "Failed to delete options in group",
+			})
+		}
+
+		// ลบกลุ่มตัวเลือก
+		if err := tx.Delete(&existingOptionGroup).Error; err != nil {
+			tx.Rollback()
+			return c.Status(http.StatusInternalServerError).JSON(map[string]interface{}{
+				"error": "Failed to delete Option Group",
+			})
+		}
 	}
 
 	// Commit transaction
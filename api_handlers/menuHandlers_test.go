@@ -407,7 +407,8 @@ func setupTestDB(t *testing.T) {
 	}
 
 	// Migrate the schema
-	err = db.DB.AutoMigrate(&models.MenuItem{}, &models.OptionGroup{}, &models.MenuOption{}, &models.Category{})
+	err = db.DB.AutoMigrate(&models.MenuItem{}, &models.OptionGroup{}, &models.MenuOption{}, &models.Category{}, &models.MenuItemOptionPrice{},
+		&models.Printer{}) // Printer สร้างตาราง printer_categories ที่ loadCategoryTree ใช้
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
@@ -416,4 +417,18 @@ func setupTestDB(t *testing.T) {
 	db.DB.Create(&models.Category{
 		Name: "Default Category",
 	})
-}
\ No newline at end of file
+}
+
+// sendJSON ส่ง request พร้อม body แบบ JSON ไปยัง app ที่ทดสอบ body เป็น nil ได้ (ส่ง body ว่าง)
+func sendJSON(t *testing.T, app *fiber.App, method, url string, body interface{}) *http.Response {
+	reader := bytes.NewBuffer(nil)
+	if body != nil {
+		jsonBody, _ := json.Marshal(body)
+		reader = bytes.NewBuffer(jsonBody)
+	}
+	req := httptest.NewRequest(method, url, reader)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.Nil(t, err)
+	return resp
+}
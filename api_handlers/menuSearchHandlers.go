@@ -0,0 +1,255 @@
+package api_handlers
+
+import (
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// พจนานุกรมสำหรับตัดคำภาษาไทย (โหลดครั้งแรกที่มีการค้นหา)
+const menuSearchDictPath = "dict/lexitron.txt"
+
+const (
+	menuSearchDefaultLimit = 20
+	menuSearchMaxLimit     = 50
+	menuSearchMaxQuery     = 100 // จำนวนตัวอักษรสูงสุดของคำค้น
+)
+
+// คะแนนของการจับคู่คำค้นหนึ่งคำกับฟิลด์ (คูณด้วยน้ำหนักของฟิลด์)
+const (
+	searchScoreExact     = 10
+	searchScorePrefix    = 7
+	searchScoreSubstring = 5
+	searchScoreFuzzy     = 3
+)
+
+var (
+	menuSearchTokenizer     *service.ThaiTokenizer
+	menuSearchTokenizerOnce sync.Once
+)
+
+// getMenuSearchTokenizer คืนตัวตัดคำ ถ้าโหลดพจนานุกรมไม่ได้ ภาษาไทยจะถูกจับคู่แบบ substring อย่างเดียว
+func getMenuSearchTokenizer() *service.ThaiTokenizer {
+	menuSearchTokenizerOnce.Do(func() {
+		tokenizer, err := service.LoadThaiTokenizer(menuSearchDictPath)
+		if err != nil {
+			log.Printf("Failed to load search dictionary %s: %v", menuSearchDictPath, err)
+			tokenizer = service.NewThaiTokenizer(nil)
+		}
+		menuSearchTokenizer = tokenizer
+	})
+	return menuSearchTokenizer
+}
+
+// searchField ข้อความของฟิลด์หนึ่งที่ตัดคำแล้ว
+type searchField struct {
+	text    string // ตัวพิมพ์เล็ก
+	compact string // ตัวพิมพ์เล็กไม่มีช่องว่าง ใช้จับคู่คำค้นที่พิมพ์ติดกัน เช่น tomyum
+	tokens  []string
+	weight  int
+	isName  bool
+}
+
+func newSearchField(tokenizer *service.ThaiTokenizer, text string, weight int, isName bool) searchField {
+	lower := strings.ToLower(strings.TrimSpace(text))
+	return searchField{
+		text:    lower,
+		compact: strings.Join(strings.Fields(lower), ""),
+		tokens:  tokenizer.Tokenize(lower),
+		weight:  weight,
+		isName:  isName,
+	}
+}
+
+// maxFuzzyEdits จำนวนตัวอักษรที่พิมพ์ผิดได้ตามความยาวคำค้น (คำสั้นต้องตรงเท่านั้น)
+func maxFuzzyEdits(length int) int {
+	switch {
+	case length >= 8:
+		return 2
+	case length >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// levenshtein ระยะแก้ไขระหว่างสองคำ นับเป็น rune
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// matchSearchToken ให้คะแนนคำค้นหนึ่งคำกับฟิลด์ (0 = ไม่ตรง)
+func matchSearchToken(token string, field searchField) int {
+	best := 0
+	for _, fieldToken := range field.tokens {
+		switch {
+		case fieldToken == token:
+			return searchScoreExact
+		case strings.HasPrefix(fieldToken, token):
+			best = max(best, searchScorePrefix)
+		}
+	}
+	if best > 0 {
+		return best
+	}
+
+	if strings.Contains(field.text, token) || strings.Contains(field.compact, token) {
+		return searchScoreSubstring
+	}
+
+	if edits := maxFuzzyEdits(utf8.RuneCountInString(token)); edits > 0 {
+		for _, fieldToken := range field.tokens {
+			if levenshtein(token, fieldToken) <= edits {
+				return searchScoreFuzzy
+			}
+		}
+	}
+	return 0
+}
+
+// scoreMenuSearch ให้คะแนนเมนูกับคำค้น ทุกคำค้นต้องตรงกับอย่างน้อยหนึ่งฟิลด์ (0 = ไม่แสดง)
+func scoreMenuSearch(tokenizer *service.ThaiTokenizer, item models.MenuItem, query string, queryTokens []string) int {
+	fields := []searchField{
+		newSearchField(tokenizer, item.Name, 3, true),
+		newSearchField(tokenizer, item.NameEn, 3, true),
+		newSearchField(tokenizer, item.NameCh, 3, true),
+		newSearchField(tokenizer, item.Description, 1, false),
+		newSearchField(tokenizer, item.DescriptionEn, 1, false),
+		newSearchField(tokenizer, item.DescriptionCh, 1, false),
+	}
+
+	score := 0
+	for _, token := range queryTokens {
+		best := 0
+		for _, field := range fields {
+			best = max(best, matchSearchToken(token, field)*field.weight)
+		}
+		if best == 0 {
+			return 0
+		}
+		score += best
+	}
+
+	// โบนัสเมื่อชื่อตรงหรือขึ้นต้นด้วยคำค้นทั้งประโยค
+	for _, field := range fields {
+		if !field.isName || field.text == "" {
+			continue
+		}
+		if field.text == query {
+			score += 50
+		} else if strings.HasPrefix(field.text, query) {
+			score += 20
+		}
+	}
+	return score
+}
+
+// @Summary ค้นหาเมนู
+// @Description ค้นหาเมนูที่เปิดขายจากชื่อและคำอธิบายทั้งภาษาไทย อังกฤษ และจีน ตัดคำภาษาไทยด้วยพจนานุกรม
+// @Description เรียงตามความตรง: ตรงทั้งคำ > ขึ้นต้นด้วย > มีอยู่ในข้อความ > พิมพ์ผิดเล็กน้อย และชื่อมีน้ำหนักมากกว่าคำอธิบาย
+// @Produce json
+// @Param q query string true "คำค้น"
+// @Param limit query integer false "จำนวนผลลัพธ์สูงสุด (ค่าเริ่มต้น 20 สูงสุด 50)"
+// @Param lang query string false "รหัสภาษา เช่น th, en, zh"
+// @Success 200 {array} models.MenuItem "เมนูที่ตรงกับคำค้น เรียงตามความตรง"
+// @Failure 400 {object} map[string]interface{} "ไม่ได้ระบุคำค้น"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการค้นหา"
+// @Router /api/menu/search [get]
+// @Tags menu
+func SearchMenu(c *fiber.Ctx) error {
+	query := strings.ToLower(strings.TrimSpace(c.Query("q")))
+	if query == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Search query is required",
+		})
+	}
+	if runes := []rune(query); len(runes) > menuSearchMaxQuery {
+		query = string(runes[:menuSearchMaxQuery])
+	}
+
+	limit := menuSearchDefaultLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid limit",
+			})
+		}
+		limit = min(parsed, menuSearchMaxLimit)
+	}
+
+	tokenizer := getMenuSearchTokenizer()
+	queryTokens := tokenizer.Tokenize(query)
+	if len(queryTokens) == 0 {
+		return c.JSON([]models.MenuItem{})
+	}
+
+	var menuItems []models.MenuItem
+	if err := db.DB.Preload("Category").Preload("Tags").Preload("OptionGroups").Preload("OptionGroups.Options").
+		Where("is_available = ?", true).
+		Find(&menuItems).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to search menu",
+		})
+	}
+
+	if err := applyOptionPriceOverrides(db.DB, menuItems); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to search menu",
+		})
+	}
+
+	type scoredItem struct {
+		item  models.MenuItem
+		score int
+	}
+	var matches []scoredItem
+	for _, item := range menuItems {
+		if score := scoreMenuSearch(tokenizer, item, query, queryTokens); score > 0 {
+			matches = append(matches, scoredItem{item: item, score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].item.ID < matches[j].item.ID
+	})
+
+	l := newLocalizer(c)
+	results := make([]models.MenuItem, 0, min(len(matches), limit))
+	for i := 0; i < len(matches) && i < limit; i++ {
+		item := matches[i].item
+		l.menuItem(&item)
+		results = append(results, item)
+	}
+
+	return c.JSON(results)
+}
@@ -0,0 +1,92 @@
+package api_handlers
+
+import (
+	"encoding/json"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThaiTokenizer(t *testing.T) {
+	tokenizer, err := service.LoadThaiTokenizer("../" + menuSearchDictPath)
+	assert.Nil(t, err)
+
+	// คำประสมที่อยู่ในพจนานุกรม (ข้าวผัด, ต้มยำ) ถูกเก็บเป็นคำเดียว
+	assert.Equal(t, []string{"ข้าวผัด", "ไก่"}, tokenizer.Tokenize("ข้าวผัดไก่"))
+	assert.Equal(t, []string{"tom", "yum", "ต้มยำ", "冬", "阴", "功"}, tokenizer.Tokenize("Tom-Yum ต้มยำ 冬阴功"))
+	// ภาษาอังกฤษที่พิมพ์ติดกับภาษาไทยแยกเป็นคนละคำ
+	assert.Equal(t, []string{"ไก่", "xyz"}, tokenizer.Tokenize("ไก่xyz"))
+}
+
+func TestSearchMenu(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.DietaryTag{})
+
+	// ใช้พจนานุกรมเล็กๆ แทนการโหลดไฟล์จริง
+	menuSearchTokenizerOnce.Do(func() {})
+	menuSearchTokenizer = service.NewThaiTokenizer([]string{"ข้าว", "ผัด", "ไก่", "ต้ม", "ยำ", "กุ้ง", "กะเพรา", "หมู"})
+
+	app.Get("/api/menu/search", SearchMenu)
+
+	items := []models.MenuItem{
+		{Name: "ข้าวผัดไก่", NameEn: "Chicken Fried Rice", NameCh: "鸡肉炒饭", CategoryID: 1, Price: 60},
+		{Name: "ต้มยำกุ้ง", NameEn: "Tom Yum Goong", NameCh: "冬阴功", CategoryID: 1, Price: 120, Description: "ต้มยำรสจัด"},
+		{Name: "กะเพราหมู", NameEn: "Basil Pork", NameCh: "罗勒猪肉", CategoryID: 1, Price: 70, Description: "เสิร์ฟพร้อมข้าว"},
+		{Name: "ข้าวผัดกุ้ง", NameEn: "Shrimp Fried Rice", NameCh: "虾炒饭", CategoryID: 1, Price: 80},
+	}
+	for i := range items {
+		db.DB.Create(&items[i])
+	}
+	db.DB.Model(&items[3]).Update("is_available", false)
+
+	search := func(q string) []models.MenuItem {
+		req := httptest.NewRequest("GET", "/api/menu/search?q="+url.QueryEscape(q), nil)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var results []models.MenuItem
+		json.NewDecoder(resp.Body).Decode(&results)
+		return results
+	}
+
+	names := func(results []models.MenuItem) []string {
+		var list []string
+		for _, item := range results {
+			list = append(list, item.Name)
+		}
+		return list
+	}
+
+	// กรณีทดสอบที่ 1: ภาษาไทยตัดคำแล้วชื่อมาก่อนคำอธิบาย และไม่แสดงเมนูที่ปิดขาย
+	t.Run("Success - Thai search", func(t *testing.T) {
+		assert.Equal(t, []string{"ข้าวผัดไก่", "กะเพราหมู"}, names(search("ข้าว")))
+		assert.Equal(t, []string{"ต้มยำกุ้ง"}, names(search("กุ้ง")))
+	})
+
+	// กรณีทดสอบที่ 2: อังกฤษแบบขึ้นต้นและพิมพ์ผิด
+	t.Run("Success - Prefix and fuzzy", func(t *testing.T) {
+		assert.Equal(t, []string{"ข้าวผัดไก่"}, names(search("chick")))
+		assert.Equal(t, []string{"ข้าวผัดไก่"}, names(search("chiken")))
+		assert.Equal(t, []string{"ต้มยำกุ้ง"}, names(search("tomyum")))
+	})
+
+	// กรณีทดสอบที่ 3: ภาษาจีน
+	t.Run("Success - Chinese search", func(t *testing.T) {
+		assert.Equal(t, []string{"ข้าวผัดไก่"}, names(search("炒饭")))
+	})
+
+	// กรณีทดสอบที่ 4: ไม่ระบุคำค้น
+	t.Run("Failure - Missing query", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/menu/search", nil)
+		resp, _ := app.Test(req)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
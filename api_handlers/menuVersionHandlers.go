@@ -0,0 +1,771 @@
+package api_handlers
+
+import (
+	"errors"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	utils "food-ordering-api/utility"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// MenuFieldChange ค่าที่ต่างกันระหว่างเมนูที่ใช้อยู่กับเวอร์ชัน
+type MenuFieldChange struct {
+	Field   string      `json:"field"`
+	Live    interface{} `json:"live"`
+	Version interface{} `json:"version"`
+}
+
+// MenuVersionDiff ความต่างของเมนูหนึ่งรายการ
+type MenuVersionDiff struct {
+	MenuItemID  uint              `json:"menu_item_id"`
+	Name        string            `json:"name"`
+	LiveMissing bool              `json:"live_missing,omitempty"` // เมนูถูกลบไปแล้ว เผยแพร่จะข้ามรายการนี้
+	Changes     []MenuFieldChange `json:"changes"`
+}
+
+// currentUserID ดึง user_id จาก token (nil ถ้าไม่มี token)
+func currentUserID(c *fiber.Ctx) *uint {
+	claims, err := utils.GetUserFromToken(c)
+	if err != nil {
+		return nil
+	}
+	userID, ok := (*claims)["user_id"].(float64)
+	if !ok {
+		return nil
+	}
+	id := uint(userID)
+	return &id
+}
+
+// snapshotLiveMenu คัดลอกเมนูและราคาตัวเลือกที่ใช้อยู่ลงในเวอร์ชัน
+func snapshotLiveMenu(tx *gorm.DB, versionID uint) error {
+	var menuItems []models.MenuItem
+	if err := tx.Preload("OptionGroups.Options").Order("id").Find(&menuItems).Error; err != nil {
+		return err
+	}
+	// เก็บราคาตัวเลือกที่ใช้จริงของแต่ละเมนู (รวมราคาเฉพาะเมนูของกลุ่มที่ใช้ร่วมกัน)
+	if err := applyOptionPriceOverrides(tx, menuItems); err != nil {
+		return err
+	}
+
+	for _, menu := range menuItems {
+		item := models.MenuVersionItem{
+			MenuVersionID: versionID,
+			MenuItemID:    menu.ID,
+			Name:          menu.Name,
+			NameEn:        menu.NameEn,
+			NameCh:        menu.NameCh,
+			Description:   menu.Description,
+			DescriptionEn: menu.DescriptionEn,
+			DescriptionCh: menu.DescriptionCh,
+			CategoryID:    menu.CategoryID,
+			Price:         menu.Price,
+			IsAvailable:   menu.Is_available,
+		}
+		if err := tx.Create(&item).Error; err != nil {
+			return err
+		}
+
+		for _, group := range menu.OptionGroups {
+			for _, opt := range group.Options {
+				option := models.MenuVersionOption{
+					MenuVersionID: versionID,
+					MenuOptionID:  opt.ID,
+					MenuItemID:    menu.ID,
+					Name:          opt.Name,
+					Price:         opt.Price,
+				}
+				if err := tx.Create(&option).Error; err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// copyMenuVersion คัดลอกรายการทั้งหมดจากเวอร์ชันต้นทาง
+func copyMenuVersion(tx *gorm.DB, sourceID, versionID uint) error {
+	var items []models.MenuVersionItem
+	if err := tx.Where("menu_version_id = ?", sourceID).Find(&items).Error; err != nil {
+		return err
+	}
+	for _, item := range items {
+		item.ID = 0
+		item.MenuVersionID = versionID
+		if err := tx.Create(&item).Error; err != nil {
+			return err
+		}
+	}
+
+	var options []models.MenuVersionOption
+	if err := tx.Where("menu_version_id = ?", sourceID).Find(&options).Error; err != nil {
+		return err
+	}
+	for _, option := range options {
+		option.ID = 0
+		option.MenuVersionID = versionID
+		if err := tx.Create(&option).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordMenuPriceChange ปิดช่วงราคาเดิมและเปิดช่วงราคาใหม่ของเมนู
+// การเปลี่ยนราคาครั้งแรกจะบันทึกราคาเดิม (oldPrice) ตั้งแต่สร้างเมนูไว้ด้วย เพื่อให้ประวัติเห็นราคาก่อนเปลี่ยน
+func recordMenuPriceChange(tx *gorm.DB, menuItemID uint, oldPrice, price int16, versionID *uint, now time.Time) error {
+	result := tx.Model(&models.MenuPriceHistory{}).
+		Where("menu_item_id = ? AND effective_to IS NULL", menuItemID).
+		Update("effective_to", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var menu models.MenuItem
+		if err := tx.Unscoped().Select("id", "created_at").First(&menu, menuItemID).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.MenuPriceHistory{
+			MenuItemID:    menuItemID,
+			Price:         oldPrice,
+			EffectiveFrom: menu.CreatedAt,
+			EffectiveTo:   &now,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Create(&models.MenuPriceHistory{
+		MenuItemID:    menuItemID,
+		Price:         price,
+		EffectiveFrom: now,
+		MenuVersionID: versionID,
+	}).Error
+}
+
+// applyMenuVersion นำข้อมูลในเวอร์ชันไปเขียนทับเมนูจริง แล้วตั้งเป็นเวอร์ชันที่ใช้งานอยู่
+// เมนูที่ถูกลบไปแล้วหรือสร้างหลังเวอร์ชันนี้จะไม่ถูกแตะ
+func applyMenuVersion(tx *gorm.DB, version *models.MenuVersion, now time.Time) error {
+	var items []models.MenuVersionItem
+	if err := tx.Where("menu_version_id = ?", version.ID).Find(&items).Error; err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		var menu models.MenuItem
+		if err := tx.First(&menu, item.MenuItemID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return err
+		}
+
+		// Updates จะเขียนค่าใหม่ทับ struct จึงต้องเก็บราคาเดิมไว้ก่อน
+		oldPrice := menu.Price
+		if err := tx.Model(&menu).Updates(map[string]interface{}{
+			"name":           item.Name,
+			"name_en":        item.NameEn,
+			"name_ch":        item.NameCh,
+			"description":    item.Description,
+			"description_en": item.DescriptionEn,
+			"description_ch": item.DescriptionCh,
+			"category_id":    item.CategoryID,
+			"price":          item.Price,
+			"is_available":   item.IsAvailable,
+		}).Error; err != nil {
+			return err
+		}
+
+		if oldPrice != item.Price {
+			if err := recordMenuPriceChange(tx, menu.ID, oldPrice, item.Price, &version.ID, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	var options []models.MenuVersionOption
+	if err := tx.Where("menu_version_id = ?", version.ID).Find(&options).Error; err != nil {
+		return err
+	}
+	for _, option := range options {
+		if err := setMenuOptionPrice(tx, option.MenuItemID, option.MenuOptionID, option.Price); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&models.MenuVersion{}).
+		Where("status = ? AND id != ?", models.MenuVersionPublished, version.ID).
+		Update("status", models.MenuVersionArchived).Error; err != nil {
+		return err
+	}
+
+	version.Status = models.MenuVersionPublished
+	version.PublishedAt = &now
+	return tx.Model(version).Updates(map[string]interface{}{
+		"status":       version.Status,
+		"published_at": now,
+	}).Error
+}
+
+// PublishScheduledMenuVersions เผยแพร่เวอร์ชันเมนูที่ถึงเวลาที่ตั้งไว้
+func PublishScheduledMenuVersions() {
+	for {
+		publishDueMenuVersions(time.Now())
+		time.Sleep(1 * time.Minute) // ตรวจสอบทุก 1 นาที
+	}
+}
+
+// publishDueMenuVersions เผยแพร่เวอร์ชันที่ publish_at ถึงแล้ว ตามลำดับเวลา (หนึ่งรอบของ worker)
+func publishDueMenuVersions(now time.Time) {
+	var versions []models.MenuVersion
+	if err := db.DB.Where("status = ? AND publish_at <= ?", models.MenuVersionScheduled, now).
+		Order("publish_at").
+		Find(&versions).Error; err != nil {
+		log.Printf("Failed to load scheduled menu versions: %v", err)
+		return
+	}
+
+	for i := range versions {
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			// ยืนยันสถานะอีกครั้ง กันกรณีถูกยกเลิกหรือเผยแพร่ไปแล้วระหว่างรอบ
+			result := tx.Model(&models.MenuVersion{}).
+				Where("id = ? AND status = ?", versions[i].ID, models.MenuVersionScheduled).
+				Update("status", models.MenuVersionPublished)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return nil
+			}
+			return applyMenuVersion(tx, &versions[i], now)
+		})
+		if err != nil {
+			log.Printf("Failed to publish menu version %d: %v", versions[i].ID, err)
+			continue
+		}
+		log.Printf("Published scheduled menu version %d (%s)", versions[i].ID, versions[i].Name)
+	}
+}
+
+// getMenuVersionFromParam โหลดเวอร์ชันจาก path param :id
+func getMenuVersionFromParam(c *fiber.Ctx) (*models.MenuVersion, error) {
+	versionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return nil, c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid menu version ID format",
+		})
+	}
+
+	var version models.MenuVersion
+	if err := db.DB.First(&version, versionID).Error; err != nil {
+		return nil, c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Menu version not found",
+		})
+	}
+	return &version, nil
+}
+
+// @Summary สร้างเวอร์ชันเมนู (draft)
+// @Description สร้าง draft จากเมนูที่ใช้อยู่ หรือคัดลอกจากเวอร์ชันอื่นเมื่อระบุ based_on_id
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateMenuVersionRequest true "ข้อมูลเวอร์ชัน"
+// @Success 201 {object} models.MenuVersion "เวอร์ชันที่สร้าง"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 404 {object} map[string]interface{} "ไม่พบเวอร์ชันต้นทาง"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการสร้างเวอร์ชัน"
+// @Router /api/menu/versions [post]
+// @Tags menu-versions
+func CreateMenuVersion(c *fiber.Ctx) error {
+	var req models.CreateMenuVersionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input format",
+		})
+	}
+
+	if req.Name == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Version name is required",
+		})
+	}
+
+	if req.BasedOnID != nil {
+		if err := db.DB.First(&models.MenuVersion{}, *req.BasedOnID).Error; err != nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"error": "Base menu version not found",
+			})
+		}
+	}
+
+	version := models.MenuVersion{
+		Name:      req.Name,
+		Note:      req.Note,
+		Status:    models.MenuVersionDraft,
+		BasedOnID: req.BasedOnID,
+		CreatedBy: currentUserID(c),
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&version).Error; err != nil {
+			return err
+		}
+		if req.BasedOnID != nil {
+			return copyMenuVersion(tx, *req.BasedOnID, version.ID)
+		}
+		return snapshotLiveMenu(tx, version.ID)
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create menu version",
+		})
+	}
+
+	return c.Status(http.StatusCreated).JSON(version)
+}
+
+// @Summary ดูรายการเวอร์ชันเมนู
+// @Description ดึงเวอร์ชันเมนูทั้งหมด กรองตามสถานะได้
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "สถานะ" Enums(draft, scheduled, published, archived)
+// @Success 200 {array} models.MenuVersion "รายการเวอร์ชัน"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
+// @Router /api/menu/versions [get]
+// @Tags menu-versions
+func GetMenuVersions(c *fiber.Ctx) error {
+	query := db.DB.Order("created_at DESC")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var versions []models.MenuVersion
+	if err := query.Find(&versions).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch menu versions",
+		})
+	}
+
+	return c.JSON(versions)
+}
+
+// @Summary ดูรายละเอียดเวอร์ชันเมนู
+// @Description ดึงเวอร์ชันพร้อมรายการเมนูและราคาตัวเลือกทั้งหมด
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของเวอร์ชัน"
+// @Success 200 {object} models.MenuVersion "รายละเอียดเวอร์ชัน"
+// @Failure 404 {object} map[string]interface{} "ไม่พบเวอร์ชัน"
+// @Router /api/menu/versions/{id} [get]
+// @Tags menu-versions
+func GetMenuVersion(c *fiber.Ctx) error {
+	versionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid menu version ID format",
+		})
+	}
+
+	var version models.MenuVersion
+	if err := db.DB.Preload("Items", func(tx *gorm.DB) *gorm.DB { return tx.Order("menu_item_id") }).
+		Preload("Options", func(tx *gorm.DB) *gorm.DB { return tx.Order("menu_option_id") }).
+		First(&version, versionID).Error; err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Menu version not found",
+		})
+	}
+
+	return c.JSON(version)
+}
+
+// @Summary แก้ไขเมนูใน draft
+// @Description แก้ไขข้อมูลเมนูหนึ่งรายการในเวอร์ชันที่ยังเป็น draft (ไม่มีผลกับเมนูจริงจนกว่าจะเผยแพร่)
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของเวอร์ชัน"
+// @Param menu_item_id path integer true "ID ของเมนู"
+// @Param request body models.UpdateMenuVersionItemRequest true "ข้อมูลที่ต้องการแก้ไข"
+// @Success 200 {object} models.MenuVersionItem "ข้อมูลเมนูใน draft"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 404 {object} map[string]interface{} "ไม่พบเวอร์ชันหรือเมนู"
+// @Failure 409 {object} map[string]interface{} "เวอร์ชันไม่ใช่ draft"
+// @Router /api/menu/versions/{id}/items/{menu_item_id} [put]
+// @Tags menu-versions
+func UpdateMenuVersionItem(c *fiber.Ctx) error {
+	version, err := getMenuVersionFromParam(c)
+	if version == nil {
+		return err
+	}
+
+	if version.Status != models.MenuVersionDraft {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error": "Only draft versions can be edited",
+		})
+	}
+
+	menuItemID, err := strconv.Atoi(c.Params("menu_item_id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid menu ID format",
+		})
+	}
+
+	var req models.UpdateMenuVersionItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input format",
+		})
+	}
+
+	if req.Price != nil && *req.Price < 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Price cannot be negative",
+		})
+	}
+	for _, price := range req.OptionPrices {
+		if price < 0 {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Option price cannot be negative",
+			})
+		}
+	}
+
+	var item models.MenuVersionItem
+	if err := db.DB.Where("menu_version_id = ? AND menu_item_id = ?", version.ID, menuItemID).
+		First(&item).Error; err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Menu item not found in this version",
+		})
+	}
+
+	if req.CategoryID != nil {
+		if err := db.DB.First(&models.Category{}, *req.CategoryID).Error; err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Category not found",
+			})
+		}
+	}
+
+	updates := map[string]interface{}{}
+	setIfPresent := func(column string, value interface{}, present bool) {
+		if present {
+			updates[column] = value
+		}
+	}
+	setIfPresent("name", req.Name, req.Name != nil)
+	setIfPresent("name_en", req.NameEn, req.NameEn != nil)
+	setIfPresent("name_ch", req.NameCh, req.NameCh != nil)
+	setIfPresent("description", req.Description, req.Description != nil)
+	setIfPresent("description_en", req.DescriptionEn, req.DescriptionEn != nil)
+	setIfPresent("description_ch", req.DescriptionCh, req.DescriptionCh != nil)
+	setIfPresent("category_id", req.CategoryID, req.CategoryID != nil)
+	setIfPresent("price", req.Price, req.Price != nil)
+	setIfPresent("is_available", req.IsAvailable, req.IsAvailable != nil)
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(&item).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		for optionID, price := range req.OptionPrices {
+			result := tx.Model(&models.MenuVersionOption{}).
+				Where("menu_version_id = ? AND menu_item_id = ? AND menu_option_id = ?", version.ID, item.MenuItemID, optionID).
+				Update("price", price)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("option %d not found in this menu item", optionID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to update menu version item: " + err.Error(),
+		})
+	}
+
+	db.DB.First(&item, item.ID)
+	return c.JSON(item)
+}
+
+// @Summary เปรียบเทียบเวอร์ชันกับเมนูที่ใช้อยู่
+// @Description แสดงเฉพาะเมนูและตัวเลือกที่ค่าในเวอร์ชันต่างจากเมนูจริง
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของเวอร์ชัน"
+// @Success 200 {array} MenuVersionDiff "รายการที่ต่างกัน"
+// @Failure 404 {object} map[string]interface{} "ไม่พบเวอร์ชัน"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการเปรียบเทียบ"
+// @Router /api/menu/versions/{id}/diff [get]
+// @Tags menu-versions
+func GetMenuVersionDiff(c *fiber.Ctx) error {
+	version, err := getMenuVersionFromParam(c)
+	if version == nil {
+		return err
+	}
+
+	var items []models.MenuVersionItem
+	var options []models.MenuVersionOption
+	if err := db.DB.Where("menu_version_id = ?", version.ID).Order("menu_item_id").Find(&items).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch menu version items",
+		})
+	}
+	if err := db.DB.Where("menu_version_id = ?", version.ID).Order("menu_option_id").Find(&options).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch menu version options",
+		})
+	}
+
+	var liveMenus []models.MenuItem
+	if err := db.DB.Find(&liveMenus).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch menu items",
+		})
+	}
+	live := make(map[uint]models.MenuItem, len(liveMenus))
+	for _, menu := range liveMenus {
+		live[menu.ID] = menu
+	}
+
+	var liveOptions []models.MenuOption
+	if err := db.DB.Find(&liveOptions).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch menu options",
+		})
+	}
+	liveOptionPrices := make(map[uint]float64, len(liveOptions))
+	for _, opt := range liveOptions {
+		liveOptionPrices[opt.ID] = opt.Price
+	}
+	liveIDs := make([]uint, 0, len(liveMenus))
+	for _, menu := range liveMenus {
+		liveIDs = append(liveIDs, menu.ID)
+	}
+	overrides, err := loadOptionPriceOverrides(db.DB, liveIDs)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch menu options",
+		})
+	}
+
+	optionsByItem := map[uint][]models.MenuVersionOption{}
+	for _, opt := range options {
+		optionsByItem[opt.MenuItemID] = append(optionsByItem[opt.MenuItemID], opt)
+	}
+
+	diffs := []MenuVersionDiff{}
+	for _, item := range items {
+		menu, ok := live[item.MenuItemID]
+		if !ok {
+			diffs = append(diffs, MenuVersionDiff{MenuItemID: item.MenuItemID, Name: item.Name, LiveMissing: true, Changes: []MenuFieldChange{}})
+			continue
+		}
+
+		changes := []MenuFieldChange{}
+		compare := func(field string, liveValue, versionValue interface{}) {
+			if liveValue != versionValue {
+				changes = append(changes, MenuFieldChange{Field: field, Live: liveValue, Version: versionValue})
+			}
+		}
+		compare("name", menu.Name, item.Name)
+		compare("name_en", menu.NameEn, item.NameEn)
+		compare("name_ch", menu.NameCh, item.NameCh)
+		compare("description", menu.Description, item.Description)
+		compare("description_en", menu.DescriptionEn, item.DescriptionEn)
+		compare("description_ch", menu.DescriptionCh, item.DescriptionCh)
+		compare("category_id", menu.CategoryID, item.CategoryID)
+		compare("price", menu.Price, item.Price)
+		compare("is_available", menu.Is_available, item.IsAvailable)
+		for _, opt := range optionsByItem[item.MenuItemID] {
+			if livePrice, ok := liveOptionPrices[opt.MenuOptionID]; ok {
+				if override, ok := overrides[item.MenuItemID][opt.MenuOptionID]; ok {
+					livePrice = override
+				}
+				compare(fmt.Sprintf("option_price:%d:%s", opt.MenuOptionID, opt.Name), livePrice, opt.Price)
+			}
+		}
+
+		if len(changes) > 0 {
+			diffs = append(diffs, MenuVersionDiff{MenuItemID: item.MenuItemID, Name: item.Name, Changes: changes})
+		}
+	}
+
+	return c.JSON(diffs)
+}
+
+// @Summary เผยแพร่เวอร์ชันเมนู
+// @Description เผยแพร่ draft ทันที หรือตั้งเวลาให้ worker เผยแพร่เมื่อระบุ publish_at ในอนาคต
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของเวอร์ชัน"
+// @Param request body models.PublishMenuVersionRequest false "เวลาที่ต้องการเผยแพร่"
+// @Success 200 {object} models.MenuVersion "เวอร์ชันหลังเผยแพร่/ตั้งเวลา"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 404 {object} map[string]interface{} "ไม่พบเวอร์ชัน"
+// @Failure 409 {object} map[string]interface{} "เวอร์ชันไม่ใช่ draft"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการเผยแพร่"
+// @Router /api/menu/versions/{id}/publish [post]
+// @Tags menu-versions
+func PublishMenuVersion(c *fiber.Ctx) error {
+	version, err := getMenuVersionFromParam(c)
+	if version == nil {
+		return err
+	}
+
+	var req models.PublishMenuVersionRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid input format",
+			})
+		}
+	}
+
+	if version.Status != models.MenuVersionDraft && version.Status != models.MenuVersionScheduled {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error": "Only draft or scheduled versions can be published",
+		})
+	}
+
+	now := time.Now()
+	if req.PublishAt != nil && req.PublishAt.After(now) {
+		version.Status = models.MenuVersionScheduled
+		version.PublishAt = req.PublishAt
+		if err := db.DB.Model(version).Updates(map[string]interface{}{
+			"status":     version.Status,
+			"publish_at": version.PublishAt,
+		}).Error; err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to schedule menu version",
+			})
+		}
+		return c.JSON(version)
+	}
+
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		return applyMenuVersion(tx, version, now)
+	}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to publish menu version",
+		})
+	}
+
+	return c.JSON(version)
+}
+
+// @Summary ยกเลิกการตั้งเวลาเผยแพร่
+// @Description เปลี่ยนเวอร์ชันที่ตั้งเวลาไว้กลับเป็น draft
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของเวอร์ชัน"
+// @Success 200 {object} models.MenuVersion "เวอร์ชันหลังยกเลิก"
+// @Failure 404 {object} map[string]interface{} "ไม่พบเวอร์ชัน"
+// @Failure 409 {object} map[string]interface{} "เวอร์ชันไม่ได้ตั้งเวลาไว้"
+// @Router /api/menu/versions/{id}/unschedule [post]
+// @Tags menu-versions
+func UnscheduleMenuVersion(c *fiber.Ctx) error {
+	version, err := getMenuVersionFromParam(c)
+	if version == nil {
+		return err
+	}
+
+	result := db.DB.Model(&models.MenuVersion{}).
+		Where("id = ? AND status = ?", version.ID, models.MenuVersionScheduled).
+		Updates(map[string]interface{}{
+			"status":     models.MenuVersionDraft,
+			"publish_at": nil,
+		})
+	if result.Error != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to unschedule menu version",
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error": "Menu version is not scheduled",
+		})
+	}
+
+	db.DB.First(version, version.ID)
+	return c.JSON(version)
+}
+
+// @Summary ย้อนกลับไปใช้เวอร์ชันเก่า
+// @Description นำเวอร์ชันที่เคยเผยแพร่แล้ว (archived) กลับมาใช้ทันที ราคาจะถูกบันทึกในประวัติราคา
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของเวอร์ชัน"
+// @Success 200 {object} models.MenuVersion "เวอร์ชันที่ใช้งานอยู่"
+// @Failure 404 {object} map[string]interface{} "ไม่พบเวอร์ชัน"
+// @Failure 409 {object} map[string]interface{} "เวอร์ชันไม่เคยเผยแพร่"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการย้อนกลับ"
+// @Router /api/menu/versions/{id}/rollback [post]
+// @Tags menu-versions
+func RollbackMenuVersion(c *fiber.Ctx) error {
+	version, err := getMenuVersionFromParam(c)
+	if version == nil {
+		return err
+	}
+
+	if version.Status != models.MenuVersionArchived {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error": "Only previously published versions can be rolled back to",
+		})
+	}
+
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		return applyMenuVersion(tx, version, time.Now())
+	}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to roll back menu version",
+		})
+	}
+
+	return c.JSON(version)
+}
+
+// @Summary ประวัติราคาเมนู
+// @Description ดึงช่วงเวลาที่เมนูใช้แต่ละราคา สำหรับทำรายงานย้อนหลัง
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของเมนู"
+// @Success 200 {array} models.MenuPriceHistory "ประวัติราคา"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
+// @Router /api/menu/{id}/price-history [get]
+// @Tags menu-versions
+func GetMenuPriceHistory(c *fiber.Ctx) error {
+	menuID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid menu ID format",
+		})
+	}
+
+	var history []models.MenuPriceHistory
+	if err := db.DB.Where("menu_item_id = ?", menuID).
+		Order("effective_from DESC").
+		Find(&history).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch price history",
+		})
+	}
+
+	return c.JSON(history)
+}
@@ -0,0 +1,142 @@
+package api_handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMenuVersionWorkflow(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.MenuVersion{}, &models.MenuVersionItem{}, &models.MenuVersionOption{}, &models.MenuPriceHistory{})
+
+	app.Post("/api/menu/versions", CreateMenuVersion)
+	app.Put("/api/menu/versions/:id/items/:menu_item_id", UpdateMenuVersionItem)
+	app.Get("/api/menu/versions/:id/diff", GetMenuVersionDiff)
+	app.Post("/api/menu/versions/:id/publish", PublishMenuVersion)
+	app.Post("/api/menu/versions/:id/rollback", RollbackMenuVersion)
+	app.Get("/api/menu/:id/price-history", GetMenuPriceHistory)
+
+	menu := models.MenuItem{
+		Name: "ผัดไทย", NameEn: "Pad Thai", NameCh: "泰式炒河粉", CategoryID: 1, Price: 80,
+		OptionGroups: []models.OptionGroup{{
+			Name: "ท็อปปิ้ง", NameEn: "Topping", NameCh: "配料", MaxSelections: 1,
+			Options: []models.MenuOption{{Name: "ไข่ดาว", NameEn: "Fried egg", NameCh: "煎蛋", Price: 10}},
+		}},
+	}
+	db.DB.Create(&menu)
+	optionID := menu.OptionGroups[0].Options[0].ID
+
+	createVersion := func(name string) models.MenuVersion {
+		resp := sendJSON(t, app, "POST", "/api/menu/versions", models.CreateMenuVersionRequest{Name: name})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		var version models.MenuVersion
+		json.NewDecoder(resp.Body).Decode(&version)
+		return version
+	}
+
+	var first models.MenuVersion
+
+	// กรณีทดสอบที่ 1: แก้ draft ไม่กระทบเมนูจริง และ diff แสดงความต่าง
+	t.Run("Success - Edit draft and diff", func(t *testing.T) {
+		first = createVersion("ราคาใหม่")
+		assert.Equal(t, models.MenuVersionDraft, first.Status)
+
+		price := int16(95)
+		resp := sendJSON(t, app, "PUT", fmt.Sprintf("/api/menu/versions/%d/items/%d", first.ID, menu.ID), models.UpdateMenuVersionItemRequest{
+			Price:        &price,
+			OptionPrices: map[uint]float64{optionID: 15},
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var live models.MenuItem
+		db.DB.First(&live, menu.ID)
+		assert.Equal(t, int16(80), live.Price)
+
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/menu/versions/%d/diff", first.ID), nil)
+		resp, _ = app.Test(req)
+		var diffs []MenuVersionDiff
+		json.NewDecoder(resp.Body).Decode(&diffs)
+		assert.Equal(t, 1, len(diffs))
+		assert.Equal(t, 2, len(diffs[0].Changes))
+		assert.Equal(t, "price", diffs[0].Changes[0].Field)
+	})
+
+	// กรณีทดสอบที่ 2: ตั้งเวลาแล้ว worker เผยแพร่เมื่อถึงเวลา
+	t.Run("Success - Scheduled publish", func(t *testing.T) {
+		publishAt := time.Now().Add(time.Hour)
+		resp := sendJSON(t, app, "POST", fmt.Sprintf("/api/menu/versions/%d/publish", first.ID), models.PublishMenuVersionRequest{PublishAt: &publishAt})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		publishDueMenuVersions(time.Now())
+		var live models.MenuItem
+		db.DB.First(&live, menu.ID)
+		assert.Equal(t, int16(80), live.Price)
+
+		// เลื่อนเวลาที่ตั้งไว้ให้ถึงกำหนด แทนการรอจริง
+		db.DB.Model(&models.MenuVersion{}).Where("id = ?", first.ID).Update("publish_at", time.Now().Add(-time.Minute))
+		publishDueMenuVersions(time.Now())
+		db.DB.Preload("OptionGroups.Options").First(&live, menu.ID)
+		assert.Equal(t, int16(95), live.Price)
+		assert.Equal(t, 15.0, live.OptionGroups[0].Options[0].Price)
+
+		var version models.MenuVersion
+		db.DB.First(&version, first.ID)
+		assert.Equal(t, models.MenuVersionPublished, version.Status)
+	})
+
+	// กรณีทดสอบที่ 3: เผยแพร่ทันที แล้ว rollback กลับไปเวอร์ชันก่อนหน้า
+	t.Run("Success - Publish now and rollback", func(t *testing.T) {
+		second := createVersion("ลดราคา")
+		price := int16(70)
+		sendJSON(t, app, "PUT", fmt.Sprintf("/api/menu/versions/%d/items/%d", second.ID, menu.ID), models.UpdateMenuVersionItemRequest{Price: &price})
+
+		resp := sendJSON(t, app, "POST", fmt.Sprintf("/api/menu/versions/%d/publish", second.ID), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var live models.MenuItem
+		db.DB.First(&live, menu.ID)
+		assert.Equal(t, int16(70), live.Price)
+
+		resp = sendJSON(t, app, "POST", fmt.Sprintf("/api/menu/versions/%d/rollback", first.ID), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		db.DB.First(&live, menu.ID)
+		assert.Equal(t, int16(95), live.Price)
+
+		var version models.MenuVersion
+		db.DB.First(&version, second.ID)
+		assert.Equal(t, models.MenuVersionArchived, version.Status)
+	})
+
+	// กรณีทดสอบที่ 4: ประวัติราคาครบทุกครั้งที่เปลี่ยน รวมราคาเดิมก่อนเปลี่ยนครั้งแรก
+	t.Run("Success - Price history", func(t *testing.T) {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/menu/%d/price-history", menu.ID), nil)
+		resp, _ := app.Test(req)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var history []models.MenuPriceHistory
+		json.NewDecoder(resp.Body).Decode(&history)
+		assert.Equal(t, 4, len(history))
+		assert.Nil(t, history[0].EffectiveTo)
+		assert.Equal(t, int16(95), history[0].Price)
+		assert.NotNil(t, history[1].EffectiveTo)
+		assert.Equal(t, int16(80), history[3].Price)
+		assert.Nil(t, history[3].MenuVersionID)
+	})
+
+	// กรณีทดสอบที่ 5: แก้ไขเวอร์ชันที่เผยแพร่แล้วไม่ได้
+	t.Run("Failure - Edit published version", func(t *testing.T) {
+		price := int16(100)
+		resp := sendJSON(t, app, "PUT", fmt.Sprintf("/api/menu/versions/%d/items/%d", first.ID, menu.ID), models.UpdateMenuVersionItemRequest{Price: &price})
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+}
@@ -0,0 +1,642 @@
+package api_handlers
+
+import (
+	"errors"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ModifierGroupResponse กลุ่มตัวเลือกในคลัง พร้อมเมนูที่ใช้กลุ่มนี้อยู่
+type ModifierGroupResponse struct {
+	models.OptionGroup
+	MenuItemIDs []uint `json:"menu_item_ids"`
+}
+
+// loadOptionPriceOverrides ดึงราคาเฉพาะเมนู: menu_item_id -> menu_option_id -> ราคา
+func loadOptionPriceOverrides(tx *gorm.DB, menuItemIDs []uint) (map[uint]map[uint]float64, error) {
+	overrides := map[uint]map[uint]float64{}
+	if len(menuItemIDs) == 0 {
+		return overrides, nil
+	}
+
+	var rows []models.MenuItemOptionPrice
+	if err := tx.Where("menu_item_id IN ?", menuItemIDs).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if overrides[row.MenuItemID] == nil {
+			overrides[row.MenuItemID] = map[uint]float64{}
+		}
+		overrides[row.MenuItemID][row.MenuOptionID] = row.Price
+	}
+	return overrides, nil
+}
+
+// applyOptionPriceOverrides แทนราคาตัวเลือกด้วยราคาเฉพาะเมนู (ต้อง preload OptionGroups.Options มาก่อน)
+// กลุ่มที่ใช้ร่วมกันจะแชร์ slice ของ Options ระหว่างเมนู จึงต้องคัดลอกก่อนแก้ราคา
+func applyOptionPriceOverrides(tx *gorm.DB, items []models.MenuItem) error {
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	overrides, err := loadOptionPriceOverrides(tx, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range items {
+		prices := overrides[items[i].ID]
+		if len(prices) == 0 {
+			continue
+		}
+		for gi := range items[i].OptionGroups {
+			group := &items[i].OptionGroups[gi]
+			options := append([]models.MenuOption{}, group.Options...)
+			for oi := range options {
+				if price, ok := prices[options[oi].ID]; ok {
+					base := options[oi].Price
+					options[oi].BasePrice = &base
+					options[oi].Price = price
+				}
+			}
+			group.Options = options
+		}
+	}
+	return nil
+}
+
+// menuItemHasOptionGroup ตรวจว่ากลุ่มตัวเลือกผูกกับเมนูนี้หรือไม่
+func menuItemHasOptionGroup(tx *gorm.DB, menuItemID, groupID uint) (bool, error) {
+	var count int64
+	err := tx.Table("menu_item_option_groups").
+		Where("menu_item_id = ? AND option_group_id = ?", menuItemID, groupID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// optionGroupNameTaken ตรวจว่าเมนูใน menuItemIDs (slice หรือ subquery) มีกลุ่มอื่นที่ยังไม่ถูกลบชื่อ name ผูกอยู่แล้วหรือไม่
+func optionGroupNameTaken(tx *gorm.DB, menuItemIDs interface{}, name string, excludeGroupID uint) (bool, error) {
+	var count int64
+	err := tx.Table("menu_item_option_groups").
+		Joins("JOIN option_groups ON option_groups.id = menu_item_option_groups.option_group_id AND option_groups.deleted_at IS NULL").
+		Where("menu_item_option_groups.menu_item_id IN (?) AND option_groups.name = ? AND option_groups.id != ?", menuItemIDs, name, excludeGroupID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// optionGroupMenuIDs subquery ของเมนูที่ผูกกับกลุ่มนี้
+func optionGroupMenuIDs(tx *gorm.DB, groupID uint) *gorm.DB {
+	return tx.Table("menu_item_option_groups").Select("menu_item_id").Where("option_group_id = ?", groupID)
+}
+
+// menuOptionGroupIDs subquery ของกลุ่มที่ผูกกับเมนูนี้
+func menuOptionGroupIDs(tx *gorm.DB, menuItemID uint) *gorm.DB {
+	return tx.Table("menu_item_option_groups").Select("option_group_id").Where("menu_item_id = ?", menuItemID)
+}
+
+// menuOptionPrice ราคาของตัวเลือกเมื่อสั่งกับเมนูนี้ (ราคาเฉพาะเมนูถ้ามี ไม่งั้นราคากลาง)
+func menuOptionPrice(tx *gorm.DB, menuItemID uint, option models.MenuOption) (float64, error) {
+	var override models.MenuItemOptionPrice
+	err := tx.Where("menu_item_id = ? AND menu_option_id = ?", menuItemID, option.ID).First(&override).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return option.Price, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return override.Price, nil
+}
+
+// setMenuOptionPrice ตั้งราคาตัวเลือกของเมนูหนึ่ง (ใช้ตอนเผยแพร่เวอร์ชันเมนู)
+// ถ้ากลุ่มนี้ใช้อยู่เมนูเดียวจะแก้ราคากลางตรงๆ ถ้าใช้ร่วมหลายเมนูจะบันทึกเป็นราคาเฉพาะเมนูแทน
+func setMenuOptionPrice(tx *gorm.DB, menuItemID, optionID uint, price float64) error {
+	var option models.MenuOption
+	if err := tx.First(&option, optionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	var links int64
+	if err := tx.Table("menu_item_option_groups").Where("option_group_id = ?", option.GroupID).Count(&links).Error; err != nil {
+		return err
+	}
+
+	if links <= 1 {
+		if err := tx.Where("menu_item_id = ? AND menu_option_id = ?", menuItemID, optionID).
+			Delete(&models.MenuItemOptionPrice{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&option).Update("price", price).Error
+	}
+
+	if price == option.Price {
+		return tx.Where("menu_item_id = ? AND menu_option_id = ?", menuItemID, optionID).
+			Delete(&models.MenuItemOptionPrice{}).Error
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "menu_item_id"}, {Name: "menu_option_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"price", "updated_at"}),
+	}).Create(&models.MenuItemOptionPrice{MenuItemID: menuItemID, MenuOptionID: optionID, Price: price}).Error
+}
+
+// unlinkOptionGroup เอากลุ่มออกจากเมนูพร้อมราคาเฉพาะเมนูของกลุ่มนั้น (รวมกลุ่มย่อยที่ผูกกับเมนูนี้)
+// กลุ่มที่เมนูนี้สร้างไว้จะกลายเป็นกลุ่มในคลัง หรือถ้า deleteOrphan และไม่มีเมนูอื่นใช้แล้วจะถูก soft delete
+func unlinkOptionGroup(tx *gorm.DB, menuItemID uint, group models.OptionGroup, deleteOrphan bool) error {
+	children, err := childOptionGroups(tx, group.ID)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		linked, err := menuItemHasOptionGroup(tx, menuItemID, child.ID)
+		if err != nil {
+			return err
+		}
+		if linked {
+			if err := unlinkOptionGroup(tx, menuItemID, child, deleteOrphan); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Exec("DELETE FROM menu_item_option_groups WHERE menu_item_id = ? AND option_group_id = ?", menuItemID, group.ID).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("menu_item_id = ? AND menu_option_id IN (?)", menuItemID,
+		tx.Model(&models.MenuOption{}).Unscoped().Select("id").Where("group_id = ?", group.ID)).
+		Delete(&models.MenuItemOptionPrice{}).Error; err != nil {
+		return err
+	}
+
+	if group.MenuItemID == nil || *group.MenuItemID != menuItemID {
+		return nil
+	}
+
+	var links int64
+	if err := tx.Table("menu_item_option_groups").Where("option_group_id = ?", group.ID).Count(&links).Error; err != nil {
+		return err
+	}
+	if deleteOrphan && links == 0 {
+		if err := tx.Where("group_id = ?", group.ID).Delete(&models.MenuOption{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&group).Error
+	}
+	return tx.Model(&group).Update("menu_item_id", nil).Error
+}
+
+// childOptionGroups ดึงกลุ่มย่อยที่เปิดจากตัวเลือกในกลุ่มนี้
+func childOptionGroups(tx *gorm.DB, groupID uint) ([]models.OptionGroup, error) {
+	var children []models.OptionGroup
+	err := tx.Where("parent_option_id IN (?)", tx.Model(&models.MenuOption{}).Select("id").Where("group_id = ?", groupID)).
+		Order("id").Find(&children).Error
+	return children, err
+}
+
+// linkChildOptionGroups ผูกกลุ่มย่อยทุกชั้นของกลุ่มนี้กับเมนูด้วย
+func linkChildOptionGroups(tx *gorm.DB, menuItemID, groupID uint) error {
+	children, err := childOptionGroups(tx, groupID)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Table("menu_item_option_groups").
+			Create(map[string]interface{}{"menu_item_id": menuItemID, "option_group_id": child.ID}).Error; err != nil {
+			return err
+		}
+		if err := linkChildOptionGroups(tx, menuItemID, child.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateParentOption ตรวจตัวเลือกแม่ของกลุ่มย่อย ถ้าระบุเมนู กลุ่มของตัวเลือกแม่ต้องผูกกับเมนูนั้นอยู่แล้ว
+func validateParentOption(tx *gorm.DB, menuItemID *uint, parentOptionID uint) error {
+	var parent models.MenuOption
+	if err := tx.First(&parent, parentOptionID).Error; err != nil {
+		return fmt.Errorf("parent option ID %d not found", parentOptionID)
+	}
+	if menuItemID == nil {
+		return nil
+	}
+	linked, err := menuItemHasOptionGroup(tx, *menuItemID, parent.GroupID)
+	if err != nil {
+		return err
+	}
+	if !linked {
+		return fmt.Errorf("parent option ID %d is not in this menu", parentOptionID)
+	}
+	return nil
+}
+
+// createOptionGroupTree สร้างกลุ่มพร้อมตัวเลือกและกลุ่มย่อยของแต่ละตัวเลือก (child_groups) ทุกชั้น
+// กลุ่มย่อยที่ MaxSelections เป็น 0 จะถูกข้ามเหมือนตอนสร้างเมนู
+func createOptionGroupTree(tx *gorm.DB, menuItemID, parentOptionID *uint, req models.OptionGroupRequest) (models.OptionGroup, error) {
+	group := models.OptionGroup{
+		MenuItemID:     menuItemID,
+		ParentOptionID: parentOptionID,
+		Name:           req.Name,
+		NameEn:         req.NameEn,
+		NameCh:         req.NameCh,
+		MaxSelections:  req.MaxSelections,
+		IsRequired:     req.IsRequired,
+	}
+	if err := tx.Create(&group).Error; err != nil {
+		return group, err
+	}
+
+	for _, opt := range req.Options {
+		option := models.MenuOption{
+			GroupID: group.ID,
+			Name:    opt.Name,
+			NameEn:  opt.NameEn,
+			NameCh:  opt.NameCh,
+			Price:   opt.Price,
+		}
+		if err := tx.Create(&option).Error; err != nil {
+			return group, err
+		}
+		for _, child := range opt.ChildGroups {
+			if child.MaxSelections <= 0 {
+				continue
+			}
+			if _, err := createOptionGroupTree(tx, menuItemID, &option.ID, child); err != nil {
+				return group, err
+			}
+		}
+		group.Options = append(group.Options, option)
+	}
+	return group, nil
+}
+
+// validateOrderItemOptions ตรวจตัวเลือกที่สั่งกับเมนูหนึ่งรายการ
+// กลุ่มหลักตรวจเสมอ ส่วนกลุ่มย่อยตรวจว่าบังคับเลือก/เลือกเกินเฉพาะเมื่อเลือกตัวเลือกแม่ของกลุ่มนั้น
+// และเลือกตัวเลือกในกลุ่มย่อยโดยไม่ได้เลือกตัวเลือกแม่ไม่ได้
+func validateOrderItemOptions(tx *gorm.DB, menuItemID uint, optionIDs []uint) error {
+	var groups []models.OptionGroup
+	if err := tx.Joins("JOIN menu_item_option_groups ON menu_item_option_groups.option_group_id = option_groups.id").
+		Where("menu_item_option_groups.menu_item_id = ?", menuItemID).
+		Preload("Options").Order("option_groups.id").Find(&groups).Error; err != nil {
+		return err
+	}
+
+	groupOfOption := map[uint]uint{}
+	for _, group := range groups {
+		for _, option := range group.Options {
+			groupOfOption[option.ID] = group.ID
+		}
+	}
+
+	selected := map[uint]bool{}
+	counts := map[uint]int{}
+	for _, optionID := range optionIDs {
+		groupID, ok := groupOfOption[optionID]
+		if !ok {
+			return fmt.Errorf("menu option ID %d is not available for menu item ID %d", optionID, menuItemID)
+		}
+		selected[optionID] = true
+		counts[groupID]++
+	}
+
+	for _, group := range groups {
+		if group.ParentOptionID != nil && !selected[*group.ParentOptionID] {
+			if counts[group.ID] > 0 {
+				return fmt.Errorf("option group %q requires its parent option to be selected", group.Name)
+			}
+			continue
+		}
+		if group.IsRequired && counts[group.ID] == 0 {
+			return fmt.Errorf("option group %q is required for menu item ID %d", group.Name, menuItemID)
+		}
+		if group.MaxSelections > 0 && counts[group.ID] > group.MaxSelections {
+			return fmt.Errorf("option group %q allows at most %d selections", group.Name, group.MaxSelections)
+		}
+	}
+	return nil
+}
+
+// @Summary ดูคลังกลุ่มตัวเลือก
+// @Description ดึงกลุ่มตัวเลือกทั้งหมดพร้อม ID ของเมนูที่ใช้กลุ่มนั้น แก้ไขกลุ่มหรือราคาตัวเลือกครั้งเดียวมีผลทุกเมนู
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} ModifierGroupResponse "รายการกลุ่มตัวเลือก"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
+// @Router /api/menu/modifier-groups [get]
+// @Tags menu-modifiers
+func GetModifierGroups(c *fiber.Ctx) error {
+	var groups []models.OptionGroup
+	if err := db.DB.Preload("Options").Order("id").Find(&groups).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch option groups",
+		})
+	}
+
+	var links []struct {
+		MenuItemID    uint
+		OptionGroupID uint
+	}
+	if err := db.DB.Table("menu_item_option_groups").
+		Joins("JOIN menu_items ON menu_items.id = menu_item_option_groups.menu_item_id AND menu_items.deleted_at IS NULL").
+		Select("menu_item_option_groups.menu_item_id, menu_item_option_groups.option_group_id").
+		Order("menu_item_option_groups.menu_item_id").
+		Scan(&links).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch option group usage",
+		})
+	}
+	usage := map[uint][]uint{}
+	for _, link := range links {
+		usage[link.OptionGroupID] = append(usage[link.OptionGroupID], link.MenuItemID)
+	}
+
+	response := make([]ModifierGroupResponse, 0, len(groups))
+	for _, group := range groups {
+		menuItemIDs := usage[group.ID]
+		if menuItemIDs == nil {
+			menuItemIDs = []uint{}
+		}
+		response = append(response, ModifierGroupResponse{OptionGroup: group, MenuItemIDs: menuItemIDs})
+	}
+	return c.JSON(response)
+}
+
+// @Summary สร้างกลุ่มตัวเลือกในคลัง
+// @Description สร้างกลุ่มตัวเลือกที่ยังไม่ผูกกับเมนูใด แล้วนำไปผูกกับหลายเมนูได้ผ่าน PUT /api/menu/{id}/option-groups/{group_id}
+// @Description ใส่ child_groups ในตัวเลือกเพื่อสร้างกลุ่มย่อย หรือ parent_option_id เพื่อสร้างเป็นกลุ่มย่อยของตัวเลือกที่มีอยู่
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.OptionGroupRequest true "ข้อมูลกลุ่มและตัวเลือก"
+// @Success 201 {object} models.OptionGroup "กลุ่มที่สร้าง"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Router /api/menu/modifier-groups [post]
+// @Tags menu-modifiers
+func CreateModifierGroup(c *fiber.Ctx) error {
+	var req models.OptionGroupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+
+	if req.Name == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Option group name is required",
+		})
+	}
+	if req.MaxSelections <= 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "MaxSelections ต้องมากกว่า 0",
+		})
+	}
+
+	if req.ParentOptionID != nil {
+		if err := validateParentOption(db.DB, nil, *req.ParentOptionID); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	var group models.OptionGroup
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		group, err = createOptionGroupTree(tx, nil, req.ParentOptionID, req)
+		return err
+	}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error creating option group",
+		})
+	}
+
+	return c.Status(http.StatusCreated).JSON(group)
+}
+
+// getMenuAndGroupFromParams ดึงเมนูจาก :id และกลุ่มจาก :group_id
+func getMenuAndGroupFromParams(c *fiber.Ctx) (*models.MenuItem, *models.OptionGroup, error) {
+	menuID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return nil, nil, c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid menu ID format",
+		})
+	}
+	groupID, err := strconv.Atoi(c.Params("group_id"))
+	if err != nil {
+		return nil, nil, c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid option group ID format",
+		})
+	}
+
+	var menu models.MenuItem
+	if err := db.DB.First(&menu, menuID).Error; err != nil {
+		return nil, nil, c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Menu not found",
+		})
+	}
+	var group models.OptionGroup
+	if err := db.DB.First(&group, groupID).Error; err != nil {
+		return nil, nil, c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Option group not found",
+		})
+	}
+	return &menu, &group, nil
+}
+
+// loadMenuWithOptions ดึงเมนูพร้อมกลุ่มตัวเลือกและราคาเฉพาะเมนู
+func loadMenuWithOptions(menuID uint) (models.MenuItem, error) {
+	var menu models.MenuItem
+	if err := db.DB.Preload("Category").Preload("OptionGroups.Options").First(&menu, menuID).Error; err != nil {
+		return menu, err
+	}
+	items := []models.MenuItem{menu}
+	if err := applyOptionPriceOverrides(db.DB, items); err != nil {
+		return menu, err
+	}
+	return items[0], nil
+}
+
+// @Summary ผูกกลุ่มตัวเลือกกับเมนู
+// @Description ผูกกลุ่มตัวเลือกที่มีอยู่แล้ว (จากคลังหรือจากเมนูอื่น) กับเมนูนี้ ผูกซ้ำได้โดยไม่มีผล กลุ่มย่อยของกลุ่มนี้จะถูกผูกด้วย
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของเมนู"
+// @Param group_id path integer true "ID ของกลุ่มตัวเลือก"
+// @Success 200 {object} models.MenuItem "เมนูพร้อมกลุ่มตัวเลือก"
+// @Failure 400 {object} map[string]interface{} "กลุ่มของตัวเลือกแม่ยังไม่ได้ผูกกับเมนูนี้"
+// @Failure 404 {object} map[string]interface{} "ไม่พบเมนูหรือกลุ่ม"
+// @Failure 409 {object} map[string]interface{} "เมนูมีกลุ่มชื่อนี้อยู่แล้ว"
+// @Router /api/menu/{id}/option-groups/{group_id} [put]
+// @Tags menu-modifiers
+func LinkOptionGroup(c *fiber.Ctx) error {
+	menu, group, err := getMenuAndGroupFromParams(c)
+	if menu == nil {
+		return err
+	}
+
+	// ชื่อกลุ่มต้องไม่ซ้ำกับกลุ่มอื่นในเมนูเดียวกัน
+	if taken, _ := optionGroupNameTaken(db.DB, []uint{menu.ID}, group.Name, group.ID); taken {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error": "Option group name already exists in this menu",
+		})
+	}
+
+	// กลุ่มย่อยต้องผูกหลังกลุ่มที่มีตัวเลือกแม่
+	if group.ParentOptionID != nil {
+		if err := validateParentOption(db.DB, &menu.ID, *group.ParentOptionID); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(menu).Association("OptionGroups").Append(group); err != nil {
+			return err
+		}
+		return linkChildOptionGroups(tx, menu.ID, group.ID)
+	}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to link option group",
+		})
+	}
+
+	result, err := loadMenuWithOptions(menu.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error loading menu item",
+		})
+	}
+	return c.JSON(result)
+}
+
+// @Summary ยกเลิกการผูกกลุ่มตัวเลือกกับเมนู
+// @Description เอากลุ่มออกจากเมนูนี้ (รวมกลุ่มย่อย) และลบราคาเฉพาะเมนูของกลุ่มนั้น กลุ่มยังอยู่ในคลังและเมนูอื่นยังใช้ได้
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของเมนู"
+// @Param group_id path integer true "ID ของกลุ่มตัวเลือก"
+// @Success 200 {object} models.MenuItem "เมนูพร้อมกลุ่มตัวเลือกที่เหลือ"
+// @Failure 404 {object} map[string]interface{} "ไม่พบเมนูหรือกลุ่ม หรือกลุ่มไม่ได้ผูกกับเมนูนี้"
+// @Router /api/menu/{id}/option-groups/{group_id} [delete]
+// @Tags menu-modifiers
+func UnlinkOptionGroup(c *fiber.Ctx) error {
+	menu, group, err := getMenuAndGroupFromParams(c)
+	if menu == nil {
+		return err
+	}
+
+	linked, err := menuItemHasOptionGroup(db.DB, menu.ID, group.ID)
+	if err != nil || !linked {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Option group is not linked to this menu",
+		})
+	}
+
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		return unlinkOptionGroup(tx, menu.ID, *group, false)
+	}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to unlink option group",
+		})
+	}
+
+	result, err := loadMenuWithOptions(menu.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error loading menu item",
+		})
+	}
+	return c.JSON(result)
+}
+
+// @Summary กำหนดราคาตัวเลือกเฉพาะเมนู
+// @Description ตั้งราคาตัวเลือกสำหรับเมนูนี้โดยไม่กระทบเมนูอื่นที่ใช้กลุ่มเดียวกัน ส่ง price เป็น null เพื่อกลับไปใช้ราคากลาง
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของเมนู"
+// @Param request body models.SetOptionPricesRequest true "ราคาตัวเลือก"
+// @Success 200 {object} models.MenuItem "เมนูพร้อมราคาตัวเลือกที่ใช้จริง (base_price คือราคากลาง)"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้องหรือตัวเลือกไม่ได้อยู่ในเมนูนี้"
+// @Failure 404 {object} map[string]interface{} "ไม่พบเมนู"
+// @Router /api/menu/{id}/option-prices [put]
+// @Tags menu-modifiers
+func SetMenuOptionPrices(c *fiber.Ctx) error {
+	menuID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid menu ID format",
+		})
+	}
+
+	var req models.SetOptionPricesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input format",
+		})
+	}
+
+	var menu models.MenuItem
+	if err := db.DB.First(&menu, menuID).Error; err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Menu not found",
+		})
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		for _, price := range req.Prices {
+			var option models.MenuOption
+			if err := tx.First(&option, price.MenuOptionID).Error; err != nil {
+				return fmt.Errorf("menu option ID %d not found", price.MenuOptionID)
+			}
+			linked, err := menuItemHasOptionGroup(tx, menu.ID, option.GroupID)
+			if err != nil {
+				return err
+			}
+			if !linked {
+				return fmt.Errorf("menu option ID %d is not in this menu", option.ID)
+			}
+
+			if price.Price == nil {
+				if err := tx.Where("menu_item_id = ? AND menu_option_id = ?", menu.ID, option.ID).
+					Delete(&models.MenuItemOptionPrice{}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if *price.Price < 0 {
+				return fmt.Errorf("price of menu option ID %d cannot be negative", option.ID)
+			}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "menu_item_id"}, {Name: "menu_option_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"price", "updated_at"}),
+			}).Create(&models.MenuItemOptionPrice{MenuItemID: menu.ID, MenuOptionID: option.ID, Price: *price.Price}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	result, err := loadMenuWithOptions(menu.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error loading menu item",
+		})
+	}
+	return c.JSON(result)
+}
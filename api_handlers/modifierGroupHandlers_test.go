@@ -0,0 +1,413 @@
+package api_handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"food-ordering-api/api_v2"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestSharedModifierGroups(t *testing.T) {
+	app := fiber.New()
+	setupDietaryTags(t)
+
+	app.Get("/api/menu/ActiveMenu", GetActiveMenu)
+	app.Get("/api/menu/modifier-groups", GetModifierGroups)
+	app.Post("/api/menu/modifier-groups", CreateModifierGroup)
+	app.Put("/api/menu/:id/option-groups/:group_id", LinkOptionGroup)
+	app.Delete("/api/menu/:id/option-groups/:group_id", UnlinkOptionGroup)
+	app.Put("/api/menu/:id/option-prices", SetMenuOptionPrices)
+
+	friedRice := models.MenuItem{Name: "ข้าวผัด", NameEn: "Fried Rice", NameCh: "炒饭", CategoryID: 1, Price: 60}
+	basil := models.MenuItem{Name: "กะเพรา", NameEn: "Basil", NameCh: "罗勒", CategoryID: 1, Price: 70}
+	db.DB.Create(&friedRice)
+	db.DB.Create(&basil)
+
+	findMenu := func(id uint) models.MenuItem {
+		resp := sendJSON(t, app, "GET", "/api/menu/ActiveMenu", nil)
+		var items []models.MenuItem
+		json.NewDecoder(resp.Body).Decode(&items)
+		for _, item := range items {
+			if item.ID == id {
+				return item
+			}
+		}
+		t.Fatalf("menu %d not found", id)
+		return models.MenuItem{}
+	}
+
+	var group models.OptionGroup
+
+	// กรณีทดสอบที่ 1: สร้างกลุ่มในคลังแล้วผูกกับสองเมนู
+	t.Run("Success - Link shared group", func(t *testing.T) {
+		resp := sendJSON(t, app, "POST", "/api/menu/modifier-groups", models.OptionGroupRequest{
+			Name: "ท็อปปิ้ง", NameEn: "Topping", NameCh: "配料", MaxSelections: 2,
+			Options: []models.OptionRequest{
+				{Name: "ไข่ดาว", NameEn: "Fried egg", NameCh: "煎蛋", Price: 10},
+				{Name: "ไข่เจียว", NameEn: "Omelette", NameCh: "煎蛋卷", Price: 15},
+			},
+		})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		json.NewDecoder(resp.Body).Decode(&group)
+
+		for _, menuID := range []uint{friedRice.ID, basil.ID} {
+			resp = sendJSON(t, app, "PUT", fmt.Sprintf("/api/menu/%d/option-groups/%d", menuID, group.ID), nil)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+
+		resp = sendJSON(t, app, "GET", "/api/menu/modifier-groups", nil)
+		var groups []ModifierGroupResponse
+		json.NewDecoder(resp.Body).Decode(&groups)
+		if assert.Equal(t, 1, len(groups)) {
+			assert.Equal(t, []uint{friedRice.ID, basil.ID}, groups[0].MenuItemIDs)
+		}
+	})
+
+	// กรณีทดสอบที่ 2: ผูกกลุ่มเดิมซ้ำไม่มีผล แต่กลุ่มอื่นที่ชื่อซ้ำในเมนูเดียวกันผูกไม่ได้
+	t.Run("Failure - Duplicate group name", func(t *testing.T) {
+		resp := sendJSON(t, app, "PUT", fmt.Sprintf("/api/menu/%d/option-groups/%d", basil.ID, group.ID), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = sendJSON(t, app, "POST", "/api/menu/modifier-groups", models.OptionGroupRequest{
+			Name: "ท็อปปิ้ง", NameEn: "Topping", NameCh: "配料", MaxSelections: 1,
+		})
+		var other models.OptionGroup
+		json.NewDecoder(resp.Body).Decode(&other)
+		resp = sendJSON(t, app, "PUT", fmt.Sprintf("/api/menu/%d/option-groups/%d", basil.ID, other.ID), nil)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 3: ราคาเฉพาะเมนูไม่กระทบเมนูอื่นที่ใช้กลุ่มเดียวกัน
+	t.Run("Success - Per-item price override", func(t *testing.T) {
+		price := 5.0
+		resp := sendJSON(t, app, "PUT", fmt.Sprintf("/api/menu/%d/option-prices", friedRice.ID), models.SetOptionPricesRequest{
+			Prices: []models.OptionPriceOverrideRequest{{MenuOptionID: group.Options[0].ID, Price: &price}},
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		option := findMenu(friedRice.ID).OptionGroups[0].Options[0]
+		assert.Equal(t, 5.0, option.Price)
+		if assert.NotNil(t, option.BasePrice) {
+			assert.Equal(t, 10.0, *option.BasePrice)
+		}
+
+		option = findMenu(basil.ID).OptionGroups[0].Options[0]
+		assert.Equal(t, 10.0, option.Price)
+		assert.Nil(t, option.BasePrice)
+	})
+
+	// กรณีทดสอบที่ 4: เอากลุ่มออกจากเมนูเดียว กลุ่มยังอยู่ในเมนูอื่น
+	t.Run("Success - Unlink group", func(t *testing.T) {
+		resp := sendJSON(t, app, "DELETE", fmt.Sprintf("/api/menu/%d/option-groups/%d", friedRice.ID, group.ID), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Equal(t, 0, len(findMenu(friedRice.ID).OptionGroups))
+		assert.Equal(t, 1, len(findMenu(basil.ID).OptionGroups))
+
+		var overrides int64
+		db.DB.Model(&models.MenuItemOptionPrice{}).Where("menu_item_id = ?", friedRice.ID).Count(&overrides)
+		assert.Equal(t, int64(0), overrides)
+	})
+}
+
+func TestMigrateSharedOptionGroups(t *testing.T) {
+	setupDietaryTags(t)
+	db.DB.AutoMigrate(&models.OrderItemOption{}, &models.MenuVersionOption{})
+
+	noodle := models.MenuItem{Name: "ก๋วยเตี๋ยว", NameEn: "Noodles", NameCh: "粿条", CategoryID: 1, Price: 50}
+	soup := models.MenuItem{Name: "ต้มยำ", NameEn: "Tom Yum", NameCh: "冬阴", CategoryID: 1, Price: 90}
+	db.DB.Create(&noodle)
+	db.DB.Create(&soup)
+
+	// จำลองข้อมูลแบบเก่าที่กลุ่มอ้างถึงเมนูผ่าน menu_item_id อย่างเดียว
+	groups := make([]models.OptionGroup, 0, 2)
+	for _, menuID := range []uint{noodle.ID, soup.ID} {
+		group := models.OptionGroup{
+			MenuItemID: &menuID, Name: "ความเผ็ด", NameEn: "Spiciness", NameCh: "辣度", MaxSelections: 1, IsRequired: true,
+			Options: []models.MenuOption{
+				{Name: "ไม่เผ็ด", NameEn: "Mild", NameCh: "不辣"},
+				{Name: "เผ็ดมาก", NameEn: "Hot", NameCh: "很辣"},
+			},
+		}
+		db.DB.Create(&group)
+		groups = append(groups, group)
+	}
+	db.DB.Exec("DELETE FROM menu_item_option_groups")
+	oldOrderOption := models.OrderItemOption{OrderItemID: 1, MenuOptionID: groups[1].Options[1].ID}
+	db.DB.Create(&oldOrderOption)
+
+	assert.Nil(t, db.MigrateSharedOptionGroups())
+
+	var groupIDs []uint
+	db.DB.Model(&models.OptionGroup{}).Pluck("id", &groupIDs)
+	assert.Equal(t, []uint{groups[0].ID}, groupIDs)
+
+	var links int64
+	db.DB.Table("menu_item_option_groups").Where("option_group_id = ?", groups[0].ID).Count(&links)
+	assert.Equal(t, int64(2), links)
+
+	// ออเดอร์เก่ายังอ้างถึงตัวเลือกเดิม ไม่ถูกแก้ประวัติ
+	db.DB.First(&oldOrderOption, oldOrderOption.ID)
+	assert.Equal(t, groups[1].Options[1].ID, oldOrderOption.MenuOptionID)
+
+	// เรียกซ้ำไม่เปลี่ยนอะไร
+	assert.Nil(t, db.MigrateSharedOptionGroups())
+	db.DB.Table("menu_item_option_groups").Count(&links)
+	assert.Equal(t, int64(2), links)
+}
+
+func TestMigrateNestedOptionGroups(t *testing.T) {
+	setupDietaryTags(t)
+
+	// ก๋วยเตี๋ยวสองเมนูมีกลุ่ม "แบบ" และกลุ่มย่อย "ท็อปปิ้ง" ใต้ตัวเลือก "น้ำ" เหมือนกัน
+	// ข้าวผัดมีกลุ่ม "ท็อปปิ้ง" ระดับบนสุดที่เหมือนกลุ่มย่อยทุกอย่างยกเว้นตัวเลือกแม่
+	type legacyMenu struct {
+		menu         models.MenuItem
+		style, extra models.OptionGroup
+	}
+	newGroup := func(menuID uint, name, nameEn string, option models.MenuOption) models.OptionGroup {
+		group := models.OptionGroup{
+			MenuItemID: &menuID, Name: name, NameEn: nameEn, NameCh: nameEn, MaxSelections: 1,
+			Options: []models.MenuOption{option},
+		}
+		db.DB.Create(&group)
+		return group
+	}
+	noodles := make([]legacyMenu, 0, 2)
+	for _, name := range []string{"ก๋วยเตี๋ยวหมู", "ก๋วยเตี๋ยวไก่"} {
+		item := legacyMenu{menu: models.MenuItem{Name: name, CategoryID: 1, Price: 50}}
+		db.DB.Create(&item.menu)
+		item.style = newGroup(item.menu.ID, "แบบ", "Style", models.MenuOption{Name: "น้ำ", NameEn: "Soup", NameCh: "汤"})
+		item.extra = newGroup(item.menu.ID, "ท็อปปิ้ง", "Topping", models.MenuOption{Name: "ไข่", NameEn: "Egg", NameCh: "蛋", Price: 10})
+		db.DB.Model(&item.extra).Update("parent_option_id", item.style.Options[0].ID)
+		noodles = append(noodles, item)
+	}
+	rice := models.MenuItem{Name: "ข้าวผัด", CategoryID: 1, Price: 60}
+	db.DB.Create(&rice)
+	topLevel := newGroup(rice.ID, "ท็อปปิ้ง", "Topping", models.MenuOption{Name: "ไข่", NameEn: "Egg", NameCh: "蛋", Price: 10})
+	db.DB.Exec("DELETE FROM menu_item_option_groups")
+
+	assert.Nil(t, db.MigrateSharedOptionGroups())
+
+	var groupIDs []uint
+	db.DB.Model(&models.OptionGroup{}).Order("id").Pluck("id", &groupIDs)
+	assert.Equal(t, []uint{noodles[0].style.ID, noodles[0].extra.ID, topLevel.ID}, groupIDs)
+
+	// กลุ่มย่อยยังอยู่ใต้ตัวเลือกแม่ กลุ่มระดับบนสุดไม่ถูกรวมเข้าไป
+	var nested, top models.OptionGroup
+	db.DB.First(&nested, noodles[0].extra.ID)
+	db.DB.First(&top, topLevel.ID)
+	if assert.NotNil(t, nested.ParentOptionID) {
+		assert.Equal(t, noodles[0].style.Options[0].ID, *nested.ParentOptionID)
+	}
+	assert.Nil(t, top.ParentOptionID)
+
+	for groupID, expected := range map[uint]int64{noodles[0].style.ID: 2, noodles[0].extra.ID: 2, topLevel.ID: 1} {
+		var links int64
+		db.DB.Table("menu_item_option_groups").Where("option_group_id = ?", groupID).Count(&links)
+		assert.Equal(t, expected, links, groupID)
+	}
+}
+
+func TestNestedOptionGroups(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+
+	app.Post("/api/menu", CreateMenuItemHandler)
+	app.Post("/api/menu/option-groups", AddMoreGroup)
+
+	reqBody := models.CreateMenuRequest{
+		MenuItem: models.MenuItemRequest{Name: "ก๋วยเตี๋ยว", NameEn: "Noodles", NameCh: "粿条", CategoryID: 1, Price: 50},
+		OptionGroups: []models.OptionGroupRequest{{
+			Name: "แบบ", NameEn: "Style", NameCh: "做法", MaxSelections: 1, IsRequired: true,
+			Options: []models.OptionRequest{
+				{
+					Name: "น้ำ", NameEn: "Soup", NameCh: "汤",
+					ChildGroups: []models.OptionGroupRequest{{
+						Name: "เส้น", NameEn: "Noodle", NameCh: "面", MaxSelections: 1, IsRequired: true,
+						Options: []models.OptionRequest{
+							{Name: "เส้นเล็ก", NameEn: "Thin", NameCh: "细面"},
+							{Name: "บะหมี่", NameEn: "Egg noodle", NameCh: "蛋面", Price: 5},
+						},
+					}},
+				},
+				{Name: "แห้ง", NameEn: "Dry", NameCh: "干"},
+			},
+		}},
+	}
+	jsonBody, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/api/menu", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var menu models.MenuItem
+	db.DB.Preload("OptionGroups", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+		Preload("OptionGroups.Options").Where("name = ?", "ก๋วยเตี๋ยว").First(&menu)
+	assert.Equal(t, 2, len(menu.OptionGroups))
+	style, noodle := menu.OptionGroups[0], menu.OptionGroups[1]
+	soup, dry := style.Options[0], style.Options[1]
+	if assert.NotNil(t, noodle.ParentOptionID) {
+		assert.Equal(t, soup.ID, *noodle.ParentOptionID)
+	}
+
+	// กรณีทดสอบที่ 1: กลุ่มย่อยบังคับเลือกเฉพาะเมื่อเลือกตัวเลือกแม่
+	t.Run("Validate chosen path", func(t *testing.T) {
+		assert.Nil(t, validateOrderItemOptions(db.DB, menu.ID, []uint{dry.ID}))
+		assert.Nil(t, validateOrderItemOptions(db.DB, menu.ID, []uint{soup.ID, noodle.Options[1].ID}))
+
+		assert.NotNil(t, validateOrderItemOptions(db.DB, menu.ID, []uint{soup.ID}))
+		assert.NotNil(t, validateOrderItemOptions(db.DB, menu.ID, []uint{dry.ID, noodle.Options[0].ID}))
+		assert.NotNil(t, validateOrderItemOptions(db.DB, menu.ID, []uint{}))
+		assert.NotNil(t, validateOrderItemOptions(db.DB, menu.ID, []uint{soup.ID, noodle.Options[0].ID, noodle.Options[1].ID}))
+	})
+
+	// กรณีทดสอบที่ 2: ตัวเลือกแม่ต้องอยู่ในเมนูเดียวกัน
+	t.Run("Failure - Parent option from another menu", func(t *testing.T) {
+		other := models.MenuItem{Name: "ข้าวมันไก่", NameEn: "Chicken Rice", NameCh: "海南鸡饭", CategoryID: 1, Price: 60}
+		db.DB.Create(&other)
+
+		body, _ := json.Marshal(models.OptionGroupRequest{
+			Name: "ซอส", NameEn: "Sauce", NameCh: "酱", MaxSelections: 1, ParentOptionID: &soup.ID,
+		})
+		req := httptest.NewRequest("POST", fmt.Sprintf("/api/menu/option-groups?menu_id=%d", other.ID), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 3: ใบสั่งครัวเรียงตัวเลือกของกลุ่มย่อยต่อจากตัวเลือกแม่
+	t.Run("Success - Nested print order", func(t *testing.T) {
+		thin := noodle.Options[0]
+		thin.OptionGroup = noodle
+		soupOption := soup
+		soupOption.OptionGroup = style
+
+		nested := api_v2.NestOrderItemOptions([]models.OrderItemOption{
+			{MenuOptionID: thin.ID, MenuOption: thin},
+			{MenuOptionID: soup.ID, MenuOption: soupOption},
+		})
+		if assert.Equal(t, 2, len(nested)) {
+			assert.Equal(t, soup.ID, nested[0].Option.MenuOptionID)
+			assert.Equal(t, 0, nested[0].Depth)
+			assert.Equal(t, thin.ID, nested[1].Option.MenuOptionID)
+			assert.Equal(t, 1, nested[1].Depth)
+		}
+	})
+}
+
+func TestDeleteSharedOptionGroup(t *testing.T) {
+	app := fiber.New()
+	setupDietaryTags(t)
+
+	app.Post("/api/menu/option-groups", AddMoreGroup)
+	app.Put("/api/menu/option-groups/:id", UpdateOptionGroup)
+	app.Delete("/api/menu/option-groups/:id", SoftDelete_OptionGroup)
+	app.Post("/api/menu/restore/:id", RestoreMenu)
+	app.Post("/api/menu/restore-group/:id", RestoreOptionGroup)
+	app.Put("/api/menu/:id/option-groups/:group_id", LinkOptionGroup)
+
+	linkedGroups := func(menuID uint) []string {
+		var names []string
+		db.DB.Table("option_groups").
+			Joins("JOIN menu_item_option_groups ON menu_item_option_groups.option_group_id = option_groups.id").
+			Where("menu_item_option_groups.menu_item_id = ? AND option_groups.deleted_at IS NULL", menuID).
+			Order("option_groups.id").Pluck("option_groups.name", &names)
+		return names
+	}
+	groupDeleted := func(id uint) bool {
+		var group models.OptionGroup
+		db.DB.Unscoped().First(&group, id)
+		return group.DeletedAt.Valid
+	}
+
+	friedRice := models.MenuItem{Name: "ข้าวผัด", NameEn: "Fried Rice", NameCh: "炒饭", CategoryID: 1, Price: 60}
+	basil := models.MenuItem{Name: "กะเพรา", NameEn: "Basil", NameCh: "罗勒", CategoryID: 1, Price: 70}
+	db.DB.Create(&friedRice)
+	db.DB.Create(&basil)
+
+	// กลุ่ม "ความเผ็ด" สร้างจากข้าวผัดแล้วผูกกับกะเพราด้วย
+	resp := sendJSON(t, app, "POST", fmt.Sprintf("/api/menu/option-groups?menu_id=%d", friedRice.ID), models.OptionGroupRequest{
+		Name: "ความเผ็ด", NameEn: "Spiciness", NameCh: "辣度", MaxSelections: 1,
+		Options: []models.OptionRequest{{Name: "เผ็ดน้อย", NameEn: "Mild", NameCh: "微辣"}},
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var spicy models.OptionGroup
+	db.DB.Where("name = ?", "ความเผ็ด").First(&spicy)
+	resp = sendJSON(t, app, "PUT", fmt.Sprintf("/api/menu/%d/option-groups/%d", basil.ID, spicy.ID), nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// กรณีทดสอบที่ 1: ลบจากเมนูที่สร้างกลุ่ม เมนูอื่นยังใช้กลุ่มเดิมได้
+	t.Run("Success - Delete from one menu keeps other links", func(t *testing.T) {
+		resp := sendJSON(t, app, "DELETE", fmt.Sprintf("/api/menu/option-groups/%d", spicy.ID), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Empty(t, linkedGroups(friedRice.ID))
+		assert.Equal(t, []string{"ความเผ็ด"}, linkedGroups(basil.ID))
+		assert.False(t, groupDeleted(spicy.ID))
+
+		var options int64
+		db.DB.Model(&models.MenuOption{}).Where("group_id = ?", spicy.ID).Count(&options)
+		assert.Equal(t, int64(1), options)
+	})
+
+	// กรณีทดสอบที่ 2: ชื่อซ้ำกับกลุ่มที่ผูกผ่านตาราง menu_item_option_groups อย่างเดียว
+	t.Run("Failure - Duplicate name through shared link", func(t *testing.T) {
+		resp := sendJSON(t, app, "POST", fmt.Sprintf("/api/menu/option-groups?menu_id=%d", basil.ID), models.OptionGroupRequest{
+			Name: "ความเผ็ด", NameEn: "Spiciness", NameCh: "辣度", MaxSelections: 1,
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = sendJSON(t, app, "POST", fmt.Sprintf("/api/menu/option-groups?menu_id=%d", basil.ID), models.OptionGroupRequest{
+			Name: "ขนาด", NameEn: "Size", NameCh: "大小", MaxSelections: 1,
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var size models.OptionGroup
+		db.DB.Where("name = ?", "ขนาด").First(&size)
+
+		resp = sendJSON(t, app, "PUT", fmt.Sprintf("/api/menu/option-groups/%d", size.ID), map[string]interface{}{
+			"name": "ความเผ็ด", "name_en": "Spiciness", "name_ch": "辣度", "MaxSelections": 1,
+		})
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 3: กลุ่มที่ไม่มีเมนูใช้แล้วถูกลบ กู้คืนแล้วกลับไปอยู่ในเมนูที่สร้าง
+	t.Run("Success - Delete orphan and restore", func(t *testing.T) {
+		var size models.OptionGroup
+		db.DB.Where("name = ?", "ขนาด").First(&size)
+		resp := sendJSON(t, app, "DELETE", fmt.Sprintf("/api/menu/option-groups/%d?menu_id=%d", size.ID, basil.ID), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.True(t, groupDeleted(size.ID))
+
+		resp = sendJSON(t, app, "POST", fmt.Sprintf("/api/menu/restore-group/%d", size.ID), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"ความเผ็ด", "ขนาด"}, linkedGroups(basil.ID))
+
+		// กลุ่มในคลังที่ยังมีเมนูใช้ลบไม่ได้ถ้าไม่ระบุเมนู
+		resp = sendJSON(t, app, "DELETE", fmt.Sprintf("/api/menu/option-groups/%d", spicy.ID), nil)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		resp = sendJSON(t, app, "DELETE", fmt.Sprintf("/api/menu/option-groups/%d?menu_id=%d", spicy.ID, friedRice.ID), nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 4: กู้คืนเมนูพร้อมกลุ่มที่ผูกผ่านตาราง menu_item_option_groups
+	t.Run("Success - Restore menu with shared group", func(t *testing.T) {
+		db.DB.Delete(&spicy)
+		db.DB.Delete(&basil)
+
+		resp := sendJSON(t, app, "POST", fmt.Sprintf("/api/menu/restore/%d", basil.ID), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.False(t, groupDeleted(spicy.ID))
+		assert.Equal(t, []string{"ความเผ็ด", "ขนาด"}, linkedGroups(basil.ID))
+	})
+}
@@ -3,8 +3,10 @@ package api_handlers
 import (
 	"bytes"
 	"fmt"
+	"food-ordering-api/api_v2"
 	"food-ordering-api/db"
 	"food-ordering-api/models"
+	service "food-ordering-api/services"
 	"log"
 	"net/http"
 	"strings"
@@ -16,10 +18,11 @@ import (
 
 // สำหรับรับข้อมูลการสั่งอาหาร
 type CreateOrderRequest struct {
-	UUID     string             `json:"uuid" binding:"required"`     // UUID ของ QR Code
-	TableID  uint               `json:"table_id" binding:"required"` // ID ของโต๊ะ
-	Items    []orderItemRequest `json:"items" binding:"required"`    // รายการอาหารที่สั่ง
-	UsePromo []UsePromoRequest  `json:"use_promo,omitempty"`         // โปรโมชั่นที่ใช้ (ถ้ามี)
+	UUID     string              `json:"uuid" binding:"required"`     // token ของ session ใน QR Code
+	TableID  uint                `json:"table_id" binding:"required"` // ID ของโต๊ะ
+	Items    []orderItemRequest  `json:"items" binding:"required"`    // รายการอาหารที่สั่ง
+	UsePromo []UsePromoRequest   `json:"use_promo,omitempty"`         // โปรโมชั่นที่ใช้ (ถ้ามี)
+	Combos   []OrderComboRequest `json:"combos,omitempty"`            // ชุดอาหารที่สั่ง (ถ้ามี)
 }
 
 type orderItemRequest struct {
@@ -38,12 +41,26 @@ type UsePromoRequest struct {
 	MenuItemIDs []uint `json:"menu_item_ids" binding:"required"` // รายการอาหารที่ใช้โปรโมชั่น
 }
 
+type OrderComboRequest struct {
+	ComboID    uint                    `json:"combo_id" binding:"required"`
+	Quantity   int                     `json:"quantity"` // ไม่ส่ง = 1
+	Selections []ComboSelectionRequest `json:"selections" binding:"required"`
+	Notes      string                  `json:"notes,omitempty"`
+}
+
+// ComboSelectionRequest - เมนูที่เลือกในช่องของชุด พร้อมตัวเลือกเสริมของเมนูนั้น
+type ComboSelectionRequest struct {
+	SlotID     uint                     `json:"slot_id" binding:"required"`
+	MenuItemID uint                     `json:"menu_item_id" binding:"required"`
+	Options    []OrderItemOptionRequest `json:"options,omitempty"`
+}
+
 type updateStatusRequest struct {
 	Status string `json:"status" binding:"required,oneof=pending preparing ready served cancelled"`
 }
 
 // @Summary สร้างออเดอร์ใหม่
-// @Description สร้างออเดอร์ใหม่พร้อมรายการอาหารและโปรโมชั่น (ถ้ามี)
+// @Description สร้างออเดอร์ใหม่พร้อมรายการอาหาร โปรโมชั่น และชุดอาหาร (ถ้ามี)
 // @Accept json
 // @Produce json
 // @Param order body CreateOrderRequest true "ข้อมูลออเดอร์"
@@ -65,10 +82,9 @@ func CreateOrder(c *fiber.Ctx) error {
 		}
 	}()
 
-	// 1. ตรวจสอบ QR Code และโต๊ะ
-	var qrCode models.QRCode
-	if err := tx.Where("uuid = ? AND table_id = ? AND is_active = ? AND expiry_at > ?",
-		req.UUID, req.TableID, true, time.Now()).First(&qrCode).Error; err != nil {
+	// 1. ตรวจสอบว่า token ใน QR Code เป็น session ที่เปิดอยู่ของโต๊ะนี้
+	session, err := service.ResolveSessionToken(tx, req.UUID, req.TableID)
+	if err != nil {
 		tx.Rollback()
 		return c.Status(http.StatusForbidden).JSON(fiber.Map{
 			"error": "Invalid or expired QR code",
@@ -77,10 +93,11 @@ func CreateOrder(c *fiber.Ctx) error {
 
 	// 2. สร้าง Order
 	order := models.Order{
-		UUID:    req.UUID,
-		TableID: int(req.TableID),
-		Status:  "pending",
-		Total:   0,
+		UUID:      session.Token,
+		TableID:   int(req.TableID),
+		Status:    "pending",
+		Total:     0,
+		SessionID: &session.ID,
 	}
 
 	if err := tx.Create(&order).Error; err != nil {
@@ -117,6 +134,18 @@ func CreateOrder(c *fiber.Ctx) error {
 			})
 		}
 
+		// ตัวเลือกต้องอยู่ในกลุ่มที่ผูกกับเมนูนี้ และครบตามกลุ่มที่บังคับเลือกตามเส้นทางที่เลือก (รวมกลุ่มย่อย)
+		optionIDs := make([]uint, 0, len(item.Options))
+		for _, opt := range item.Options {
+			optionIDs = append(optionIDs, opt.MenuOptionID)
+		}
+		if err := validateOrderItemOptions(tx, item.MenuItemID, optionIDs); err != nil {
+			tx.Rollback()
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
 		// บันทึก Options
 		for _, opt := range item.Options {
 			var menuOption models.MenuOption
@@ -127,6 +156,16 @@ func CreateOrder(c *fiber.Ctx) error {
 				})
 			}
 
+			// ใช้ราคาเฉพาะเมนูถ้ามี
+			optionPrice, err := menuOptionPrice(tx, item.MenuItemID, menuOption)
+			if err != nil {
+				tx.Rollback()
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to resolve option price",
+				})
+			}
+			menuOption.Price = optionPrice
+
 			orderItemOption := models.OrderItemOption{
 				OrderItemID:  orderItem.ID,
 				MenuOptionID: opt.MenuOptionID,
@@ -267,6 +306,26 @@ func CreateOrder(c *fiber.Ctx) error {
 		}
 	}
 
+	// 4.1 จัดการชุดอาหาร ราคาชุดถูกแบ่งให้แต่ละรายการตามสัดส่วนราคา
+	for _, comboReq := range req.Combos {
+		combo, selections, unitPrice, err := resolveComboOrder(tx, comboReq)
+		if err != nil {
+			tx.Rollback()
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		comboTotal, err := createComboOrderItems(tx, order.ID, comboReq, combo, selections, unitPrice)
+		if err != nil {
+			tx.Rollback()
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to create combo order items",
+			})
+		}
+		totalAmount += comboTotal
+	}
+
 	// 5. อัพเดทยอดรวมของ Order
 	if err := tx.Model(&order).Update("total", totalAmount).Error; err != nil {
 		tx.Rollback()
@@ -279,6 +338,7 @@ func CreateOrder(c *fiber.Ctx) error {
 	var completeOrder models.Order
 	if err := tx.Preload("Items.MenuItem.Category").
 		Preload("Items.Options.MenuOption").
+		Preload("Items.ComboUsage.Combo", models.WithDeletedCombo).
 		First(&completeOrder, order.ID).Error; err != nil {
 		tx.Rollback()
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
@@ -299,18 +359,25 @@ func CreateOrder(c *fiber.Ctx) error {
 		Items      []models.OrderItem
 	})
 
+	categoryTree, err := loadCategoryTree(tx)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch printers for category",
+		})
+	}
+
 	// จัดกลุ่มรายการตามเครื่องพิมพ์
 	for categoryID, items := range categoryItems {
-		// ดึงเครื่องพิมพ์ที่รองรับหมวดหมู่นี้
+		// ดึงเครื่องพิมพ์ที่รองรับหมวดหมู่นี้ (หมวดหมู่ย่อยที่ไม่ได้กำหนดเองใช้ของหมวดหมู่แม่)
 		var categoryPrinters []models.Printer
-		if err := tx.Table("printers").
-			Joins("JOIN printer_categories ON printers.id = printer_categories.printer_id").
-			Where("printer_categories.category_id = ?", categoryID).
-			Find(&categoryPrinters).Error; err != nil {
-			tx.Rollback()
-			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to fetch printers for category",
-			})
+		if printerIDs := categoryTree.printerIDs(categoryID); len(printerIDs) > 0 {
+			if err := tx.Find(&categoryPrinters, printerIDs).Error; err != nil {
+				tx.Rollback()
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to fetch printers for category",
+				})
+			}
 		}
 
 		// ถ้าไม่มีเครื่องพิมพ์สำหรับหมวดหมู่นี้ ใช้เครื่องพิมพ์หลัก
@@ -416,8 +483,13 @@ func createOrderPrintContent(order models.Order, categoryName string, items []mo
 	buf.WriteString(fmt.Sprintf("Category: %s\n", categoryName))
 	buf.WriteString("-------------------------\n")
 
-	// Menu items
-	for _, item := range items {
+	// Menu items (รายการในชุดเดียวกันพิมพ์ติดกันใต้หัวข้อชุด)
+	items = api_v2.GroupKitchenItems(items)
+	for i, item := range items {
+		if header := api_v2.ComboKitchenHeader(items, i); header != "" {
+			buf.WriteString(header + "\n")
+		}
+
 		// Bold for item name
 		buf.Write([]byte{0x1B, 0x45, 0x01}) // Bold on
 		buf.WriteString(fmt.Sprintf("%s", item.MenuItem.Name))
@@ -686,6 +758,7 @@ type OrderItemOptionResponse struct {
 // @Description ดึงรายการออเดอร์ที่ยังไม่เสร็จสิ้น (pending, preparing, ready)
 // @Produce json
 // @Success 200 {array} OrderResponse
+// @Param lang query string false "รหัสภาษาของชื่อเมนู เช่น th, en, zh"
 // @Router /api/orders/active [get]
 // @Tags Order_ใหม่
 func GetActiveOrders(c *fiber.Ctx) error {
@@ -693,7 +766,7 @@ func GetActiveOrders(c *fiber.Ctx) error {
 
 	if err := db.DB.Preload("Items").
 		Preload("Items.MenuItem").
-		Preload("Items.Options").
+		Preload("Items.Options.MenuOption").
 		Where("status IN ?", []string{"pending", "preparing", "ready"}).
 		Find(&orders).Error; err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
@@ -701,6 +774,7 @@ func GetActiveOrders(c *fiber.Ctx) error {
 		})
 	}
 
+	l := newLocalizer(c)
 	var response []OrderResponse
 	for _, order := range orders {
 		orderResp := OrderResponse{
@@ -717,7 +791,7 @@ func GetActiveOrders(c *fiber.Ctx) error {
 				MenuItemID: item.MenuItemID,
 				MenuItem: MenuItemResponseMin{
 					ID:    item.MenuItem.ID,
-					Name:  item.MenuItem.Name,
+					Name:  l.text(models.TranslatableMenuItem, item.MenuItem.ID, "name", item.MenuItem.Name, item.MenuItem.NameEn, item.MenuItem.NameCh),
 					Price: item.MenuItem.Price,
 				},
 				Quantity: item.Quantity,
@@ -730,7 +804,7 @@ func GetActiveOrders(c *fiber.Ctx) error {
 			for _, opt := range item.Options {
 				orderItem.Options = append(orderItem.Options, OrderItemOptionResponse{
 					ID:    opt.ID,
-					Name:  opt.MenuOption.Name,
+					Name:  l.text(models.TranslatableMenuOption, opt.MenuOption.ID, "name", opt.MenuOption.Name, opt.MenuOption.NameEn, opt.MenuOption.NameCh),
 					Price: opt.Price,
 				})
 			}
@@ -749,6 +823,7 @@ func GetActiveOrders(c *fiber.Ctx) error {
 // @Produce json
 // @Param uuid path string true "uuid"
 // @Success 200 {array} OrderResponse
+// @Param lang query string false "รหัสภาษาของชื่อเมนู เช่น th, en, zh"
 // @Router /api/orders/table/{uuid} [get]
 // @Tags Order_ใหม่
 func GetOrdersByid(c *fiber.Ctx) error {
@@ -761,16 +836,24 @@ func GetOrdersByid(c *fiber.Ctx) error {
 		})
 	}
 
+	session, err := service.FindSessionByToken(db.DB, uuid)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Table session not found",
+		})
+	}
+
 	if err := db.DB.Preload("Items").
 		Preload("Items.MenuItem").
-		Preload("Items.Options").
-		Where("status IN ? AND uuid = ?", []string{"pending", "preparing", "ready"}, uuid).
+		Preload("Items.Options.MenuOption").
+		Where("status IN ? AND session_id = ?", []string{"pending", "preparing", "ready"}, session.ID).
 		Find(&orders).Error; err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch active orders",
 		})
 	}
 
+	l := newLocalizer(c)
 	var response []OrderResponse
 	for _, order := range orders {
 		orderResp := OrderResponse{
@@ -787,7 +870,7 @@ func GetOrdersByid(c *fiber.Ctx) error {
 				MenuItemID: item.MenuItemID,
 				MenuItem: MenuItemResponseMin{
 					ID:    item.MenuItem.ID,
-					Name:  item.MenuItem.Name,
+					Name:  l.text(models.TranslatableMenuItem, item.MenuItem.ID, "name", item.MenuItem.Name, item.MenuItem.NameEn, item.MenuItem.NameCh),
 					Price: item.MenuItem.Price,
 				},
 				Quantity: item.Quantity,
@@ -800,7 +883,7 @@ func GetOrdersByid(c *fiber.Ctx) error {
 			for _, opt := range item.Options {
 				orderItem.Options = append(orderItem.Options, OrderItemOptionResponse{
 					ID:    opt.ID,
-					Name:  opt.MenuOption.Name,
+					Name:  l.text(models.TranslatableMenuOption, opt.MenuOption.ID, "name", opt.MenuOption.Name, opt.MenuOption.NameEn, opt.MenuOption.NameCh),
 					Price: opt.Price,
 				})
 			}
@@ -864,8 +947,16 @@ func CancelOrderItem(c *fiber.Ctx) error {
 
 	tx := db.DB.Begin()
 
+	session, err := service.FindSessionByToken(tx, req.OrderUUID)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Order not found",
+		})
+	}
+
 	var order models.Order
-	if err := tx.Where("uuid = ? AND table_id = ?", req.OrderUUID, req.Table_id).
+	if err := tx.Where("session_id = ? AND table_id = ?", session.ID, req.Table_id).
 		Preload("Items.MenuItem").
 		Preload("Items.Options").
 		First(&order).Error; err != nil {
@@ -998,7 +1089,7 @@ func CancelOrderItem(c *fiber.Ctx) error {
 	}
 
 	// อัพเดทยอดรวม
-	if err := updateOrdersTotalByUUIDAndTableID(tx, req.OrderUUID, req.Table_id); err != nil {
+	if err := updateSessionOrdersTotal(tx, session.ID, req.Table_id); err != nil {
 		tx.Rollback()
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update order total",
@@ -1028,13 +1119,27 @@ func CancelOrderItem(c *fiber.Ctx) error {
 
 	// สร้าง print jobs
 	if len(printContents) > 0 { // ตอนนี้จะรวมทั้งรายการปกติและโปรโมชั่น
+		// เครื่องพิมพ์ของหมวดหมู่ของเมนูในออเดอร์ (หมวดหมู่ย่อยที่ไม่ได้กำหนดเองใช้ของหมวดหมู่แม่)
 		var printers []models.Printer
-		err := tx.Distinct("printers.*").
-			Joins("JOIN printer_categories ON printers.id = printer_categories.printer_id").
-			Joins("JOIN order_items ON order_items.order_id = ?", order.ID).
-			Joins("JOIN menu_items ON menu_items.id = order_items.menu_item_id").
-			Where("printer_categories.category_id = menu_items.category_id").
-			Find(&printers).Error
+		var categoryIDs []uint
+		err := tx.Model(&models.MenuItem{}).Distinct("menu_items.category_id").
+			Joins("JOIN order_items ON order_items.menu_item_id = menu_items.id").
+			Where("order_items.order_id = ?", order.ID).
+			Pluck("menu_items.category_id", &categoryIDs).Error
+
+		var tree *categoryTree
+		if err == nil {
+			tree, err = loadCategoryTree(tx)
+		}
+		if err == nil {
+			printerIDs := []uint{}
+			for _, categoryID := range categoryIDs {
+				printerIDs = append(printerIDs, tree.printerIDs(categoryID)...)
+			}
+			if len(printerIDs) > 0 {
+				err = tx.Find(&printers, printerIDs).Error
+			}
+		}
 
 		if err != nil {
 			tx.Rollback()
@@ -1094,10 +1199,10 @@ func getOrderItemIDs(items []struct {
 	}
 	return ids
 }
-func updateOrdersTotalByUUIDAndTableID(tx *gorm.DB, uuid string, tableID uint) error {
-	// ดึงข้อมูล Orders ทั้งหมดที่ตรงกับ UUID และ Table ID
+func updateSessionOrdersTotal(tx *gorm.DB, sessionID uint, tableID uint) error {
+	// ดึงข้อมูล Orders ทั้งหมดของ session ที่สั่งจากโต๊ะนี้
 	var orders []models.Order
-	err := tx.Where("uuid = ? AND table_id = ?", uuid, tableID).
+	err := tx.Where("session_id = ? AND table_id = ?", sessionID, tableID).
 		Preload("Items.MenuItem").
 		Preload("Items.Options").
 		Find(&orders).Error
@@ -1107,7 +1212,7 @@ func updateOrdersTotalByUUIDAndTableID(tx *gorm.DB, uuid string, tableID uint) e
 
 	// ถ้าไม่เจอ orders ใด ๆ
 	if len(orders) == 0 {
-		return fmt.Errorf("no orders found for uuid %s and table_id %d", uuid, tableID)
+		return fmt.Errorf("no orders found for session %d and table_id %d", sessionID, tableID)
 	}
 
 	// คำนวณยอดรวมใหม่สำหรับแต่ละ order
@@ -1222,10 +1327,18 @@ func FinalizeOrderItems(c *fiber.Ctx) error {
 		})
 	}
 
-	// ดึงข้อมูลออเดอร์ทั้งหมดของโต๊ะ (ที่ยังไม่เสร็จสิ้นหรือยกเลิก)
+	// ดึงข้อมูลออเดอร์ทั้งหมดของรอบที่โต๊ะนี้เปิดอยู่ (ที่ยังไม่เสร็จสิ้นหรือยกเลิก)
+	session, err := service.FindOpenSessionForTable(tx, req.TableID)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "ไม่พบออเดอร์ของโต๊ะนี้",
+		})
+	}
+
 	var orders []models.Order
-	if err := tx.Where("table_id = ? AND status NOT IN (?, ?)",
-		req.TableID, "completed", "cancelled").
+	if err := tx.Where("session_id = ? AND status NOT IN (?, ?)",
+		session.ID, "completed", "cancelled").
 		Preload("Items.MenuItem").
 		Preload("Items.Options.MenuOption").
 		Find(&orders).Error; err != nil {
@@ -0,0 +1,434 @@
+package api_handlers
+
+import (
+	"errors"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	utils "food-ordering-api/utility"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// transferResult ผลการย้ายรายการอาหาร
+type transferResult struct {
+	Message       string         `json:"message"`
+	FromSessionID uint           `json:"from_session_id"`
+	ToSessionID   uint           `json:"to_session_id"`
+	Orders        []models.Order `json:"orders"` // ออเดอร์ของโต๊ะปลายทางที่ได้รับรายการ
+}
+
+// checkWholeSetTransfer รายการในโปรโมชั่นหรือชุดอาหารต้องย้ายครบทั้งชุดและครบจำนวน
+// เพราะราคาชุดถูกแบ่งไว้ในแต่ละรายการ ถ้าย้ายบางส่วนยอดของทั้งสองโต๊ะจะไม่ตรงกับราคาชุด
+func checkWholeSetTransfer(tx *gorm.DB, items []models.OrderItem, moveQty map[uint]int) error {
+	promoItems := map[uint]int{}
+	comboItems := map[uint]int{}
+	for _, item := range items {
+		if item.PromotionUsageID == nil && item.ComboUsageID == nil {
+			continue
+		}
+		if moveQty[item.ID] != item.Quantity {
+			return fmt.Errorf("must transfer the full quantity of promotion or combo item ID %d", item.ID)
+		}
+		if item.PromotionUsageID != nil {
+			promoItems[*item.PromotionUsageID]++
+		}
+		if item.ComboUsageID != nil {
+			comboItems[*item.ComboUsageID]++
+		}
+	}
+
+	for usageID, count := range promoItems {
+		var total int64
+		if err := tx.Model(&models.OrderItem{}).
+			Where("promotion_usage_id = ? AND status != ?", usageID, "cancelled").
+			Count(&total).Error; err != nil {
+			return err
+		}
+		if int64(count) < total {
+			return errors.New("must transfer all items in a promotion together")
+		}
+	}
+	for usageID, count := range comboItems {
+		var total int64
+		if err := tx.Model(&models.OrderItem{}).
+			Where("combo_usage_id = ? AND status != ?", usageID, "cancelled").
+			Count(&total).Error; err != nil {
+			return err
+		}
+		if int64(count) < total {
+			return errors.New("must transfer all items in a combo together")
+		}
+	}
+	return nil
+}
+
+// splitOptionQuantity แบ่งจำนวนตัวเลือกเสริมตามสัดส่วนจำนวนที่ย้าย (ปัดเศษ) คืนจำนวนที่ย้าย
+// ส่วนที่เหลือคือ optionQty ลบจำนวนที่ย้ายเสมอ ถ้ามีตั้งแต่ 2 ขึ้นไปทั้งสองฝั่งจะได้อย่างน้อยฝั่งละ 1
+func splitOptionQuantity(optionQty, quantity, itemQty int) int {
+	moved := (optionQty*quantity*2 + itemQty) / (itemQty * 2)
+	if optionQty >= 2 {
+		moved = min(max(moved, 1), optionQty-1)
+	}
+	return moved
+}
+
+// splitOrderItem แยกรายการอาหารบางจำนวนไปไว้ในออเดอร์ปลายทาง ตัวเลือกเสริมถูกแบ่งตามสัดส่วนจำนวน
+// และคงไว้ทั้งสองฝั่ง แม้ฝั่งใดจะได้จำนวน 0
+func splitOrderItem(tx *gorm.DB, item models.OrderItem, quantity int, destOrderID uint) error {
+	moved := models.OrderItem{
+		OrderID:    destOrderID,
+		MenuItemID: item.MenuItemID,
+		Quantity:   quantity,
+		Price:      item.Price,
+		Notes:      item.Notes,
+		Status:     item.Status,
+		ServedAt:   item.ServedAt,
+		CreatedAt:  item.CreatedAt,
+	}
+	if err := tx.Create(&moved).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.OrderItem{}).Where("id = ?", item.ID).
+		Update("quantity", item.Quantity-quantity).Error; err != nil {
+		return err
+	}
+
+	for _, opt := range item.Options {
+		movedQty := splitOptionQuantity(opt.Quantity, quantity, item.Quantity)
+		if err := tx.Create(&models.OrderItemOption{
+			OrderItemID:  moved.ID,
+			MenuOptionID: opt.MenuOptionID,
+			Value:        opt.Value,
+			Quantity:     movedQty,
+			Price:        opt.Price,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.OrderItemOption{}).Where("id = ?", opt.ID).
+			Update("quantity", opt.Quantity-movedQty).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createTransferSlip สร้างใบแจ้งย้ายรายการไปยังเครื่องพิมพ์ของหมวดหมู่ที่เกี่ยวข้อง (ถ้าไม่มีจะใช้เครื่องพิมพ์หลัก)
+func createTransferSlip(tx *gorm.DB, fromTable, toTable models.Table, lines []string, categoryIDs []uint, note string, orderID uint) error {
+	tree, err := loadCategoryTree(tx)
+	if err != nil {
+		return err
+	}
+	printerIDs := []uint{}
+	for _, categoryID := range categoryIDs {
+		printerIDs = append(printerIDs, tree.printerIDs(categoryID)...)
+	}
+
+	var printers []models.Printer
+	if len(printerIDs) > 0 {
+		if err := tx.Find(&printers, printerIDs).Error; err != nil {
+			return err
+		}
+	}
+	if len(printers) == 0 {
+		var mainPrinter models.Printer
+		if err := tx.Where("name = ?", "main").First(&mainPrinter).Error; err != nil {
+			return err
+		}
+		printers = append(printers, mainPrinter)
+	}
+
+	content := fmt.Sprintf("แจ้งย้ายรายการอาหาร\n"+
+		"จากโต๊ะ %s ไปโต๊ะ %s\n"+
+		"เวลา: %s\n"+
+		"--------------------------------\n"+
+		"%s\n",
+		fromTable.Name,
+		toTable.Name,
+		time.Now().Format("02/01/2006 15:04:05"),
+		strings.Join(lines, "\n"))
+	if note != "" {
+		content += fmt.Sprintf("หมายเหตุ: %s\n", note)
+	}
+
+	for _, printer := range printers {
+		printJob := models.PrintJob{
+			PrinterID: printer.ID,
+			OrderID:   &orderID,
+			Content:   []byte(content),
+			Status:    "pending",
+			JobType:   "item_transfer",
+		}
+		if err := tx.Create(&printJob).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// @Summary ย้ายรายการอาหารบางส่วนไปโต๊ะอื่น
+// @Description ย้ายรายการอาหารที่เลือก (หรือบางจำนวน) จากรอบการใช้โต๊ะหนึ่งไปยังโต๊ะที่เปิดอยู่อีกโต๊ะ
+// @Description รายการในโปรโมชั่นหรือชุดอาหารต้องย้ายทั้งชุด ถ้าย้ายรายการที่ยังไม่ยกเลิกของออเดอร์ครบทุกรายการจะย้ายทั้งออเดอร์
+// @Description คำนวณยอดของออเดอร์ทั้งสองฝั่งใหม่และพิมพ์ใบแจ้งย้ายรายการ
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TransferOrderItemsRequest true "รายการที่ต้องการย้าย"
+// @Success 200 {object} transferResult "ย้ายรายการสำเร็จ"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 404 {object} map[string]interface{} "ไม่พบโต๊ะหรือรายการอาหาร"
+// @Failure 422 {object} map[string]interface{} "โต๊ะไม่ได้เปิดอยู่หรือรายการย้ายไม่ได้แล้ว"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการย้ายรายการ"
+// @Router /api/orders/items/transfer [post]
+// @Tags Order_ใหม่
+func TransferOrderItems(c *fiber.Ctx) error {
+	var req models.TransferOrderItemsRequest
+	if err := c.BodyParser(&req); err != nil || len(req.Items) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+	if req.FromTableID == req.ToTableID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Cannot transfer items to the same table",
+		})
+	}
+
+	tx := db.DB.Begin()
+
+	var fromTable, toTable models.Table
+	if err := tx.First(&fromTable, req.FromTableID).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Source table not found"})
+	}
+	if err := tx.First(&toTable, req.ToTableID).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Destination table not found"})
+	}
+
+	fromSession, err := service.FindOpenSessionForTable(tx, fromTable.ID)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Source table has no open session"})
+	}
+	toSession, err := service.FindOpenSessionForTable(tx, toTable.ID)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Destination table has no open session"})
+	}
+
+	// 1. ตรวจสอบรายการที่จะย้าย
+	itemIDs := make([]uint, 0, len(req.Items))
+	for _, reqItem := range req.Items {
+		if slices.Contains(itemIDs, reqItem.OrderItemID) {
+			tx.Rollback()
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("Order item ID %d is listed more than once", reqItem.OrderItemID),
+			})
+		}
+		itemIDs = append(itemIDs, reqItem.OrderItemID)
+	}
+
+	var items []models.OrderItem
+	if err := tx.Preload("Order").Preload("MenuItem").Preload("Options").
+		Where("id IN ?", itemIDs).Order("id").Find(&items).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch order items"})
+	}
+	if len(items) != len(itemIDs) {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Some order items not found"})
+	}
+
+	requested := make(map[uint]int, len(req.Items))
+	for _, reqItem := range req.Items {
+		requested[reqItem.OrderItemID] = reqItem.Quantity
+	}
+
+	moveQty := make(map[uint]int, len(items))
+	for _, item := range items {
+		order := item.Order
+		if order.SessionID == nil || *order.SessionID != fromSession.ID || uint(order.TableID) != fromTable.ID {
+			tx.Rollback()
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("Order item ID %d does not belong to the source table", item.ID),
+			})
+		}
+		if item.Status == "cancelled" || order.ReceiptID != nil || order.Status == "completed" || order.Status == "cancelled" {
+			tx.Rollback()
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error": fmt.Sprintf("Order item ID %d can no longer be transferred", item.ID),
+			})
+		}
+
+		quantity := requested[item.ID]
+		if quantity == 0 {
+			quantity = item.Quantity
+		}
+		if quantity < 0 || quantity > item.Quantity {
+			tx.Rollback()
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("Transfer quantity exceeds ordered quantity for item ID %d", item.ID),
+			})
+		}
+		moveQty[item.ID] = quantity
+	}
+
+	if err := checkWholeSetTransfer(tx, items, moveQty); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// 2. ย้ายรายการทีละออเดอร์ต้นทาง
+	var sourceOrderIDs []uint
+	bySource := map[uint][]models.OrderItem{}
+	for _, item := range items {
+		if _, ok := bySource[item.OrderID]; !ok {
+			sourceOrderIDs = append(sourceOrderIDs, item.OrderID)
+		}
+		bySource[item.OrderID] = append(bySource[item.OrderID], item)
+	}
+
+	var destOrderIDs []uint
+	var slipLines []string
+	var categoryIDs []uint
+	for _, orderID := range sourceOrderIDs {
+		group := bySource[orderID]
+		source := group[0].Order
+
+		for _, item := range group {
+			slipLines = append(slipLines, fmt.Sprintf("%s x%d", item.MenuItem.Name, moveQty[item.ID]))
+			categoryIDs = append(categoryIDs, item.MenuItem.CategoryID)
+		}
+
+		// ย้ายรายการที่ยังไม่ยกเลิกครบทุกรายการและครบจำนวน ย้ายทั้งออเดอร์ (รวมโปรโมชั่นและชุดอาหารที่ผูกอยู่)
+		var activeCount int64
+		if err := tx.Model(&models.OrderItem{}).
+			Where("order_id = ? AND status != ?", orderID, "cancelled").
+			Count(&activeCount).Error; err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to check order items"})
+		}
+		whole := int(activeCount) == len(group)
+		for _, item := range group {
+			whole = whole && moveQty[item.ID] == item.Quantity
+		}
+		if whole {
+			if err := tx.Model(&models.Order{}).Where("id = ?", orderID).Updates(map[string]interface{}{
+				"uuid":       toSession.Token,
+				"table_id":   int(toTable.ID),
+				"session_id": toSession.ID,
+			}).Error; err != nil {
+				tx.Rollback()
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to transfer order"})
+			}
+			destOrderIDs = append(destOrderIDs, orderID)
+			continue
+		}
+
+		dest := models.Order{
+			UUID:      toSession.Token,
+			TableID:   int(toTable.ID),
+			Status:    source.Status,
+			SessionID: &toSession.ID,
+		}
+		if err := tx.Create(&dest).Error; err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create destination order"})
+		}
+		destOrderIDs = append(destOrderIDs, dest.ID)
+
+		promoUsageIDs := []uint{}
+		comboUsageIDs := []uint{}
+		for _, item := range group {
+			var err error
+			if moveQty[item.ID] == item.Quantity {
+				err = tx.Model(&models.OrderItem{}).Where("id = ?", item.ID).Update("order_id", dest.ID).Error
+			} else {
+				err = splitOrderItem(tx, item, moveQty[item.ID], dest.ID)
+			}
+			if err != nil {
+				tx.Rollback()
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": fmt.Sprintf("Failed to transfer order item ID %d", item.ID),
+				})
+			}
+			if item.PromotionUsageID != nil {
+				promoUsageIDs = append(promoUsageIDs, *item.PromotionUsageID)
+			}
+			if item.ComboUsageID != nil {
+				comboUsageIDs = append(comboUsageIDs, *item.ComboUsageID)
+			}
+		}
+
+		// โปรโมชั่นและชุดอาหารผูกกับออเดอร์ ต้องย้ายตามรายการไปด้วย
+		if len(promoUsageIDs) > 0 {
+			if err := tx.Model(&models.PromotionUsage{}).Where("id IN ?", promoUsageIDs).
+				Update("order_id", dest.ID).Error; err != nil {
+				tx.Rollback()
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to transfer promotion usage"})
+			}
+		}
+		if len(comboUsageIDs) > 0 {
+			if err := tx.Model(&models.ComboUsage{}).Where("id IN ?", comboUsageIDs).
+				Update("order_id", dest.ID).Error; err != nil {
+				tx.Rollback()
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to transfer combo usage"})
+			}
+		}
+
+		if err := updateOrderTotal(tx, orderID); err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update order total"})
+		}
+		if err := updateOrderTotal(tx, dest.ID); err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update order total"})
+		}
+	}
+
+	// 3. พิมพ์ใบแจ้งย้ายรายการ
+	if err := createTransferSlip(tx, fromTable, toTable, slipLines, categoryIDs, req.Note, destOrderIDs[0]); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create transfer slip"})
+	}
+
+	// 4. บันทึกประวัติของทั้งสองโต๊ะ
+	staffID := utils.GetStaffID(c)
+	record := func(table models.Table, relatedTableID, sessionID uint) error {
+		return service.RecordTableHistory(tx, service.NewTableHistory(models.TableActionTransfer, table, table,
+			models.TableHistory{
+				RelatedTableID: &relatedTableID,
+				SessionID:      &sessionID,
+				OrderIDs:       destOrderIDs,
+				StaffID:        staffID,
+				Note:           strings.Join(slipLines, ", "),
+			}))
+	}
+	if err := record(fromTable, toTable.ID, fromSession.ID); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record table history"})
+	}
+	if err := record(toTable, fromTable.ID, toSession.ID); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record table history"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit changes"})
+	}
+
+	var orders []models.Order
+	db.DB.Preload("Items.MenuItem").Preload("Items.Options").Where("id IN ?", destOrderIDs).Order("id").Find(&orders)
+	return c.JSON(transferResult{
+		Message:       "Items transferred successfully",
+		FromSessionID: fromSession.ID,
+		ToSessionID:   toSession.ID,
+		Orders:        orders,
+	})
+}
@@ -0,0 +1,176 @@
+package api_handlers
+
+import (
+	"encoding/json"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransferOrderItems(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.Table{}, &models.TableSession{}, &models.QRCode{}, &models.Order{},
+		&models.OrderItem{}, &models.OrderItemOption{}, &models.PromotionUsage{}, &models.ComboUsage{},
+		&models.Printer{}, &models.PrintJob{}, &models.TableHistory{})
+
+	app.Post("/api/orders/items/transfer", TransferOrderItems)
+
+	orderTotal := func(orderID uint) float64 {
+		var order models.Order
+		db.DB.First(&order, orderID)
+		return order.Total
+	}
+
+	db.DB.Create(&models.Printer{Name: "main", Type: "network"})
+	beer := models.MenuItem{Name: "เบียร์", CategoryID: 1, Price: 100}
+	rice := models.MenuItem{Name: "ข้าวผัด", CategoryID: 1, Price: 60}
+	db.DB.Create(&beer)
+	db.DB.Create(&rice)
+
+	bar := models.Table{Name: "BAR1", Status: "occupied"}
+	dining := models.Table{Name: "D1", Status: "occupied"}
+	empty := models.Table{Name: "D2", Status: "available"}
+	for _, table := range []*models.Table{&bar, &dining, &empty} {
+		db.DB.Create(table)
+	}
+	barSession, err := service.OpenTableSession(db.DB, bar, 2, nil, nil)
+	assert.Nil(t, err)
+	diningSession, err := service.OpenTableSession(db.DB, dining, 4, nil, nil)
+	assert.Nil(t, err)
+
+	// ออเดอร์ของโต๊ะบาร์: เบียร์ 3 ขวด (มีตัวเลือกเสริม) ข้าวผัด 1 จาน และโปรโมชั่น 2 รายการ
+	order := models.Order{UUID: barSession.Token, TableID: int(bar.ID), Status: "pending", SessionID: &barSession.ID}
+	db.DB.Create(&order)
+	beerItem := models.OrderItem{OrderID: order.ID, MenuItemID: beer.ID, Quantity: 3, Price: 100, Status: "served"}
+	riceItem := models.OrderItem{OrderID: order.ID, MenuItemID: rice.ID, Quantity: 1, Price: 60, Status: "pending"}
+	db.DB.Create(&beerItem)
+	db.DB.Create(&riceItem)
+	db.DB.Create(&models.OrderItemOption{OrderItemID: beerItem.ID, MenuOptionID: 1, Value: "เย็น", Quantity: 3, Price: 10})
+	db.DB.Create(&models.OrderItemOption{OrderItemID: beerItem.ID, MenuOptionID: 2, Value: "ไม่ใส่น้ำแข็ง", Quantity: 0, Price: 0})
+	usage := models.PromotionUsage{PromotionID: 1, OrderID: order.ID}
+	db.DB.Create(&usage)
+	promoA := models.OrderItem{OrderID: order.ID, MenuItemID: beer.ID, Quantity: 1, Price: 50, Status: "pending", PromotionUsageID: &usage.ID}
+	promoB := models.OrderItem{OrderID: order.ID, MenuItemID: rice.ID, Quantity: 1, Price: 50, Status: "pending", PromotionUsageID: &usage.ID}
+	db.DB.Create(&promoA)
+	db.DB.Create(&promoB)
+	assert.Nil(t, updateOrderTotal(db.DB, order.ID))
+	assert.Equal(t, 490.0, orderTotal(order.ID))
+
+	// กรณีทดสอบที่ 1: ย้ายเบียร์ 2 ใน 3 ขวด แยกรายการและตัวเลือกเสริมตามจำนวน คำนวณยอดทั้งสองฝั่งใหม่
+	t.Run("Success - Transfer partial quantity", func(t *testing.T) {
+		resp := sendJSON(t, app, "POST", "/api/orders/items/transfer", models.TransferOrderItemsRequest{
+			FromTableID: bar.ID,
+			ToTableID:   dining.ID,
+			Items:       []models.TransferOrderItem{{OrderItemID: beerItem.ID, Quantity: 2}},
+			Note:        "ลูกค้าย้ายไปนั่งโต๊ะ",
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var result transferResult
+		json.NewDecoder(resp.Body).Decode(&result)
+		assert.Len(t, result.Orders, 1)
+		dest := result.Orders[0]
+		assert.Equal(t, int(dining.ID), dest.TableID)
+		assert.Equal(t, diningSession.ID, *dest.SessionID)
+		assert.Len(t, dest.Items, 1)
+		assert.Equal(t, 2, dest.Items[0].Quantity)
+		assert.Equal(t, "served", dest.Items[0].Status)
+		assert.Len(t, dest.Items[0].Options, 2)
+		assert.Equal(t, 2, dest.Items[0].Options[0].Quantity)
+		assert.Equal(t, 220.0, dest.Total)
+
+		var source models.OrderItem
+		db.DB.Preload("Options").First(&source, beerItem.ID)
+		assert.Equal(t, 1, source.Quantity)
+		assert.Len(t, source.Options, 2)
+		assert.Equal(t, 1, source.Options[0].Quantity)
+
+		// จำนวนตัวเลือกเสริมสองฝั่งรวมกันเท่าเดิมเสมอ
+		assert.Equal(t, 1, splitOptionQuantity(1, 2, 3))
+		assert.Equal(t, 0, splitOptionQuantity(1, 1, 3))
+		assert.Equal(t, 1, splitOptionQuantity(2, 1, 10))
+		assert.Equal(t, 6, splitOptionQuantity(7, 9, 10))
+		assert.Equal(t, 270.0, orderTotal(order.ID))
+
+		var slips int64
+		db.DB.Model(&models.PrintJob{}).Where("job_type = ?", "item_transfer").Count(&slips)
+		assert.Equal(t, int64(1), slips)
+
+		var history []models.TableHistory
+		db.DB.Where("action = ?", models.TableActionTransfer).Find(&history)
+		assert.Len(t, history, 2)
+		assert.Equal(t, []uint{dest.ID}, history[0].OrderIDs)
+	})
+
+	// กรณีทดสอบที่ 2: ย้ายโปรโมชั่นไม่ครบชุด หรือเกินจำนวน หรือไปโต๊ะที่ไม่ได้เปิด ไม่ได้
+	t.Run("Failure - Invalid transfers", func(t *testing.T) {
+		resp := sendJSON(t, app, "POST", "/api/orders/items/transfer", models.TransferOrderItemsRequest{
+			FromTableID: bar.ID, ToTableID: dining.ID,
+			Items: []models.TransferOrderItem{{OrderItemID: promoA.ID}},
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = sendJSON(t, app, "POST", "/api/orders/items/transfer", models.TransferOrderItemsRequest{
+			FromTableID: bar.ID, ToTableID: dining.ID,
+			Items: []models.TransferOrderItem{{OrderItemID: riceItem.ID, Quantity: 2}},
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = sendJSON(t, app, "POST", "/api/orders/items/transfer", models.TransferOrderItemsRequest{
+			FromTableID: dining.ID, ToTableID: bar.ID,
+			Items: []models.TransferOrderItem{{OrderItemID: riceItem.ID}},
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = sendJSON(t, app, "POST", "/api/orders/items/transfer", models.TransferOrderItemsRequest{
+			FromTableID: bar.ID, ToTableID: empty.ID,
+			Items: []models.TransferOrderItem{{OrderItemID: riceItem.ID}},
+		})
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+		assert.Equal(t, 270.0, orderTotal(order.ID))
+	})
+
+	// กรณีทดสอบที่ 3: ย้ายโปรโมชั่นทั้งชุด การใช้โปรโมชั่นย้ายตามไปที่ออเดอร์ปลายทาง
+	t.Run("Success - Transfer whole promotion", func(t *testing.T) {
+		resp := sendJSON(t, app, "POST", "/api/orders/items/transfer", models.TransferOrderItemsRequest{
+			FromTableID: bar.ID, ToTableID: dining.ID,
+			Items: []models.TransferOrderItem{{OrderItemID: promoA.ID}, {OrderItemID: promoB.ID}},
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var result transferResult
+		json.NewDecoder(resp.Body).Decode(&result)
+		dest := result.Orders[0]
+		assert.Equal(t, 100.0, dest.Total)
+
+		var movedUsage models.PromotionUsage
+		db.DB.First(&movedUsage, usage.ID)
+		assert.Equal(t, dest.ID, movedUsage.OrderID)
+		assert.Equal(t, 170.0, orderTotal(order.ID))
+	})
+
+	// กรณีทดสอบที่ 4: ย้ายรายการที่เหลือครบทั้งออเดอร์ ย้ายทั้งออเดอร์ไม่สร้างออเดอร์ใหม่
+	t.Run("Success - Transfer remaining items moves the whole order", func(t *testing.T) {
+		resp := sendJSON(t, app, "POST", "/api/orders/items/transfer", models.TransferOrderItemsRequest{
+			FromTableID: bar.ID, ToTableID: dining.ID,
+			Items: []models.TransferOrderItem{{OrderItemID: beerItem.ID}, {OrderItemID: riceItem.ID}},
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var moved models.Order
+		db.DB.First(&moved, order.ID)
+		assert.Equal(t, int(dining.ID), moved.TableID)
+		assert.Equal(t, diningSession.Token, moved.UUID)
+		assert.Equal(t, 170.0, moved.Total)
+
+		var left int64
+		db.DB.Model(&models.Order{}).Where("session_id = ?", barSession.ID).Count(&left)
+		assert.Equal(t, int64(0), left)
+	})
+}
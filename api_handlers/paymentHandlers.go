@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"food-ordering-api/db"
 	"food-ordering-api/models"
+	service "food-ordering-api/services"
 	"net/http"
 	"strconv"
 	"time"
@@ -54,13 +55,12 @@ func ProcessPayment(c *fiber.Ctx) error {
 
 	tx := db.DB.Begin()
 
-	// 1. ตรวจสอบ QR Code
-	var qrCode models.QRCode
-	if err := tx.Where("uuid = ? AND table_id = ? AND is_active = ? AND expiry_at > ?",
-		req.UUID, req.TableID, true, time.Now()).First(&qrCode).Error; err != nil {
+	// 1. ตรวจสอบว่า token เป็น session ที่เปิดอยู่ของโต๊ะนี้ (ชำระเงินได้แม้ QR Code หมดอายุสำหรับการสั่งอาหารแล้ว)
+	session, err := service.FindOpenSession(tx, req.UUID, req.TableID)
+	if err != nil {
 		tx.Rollback()
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Invalid or expired QR code",
+			"error": "Invalid or closed table session",
 		})
 	}
 
@@ -77,13 +77,13 @@ func ProcessPayment(c *fiber.Ctx) error {
 	// 	})
 	// }
 
-	// 2. ดึงทุก orders ที่ยังไม่ได้ชำระเงิน (ยกเว้นที่ถูกยกเลิก)
+	// 2. ดึงทุก orders ของ session ที่ยังไม่ได้ชำระเงิน (ยกเว้นที่ถูกยกเลิก) รวมออเดอร์จากทุกโต๊ะใน session
 	var orders []models.Order
 	if err := tx.Preload("Items", "status != ?", "cancelled").
 		Preload("Items.MenuItem").
 		Preload("Items.Options.MenuOption").
-		Where("uuid = ? AND table_id = ? AND status NOT IN (?, ?) AND receipt_id IS NULL",
-			req.UUID, req.TableID, "completed", "cancelled").
+		Where("session_id = ? AND status NOT IN (?, ?) AND receipt_id IS NULL",
+			session.ID, "completed", "cancelled").
 		Find(&orders).Error; err != nil {
 		tx.Rollback()
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -106,9 +106,11 @@ func ProcessPayment(c *fiber.Ctx) error {
 
 	// 4. สร้างใบเสร็จ
 	receipt := models.Receipt{
-		UUID:     req.UUID,
-		TableID:  strconv.Itoa(int(req.TableID)),
-		SubTotal: subTotal,
+		UUID:       session.Token,
+		SessionID:  &session.ID,
+		GuestCount: session.GuestCount,
+		TableID:    strconv.Itoa(int(req.TableID)),
+		SubTotal:   subTotal,
 		// ServiceCharge: (subTotal * req.ServiceCharge) / 100, // Calculate as percentage
 		ServiceCharge: (subTotal * 0.07), // เปลี่ยนจาก ServiceCharge เป็น VAT 7%
 		PaymentMethod: req.PaymentMethod,
@@ -230,23 +232,18 @@ func ProcessPayment(c *fiber.Ctx) error {
 		}
 	}
 
-	// อัพเดทสถานะโต๊ะเป็น available หลังจากชำระเงิน
-	if err := tx.Model(&models.Table{}).
-		Where("id = ?", req.TableID).
-		Update("status", "available").Error; err != nil {
+	// ปิด session: QR Code ใช้ไม่ได้อีก และทุกโต๊ะใน session กลับเป็น available
+	tableIDs, err := service.SessionTableIDs(tx, session.ID)
+	if err != nil {
 		tx.Rollback()
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update table status",
+			"error": "Failed to load session tables",
 		})
 	}
-
-	// อัพเดทสถานะ QR Code เป็น inactive
-	if err := tx.Model(&models.QRCode{}).
-		Where("uuid = ? AND table_id = ?", req.UUID, req.TableID).
-		Update("is_active", false).Error; err != nil {
+	if err := service.CloseTableSession(tx, session, &req.StaffID); err != nil {
 		tx.Rollback()
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update QR code status",
+			"error": "Failed to close table session",
 		})
 	}
 
@@ -256,6 +253,7 @@ func ProcessPayment(c *fiber.Ctx) error {
 			"error": "Failed to commit transaction",
 		})
 	}
+	service.PublishTableChange(service.TableEventClosed, tableIDs...)
 
 	// เรียกใช้ฟังก์ชันการพิมพ์ใบเสร็จ
 	if err := PrintReceipt(receipt.ID); err != nil {
@@ -320,11 +320,18 @@ func prepareOrderPrintContent(job models.PrintJob) ([]byte, error) {
 			}
 		}
 
+		// รายการในชุดเดียวกันพิมพ์ติดกันใต้หัวข้อชุด
+		newItems = api_v2.GroupKitchenItems(newItems)
+
 		if len(newItems) > 0 {
 			content.WriteString("\n[รายการใหม่]\n")
 			content.WriteString("----------------------------------------\n")
 
 			for i, item := range newItems {
+				if header := api_v2.ComboKitchenHeader(newItems, i); header != "" {
+					content.WriteString(cleanText(header) + "\n")
+				}
+
 				// หมายเลขรายการและชื่ออาหาร
 				itemLine := fmt.Sprintf("%d. %s", i+1, item.MenuItem.Name)
 				if item.Quantity > 1 {
@@ -333,9 +340,11 @@ func prepareOrderPrintContent(job models.PrintJob) ([]byte, error) {
 				content.WriteString(cleanText(itemLine) + "\n")
 
 				// ตัวเลือกเพิ่มเติม
-				for _, opt := range item.Options {
-					// ใช้ MenuOption ที่เชื่อมโยงกับ OrderItemOption
-					optionLine := fmt.Sprintf("   • %s: %s",
+				for _, nested := range api_v2.NestOrderItemOptions(item.Options) {
+					// ใช้ MenuOption ที่เชื่อมโยงกับ OrderItemOption (ตัวเลือกของกลุ่มย่อยย่อหน้าลึกขึ้น)
+					opt := nested.Option
+					optionLine := fmt.Sprintf("%s%s: %s",
+						api_v2.NestedOptionPrefix("   ", nested.Depth),
 						cleanText(opt.MenuOption.OptionGroup.Name),
 						cleanText(opt.MenuOption.Name))
 					content.WriteString(optionLine + "\n")
@@ -414,9 +423,13 @@ func prepareReceiptPrintContent(job models.PrintJob) ([]byte, error) {
 			Notes    string
 		})
 
-		// จัดกลุ่มรายการที่เหมือนกันทุกประการ
+		// จัดกลุ่มรายการที่เหมือนกันทุกประการ (ชุดอาหารแยกพิมพ์เป็นบรรทัดชุด)
+		var comboLines []api_v2.ComboLine
 		for _, order := range job.Receipt.Orders {
-			for _, item := range order.Items {
+			regularItems, combos := api_v2.GroupComboItems(order.Items)
+			comboLines = append(comboLines, combos...)
+
+			for _, item := range regularItems {
 				if item.Status != "cancelled" {
 					// สร้าง options string ที่เรียงลำดับแล้ว
 					var optStrings []string
@@ -491,8 +504,10 @@ func prepareReceiptPrintContent(job models.PrintJob) ([]byte, error) {
 			content.WriteString(cleanText(itemLine) + "\n")
 
 			// พิมพ์ options
-			for _, opt := range group.Options {
-				optionLine := fmt.Sprintf("   • %s   ฿%.2f",
+			for _, nested := range api_v2.NestOrderItemOptions(group.Options) {
+				opt := nested.Option
+				optionLine := fmt.Sprintf("%s%s   ฿%.2f",
+					api_v2.NestedOptionPrefix("   ", nested.Depth),
 					cleanText(opt.MenuOption.Name),
 					opt.Price)
 				content.WriteString(optionLine + "\n")
@@ -503,6 +518,7 @@ func prepareReceiptPrintContent(job models.PrintJob) ([]byte, error) {
 				content.WriteString(fmt.Sprintf("   [หมายเหตุ: %s]\n", cleanText(group.Notes)))
 			}
 		}
+		writeComboLines(&content, comboLines, len(groupedItems)+1)
 
 		content.WriteString("----------------------------------------\n")
 
@@ -549,6 +565,28 @@ func prepareReceiptPrintContent(job models.PrintJob) ([]byte, error) {
 	return content.Bytes(), nil
 }
 
+// writeComboLines พิมพ์ชุดอาหารต่อจากรายการปกติ ราคารวมอยู่ที่บรรทัดชุด รายการในชุดพิมพ์ใต้ชุดโดยไม่มีราคา
+func writeComboLines(content *bytes.Buffer, combos []api_v2.ComboLine, start int) {
+	for i, combo := range combos {
+		comboLine := fmt.Sprintf("%d. [ชุด] %s", start+i, combo.Name)
+		if combo.Quantity > 1 {
+			comboLine += fmt.Sprintf(" x%d", combo.Quantity)
+		}
+		comboLine += fmt.Sprintf("   ฿%.2f", combo.Price)
+		content.WriteString(cleanText(comboLine) + "\n")
+
+		for _, item := range combo.Items {
+			content.WriteString("   • " + cleanText(item.MenuItem.Name) + "\n")
+			for _, nested := range api_v2.NestOrderItemOptions(item.Options) {
+				content.WriteString(api_v2.NestedOptionPrefix("   ", nested.Depth+1) + cleanText(nested.Option.MenuOption.Name) + "\n")
+			}
+		}
+		if notes := combo.Items[0].Notes; notes != "" {
+			content.WriteString(fmt.Sprintf("   [หมายเหตุ: %s]\n", cleanText(notes)))
+		}
+	}
+}
+
 func prepareCancelPrintContent(job models.PrintJob) ([]byte, error) {
 	var content bytes.Buffer
 
@@ -721,11 +759,13 @@ func GetPendingPrintJobs(c *fiber.Ctx) error {
 			return db.Where("status = ?", "pending")
 		}).
 		Preload("Order.Items.Options.MenuOption.OptionGroup").
+		Preload("Order.Items.ComboUsage.Combo", models.WithDeletedCombo).
 		Preload("Receipt.Orders.Items", func(db *gorm.DB) *gorm.DB {
 			return db.Where("status != ?", "cancelled")
 		}).
 		Preload("Receipt.Orders.Items.MenuItem").
-		Preload("Receipt.Orders.Items.Options.MenuOption").
+		Preload("Receipt.Orders.Items.Options.MenuOption.OptionGroup").
+		Preload("Receipt.Orders.Items.ComboUsage.Combo", models.WithDeletedCombo).
 		Preload("Receipt.Discounts.DiscountType").
 		Preload("Receipt.Charges.ChargeType").
 		Find(&jobs).Error
@@ -842,9 +882,14 @@ func GetPendingPrintJobs(c *fiber.Ctx) error {
 func findAppropiatePrinter(menuItem models.MenuItem) (*models.Printer, error) {
 	// หาเครื่องพิมพ์ที่รองรับหมวดหมู่นี้
 	var printer models.Printer
-	err := db.DB.Joins("JOIN printer_categories ON printers.id = printer_categories.printer_id").
-		Where("printer_categories.category_id = ?", menuItem.CategoryID).
-		First(&printer).Error
+	tree, err := loadCategoryTree(db.DB)
+	if err == nil {
+		// หมวดหมู่ย่อยที่ไม่ได้กำหนดเครื่องพิมพ์เองใช้เครื่องพิมพ์ของหมวดหมู่แม่
+		err = gorm.ErrRecordNotFound
+		if printerIDs := tree.printerIDs(menuItem.CategoryID); len(printerIDs) > 0 {
+			err = db.DB.First(&printer, printerIDs[0]).Error
+		}
+	}
 
 	if err != nil {
 		// ถ้าไม่พบเครื่องพิมพ์ที่รองรับหมวดหมู่นี้ ใช้เครื่องพิมพ์ main
@@ -1092,13 +1137,18 @@ func findPrinterByIdentifier(printerID string) (*models.Printer, error) {
 }
 
 func getPendingJobs(printer *models.Printer, categories []models.Category) ([]models.PrintJob, error) {
-	categoryIDs := make(map[uint]bool)
+	// รวมหมวดหมู่ย่อยที่ไม่ได้กำหนดเครื่องพิมพ์เองและสืบทอดเครื่องพิมพ์นี้มาจากหมวดหมู่แม่
+	tree, err := loadCategoryTree(db.DB)
+	if err != nil {
+		return nil, err
+	}
+	categoryIDs := tree.printerCategoryIDs(printer.ID)
 	for _, cat := range categories {
 		categoryIDs[cat.ID] = true
 	}
 
 	var jobs []models.PrintJob
-	err := db.DB.Where("printer_id = ? AND status = ?", printer.ID, "pending").
+	err = db.DB.Where("printer_id = ? AND status = ?", printer.ID, "pending").
 		Preload("Order").
 		Preload("Order.Items", func(db *gorm.DB) *gorm.DB {
 			return db.Where("status = ?", "pending")
@@ -1108,6 +1158,7 @@ func getPendingJobs(printer *models.Printer, categories []models.Category) ([]mo
 		Preload("Order.Items.Options").
 		Preload("Order.Items.Options.MenuOption").
 		Preload("Order.Items.Options.MenuOption.OptionGroup").
+		Preload("Order.Items.ComboUsage.Combo", models.WithDeletedCombo).
 		Find(&jobs).Error
 
 	if err != nil {
@@ -1285,6 +1336,7 @@ func GetReprintableJobs(c *fiber.Ctx) error {
 		Preload("Order.Items.MenuItem.Category").
 		Preload("Order.Items.Options.MenuOption").
 		Preload("Order.Items.Options.MenuOption.OptionGroup").
+		Preload("Order.Items.ComboUsage.Combo", models.WithDeletedCombo).
 		// Receipt และข้อมูลที่เกี่ยวข้อง
 		Preload("Receipt").
 		Preload("Receipt.Staff").
@@ -1298,6 +1350,7 @@ func GetReprintableJobs(c *fiber.Ctx) error {
 		Preload("Receipt.Orders.Items.MenuItem.Category").
 		Preload("Receipt.Orders.Items.Options.MenuOption").
 		Preload("Receipt.Orders.Items.Options.MenuOption.OptionGroup").
+		Preload("Receipt.Orders.Items.ComboUsage.Combo", models.WithDeletedCombo).
 		Preload("Receipt.Discounts.DiscountType").
 		Preload("Receipt.Charges.ChargeType")
 
@@ -1443,8 +1496,10 @@ func ReprintDocument(c *fiber.Ctx) error {
 	if err := db.DB.Preload("Printer").
 		Preload("Order.Items.MenuItem").
 		Preload("Order.Items.Options.MenuOption.OptionGroup").
+		Preload("Order.Items.ComboUsage.Combo", models.WithDeletedCombo).
 		Preload("Receipt.Orders.Items.MenuItem").
-		Preload("Receipt.Orders.Items.Options.MenuOption").
+		Preload("Receipt.Orders.Items.Options.MenuOption.OptionGroup").
+		Preload("Receipt.Orders.Items.ComboUsage.Combo", models.WithDeletedCombo).
 		Preload("Receipt.Discounts.DiscountType").
 		Preload("Receipt.Charges.ChargeType").
 		First(&originalJob, jobID).Error; err != nil {
@@ -1551,8 +1606,15 @@ func prepareBillCheckPrintContent(orders []models.Order, tableIDs []string, disc
 
 	// คำนวณยอดรวม
 	var subTotal float64
+	var comboLines []api_v2.ComboLine
 	for _, order := range orders {
-		for _, item := range order.Items {
+		regularItems, combos := api_v2.GroupComboItems(order.Items)
+		comboLines = append(comboLines, combos...)
+		for _, combo := range combos {
+			subTotal += combo.Price
+		}
+
+		for _, item := range regularItems {
 			if item.Status != "cancelled" {
 				var optStrings []string
 				for _, opt := range item.Options {
@@ -1617,8 +1679,10 @@ func prepareBillCheckPrintContent(orders []models.Order, tableIDs []string, disc
 		itemLine += fmt.Sprintf("   ฿%.2f", group.Price)
 		content.WriteString(cleanText(itemLine) + "\n")
 
-		for _, opt := range group.Options {
-			optionLine := fmt.Sprintf("   • %s   ฿%.2f",
+		for _, nested := range api_v2.NestOrderItemOptions(group.Options) {
+			opt := nested.Option
+			optionLine := fmt.Sprintf("%s%s   ฿%.2f",
+				api_v2.NestedOptionPrefix("   ", nested.Depth),
 				cleanText(opt.MenuOption.Name),
 				opt.Price*float64(opt.Quantity))
 			content.WriteString(optionLine + "\n")
@@ -1628,6 +1692,7 @@ func prepareBillCheckPrintContent(orders []models.Order, tableIDs []string, disc
 			content.WriteString(fmt.Sprintf("   [หมายเหตุ: %s]\n", cleanText(group.Notes)))
 		}
 	}
+	writeComboLines(&content, comboLines, len(groupedItems)+1)
 
 	content.WriteString("----------------------------------------\n")
 	content.WriteString(fmt.Sprintf("ยอดรวม: ฿%.2f\n", subTotal))
@@ -1735,7 +1800,8 @@ func PrintBillCheck(c *fiber.Ctx) error {
 		if err := db.DB.Preload("Items", "status != ?", "cancelled").
 			Preload("Items.MenuItem").
 			Preload("Items.MenuItem.Category").
-			Preload("Items.Options.MenuOption").
+			Preload("Items.Options.MenuOption.OptionGroup").
+			Preload("Items.ComboUsage.Combo", models.WithDeletedCombo).
 			Preload("Items.PromotionUsage.Promotion").
 			Where("table_id = ? AND status NOT IN (?, ?) AND receipt_id IS NULL",
 				tableID, "completed", "cancelled").
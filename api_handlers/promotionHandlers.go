@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"food-ordering-api/db"
 	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	"log"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -208,6 +210,11 @@ func GetActivePromotions(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch promotions"})
 	}
 
+	l := newLocalizer(c)
+	for i := range promotions {
+		l.promotion(&promotions[i])
+	}
+
 	return c.JSON(promotions)
 }
 
@@ -494,13 +501,48 @@ func UpdatePromotionImage(c *fiber.Ctx) error { //Security BearerAuth
 		})
 	}
 
-	// อัพเดทรูปภาพในฐานข้อมูล
-	if err := db.DB.Model(&existingPromotion).Update("image", buffer).Error; err != nil {
+	// เก็บรูปพร้อม thumbnail ลง image store แล้วผูก image_id กับโปรโมชั่น
+	tx := db.DB.Begin()
+	storedImage, err := service.SaveImage(tx, buffer)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Failed to process promotion image",
+			"details": err.Error(),
+		})
+	}
+
+	// Updates เขียนค่าใหม่กลับเข้า existingPromotion (ผ่าน pointer เดิม) จึงต้องคัดลอก ID รูปเดิมไว้ก่อน
+	var oldImageID *uint
+	if existingPromotion.ImageID != nil {
+		id := *existingPromotion.ImageID
+		oldImageID = &id
+	}
+	if err := tx.Model(&existingPromotion).Updates(map[string]interface{}{
+		"image_id": storedImage.ID,
+		"image":    nil,
+	}).Error; err != nil {
+		tx.Rollback()
+		service.DiscardImageFiles(*storedImage)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update promotion image",
 		})
 	}
 
+	if err := tx.Commit().Error; err != nil {
+		service.DiscardImageFiles(*storedImage)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to commit transaction",
+		})
+	}
+
+	// ลบรูปเดิมออกจาก store หลัง commit แล้วเท่านั้น
+	if oldImageID != nil {
+		if err := service.DeleteImage(db.DB, *oldImageID); err != nil {
+			log.Printf("Failed to delete old promotion image %d: %v", *oldImageID, err)
+		}
+	}
+
 	// ดึงข้อมูลที่อัพเดทแล้วมาแสดง
 	var updatedPromotion models.Promotion
 	if err := db.DB.Preload("Items.MenuItem").First(&updatedPromotion, promoID).Error; err != nil {
@@ -0,0 +1,217 @@
+package api_handlers
+
+import (
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// coverStats ยอดขายเทียบจำนวนลูกค้า (covers) ของช่วงเวลาหนึ่ง
+type coverStats struct {
+	Receipts         int     `json:"receipts"`
+	Covers           int     `json:"covers"`
+	Revenue          float64 `json:"revenue"`
+	AvgSpendPerCover float64 `json:"avg_spend_per_cover"` // คิดเฉพาะใบเสร็จที่บันทึกจำนวนลูกค้า
+	AvgCoversPerBill float64 `json:"avg_covers_per_bill"`
+	ReceiptsNoCovers int     `json:"receipts_without_covers"`
+	coveredRevenue   float64
+}
+
+// dailyCoverStats สถิติรายวัน
+type dailyCoverStats struct {
+	Date string `json:"date"`
+	coverStats
+	Seatings int `json:"seatings"`
+}
+
+// tableTurnoverStats การหมุนเวียนของโต๊ะแต่ละตัว
+type tableTurnoverStats struct {
+	TableID        uint    `json:"table_id"`
+	TableName      string  `json:"table_name"`
+	Seatings       int     `json:"seatings"` // จำนวนรอบที่ปิดในช่วงเวลา
+	Covers         int     `json:"covers"`
+	AvgDiningMins  float64 `json:"avg_dining_minutes"`
+	totalDiningMin float64
+}
+
+// coverReport รายงานจำนวนลูกค้าและการหมุนเวียนโต๊ะ
+type coverReport struct {
+	StartDate     string               `json:"start_date"`
+	EndDate       string               `json:"end_date"`
+	Summary       coverStats           `json:"summary"`
+	Seatings      int                  `json:"seatings"`
+	TableCount    int                  `json:"table_count"`
+	TurnoverRate  float64              `json:"turnover_rate"` // จำนวนรอบเฉลี่ยต่อโต๊ะต่อวัน
+	AvgDiningMins float64              `json:"avg_dining_minutes"`
+	Daily         []dailyCoverStats    `json:"daily"`
+	Tables        []tableTurnoverStats `json:"tables"`
+}
+
+func (s *coverStats) add(receipt models.Receipt) {
+	s.Receipts++
+	s.Revenue += receipt.Total
+	if receipt.GuestCount > 0 {
+		s.Covers += receipt.GuestCount
+		s.coveredRevenue += receipt.Total
+	} else {
+		s.ReceiptsNoCovers++
+	}
+}
+
+func (s *coverStats) finish() {
+	s.Revenue = roundMoney(s.Revenue)
+	if s.Covers > 0 {
+		s.AvgSpendPerCover = roundMoney(s.coveredRevenue / float64(s.Covers))
+	}
+	if billed := s.Receipts - s.ReceiptsNoCovers; billed > 0 {
+		s.AvgCoversPerBill = roundMoney(float64(s.Covers) / float64(billed))
+	}
+}
+
+// roundMoney ปัดทศนิยม 2 ตำแหน่ง
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// parseReportRange อ่านช่วงวันที่ start_date/end_date (YYYY-MM-DD) ค่าเริ่มต้นคือวันนี้
+// คืนเวลาเริ่มต้นและเวลาสิ้นสุด (ไม่รวม) ของช่วง
+func parseReportRange(c *fiber.Ctx) (time.Time, time.Time, bool) {
+	today := time.Now().Format("2006-01-02")
+	start, err := time.ParseInLocation("2006-01-02", c.Query("start_date", today), time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	end, err := time.ParseInLocation("2006-01-02", c.Query("end_date", today), time.Local)
+	if err != nil || end.Before(start) {
+		return time.Time{}, time.Time{}, false
+	}
+	return start, end.AddDate(0, 0, 1), true
+}
+
+// @Summary รายงานจำนวนลูกค้าและการหมุนเวียนโต๊ะ
+// @Description ยอดใช้จ่ายเฉลี่ยต่อลูกค้า (per cover) จากใบเสร็จ และการหมุนเวียนโต๊ะจากรอบการใช้โต๊ะที่ปิดในช่วงเวลา
+// @Description ใบเสร็จที่ไม่ได้บันทึกจำนวนลูกค้าไม่นำมาคิดยอดเฉลี่ยต่อลูกค้า
+// @Produce json
+// @Security BearerAuth
+// @Param start_date query string false "วันที่เริ่มต้น (YYYY-MM-DD) ค่าเริ่มต้นวันนี้"
+// @Param end_date query string false "วันที่สิ้นสุด (YYYY-MM-DD) ค่าเริ่มต้นวันนี้"
+// @Param zone_id query integer false "เฉพาะโต๊ะในโซนนี้ (ใบเสร็จรวมหลายโต๊ะจะไม่ถูกนับ)"
+// @Success 200 {object} coverReport "รายงาน"
+// @Failure 400 {object} map[string]interface{} "ช่วงวันที่ไม่ถูกต้อง"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
+// @Router /api/reports/covers [get]
+// @Tags Report
+func GetCoverReport(c *fiber.Ctx) error {
+	start, end, ok := parseReportRange(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid date range, use YYYY-MM-DD",
+		})
+	}
+
+	receiptQuery := db.DB.Select("id", "total", "guest_count", "created_at").
+		Where("created_at >= ? AND created_at < ?", start, end)
+	sessionQuery := db.DB.Preload("Tables").
+		Where("status = ? AND closed_at >= ? AND closed_at < ?", models.SessionClosed, start, end)
+	tableQuery := db.DB.Model(&models.Table{})
+	if zoneID := c.QueryInt("zone_id", 0); zoneID > 0 {
+		inZone := db.DB.Model(&models.Table{}).Select("id").Where("zone_id = ?", zoneID)
+		receiptQuery = receiptQuery.Where("session_id IN (?)",
+			db.DB.Model(&models.TableSession{}).Select("id").Where("table_id IN (?)", inZone))
+		sessionQuery = sessionQuery.Where("table_id IN (?)", inZone)
+		tableQuery = tableQuery.Where("zone_id = ?", zoneID)
+	}
+
+	var receipts []models.Receipt
+	if err := receiptQuery.Find(&receipts).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch receipts",
+		})
+	}
+
+	var sessions []models.TableSession
+	if err := sessionQuery.Find(&sessions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch table sessions",
+		})
+	}
+
+	var tableCount int64
+	if err := tableQuery.Count(&tableCount).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to count tables",
+		})
+	}
+
+	report := coverReport{
+		StartDate:  start.Format("2006-01-02"),
+		EndDate:    end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Seatings:   len(sessions),
+		TableCount: int(tableCount),
+		Daily:      []dailyCoverStats{},
+		Tables:     []tableTurnoverStats{},
+	}
+
+	daily := map[string]*dailyCoverStats{}
+	dayOf := func(t time.Time) *dailyCoverStats {
+		key := t.In(time.Local).Format("2006-01-02")
+		if daily[key] == nil {
+			daily[key] = &dailyCoverStats{Date: key}
+		}
+		return daily[key]
+	}
+
+	for _, receipt := range receipts {
+		report.Summary.add(receipt)
+		dayOf(receipt.CreatedAt).add(receipt)
+	}
+
+	// โต๊ะรวมนับเป็นหนึ่งรอบของทุกโต๊ะในกลุ่ม เพราะทุกโต๊ะถูกใช้ตลอดรอบนั้น
+	tables := map[uint]*tableTurnoverStats{}
+	totalDining := 0.0
+	for _, session := range sessions {
+		dayOf(*session.ClosedAt).Seatings++
+		minutes := session.ClosedAt.Sub(session.OpenedAt).Minutes()
+		totalDining += minutes
+		for _, table := range session.Tables {
+			if tables[table.ID] == nil {
+				tables[table.ID] = &tableTurnoverStats{TableID: table.ID, TableName: table.Name}
+			}
+			stats := tables[table.ID]
+			stats.Seatings++
+			stats.Covers += session.GuestCount
+			stats.totalDiningMin += minutes
+		}
+	}
+
+	report.Summary.finish()
+	if len(sessions) > 0 {
+		report.AvgDiningMins = roundMoney(totalDining / float64(len(sessions)))
+	}
+	if tableCount > 0 {
+		days := end.Sub(start).Hours() / 24
+		seatings := 0
+		for _, stats := range tables {
+			seatings += stats.Seatings
+		}
+		report.TurnoverRate = roundMoney(float64(seatings) / float64(tableCount) / math.Round(days))
+	}
+
+	for _, day := range daily {
+		day.finish()
+		report.Daily = append(report.Daily, *day)
+	}
+	sort.Slice(report.Daily, func(i, j int) bool { return report.Daily[i].Date < report.Daily[j].Date })
+
+	for _, stats := range tables {
+		stats.AvgDiningMins = roundMoney(stats.totalDiningMin / float64(stats.Seatings))
+		report.Tables = append(report.Tables, *stats)
+	}
+	sort.Slice(report.Tables, func(i, j int) bool { return report.Tables[i].TableID < report.Tables[j].TableID })
+
+	return c.JSON(report)
+}
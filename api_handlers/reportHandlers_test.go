@@ -0,0 +1,84 @@
+package api_handlers
+
+import (
+	"encoding/json"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoverReport(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.Table{}, &models.TableSession{}, &models.Receipt{})
+
+	app.Get("/api/reports/covers", GetCoverReport)
+
+	get := func(url string) (*http.Response, coverReport) {
+		resp, err := app.Test(httptest.NewRequest("GET", url, nil))
+		assert.Nil(t, err)
+		var report coverReport
+		json.NewDecoder(resp.Body).Decode(&report)
+		return resp, report
+	}
+
+	t1 := models.Table{Name: "T1"}
+	t2 := models.Table{Name: "T2"}
+	db.DB.Create(&t1)
+	db.DB.Create(&t2)
+
+	now := time.Now()
+	closed := func(tables []models.Table, guests int, minutes int) {
+		closedAt := now
+		db.DB.Create(&models.TableSession{
+			Token: time.Now().String(), TableID: tables[0].ID, Tables: tables, Status: models.SessionClosed,
+			GuestCount: guests, OpenedAt: now.Add(-time.Duration(minutes) * time.Minute), ClosedAt: &closedAt,
+			ExpiresAt: now,
+		})
+	}
+	closed([]models.Table{t1}, 2, 60)
+	closed([]models.Table{t1}, 4, 90)
+	closed([]models.Table{t1, t2}, 6, 120) // โต๊ะรวม
+	db.DB.Create(&models.TableSession{Token: "still-open", TableID: t2.ID, Status: models.SessionOpen, OpenedAt: now, ExpiresAt: now})
+
+	db.DB.Create(&models.Receipt{UUID: "r1", Total: 500, GuestCount: 2})
+	db.DB.Create(&models.Receipt{UUID: "r2", Total: 700, GuestCount: 4})
+	db.DB.Create(&models.Receipt{UUID: "r3", Total: 300}) // ไม่ได้บันทึกจำนวนลูกค้า
+	old := models.Receipt{UUID: "r4", Total: 9999, GuestCount: 1}
+	db.DB.Create(&old)
+	db.DB.Model(&old).UpdateColumn("created_at", now.AddDate(0, 0, -10))
+
+	// กรณีทดสอบที่ 1: ยอดเฉลี่ยต่อลูกค้าไม่นับใบเสร็จที่ไม่มีจำนวนลูกค้า และนับการหมุนเวียนของโต๊ะรวมทุกโต๊ะ
+	t.Run("Success - Today covers and turnover", func(t *testing.T) {
+		resp, report := get("/api/reports/covers")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Equal(t, 3, report.Summary.Receipts)
+		assert.Equal(t, 6, report.Summary.Covers)
+		assert.Equal(t, 1500.0, report.Summary.Revenue)
+		assert.Equal(t, 200.0, report.Summary.AvgSpendPerCover)
+		assert.Equal(t, 1, report.Summary.ReceiptsNoCovers)
+
+		assert.Equal(t, 3, report.Seatings)
+		assert.Equal(t, 90.0, report.AvgDiningMins)
+		assert.Equal(t, 2.0, report.TurnoverRate) // T1 3 รอบ + T2 1 รอบ / 2 โต๊ะ / 1 วัน
+		assert.Len(t, report.Tables, 2)
+		assert.Equal(t, 3, report.Tables[0].Seatings)
+		assert.Equal(t, 12, report.Tables[0].Covers)
+		assert.Len(t, report.Daily, 1)
+	})
+
+	// กรณีทดสอบที่ 2: ช่วงวันที่ไม่ถูกต้อง
+	t.Run("Failure - Invalid range", func(t *testing.T) {
+		resp, _ := get("/api/reports/covers?start_date=2026-01-10&end_date=2026-01-01")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = get("/api/reports/covers?start_date=yesterday")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
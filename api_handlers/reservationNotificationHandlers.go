@@ -0,0 +1,92 @@
+package api_handlers
+
+import (
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ManageReservationNotifications สร้างข้อความเตือนเมื่อใกล้ถึงเวลาจอง และส่งข้อความที่รอส่งทั้งหมดผ่านผู้ให้บริการที่ตั้งค่าไว้
+func ManageReservationNotifications() {
+	for {
+		now := time.Now()
+		if _, err := queueReservationReminders(now); err != nil {
+			log.Printf("Failed to queue reservation reminders: %v", err)
+		}
+		if _, err := service.DeliverPendingNotifications(db.DB, service.Notifications); err != nil {
+			log.Printf("Failed to deliver reservation notifications: %v", err)
+		}
+		time.Sleep(1 * time.Minute) // ตรวจสอบทุก 1 นาที
+	}
+}
+
+// queueReservationReminders สร้างข้อความเตือนให้การจองที่จะถึงภายใน reminder_hours_before ชั่วโมง
+// การจองที่จองหลังเวลาเตือนไปแล้ว (เพิ่งได้ข้อความยืนยัน) และการจองที่เคยได้ข้อความเตือนแล้วจะไม่ได้ซ้ำ
+func queueReservationReminders(now time.Time) (int, error) {
+	rules, err := activeReservationRules(db.DB)
+	if err != nil || rules.ReminderHoursBefore <= 0 {
+		return 0, err
+	}
+	lead := time.Duration(rules.ReminderHoursBefore) * time.Hour
+
+	var reservations []models.TableReservation
+	if err := db.DB.Where(`status = 'active' AND reserved_for > ? AND reserved_for <= ?
+			AND NOT EXISTS (
+				SELECT 1 FROM reservation_notifications n
+				WHERE n.reservation_id = table_reservations.id AND n.kind = ?
+			)`, now, now.Add(lead), models.NotificationReminder).
+		Find(&reservations).Error; err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for _, reservation := range reservations {
+		if reservation.CreatedAt.After(reservation.ReservedFor.Add(-lead)) {
+			continue
+		}
+		if err := service.QueueReservationNotification(db.DB, reservation.ID, models.NotificationReminder); err != nil {
+			return queued, err
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+// @Summary ดูประวัติการส่งข้อความของการจอง
+// @Description ข้อความยืนยัน ข้อความเตือน และข้อความติดตามเมื่อไม่มา ที่ส่งถึงลูกค้าของการจองนี้ พร้อมช่องทางและผลการส่ง
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของการจอง"
+// @Success 200 {array} models.ReservationNotification "ประวัติการส่งข้อความ เรียงจากเก่าไปใหม่"
+// @Failure 400 {object} map[string]interface{} "ID ไม่ถูกต้อง"
+// @Failure 404 {object} map[string]interface{} "ไม่พบการจอง"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
+// @Router /api/table/reservations/{id}/notifications [get]
+// @Tags Table
+func GetReservationNotifications(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid reservation ID",
+		})
+	}
+
+	var reservation models.TableReservation
+	if err := db.DB.Unscoped().First(&reservation, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "ไม่พบการจอง",
+		})
+	}
+
+	notifications := []models.ReservationNotification{}
+	if err := db.DB.Where("reservation_id = ?", reservation.ID).Order("id").Find(&notifications).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch notifications",
+		})
+	}
+	return c.JSON(notifications)
+}
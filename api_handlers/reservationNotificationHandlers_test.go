@@ -0,0 +1,204 @@
+package api_handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingNotifier ผู้ให้บริการที่ส่งไม่สำเร็จทุกครั้ง
+type failingNotifier struct{ calls int }
+
+func (n *failingNotifier) Channel() string { return service.ChannelSMS }
+
+func (n *failingNotifier) Send(msg service.NotificationMessage) (string, error) {
+	n.calls++
+	return "", errors.New("gateway unavailable")
+}
+
+func TestReservationNotifications(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.Table{}, &models.TableSession{}, &models.TableReservation{},
+		&models.ReservationRules{}, &models.TableHistory{}, &models.ReservationNotification{})
+
+	app.Post("/api/booking", CreateBooking)
+	app.Get("/api/table/reservations/:id/notifications", GetReservationNotifications)
+
+	logFile := filepath.Join(t.TempDir(), "notifications.log")
+	notifier := &service.LogNotifier{Path: logFile}
+
+	book := func(req models.BookingRequest) models.TableReservation {
+		resp := sendJSON(t, app, "POST", "/api/booking", req)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		var reservation models.TableReservation
+		db.DB.Where("confirmation_code = ?", result["confirmation_code"]).First(&reservation)
+		return reservation
+	}
+	notificationsOf := func(reservationID uint, kind string) []models.ReservationNotification {
+		var notifications []models.ReservationNotification
+		db.DB.Where("reservation_id = ? AND kind = ?", reservationID, kind).Order("id").Find(&notifications)
+		return notifications
+	}
+
+	// เตือนก่อน 3 ชั่วโมง มัดจำคนละ 100 บาท
+	db.DB.Create(&models.ReservationRules{
+		GracePeriodMinutes: 15, PreReservationMinutes: 30, FirstSlotTime: "11:00", LastSlotTime: "21:00",
+		SlotIntervalMinutes: 30, DiningDurationMinutes: 90, DepositPerGuest: 100, ReminderHoursBefore: 3, IsActive: true,
+	})
+	table := models.Table{Name: "A1", Capacity: 4, Status: "available"}
+	db.DB.Create(&table)
+
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	at := func(hour, minute int) time.Time {
+		return time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), hour, minute, 0, 0, time.Local)
+	}
+
+	// กรณีทดสอบที่ 1: จองแล้วได้ข้อความยืนยันตามภาษาที่เลือก ส่งผ่านผู้ให้บริการและบันทึกผลการส่ง
+	t.Run("Success - Confirmation on booking", func(t *testing.T) {
+		thai := book(models.BookingRequest{CustomerName: "สมชาย", PhoneNumber: "0812345678", GuestCount: 2, ReservedFor: at(18, 0)})
+		english := book(models.BookingRequest{
+			CustomerName: "John", PhoneNumber: "0898765432", GuestCount: 2, ReservedFor: at(12, 0), Language: "en",
+		})
+		assert.Len(t, notificationsOf(thai.ID, models.NotificationConfirmation), 1)
+		assert.Equal(t, models.NotificationPending, notificationsOf(thai.ID, models.NotificationConfirmation)[0].Status)
+
+		sent, err := service.DeliverPendingNotifications(db.DB, notifier)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, sent)
+
+		confirmation := notificationsOf(thai.ID, models.NotificationConfirmation)[0]
+		assert.Equal(t, models.NotificationSent, confirmation.Status)
+		assert.Equal(t, service.ChannelLog, confirmation.Channel)
+		assert.Equal(t, "0812345678", confirmation.Recipient)
+		assert.Equal(t, 1, confirmation.Attempts)
+		assert.NotEmpty(t, confirmation.ProviderRef)
+		assert.NotNil(t, confirmation.SentAt)
+		assert.Contains(t, confirmation.Body, *thai.ConfirmationCode)
+		assert.Contains(t, confirmation.Body, "มัดจำ 200 บาท")
+
+		confirmation = notificationsOf(english.ID, models.NotificationConfirmation)[0]
+		assert.Equal(t, "en", confirmation.Language)
+		assert.Contains(t, confirmation.Body, "Hi John")
+		assert.Contains(t, confirmation.Body, "12:00")
+
+		data, err := os.ReadFile(logFile)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, strings.Count(string(data), "\n"))
+
+		// ส่งไปแล้วไม่ส่งซ้ำ
+		sent, err = service.DeliverPendingNotifications(db.DB, notifier)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, sent)
+	})
+
+	// กรณีทดสอบที่ 2: ข้อความเตือนสร้างครั้งเดียว และไม่เตือนการจองที่เพิ่งจองภายในช่วงเวลาเตือน
+	t.Run("Success - Reminder queued once", func(t *testing.T) {
+		now := time.Now()
+		upcoming := models.TableReservation{
+			TableID: table.ID, CustomerName: "ล่วงหน้า", PhoneNumber: "0811111111", GuestCount: 2, Status: "active",
+			TableBlockedFrom: now.Add(90 * time.Minute), ReservedFor: now.Add(2 * time.Hour), GracePeriodUntil: now.Add(135 * time.Minute),
+			CreatedAt: now.AddDate(0, 0, -2),
+		}
+		lastMinute := models.TableReservation{
+			TableID: table.ID, CustomerName: "จองกระชั้น", PhoneNumber: "0822222222", GuestCount: 2, Status: "active",
+			TableBlockedFrom: now.Add(30 * time.Minute), ReservedFor: now.Add(time.Hour), GracePeriodUntil: now.Add(75 * time.Minute),
+			CreatedAt: now.Add(-10 * time.Minute),
+		}
+		later := models.TableReservation{
+			TableID: table.ID, CustomerName: "อีกหลายวัน", PhoneNumber: "0833333333", GuestCount: 2, Status: "active",
+			TableBlockedFrom: now.AddDate(0, 0, 3), ReservedFor: now.AddDate(0, 0, 3), GracePeriodUntil: now.AddDate(0, 0, 3),
+			CreatedAt: now.AddDate(0, 0, -1),
+		}
+		for _, reservation := range []*models.TableReservation{&upcoming, &lastMinute, &later} {
+			db.DB.Create(reservation)
+		}
+
+		queued, err := queueReservationReminders(now)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, queued)
+		assert.Len(t, notificationsOf(upcoming.ID, models.NotificationReminder), 1)
+		assert.Len(t, notificationsOf(lastMinute.ID, models.NotificationReminder), 0)
+		assert.Len(t, notificationsOf(later.ID, models.NotificationReminder), 0)
+
+		queued, err = queueReservationReminders(now.Add(time.Minute))
+		assert.Nil(t, err)
+		assert.Equal(t, 0, queued)
+
+		_, err = service.DeliverPendingNotifications(db.DB, notifier)
+		assert.Nil(t, err)
+		reminder := notificationsOf(upcoming.ID, models.NotificationReminder)[0]
+		assert.Equal(t, models.NotificationSent, reminder.Status)
+		assert.Contains(t, reminder.Subject, "เตือนการจอง")
+	})
+
+	// กรณีทดสอบที่ 3: ลูกค้าไม่มาได้ข้อความติดตาม ช่องทาง LINE ข้ามลูกค้าที่ไม่มี LINE user ID
+	t.Run("Success - No-show follow-up and skipped channel", func(t *testing.T) {
+		base := time.Now().AddDate(0, 0, -1)
+		missed := models.TableReservation{
+			TableID: table.ID, CustomerName: "ไม่มา", PhoneNumber: "0844444444", GuestCount: 2, Status: "active",
+			TableBlockedFrom: base, ReservedFor: base, GracePeriodUntil: base.Add(15 * time.Minute), NoShowFee: 300,
+		}
+		db.DB.Create(&missed)
+
+		markNoShowReservations(time.Now())
+		notifications := notificationsOf(missed.ID, models.NotificationNoShow)
+		assert.Len(t, notifications, 1)
+
+		sent, err := service.DeliverPendingNotifications(db.DB, &service.LineNotifier{ChannelToken: "token", APIURL: "http://127.0.0.1:0/push"})
+		assert.Nil(t, err)
+		assert.Equal(t, 0, sent)
+
+		noShow := notificationsOf(missed.ID, models.NotificationNoShow)[0]
+		assert.Equal(t, models.NotificationSkipped, noShow.Status)
+		assert.Equal(t, service.ChannelLine, noShow.Channel)
+		assert.Contains(t, noShow.Body, "ค่าปรับ 300 บาท")
+	})
+
+	// กรณีทดสอบที่ 4: ส่งไม่สำเร็จจะส่งซ้ำจนครบจำนวนครั้งแล้วจึงบันทึกว่าล้มเหลว และดูประวัติการส่งได้
+	t.Run("Failure - Retry until failed", func(t *testing.T) {
+		reservation := book(models.BookingRequest{CustomerName: "สมหญิง", PhoneNumber: "0855555555", GuestCount: 2, ReservedFor: at(20, 30)})
+
+		failing := &failingNotifier{}
+		for i := 0; i < 4; i++ {
+			sent, err := service.DeliverPendingNotifications(db.DB, failing)
+			assert.Nil(t, err)
+			assert.Equal(t, 0, sent)
+		}
+		assert.Equal(t, 3, failing.calls)
+
+		confirmation := notificationsOf(reservation.ID, models.NotificationConfirmation)[0]
+		assert.Equal(t, models.NotificationFailed, confirmation.Status)
+		assert.Equal(t, 3, confirmation.Attempts)
+		assert.Equal(t, "gateway unavailable", confirmation.Error)
+
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/table/reservations/%d/notifications", reservation.ID), nil)
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var history []models.ReservationNotification
+		json.NewDecoder(resp.Body).Decode(&history)
+		assert.Len(t, history, 1)
+		assert.Equal(t, models.NotificationConfirmation, history[0].Kind)
+
+		req = httptest.NewRequest("GET", "/api/table/reservations/9999/notifications", nil)
+		resp, err = app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
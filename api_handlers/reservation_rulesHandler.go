@@ -2,21 +2,68 @@ package api_handlers
 
 import (
 	"errors"
+	"fmt"
 	"food-ordering-api/db"
 	"food-ordering-api/models"
+	service "food-ordering-api/services"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
+// ค่าเริ่มต้นเมื่อยังไม่มีกฎการจองที่ใช้งานอยู่
+const (
+	defaultGracePeriodMinutes    = 15
+	defaultPreReservationMinutes = 30
+	defaultFirstSlotTime         = "11:00"
+	defaultLastSlotTime          = "21:00"
+	defaultSlotIntervalMinutes   = 30
+	defaultCancelCutoffMinutes   = 120
+	defaultReminderHoursBefore   = 3
+)
+
 type RuleRequest struct {
-	GracePeriodMinutes    int `json:"grace_period_minutes" example:"15"`
-	PreReservationMinutes int `json:"pre_reservation_minutes" example:"30"`
+	GracePeriodMinutes    int     `json:"grace_period_minutes" example:"15"`
+	PreReservationMinutes int     `json:"pre_reservation_minutes" example:"30"`
+	FirstSlotTime         string  `json:"first_slot_time" example:"11:00"`      // ไม่ส่ง = 11:00
+	LastSlotTime          string  `json:"last_slot_time" example:"21:00"`       // ไม่ส่ง = 21:00
+	SlotIntervalMinutes   int     `json:"slot_interval_minutes" example:"30"`   // ไม่ส่ง = 30
+	DiningDurationMinutes int     `json:"dining_duration_minutes" example:"90"` // ไม่ส่ง = ใช้เวลานั่งเฉลี่ยจากประวัติ
+	DepositPerGuest       float64 `json:"deposit_per_guest" example:"100"`      // ไม่ส่ง = ไม่ต้องมัดจำ
+	NoShowFee             float64 `json:"no_show_fee" example:"0"`
+	CancelCutoffMinutes   *int    `json:"cancel_cutoff_minutes" example:"120"` // ไม่ส่ง = 120
+	ReminderHoursBefore   *int    `json:"reminder_hours_before" example:"3"`   // ไม่ส่ง = 3, 0 = ไม่เตือน
+}
+
+// activeReservationRules กฎการจองที่ใช้งานอยู่ ถ้ายังไม่มีใช้ค่าเริ่มต้น
+func activeReservationRules(tx *gorm.DB) (models.ReservationRules, error) {
+	var rule models.ReservationRules
+	err := tx.Where("is_active = ?", true).First(&rule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.ReservationRules{
+			GracePeriodMinutes:    defaultGracePeriodMinutes,
+			PreReservationMinutes: defaultPreReservationMinutes,
+			FirstSlotTime:         defaultFirstSlotTime,
+			LastSlotTime:          defaultLastSlotTime,
+			SlotIntervalMinutes:   defaultSlotIntervalMinutes,
+			CancelCutoffMinutes:   defaultCancelCutoffMinutes,
+			ReminderHoursBefore:   defaultReminderHoursBefore,
+		}, nil
+	}
+	return rule, err
+}
+
+// reservationDiningDuration เวลาที่ถือว่าลูกค้าที่จองใช้โต๊ะ ตามกฎการจอง ถ้าไม่ได้กำหนดใช้เวลานั่งเฉลี่ย
+func reservationDiningDuration(tx *gorm.DB, rules models.ReservationRules) (time.Duration, error) {
+	if rules.DiningDurationMinutes > 0 {
+		return time.Duration(rules.DiningDurationMinutes) * time.Minute, nil
+	}
+	return service.AverageDiningDuration(tx)
 }
 
 // @Summary ตั้งค่ากฎการจองโต๊ะ
-// @Description กำหนดกฎการจองโต๊ะ เช่น เวลาสายที่ยอมรับได้ และเวลากั้นโต๊ะล่วงหน้า
+// @Description กำหนดกฎการจองโต๊ะ เช่น เวลาสายที่ยอมรับได้ เวลากั้นโต๊ะล่วงหน้า ช่วงเวลาที่รับจอง เวลาที่ลูกค้าใช้โต๊ะ เงินมัดจำ และนโยบายยกเลิก/ไม่มา
 // @Accept json
 // @Produce json
 // @Security BearerAuth
@@ -44,6 +91,66 @@ func SetReservationRules(c *fiber.Ctx) error {
 		})
 	}
 
+	if req.FirstSlotTime == "" {
+		req.FirstSlotTime = defaultFirstSlotTime
+	}
+	if req.LastSlotTime == "" {
+		req.LastSlotTime = defaultLastSlotTime
+	}
+	if req.SlotIntervalMinutes == 0 {
+		req.SlotIntervalMinutes = defaultSlotIntervalMinutes
+	}
+	first, err := time.Parse("15:04", req.FirstSlotTime)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "เวลาแรกที่รับจองต้องอยู่ในรูปแบบ HH:MM",
+		})
+	}
+	last, err := time.Parse("15:04", req.LastSlotTime)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "เวลาสุดท้ายที่รับจองต้องอยู่ในรูปแบบ HH:MM",
+		})
+	}
+	if last.Before(first) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "เวลาสุดท้ายที่รับจองต้องไม่ก่อนเวลาแรก",
+		})
+	}
+	if req.SlotIntervalMinutes < 5 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "ช่วงห่างของเวลาจองต้องอย่างน้อย 5 นาที",
+		})
+	}
+	if req.DiningDurationMinutes < 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "เวลาที่ใช้โต๊ะต้องไม่ติดลบ",
+		})
+	}
+	if req.DepositPerGuest < 0 || req.NoShowFee < 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "เงินมัดจำและค่าปรับต้องไม่ติดลบ",
+		})
+	}
+	cancelCutoff := defaultCancelCutoffMinutes
+	if req.CancelCutoffMinutes != nil {
+		cancelCutoff = *req.CancelCutoffMinutes
+	}
+	if cancelCutoff < 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "เวลาปิดการยกเลิกต้องไม่ติดลบ",
+		})
+	}
+	reminderHours := defaultReminderHoursBefore
+	if req.ReminderHoursBefore != nil {
+		reminderHours = *req.ReminderHoursBefore
+	}
+	if reminderHours < 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "เวลาส่งข้อความเตือนต้องไม่ติดลบ",
+		})
+	}
+
 	tx := db.DB.Begin()
 
 	// ยกเลิก rule เก่า
@@ -60,6 +167,14 @@ func SetReservationRules(c *fiber.Ctx) error {
 	newRule := models.ReservationRules{
 		GracePeriodMinutes:    req.GracePeriodMinutes,
 		PreReservationMinutes: req.PreReservationMinutes,
+		FirstSlotTime:         req.FirstSlotTime,
+		LastSlotTime:          req.LastSlotTime,
+		SlotIntervalMinutes:   req.SlotIntervalMinutes,
+		DiningDurationMinutes: req.DiningDurationMinutes,
+		DepositPerGuest:       req.DepositPerGuest,
+		NoShowFee:             req.NoShowFee,
+		CancelCutoffMinutes:   cancelCutoff,
+		ReminderHoursBefore:   reminderHours,
 		IsActive:              true,
 	}
 
@@ -90,8 +205,16 @@ func GetActiveReservationRule(c *fiber.Ctx) error {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			// ถ้าไม่พบ rule ที่ active ให้ส่งค่า default
 			return c.JSON(fiber.Map{
-				"grace_period_minutes":    15, // ค่าเริ่มต้น 15 นาที
-				"pre_reservation_minutes": 30, // ค่าเริ่มต้น 30 นาที
+				"grace_period_minutes":    defaultGracePeriodMinutes,
+				"pre_reservation_minutes": defaultPreReservationMinutes,
+				"first_slot_time":         defaultFirstSlotTime,
+				"last_slot_time":          defaultLastSlotTime,
+				"slot_interval_minutes":   defaultSlotIntervalMinutes,
+				"dining_duration_minutes": 0,
+				"deposit_per_guest":       0,
+				"no_show_fee":             0,
+				"cancel_cutoff_minutes":   defaultCancelCutoffMinutes,
+				"reminder_hours_before":   defaultReminderHoursBefore,
 			})
 		}
 		return c.Status(500).JSON(fiber.Map{
@@ -124,83 +247,149 @@ func GetReservationRulesHistory(c *fiber.Ctx) error {
 
 func AutoManageReservations() {
 	for {
-		now := time.Now()
-
-		// ค้นหาการจองที่เลยเวลา grace period
-		var expiredReservations []models.TableReservation
-		db.DB.Where("status = ? AND grace_period_until < ?", "active", now).Find(&expiredReservations)
+		markNoShowReservations(time.Now())
+		time.Sleep(1 * time.Minute) // ตรวจสอบทุก 1 นาที
+	}
+}
 
-		for _, reservation := range expiredReservations {
-			tx := db.DB.Begin()
+// markNoShowReservations เปลี่ยนการจองที่เลยเวลาสายแล้วเป็น no_show คืนโต๊ะ และส่งข้อความติดตามถึงลูกค้า
+// โต๊ะที่มีลูกค้าอื่นนั่งอยู่ หรือถูกกั้นไว้ให้การจองรอบถัดไปแล้ว จะไม่ถูกเปลี่ยนสถานะ
+func markNoShowReservations(now time.Time) {
+	// ค้นหาการจองที่เลยเวลา grace period
+	var expiredReservations []models.TableReservation
+	db.DB.Where("status = ? AND grace_period_until < ?", "active", now).Find(&expiredReservations)
 
-			// อัพเดทสถานะการจอง
-			if err := tx.Model(&reservation).Update("status", "no_show").Error; err != nil {
-				tx.Rollback()
-				continue
-			}
+	for _, reservation := range expiredReservations {
+		tx := db.DB.Begin()
 
-			// ปล่อยโต๊ะ
-			if err := tx.Model(&models.Table{}).
-				Where("id = ?", reservation.TableID).
-				Update("status", "available").Error; err != nil {
-				tx.Rollback()
-				continue
-			}
+		// อัพเดทสถานะการจอง ลูกค้าที่ไม่มาถูกริบเงินมัดจำที่ชำระแล้ว
+		updates := map[string]interface{}{"status": "no_show"}
+		if reservation.DepositStatus == models.DepositPaid {
+			updates["deposit_status"] = models.DepositForfeited
+		}
+		if err := tx.Model(&reservation).Updates(updates).Error; err != nil {
+			tx.Rollback()
+			continue
+		}
+		if err := service.QueueReservationNotification(tx, reservation.ID, models.NotificationNoShow); err != nil {
+			tx.Rollback()
+			continue
+		}
 
+		held, err := service.TableHeldByReservation(tx, reservation.TableID, now)
+		if err != nil {
+			tx.Rollback()
+			continue
+		}
+		if held {
 			tx.Commit()
+			continue
 		}
 
-		time.Sleep(1 * time.Minute) // ตรวจสอบทุก 1 นาที
-	}
-}
-
-func ManageTableReservationStatus() {
-	for {
-		now := time.Now()
-		tx := db.DB.Begin()
-
-		// อัพเดทสถานะโต๊ะเป็น reserved เมื่อถึงเวลา blocked
-		if err := tx.Exec(`
-					UPDATE tables 
-					SET status = 'reserved'
-					WHERE id IN (
-							SELECT table_id 
-							FROM table_reservations 
-							WHERE status = 'active' 
-							AND table_blocked_from <= ? 
-							AND grace_period_until > ?
-					)
-					AND status = 'available'
-			`, now, now).Error; err != nil {
+		change, err := service.BeginTableChange(tx, models.TableActionRelease, reservation.TableID)
+		if err != nil {
 			tx.Rollback()
 			continue
 		}
 
-		// คืนสถานะโต๊ะเป็น available เมื่อเลยเวลา grace period
-		if err := tx.Exec(`
-					UPDATE tables 
-					SET status = 'available'
-					WHERE id IN (
-							SELECT table_id 
-							FROM table_reservations 
-							WHERE status = 'active' 
-							AND grace_period_until <= ?
-					)
-					AND status = 'reserved'
-			`, now).Error; err != nil {
+		// ปล่อยโต๊ะ
+		result := tx.Model(&models.Table{}).
+			Where("id = ? AND status = ?", reservation.TableID, "reserved").
+			Update("status", "available")
+		if result.Error != nil {
 			tx.Rollback()
 			continue
 		}
+		if result.RowsAffected == 0 {
+			tx.Commit()
+			continue
+		}
 
-		// อัพเดทสถานะการจองเป็น expired เมื่อเลยเวลา grace period
-		if err := tx.Model(&models.TableReservation{}).
-			Where("status = 'active' AND grace_period_until <= ?", now).
-			Update("status", "expired").Error; err != nil {
+		change.Entry.Note = fmt.Sprintf("reservation #%d no_show", reservation.ID)
+		if err := change.Record(tx); err != nil {
 			tx.Rollback()
 			continue
 		}
 
-		tx.Commit()
+		if tx.Commit().Error == nil {
+			service.PublishTableChange(service.TableEventReservation, reservation.TableID)
+		}
+	}
+}
+
+func ManageTableReservationStatus() {
+	for {
+		syncReservedTables(time.Now())
 		time.Sleep(1 * time.Minute) // ตรวจสอบทุก 1 นาที
 	}
 }
+
+// syncReservedTables ปรับสถานะโต๊ะตามช่วงเวลาของการจอง ณ เวลา now
+// โต๊ะที่มีการจองต่อกัน (back-to-back) จะคงสถานะ reserved ไว้ ไม่ถูกปล่อยระหว่างรอบ
+// ไม่เปลี่ยนสถานะการจองที่เลยเวลา grace period ให้ markNoShowReservations ทำ (แจ้งลูกค้าและริบเงินมัดจำ)
+func syncReservedTables(now time.Time) error {
+	tx := db.DB.Begin()
+
+	// อัพเดทสถานะโต๊ะเป็น reserved เมื่อถึงเวลา blocked
+	blocked, err := setReservedTablesStatus(tx, models.TableActionReserve, "available", "reserved", `
+				SELECT table_id 
+				FROM table_reservations 
+				WHERE status = 'active' 
+				AND table_blocked_from <= ? 
+				AND grace_period_until > ?
+		`, now, now)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// คืนสถานะโต๊ะเป็น available เมื่อเลยเวลา grace period และไม่มีการจองรอบถัดไปกั้นโต๊ะอยู่
+	released, err := setReservedTablesStatus(tx, models.TableActionRelease, "reserved", "available", `
+				SELECT r.table_id 
+				FROM table_reservations r
+				WHERE r.status = 'active' 
+				AND r.grace_period_until <= ?
+				AND NOT EXISTS (
+					SELECT 1 FROM table_reservations n
+					WHERE n.table_id = r.table_id
+					AND n.status = 'active'
+					AND n.table_blocked_from <= ?
+					AND n.grace_period_until > ?
+				)
+		`, now, now, now)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	if len(blocked)+len(released) > 0 {
+		service.PublishTableChange(service.TableEventReservation, append(blocked, released...)...)
+	}
+	return nil
+}
+
+// setReservedTablesStatus เปลี่ยนสถานะโต๊ะที่อยู่ใน reservationQuery จาก fromStatus เป็น toStatus
+// พร้อมบันทึกประวัติโต๊ะในนามระบบ คืน ID ของโต๊ะที่ถูกเปลี่ยน
+func setReservedTablesStatus(tx *gorm.DB, action, fromStatus, toStatus, reservationQuery string, args ...interface{}) ([]uint, error) {
+	var tableIDs []uint
+	if err := tx.Model(&models.Table{}).
+		Where("status = ? AND id IN ("+reservationQuery+")", append([]interface{}{fromStatus}, args...)...).
+		Pluck("id", &tableIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(tableIDs) == 0 {
+		return nil, nil
+	}
+
+	change, err := service.BeginTableChange(tx, action, tableIDs...)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.Table{}).Where("id IN ?", tableIDs).Update("status", toStatus).Error; err != nil {
+		return nil, err
+	}
+	return tableIDs, change.Record(tx)
+}
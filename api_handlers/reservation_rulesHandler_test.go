@@ -0,0 +1,145 @@
+package api_handlers
+
+import (
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackToBackReservations(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.Table{}, &models.TableSession{}, &models.TableReservation{},
+		&models.ReservationRules{}, &models.TableHistory{}, &models.ReservationNotification{})
+
+	app.Post("/api/table/reservedTable/:id", ReservedTable)
+	app.Post("/api/reservation/rules", SetReservationRules)
+
+	reserve := func(tableID uint, reservedFor time.Time) *http.Response {
+		return sendJSON(t, app, "POST", fmt.Sprintf("/api/table/reservedTable/%d", tableID), ReservationRequest{
+			CustomerName: "สมชาย", PhoneNumber: "0812345678", GuestCount: 2, ReservedFor: reservedFor,
+		})
+	}
+	tableStatus := func(id uint) string {
+		var table models.Table
+		db.DB.First(&table, id)
+		return table.Status
+	}
+	reservationStatus := func(id uint) string {
+		var reservation models.TableReservation
+		db.DB.First(&reservation, id)
+		return reservation.Status
+	}
+
+	// กั้นโต๊ะก่อน 30 นาที สายได้ 15 นาที ลูกค้าใช้โต๊ะ 2 ชั่วโมง
+	resp := sendJSON(t, app, "POST", "/api/reservation/rules", RuleRequest{GracePeriodMinutes: 15, PreReservationMinutes: 30, DiningDurationMinutes: 120})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	now := time.Now()
+	busy := models.Table{Name: "A1", Capacity: 4, Status: "occupied"}
+	db.DB.Create(&busy)
+
+	// กรณีทดสอบที่ 1: โต๊ะที่มีลูกค้านั่งอยู่ จองรอบเย็นได้สองรอบ แต่จองรอบที่ทับกันหรือรอบที่ลูกค้ายังนั่งอยู่ไม่ได้
+	t.Run("Success - Multiple reservations per table", func(t *testing.T) {
+		resp := reserve(busy.ID, now.Add(5*time.Hour))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// รอบแรกใช้โต๊ะถึง +7 ชม. รอบสองกั้นโต๊ะตั้งแต่ +7 ชม.
+		resp = reserve(busy.ID, now.Add(7*time.Hour+30*time.Minute))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "occupied", tableStatus(busy.ID))
+
+		resp = reserve(busy.ID, now.Add(6*time.Hour))
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		resp = reserve(busy.ID, now.Add(time.Hour))
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		var count int64
+		db.DB.Model(&models.TableReservation{}).Where("table_id = ? AND status = ?", busy.ID, "active").Count(&count)
+		assert.Equal(t, int64(2), count)
+
+		resp = sendJSON(t, app, "POST", "/api/reservation/rules", RuleRequest{DiningDurationMinutes: -1})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 2: การจองต่อกันไม่ทำให้โต๊ะถูกปล่อยว่างระหว่างรอบ
+	t.Run("Success - Status loop keeps back-to-back tables reserved", func(t *testing.T) {
+		base := now.Add(48 * time.Hour).Truncate(time.Hour)
+		table := models.Table{Name: "B1", Capacity: 2, Status: "available"}
+		db.DB.Create(&table)
+		first := models.TableReservation{
+			TableID: table.ID, CustomerName: "รอบแรก", PhoneNumber: "0811111111", GuestCount: 2, Status: "active",
+			TableBlockedFrom: base, ReservedFor: base.Add(30 * time.Minute), GracePeriodUntil: base.Add(45 * time.Minute),
+		}
+		second := models.TableReservation{
+			TableID: table.ID, CustomerName: "รอบสอง", PhoneNumber: "0822222222", GuestCount: 2, Status: "active",
+			TableBlockedFrom: base.Add(40 * time.Minute), ReservedFor: base.Add(70 * time.Minute), GracePeriodUntil: base.Add(85 * time.Minute),
+		}
+		db.DB.Create(&first)
+		db.DB.Create(&second)
+
+		assert.Nil(t, syncReservedTables(base.Add(10*time.Minute)))
+		assert.Equal(t, "reserved", tableStatus(table.ID))
+
+		// รอบสถานะโต๊ะไม่เปลี่ยนสถานะการจอง รอบลูกค้าไม่มาเป็นผู้เปลี่ยน
+		assert.Nil(t, syncReservedTables(base.Add(50*time.Minute)))
+		assert.Equal(t, "reserved", tableStatus(table.ID))
+		assert.Equal(t, "active", reservationStatus(first.ID))
+		markNoShowReservations(base.Add(50 * time.Minute))
+		assert.Equal(t, "no_show", reservationStatus(first.ID))
+		assert.Equal(t, "active", reservationStatus(second.ID))
+		assert.Equal(t, "reserved", tableStatus(table.ID))
+
+		var releases int64
+		db.DB.Model(&models.TableHistory{}).Where("table_id = ? AND action = ?", table.ID, models.TableActionRelease).Count(&releases)
+		assert.Equal(t, int64(0), releases)
+
+		assert.Nil(t, syncReservedTables(base.Add(90*time.Minute)))
+		assert.Equal(t, "available", tableStatus(table.ID))
+		markNoShowReservations(base.Add(90 * time.Minute))
+		assert.Equal(t, "no_show", reservationStatus(second.ID))
+
+		var notifications int64
+		db.DB.Model(&models.ReservationNotification{}).
+			Where("reservation_id IN ? AND kind = ?", []uint{first.ID, second.ID}, models.NotificationNoShow).Count(&notifications)
+		assert.Equal(t, int64(2), notifications)
+	})
+
+	// กรณีทดสอบที่ 3: ลูกค้าไม่มา คืนโต๊ะเฉพาะเมื่อไม่มีรอบถัดไปกั้นอยู่ และไม่เปลี่ยนโต๊ะที่มีลูกค้านั่ง
+	t.Run("Success - No-show keeps next reservation and seated guests", func(t *testing.T) {
+		base := now.Add(96 * time.Hour).Truncate(time.Hour)
+		held := models.Table{Name: "C1", Capacity: 2, Status: "reserved"}
+		seated := models.Table{Name: "C2", Capacity: 2, Status: "occupied"}
+		db.DB.Create(&held)
+		db.DB.Create(&seated)
+		missed := models.TableReservation{
+			TableID: held.ID, CustomerName: "ไม่มา", PhoneNumber: "0833333333", GuestCount: 2, Status: "active",
+			TableBlockedFrom: base, ReservedFor: base.Add(30 * time.Minute), GracePeriodUntil: base.Add(45 * time.Minute),
+		}
+		next := models.TableReservation{
+			TableID: held.ID, CustomerName: "รอบถัดไป", PhoneNumber: "0844444444", GuestCount: 2, Status: "active",
+			TableBlockedFrom: base.Add(40 * time.Minute), ReservedFor: base.Add(70 * time.Minute), GracePeriodUntil: base.Add(85 * time.Minute),
+		}
+		walkIn := models.TableReservation{
+			TableID: seated.ID, CustomerName: "ไม่มา", PhoneNumber: "0855555555", GuestCount: 2, Status: "active",
+			TableBlockedFrom: base, ReservedFor: base.Add(30 * time.Minute), GracePeriodUntil: base.Add(45 * time.Minute),
+		}
+		for _, reservation := range []*models.TableReservation{&missed, &next, &walkIn} {
+			db.DB.Create(reservation)
+		}
+
+		markNoShowReservations(base.Add(50 * time.Minute))
+		assert.Equal(t, "no_show", reservationStatus(missed.ID))
+		assert.Equal(t, "no_show", reservationStatus(walkIn.ID))
+		assert.Equal(t, "active", reservationStatus(next.ID))
+		assert.Equal(t, "reserved", tableStatus(held.ID))
+		assert.Equal(t, "occupied", tableStatus(seated.ID))
+	})
+}
@@ -0,0 +1,137 @@
+package api_handlers
+
+import (
+	"food-ordering-api/db"
+	service "food-ordering-api/services"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// queryZoneID โซนที่ลูกค้าต้องการจาก ?zone_id= (ไม่ส่งหรือ 0 = ไม่ระบุ)
+func queryZoneID(c *fiber.Ctx) *uint {
+	if id := c.QueryInt("zone_id", 0); id > 0 {
+		zoneID := uint(id)
+		return &zoneID
+	}
+	return nil
+}
+
+// @Summary เสนอโต๊ะให้ลูกค้าอัตโนมัติ
+// @Description หาโต๊ะเดี่ยว โต๊ะรวมที่มีอยู่ หรือโต๊ะที่ติดกันซึ่งรวมได้ ที่นั่งพอและไม่ติดการจองในช่วงเวลาที่ลูกค้าใช้โต๊ะ
+// @Description ถ้าลูกค้ามาตอนนี้จะดูสถานะโต๊ะปัจจุบันด้วย เรียงจากเหมาะที่สุด (ที่นั่งเหลือน้อย ไม่ต้องรวมโต๊ะ อยู่ในโซนที่ต้องการ)
+// @Description โต๊ะที่ใหญ่เกินไป เช่น 2 คนนั่งโต๊ะ 8 ที่ จะมี oversized = true และอยู่ท้ายสุด
+// @Produce json
+// @Param party_size query integer true "จำนวนลูกค้า"
+// @Param at query string false "เวลาที่ลูกค้าจะนั่ง (RFC3339) ไม่ส่ง = ตอนนี้"
+// @Param zone_id query integer false "โซนที่ลูกค้าต้องการ"
+// @Param limit query integer false "จำนวนข้อเสนอ (ค่าเริ่มต้น 5)"
+// @Success 200 {array} service.TableAllocation "โต๊ะที่เสนอ"
+// @Failure 400 {object} map[string]interface{} "พารามิเตอร์ไม่ถูกต้อง"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
+// @Router /api/table/allocate [get]
+// @Tags Table
+func GetTableAllocation(c *fiber.Ctx) error {
+	partySize := c.QueryInt("party_size", 0)
+	if partySize <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "จำนวนลูกค้าต้องมากกว่า 0",
+		})
+	}
+	var at time.Time
+	if value := c.Query("at"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid at, use RFC3339",
+			})
+		}
+		at = t
+	}
+
+	rules, err := activeReservationRules(db.DB)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch reservation rules",
+		})
+	}
+	dining, err := reservationDiningDuration(db.DB, rules)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to calculate dining duration",
+		})
+	}
+
+	allocations, err := service.AllocateTables(db.DB, service.AllocationRequest{
+		PartySize: partySize,
+		At:        at,
+		Duration:  dining,
+		ZoneID:    queryZoneID(c),
+		Limit:     c.QueryInt("limit", 5),
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to allocate tables",
+		})
+	}
+	return c.JSON(allocations)
+}
+
+// @Summary จองโต๊ะโดยเลือกโต๊ะให้อัตโนมัติ
+// @Description เลือกโต๊ะเดี่ยวหรือโต๊ะรวมที่มีอยู่ที่เหมาะที่สุดในเวลาที่จองแล้วจอง ถ้าต้องรวมโต๊ะใหม่หรือมีแต่โต๊ะที่ใหญ่เกินไป
+// @Description จะไม่จองและคืนข้อเสนอให้พนักงานเลือก (รวมโต๊ะแล้วจองด้วย /api/table/reservedTable/{id})
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ReservationRequest true "ข้อมูลการจองโต๊ะ"
+// @Param zone_id query integer false "โซนที่ลูกค้าต้องการ"
+// @Success 200 {object} map[string]interface{} "จองโต๊ะสำเร็จ"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 409 {object} map[string]interface{} "ไม่มีโต๊ะที่จองได้ทันที พร้อมข้อเสนอ"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดภายในระบบ"
+// @Router /api/table/reservedTable/auto [post]
+// @Tags Table
+func AutoReserveTable(c *fiber.Ctx) error {
+	var req ReservationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "ข้อมูลการจองไม่ถูกต้อง",
+		})
+	}
+	if msg := validateReservationRequest(req); msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
+	}
+
+	rules, err := activeReservationRules(db.DB)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "ไม่สามารถดึงกฎการจองได้"})
+	}
+	dining, err := reservationDiningDuration(db.DB, rules)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "ไม่สามารถคำนวณเวลาที่ใช้โต๊ะได้"})
+	}
+
+	// โต๊ะต้องว่างตั้งแต่เวลากั้นโต๊ะก่อนเวลาจองจนลูกค้ากินเสร็จ
+	allocations, err := service.AllocateTables(db.DB, service.AllocationRequest{
+		PartySize: req.GuestCount,
+		At:        req.ReservedFor,
+		Duration:  dining,
+		Lead:      time.Duration(rules.PreReservationMinutes) * time.Minute,
+		ZoneID:    queryZoneID(c),
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "ไม่สามารถค้นหาโต๊ะได้"})
+	}
+
+	if allocation := service.BookableAllocation(allocations); allocation != nil {
+		return reserveTable(c, strconv.FormatUint(uint64(allocation.TableID), 10), req)
+	}
+	if len(allocations) > 5 {
+		allocations = allocations[:5]
+	}
+	return c.Status(409).JSON(fiber.Map{
+		"error":       "ไม่มีโต๊ะที่จองได้ทันที",
+		"allocations": allocations,
+	})
+}
@@ -0,0 +1,150 @@
+package api_handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableAllocation(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.Table{}, &models.TableZone{}, &models.TableSession{}, &models.TableReservation{},
+		&models.ReservationRules{}, &models.TableHistory{}, &models.ReservationNotification{})
+
+	app.Get("/api/table/allocate", GetTableAllocation)
+	app.Post("/api/table/reservedTable/auto", AutoReserveTable)
+
+	allocate := func(query string) []service.TableAllocation {
+		resp := sendJSON(t, app, "GET", "/api/table/allocate?"+query, nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var allocations []service.TableAllocation
+		json.NewDecoder(resp.Body).Decode(&allocations)
+		return allocations
+	}
+
+	db.DB.Create(&models.ReservationRules{GracePeriodMinutes: 15, PreReservationMinutes: 15, IsActive: true})
+	hall := models.TableZone{Name: "ห้องแอร์"}
+	terrace := models.TableZone{Name: "ระเบียง"}
+	db.DB.Create(&hall)
+	db.DB.Create(&terrace)
+
+	// โต๊ะ 2 ที่สองตัวติดกัน โต๊ะ 2 ที่อีกมุมหนึ่ง และโต๊ะ 8 ที่ในห้องแอร์ โต๊ะ 4 ที่ที่ระเบียง
+	t2a := models.Table{Name: "A1", Capacity: 2, Status: "available", ZoneID: &hall.ID, Width: 1, Height: 1}
+	t2b := models.Table{Name: "A2", Capacity: 2, Status: "available", ZoneID: &hall.ID, PosX: 1, Width: 1, Height: 1}
+	t2c := models.Table{Name: "A3", Capacity: 2, Status: "available", ZoneID: &hall.ID, PosX: 5, PosY: 5, Width: 1, Height: 1}
+	t8 := models.Table{Name: "A8", Capacity: 8, Status: "available", ZoneID: &hall.ID}
+	t4 := models.Table{Name: "T1", Capacity: 4, Status: "available", ZoneID: &terrace.ID}
+	for _, table := range []*models.Table{&t2a, &t2b, &t2c, &t8, &t4} {
+		db.DB.Create(table)
+	}
+
+	// กรณีทดสอบที่ 1: ลูกค้า 2 คนได้โต๊ะ 2 ที่ โต๊ะ 8 ที่ถูกมองว่าใหญ่เกินไปและอยู่ท้ายสุด
+	t.Run("Success - Small party avoids large table", func(t *testing.T) {
+		allocations := allocate("party_size=2&limit=10")
+		assert.Len(t, allocations, 5)
+		assert.Equal(t, t2a.ID, allocations[0].TableID)
+		assert.Equal(t, 0, allocations[0].Score)
+		last := allocations[len(allocations)-1]
+		assert.Equal(t, t8.ID, last.TableID)
+		assert.True(t, last.Oversized)
+
+		resp := sendJSON(t, app, "GET", "/api/table/allocate?party_size=0", nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 2: ลูกค้า 4 คนอยากนั่งห้องแอร์ เสนอรวมโต๊ะที่ติดกันก่อนโต๊ะ 4 ที่ในโซนอื่น
+	t.Run("Success - Zone preference and mergeable tables", func(t *testing.T) {
+		allocations := allocate(fmt.Sprintf("party_size=4&zone_id=%d", hall.ID))
+		assert.True(t, allocations[0].NeedsMerge)
+		assert.Equal(t, []uint{t2a.ID, t2b.ID}, allocations[0].TableIDs)
+		assert.Equal(t, "A1+A2", allocations[0].Name)
+		assert.Equal(t, t4.ID, allocations[1].TableID)
+		for _, allocation := range allocations {
+			assert.NotContains(t, allocation.TableIDs, t2c.ID, "A3 อยู่ห่างออกไป รวมกับโต๊ะอื่นไม่ได้")
+		}
+	})
+
+	// กรณีทดสอบที่ 3: โต๊ะที่มีการจองในช่วงเวลาที่ลูกค้านั่งจะไม่ถูกเสนอ แต่หลังลูกค้าที่จองกินเสร็จเสนอได้
+	t.Run("Success - Reservation windows block tables", func(t *testing.T) {
+		reservedFor := time.Now().Add(time.Hour)
+		db.DB.Create(&models.TableReservation{
+			TableID: t4.ID, CustomerName: "สมชาย", PhoneNumber: "0812345678", GuestCount: 4, Status: "active",
+			ReservedFor: reservedFor, TableBlockedFrom: reservedFor.Add(-15 * time.Minute), GracePeriodUntil: reservedFor.Add(15 * time.Minute),
+		})
+
+		for _, allocation := range allocate("party_size=4&limit=10") {
+			assert.NotEqual(t, t4.ID, allocation.TableID)
+		}
+
+		later := url.QueryEscape(time.Now().Add(4 * time.Hour).Format(time.RFC3339))
+		allocations := allocate("party_size=4&at=" + later)
+		assert.Equal(t, t4.ID, allocations[0].TableID)
+	})
+
+	// กรณีทดสอบที่ 4: จองอัตโนมัติเลือกโต๊ะที่เหมาะที่สุด ถ้าต้องรวมโต๊ะใหม่จะคืนข้อเสนอแทน
+	t.Run("Success - Auto reserve", func(t *testing.T) {
+		reserve := func(guests int) *http.Response {
+			return sendJSON(t, app, "POST", "/api/table/reservedTable/auto", ReservationRequest{
+				CustomerName: "สมหญิง", PhoneNumber: "0899999999", GuestCount: guests,
+				ReservedFor: time.Now().Add(4 * time.Hour),
+			})
+		}
+
+		resp := reserve(4)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		assert.Equal(t, float64(t4.ID), result["table_id"])
+
+		// 12 คนต้องรวม A1+A2+A8 ก่อน จึงยังไม่จอง
+		resp = reserve(12)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		var conflict map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&conflict)
+		assert.NotEmpty(t, conflict["allocations"])
+
+		resp = reserve(6)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		json.NewDecoder(resp.Body).Decode(&result)
+		assert.Equal(t, float64(t8.ID), result["table_id"])
+	})
+
+	// กรณีทดสอบที่ 5: โต๊ะรวมที่มีอยู่แล้วถูกเสนอได้ แต่จองอัตโนมัติไม่ได้ เพราะการจองผูกกับโต๊ะเดียว
+	t.Run("Success - Existing merged group is not auto reserved", func(t *testing.T) {
+		groupID := "merged-g1"
+		g1 := models.Table{Name: "G1", Capacity: 4, Status: "available", ZoneID: &terrace.ID, GroupID: &groupID}
+		db.DB.Create(&g1)
+		g2 := models.Table{Name: "G2", Capacity: 4, Status: "available", ZoneID: &terrace.ID, GroupID: &groupID, ParentID: &g1.ID}
+		db.DB.Create(&g2)
+
+		later := url.QueryEscape(time.Now().Add(4 * time.Hour).Format(time.RFC3339))
+		allocations := allocate("party_size=7&at=" + later)
+		assert.Len(t, allocations, 1)
+		assert.Equal(t, g1.ID, allocations[0].TableID)
+		assert.Equal(t, 8, allocations[0].Capacity)
+		assert.False(t, allocations[0].Bookable())
+
+		resp := sendJSON(t, app, "POST", "/api/table/reservedTable/auto", ReservationRequest{
+			CustomerName: "สมศักดิ์", PhoneNumber: "0877777777", GuestCount: 7,
+			ReservedFor: time.Now().Add(4 * time.Hour),
+		})
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		var conflict map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&conflict)
+		assert.NotEmpty(t, conflict["allocations"])
+
+		var count int64
+		db.DB.Model(&models.TableReservation{}).Where("table_id = ?", g1.ID).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+}
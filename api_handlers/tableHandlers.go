@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"food-ordering-api/db"
 	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	utils "food-ordering-api/utility"
 	"net/http"
 	"slices"
 	"strconv"
@@ -19,6 +21,7 @@ type Table struct {
 	Name     string `json:"name"`
 	Capacity int    `json:"capacity"`
 	Status   string `json:"status"`
+	ZoneID   *uint  `json:"zone_id"` // โซนของโต๊ะ ตอนแก้ไขไม่ส่ง = คงเดิม ส่ง 0 = ไม่อยู่ในโซนใด
 }
 
 func hasGroupID(groupID *string) bool {
@@ -98,19 +101,39 @@ func UpdateTable(c *fiber.Ctx) error {
 		})
 	}
 
+	before := table
 	if req.Status != "string" {
 		table.Status = req.Status
 	}
 
+	if req.ZoneID != nil {
+		if *req.ZoneID == 0 {
+			table.ZoneID = nil
+		} else if err := validateZoneID(db.DB, *req.ZoneID); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		} else {
+			table.ZoneID = req.ZoneID
+		}
+	}
+
 	table.Name = req.Name
 	table.Capacity = req.Capacity
 
-	if err := db.DB.Save(&table).Error; err != nil {
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&table).Error; err != nil {
+			return err
+		}
+		return service.RecordTableHistory(tx, service.NewTableHistory(models.TableActionUpdate, before, table,
+			models.TableHistory{StaffID: utils.GetStaffID(c)}))
+	}); err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "เกิดข้อผิดพลาดในการแก้ไขข้อมูลโต๊ะ",
 		})
 	}
 
+	service.PublishTableChange(service.TableEventUpdated, table.ID)
 	return c.JSON(table)
 }
 
@@ -158,12 +181,19 @@ func DeleteTable(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := db.DB.Delete(&table).Error; err != nil {
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&table).Error; err != nil {
+			return err
+		}
+		return service.RecordTableHistory(tx, service.NewTableHistory(models.TableActionDelete, table, table,
+			models.TableHistory{StaffID: utils.GetStaffID(c)}))
+	}); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
 			"error": "เกิดข้อผิดพลาดในการลบ",
 		})
 	}
 
+	service.PublishTableChange(service.TableEventDeleted, table.ID)
 	return c.Status(200).JSON(fiber.Map{
 		"ok": "ok",
 	})
@@ -210,18 +240,37 @@ func Addtable(c *fiber.Ctx) error {
 		})
 	}
 
+	if req.ZoneID != nil && *req.ZoneID == 0 {
+		req.ZoneID = nil
+	}
+	if req.ZoneID != nil {
+		if err := validateZoneID(db.DB, *req.ZoneID); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
 	table := models.Table{
 		Name:     req.Name,
 		Capacity: req.Capacity,
 		Status:   "available",
+		ZoneID:   req.ZoneID,
 	}
 
-	if err := db.DB.Create(&table).Error; err != nil {
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&table).Error; err != nil {
+			return err
+		}
+		return service.RecordTableHistory(tx, service.NewTableHistory(models.TableActionCreate, models.Table{}, table,
+			models.TableHistory{StaffID: utils.GetStaffID(c)}))
+	}); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
 			"error": "ไม่สามารถเพิ่่มโต๊ะได้",
 		})
 	}
 
+	service.PublishTableChange(service.TableEventCreated, table.ID)
 	return c.JSON(table)
 }
 
@@ -285,6 +334,12 @@ func MergeTables(c *fiber.Ctx) error {
 		}
 	}
 
+	change, err := service.BeginTableChange(tx, models.TableActionMerge, req.TableIDs...)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to read table state"})
+	}
+
 	// 3. สร้าง Group ID และผูกโต๊ะเข้าด้วยกัน
 	groupID := uuid.New().String()
 	mainTableID := req.TableIDs[0]
@@ -304,6 +359,13 @@ func MergeTables(c *fiber.Ctx) error {
 		}
 	}
 
+	change.Entry.RelatedTableID = &mainTableID
+	change.Entry.StaffID = utils.GetStaffID(c)
+	if err := change.Record(tx); err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to record table history"})
+	}
+
 	// if err := tx.Model(&models.QRCode{}).
 	// 	Where("table_id IN ? AND is_active = ?", req.TableIDs[1:], true).
 	// 	Update("is_active", false).Error; err != nil {
@@ -314,6 +376,7 @@ func MergeTables(c *fiber.Ctx) error {
 	if err := tx.Commit().Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to commit changes"})
 	}
+	service.PublishTableChange(service.TableEventMerged, req.TableIDs...)
 
 	return c.JSON(fiber.Map{
 		"message":       "Tables merged successfully",
@@ -391,11 +454,11 @@ func MoveTable(c *fiber.Ctx) error {
 		})
 	}
 
-	// 3. ดึง QR code ที่กำลังใช้งานของโต๊ะต้นทาง
-	var currentQR models.QRCode
-	if err := tx.Where("table_id = ? AND is_active = ?", fromTable.ID, true).First(&currentQR).Error; err != nil {
+	// 3. ดึง session ที่เปิดอยู่ของโต๊ะต้นทาง
+	session, err := service.FindOpenSessionForTable(tx, fromTable.ID)
+	if err != nil {
 		tx.Rollback()
-		return c.Status(500).JSON(fiber.Map{"error": "Could not find active QR code for source table"})
+		return c.Status(500).JSON(fiber.Map{"error": "Could not find open session for source table"})
 	}
 
 	// ดึงข้อมูลเครื่องพิมพ์ทั้งหมดที่ไม่ใช่ main
@@ -434,6 +497,17 @@ func MoveTable(c *fiber.Ctx) error {
 		}
 	}
 
+	fromChange, err := service.BeginTableChange(tx, models.TableActionMove, fromTable.ID)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to read table state"})
+	}
+	toChange, err := service.BeginTableChange(tx, models.TableActionMove, toTable.ID)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to read table state"})
+	}
+
 	// 4. อัพเดตสถานะโต๊ะ
 	if err := tx.Model(&fromTable).Update("status", "available").Error; err != nil {
 		tx.Rollback()
@@ -445,23 +519,31 @@ func MoveTable(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to update destination table status"})
 	}
 
-	// 5. อัพเดต table_id ใน QR code
-	if err := tx.Model(&currentQR).Update("table_id", toTable.ID).Error; err != nil {
+	// 5. ย้าย session ไปโต๊ะปลายทาง พร้อม QR code และออเดอร์ที่สั่งจากโต๊ะต้นทาง
+	movedOrderIDs, err := service.MoveTableSession(tx, session, fromTable.ID, toTable.ID)
+	if err != nil {
 		tx.Rollback()
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to update QR code table reference"})
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to move table session"})
 	}
 
-	// ย้ายเฉพาะออเดอร์ของ QR code ปัจจุบัน
-	if err := tx.Model(&models.Order{}).
-		Where("table_id = ? AND uuid = ? AND status != ?",
-			fromTable.ID,
-			currentQR.UUID,
-			"cancelled").
-		Update("table_id", toTable.ID).Error; err != nil {
+	// 6. บันทึกประวัติทั้งสองโต๊ะ โดยอ้างอิงโต๊ะอีกฝั่งและออเดอร์ที่ย้ายไป
+	staffID := utils.GetStaffID(c)
+	record := func(change *service.TableChange, relatedTableID uint) error {
+		change.Entry = models.TableHistory{
+			RelatedTableID: &relatedTableID,
+			SessionID:      &session.ID,
+			OrderIDs:       movedOrderIDs,
+			StaffID:        staffID,
+		}
+		return change.Record(tx)
+	}
+	if err := record(fromChange, toTable.ID); err != nil {
 		tx.Rollback()
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to update orders",
-		})
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to record table history"})
+	}
+	if err := record(toChange, fromTable.ID); err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to record table history"})
 	}
 
 	var updatedFromTable, updatedToTable models.Table
@@ -469,17 +551,19 @@ func MoveTable(c *fiber.Ctx) error {
 
 	tx.First(&updatedFromTable, fromTable.ID)
 	tx.First(&updatedToTable, toTable.ID)
-	tx.First(&updatedQR, currentQR.ID)
+	tx.Where("session_id = ?", session.ID).Order("id DESC").First(&updatedQR)
 
 	if err := tx.Commit().Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to commit changes"})
 	}
+	service.PublishTableChange(service.TableEventMoved, fromTable.ID, toTable.ID)
 
 	return c.JSON(fiber.Map{
 		"message":    "Table moved successfully",
 		"from_table": updatedFromTable,
 		"to_table":   updatedToTable,
 		"qr_code":    updatedQR,
+		"session_id": session.ID,
 	})
 }
 
@@ -523,7 +607,17 @@ func SplitTables(c *fiber.Ctx) error {
 		}
 	}
 
-	// 2. คืนสถานะโต๊ะให้เป็นโต๊ะเดี่ยว
+	splitIDs := make([]uint, 0, len(tables))
+	for _, table := range tables {
+		splitIDs = append(splitIDs, table.ID)
+	}
+	change, err := service.BeginTableChange(tx, models.TableActionSplit, splitIDs...)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to read table state"})
+	}
+
+	// 2. คืนสถานะโต๊ะให้เป็นโต๊ะเดี่ยว (โต๊ะว่างทั้งหมดจึงไม่มี session ที่ต้องแยก)
 	for _, table := range tables {
 		if err := tx.Model(&table).Updates(map[string]interface{}{
 			"group_id":  nil,
@@ -532,19 +626,18 @@ func SplitTables(c *fiber.Ctx) error {
 			tx.Rollback()
 			return c.Status(500).JSON(fiber.Map{"error": "Failed to split tables"})
 		}
+	}
 
-		// เปิดใช้งาน QR Code ของแต่ละโต๊ะ
-		if err := tx.Model(&models.QRCode{}).
-			Where("table_id = ?", table.ID).
-			Update("is_active", true).Error; err != nil {
-			tx.Rollback()
-			return c.Status(500).JSON(fiber.Map{"error": "Failed to update QR codes"})
-		}
+	change.Entry.StaffID = utils.GetStaffID(c)
+	if err := change.Record(tx); err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to record table history"})
 	}
 
 	if err := tx.Commit().Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to commit changes"})
 	}
+	service.PublishTableChange(service.TableEventSplit, splitIDs...)
 
 	return c.JSON(fiber.Map{
 		"message": "Tables split successfully",
@@ -556,10 +649,16 @@ type ReservationRequest struct {
 	PhoneNumber  string    `json:"phone_number"`
 	GuestCount   int       `json:"guest_count"`
 	ReservedFor  time.Time `json:"reserved_for"`
+	Note         string    `json:"note"`
+	Email        string    `json:"email"`        // สำหรับส่งข้อความทางอีเมล (ถ้ามี)
+	LineUserID   string    `json:"line_user_id"` // สำหรับส่งข้อความทาง LINE (ถ้ามี)
+	Language     string    `json:"language"`     // ภาษาของข้อความถึงลูกค้า th (ค่าเริ่มต้น), en
+	Source       string    `json:"-"`            // ที่มาของการจอง ค่าว่าง = pos
 }
 
 // @Summary จองโต๊ะ
-// @Description จองโต๊ะโดยใช้กฎการจองที่กำหนดไว้
+// @Description จองโต๊ะโดยใช้กฎการจองที่กำหนดไว้ โต๊ะเดียวกันจองได้หลายรอบต่อวันถ้าช่วงเวลาไม่ทับกัน
+// @Description แต่ละการจองใช้โต๊ะตั้งแต่เวลากั้นโต๊ะจนครบเวลาที่ใช้โต๊ะ (dining_duration_minutes ในกฎการจอง)
 // @Accept json
 // @Produce json
 // @Security BearerAuth
@@ -588,28 +687,50 @@ func ReservedTable(c *fiber.Ctx) error {
 		})
 	}
 
-	// Validation
+	if msg := validateReservationRequest(req); msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
+	}
+
+	return reserveTable(c, tableID, req)
+}
+
+// validateReservationRequest ตรวจข้อมูลการจอง คืนข้อความผิดพลาดหรือค่าว่างถ้าถูกต้อง
+func validateReservationRequest(req ReservationRequest) string {
 	if req.CustomerName == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "กรุณาระบุชื่อลูกค้า"})
+		return "กรุณาระบุชื่อลูกค้า"
 	}
 	if req.PhoneNumber == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "กรุณาระบุเบอร์โทรศัพท์"})
+		return "กรุณาระบุเบอร์โทรศัพท์"
 	}
 	if req.GuestCount <= 0 {
-		return c.Status(400).JSON(fiber.Map{"error": "จำนวนลูกค้าต้องมากกว่า 0"})
+		return "จำนวนลูกค้าต้องมากกว่า 0"
 	}
 	if req.ReservedFor.Before(time.Now()) {
-		return c.Status(400).JSON(fiber.Map{"error": "ไม่สามารถจองย้อนหลังได้"})
+		return "ไม่สามารถจองย้อนหลังได้"
+	}
+	if req.Language != "" && req.Language != "th" && req.Language != "en" {
+		return "ภาษาต้องเป็น th หรือ en"
 	}
+	return ""
+}
 
+// reserveTable จองโต๊ะตามกฎการจองที่ใช้งานอยู่ ตรวจสถานะ ความจุ และการจองซ้อนของโต๊ะ แล้วตอบกลับผลการจอง
+func reserveTable(c *fiber.Ctx, tableID string, req ReservationRequest) error {
 	tx := db.DB.Begin()
 
 	// ดึง active rule
-	var activeRule models.ReservationRules
-	if err := tx.Where("is_active = ?", true).First(&activeRule).Error; err != nil {
+	activeRule, err := activeReservationRules(tx)
+	if err != nil {
 		tx.Rollback()
 		return c.Status(500).JSON(fiber.Map{
-			"error": "ไม่พบกฎการจองที่ใช้งานอยู่",
+			"error": "ไม่สามารถดึงกฎการจองได้",
+		})
+	}
+	dining, err := reservationDiningDuration(tx, activeRule)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{
+			"error": "ไม่สามารถคำนวณเวลาที่ใช้โต๊ะได้",
 		})
 	}
 
@@ -620,83 +741,167 @@ func ReservedTable(c *fiber.Ctx) error {
 		return c.Status(404).JSON(fiber.Map{"error": "ไม่พบโต๊ะที่ระบุ"})
 	}
 
-	if table.Status != "available" {
-		tx.Rollback()
-		return c.Status(400).JSON(fiber.Map{"error": "โต๊ะไม่ว่าง"})
-	}
+	// คำนวณเวลา
+	now := time.Now()
+	tableBlockedFrom, gracePeriodUntil := reservationWindow(activeRule, req.ReservedFor)
 
-	if table.Capacity < req.GuestCount {
+	// ตรวจสอบสถานะ ความจุ และการจองซ้ำซ้อนตามช่วงเวลา
+	if status, body := checkTableReservable(tx, table, req.GuestCount, req.ReservedFor, dining, 0, activeRule); status != 0 {
 		tx.Rollback()
-		return c.Status(400).JSON(fiber.Map{
-			"error":            "จำนวนลูกค้าเกินความจุของโต๊ะ",
-			"table_capacity":   table.Capacity,
-			"requested_guests": req.GuestCount,
-		})
+		return c.Status(status).JSON(body)
 	}
 
-	// คำนวณเวลา
-	now := time.Now()
-	tableBlockedFrom := req.ReservedFor.Add(-time.Duration(activeRule.PreReservationMinutes) * time.Minute)
-	gracePeriodUntil := req.ReservedFor.Add(time.Duration(activeRule.GracePeriodMinutes) * time.Minute)
-
-	// ตรวจสอบการจองซ้ำซ้อน
-	var conflictReservation models.TableReservation
-	if err := tx.Where(`
-			table_id = ? AND status = 'active' AND (
-					(table_blocked_from BETWEEN ? AND ?) OR
-					(grace_period_until BETWEEN ? AND ?) OR
-					(? BETWEEN table_blocked_from AND grace_period_until)
-			)`,
-		tableID, tableBlockedFrom, gracePeriodUntil,
-		tableBlockedFrom, gracePeriodUntil,
-		req.ReservedFor,
-	).First(&conflictReservation).Error; err == nil {
+	code, err := newReservationCode(tx)
+	if err != nil {
 		tx.Rollback()
-		return c.Status(409).JSON(fiber.Map{
-			"error": "มีการจองในช่วงเวลานี้แล้ว",
-		})
+		return c.Status(500).JSON(fiber.Map{"error": "ไม่สามารถสร้างรหัสยืนยันการจองได้"})
+	}
+	source := req.Source
+	if source == "" {
+		source = models.ReservationSourcePOS
+	}
+	language := req.Language
+	if language == "" {
+		language = "th"
 	}
 
 	// สร้างการจอง
 	reservation := models.TableReservation{
 		TableID:          uint(table.ID),
+		ConfirmationCode: &code,
+		Source:           source,
 		CustomerName:     req.CustomerName,
 		PhoneNumber:      req.PhoneNumber,
+		Email:            req.Email,
+		LineUserID:       req.LineUserID,
+		Language:         language,
 		GuestCount:       req.GuestCount,
+		Note:             req.Note,
 		ReservedFor:      req.ReservedFor,
 		TableBlockedFrom: tableBlockedFrom,
 		GracePeriodUntil: gracePeriodUntil,
+		NoShowFee:        activeRule.NoShowFee,
 		Status:           "active",
 	}
+	applyReservationDeposit(&reservation, activeRule)
 
 	if err := tx.Create(&reservation).Error; err != nil {
 		tx.Rollback()
 		return c.Status(500).JSON(fiber.Map{"error": "ไม่สามารถบันทึกการจองได้"})
 	}
 
-	// อัพเดทสถานะโต๊ะเฉพาะเมื่อถึงเวลา tableBlockedFrom
-	if now.After(tableBlockedFrom) || now.Equal(tableBlockedFrom) {
+	// ส่งข้อความยืนยันการจองถึงลูกค้าหลังบันทึกสำเร็จ (ManageReservationNotifications เป็นผู้ส่ง)
+	if err := service.QueueReservationNotification(tx, reservation.ID, models.NotificationConfirmation); err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{"error": "ไม่สามารถบันทึกข้อความยืนยันการจองได้"})
+	}
+
+	change, err := service.BeginTableChange(tx, models.TableActionReserve, table.ID)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{"error": "ไม่สามารถอ่านสถานะโต๊ะได้"})
+	}
+
+	// อัพเดทสถานะโต๊ะเฉพาะเมื่อถึงเวลา tableBlockedFrom และโต๊ะว่างอยู่
+	// ถ้ายังไม่ถึง ManageTableReservationStatus จะเปลี่ยนให้เมื่อถึงเวลา
+	if !now.Before(tableBlockedFrom) && table.Status == "available" {
 		if err := tx.Model(&table).Update("status", "reserved").Error; err != nil {
 			tx.Rollback()
 			return c.Status(500).JSON(fiber.Map{"error": "ไม่สามารถอัพเดทสถานะโต๊ะได้"})
 		}
 	}
 
+	change.Entry.StaffID = utils.GetStaffID(c)
+	change.Entry.Note = fmt.Sprintf("reservation #%d", reservation.ID)
+	if err := change.Record(tx); err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{"error": "ไม่สามารถบันทึกประวัติโต๊ะได้"})
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "เกิดข้อผิดพลาดในการบันทึกข้อมูล"})
 	}
+	service.PublishTableChange(service.TableEventReserved, table.ID)
 
 	return c.Status(200).JSON(fiber.Map{
 		"message":            "จองโต๊ะสำเร็จ",
 		"reservation_id":     reservation.ID,
+		"confirmation_code":  code,
+		"table_id":           table.ID,
+		"table_name":         table.Name,
 		"table_blocked_from": tableBlockedFrom,
 		"reserved_for":       req.ReservedFor,
 		"grace_period_until": gracePeriodUntil,
+		"deposit_amount":     reservation.DepositAmount,
+		"deposit_status":     reservation.DepositStatus,
+		"no_show_fee":        reservation.NoShowFee,
 	})
 }
 
+// reservationWindow เวลาเริ่มกั้นโต๊ะและเวลาสิ้นสุด grace period ของการจองตามกฎการจอง
+func reservationWindow(rule models.ReservationRules, reservedFor time.Time) (time.Time, time.Time) {
+	return reservedFor.Add(-time.Duration(rule.PreReservationMinutes) * time.Minute),
+		reservedFor.Add(time.Duration(rule.GracePeriodMinutes) * time.Minute)
+}
+
+// applyReservationDeposit คำนวณเงินมัดจำตามจำนวนลูกค้าและกฎการจอง ถ้าลูกค้าชำระแล้วไม่เปลี่ยน
+func applyReservationDeposit(reservation *models.TableReservation, rule models.ReservationRules) {
+	if reservation.DepositStatus != "" && reservation.DepositStatus != models.DepositNone && reservation.DepositStatus != models.DepositPending {
+		return
+	}
+	reservation.DepositAmount = rule.DepositPerGuest * float64(reservation.GuestCount)
+	reservation.DepositStatus = models.DepositNone
+	if reservation.DepositAmount > 0 {
+		reservation.DepositStatus = models.DepositPending
+	}
+}
+
+// checkTableReservable ตรวจว่าโต๊ะรับการจองของลูกค้า guestCount คนเวลา reservedFor ได้หรือไม่
+// ตรวจสถานะ ความจุ การจองอื่นที่ทับช่วงเวลา (ยกเว้น excludeID) และลูกค้าที่นั่งอยู่ตอนนี้
+// คืน HTTP status และข้อความตอบกลับเมื่อจองไม่ได้ หรือ 0 เมื่อจองได้
+func checkTableReservable(tx *gorm.DB, table models.Table, guestCount int, reservedFor time.Time, dining time.Duration, excludeID uint, rule models.ReservationRules) (int, fiber.Map) {
+	if table.Status == "unavailable" {
+		return 400, fiber.Map{"error": "โต๊ะนี้ปิดให้บริการ"}
+	}
+
+	if table.Capacity < guestCount {
+		return 400, fiber.Map{
+			"error":            "จำนวนลูกค้าเกินความจุของโต๊ะ",
+			"table_capacity":   table.Capacity,
+			"requested_guests": guestCount,
+		}
+	}
+
+	// การจองใช้โต๊ะตั้งแต่เวลากั้นโต๊ะจนลูกค้ากินเสร็จ
+	blockedFrom, gracePeriodUntil := reservationWindow(rule, reservedFor)
+	reservedUntil := service.ReservationUntil(models.TableReservation{
+		ReservedFor:      reservedFor,
+		GracePeriodUntil: gracePeriodUntil,
+	}, dining)
+
+	// ตรวจสอบการจองซ้ำซ้อนตามช่วงเวลา ไม่ใช่สถานะโต๊ะตอนนี้ จึงจองโต๊ะเดียวกันได้หลายรอบในวันเดียว
+	conflict, err := service.FindReservationConflict(tx, table.ID, blockedFrom, reservedUntil, dining, excludeID)
+	if err != nil {
+		return 500, fiber.Map{"error": "ไม่สามารถตรวจสอบการจองได้"}
+	}
+	if conflict != nil {
+		return 409, fiber.Map{
+			"error":                   "มีการจองในช่วงเวลานี้แล้ว",
+			"conflict_reservation_id": conflict.ID,
+			"conflict_reserved_for":   conflict.ReservedFor,
+		}
+	}
+
+	// ลูกค้าที่นั่งอยู่ตอนนี้ถือว่าใช้โต๊ะอีกหนึ่งรอบการนั่ง
+	if table.Status == "occupied" && blockedFrom.Before(time.Now().Add(dining)) {
+		return 409, fiber.Map{"error": "โต๊ะมีลูกค้านั่งอยู่ในช่วงเวลานี้"}
+	}
+	return 0, nil
+}
+
 // @Summary ยกเลืกจองโต๊ะ
 // @Description ยกเลืกจองโต๊ะโดยโต๊ะต้องอยู่ในถานะพร้อมให้บริการถึงยกเลิกจองได้
+// @Description ยกเลิกเฉพาะการจองที่กั้นโต๊ะอยู่ตอนนี้ การจองรอบอื่นยกเลิกด้วย /api/v2/reservation/cancel/{id}
 // @Accept json
 // @Produce json
 // @Security BearerAuth
@@ -728,14 +933,25 @@ func UnreservedTable(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "โต๊ะนี้ไม่ได้ถูกจอง"})
 	}
 
-	// อัพเดทสถานะการจอง
+	// ยกเลิกเฉพาะการจองที่กั้นโต๊ะอยู่ตอนนี้ การจองรอบถัดไปของโต๊ะยังคงอยู่
 	if err := tx.Model(&models.TableReservation{}).
-		Where("table_id = ? AND status = ?", tableID, "active").
+		Where("table_id = ? AND status = ? AND table_blocked_from <= ?", tableID, "active", time.Now()).
 		Update("status", "cancelled").Error; err != nil {
 		tx.Rollback()
 		return c.Status(500).JSON(fiber.Map{"error": "ไม่สามารถยกเลิกการจองได้"})
 	}
 
+	groupIDs, err := service.GroupTableIDs(tx, table)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{"error": "ไม่สามารถอ่านสถานะโต๊ะได้"})
+	}
+	change, err := service.BeginTableChange(tx, models.TableActionUnreserve, groupIDs...)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{"error": "ไม่สามารถอ่านสถานะโต๊ะได้"})
+	}
+
 	// อัพเดทสถานะโต๊ะ - รวมถึงโต๊ะที่อยู่ในกลุ่มเดียวกัน
 	if hasGroupID(table.GroupID) {
 		if err := tx.Model(&models.Table{}).
@@ -751,9 +967,16 @@ func UnreservedTable(c *fiber.Ctx) error {
 		}
 	}
 
+	change.Entry.StaffID = utils.GetStaffID(c)
+	if err := change.Record(tx); err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{"error": "ไม่สามารถบันทึกประวัติโต๊ะได้"})
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "เกิดข้อผิดพลาดในการบันทึกข้อมูล"})
 	}
+	service.PublishTableChange(service.TableEventReserved, table.ID)
 
 	return c.Status(200).JSON(fiber.Map{
 		"message": "ยกเลิกการจองสำเร็จ",
@@ -810,6 +1033,7 @@ func ToggleTableStatus(c *fiber.Ctx) error {
 		newStatus = "available"
 	}
 
+	before := table
 	if err := tx.Model(&table).Update("status", newStatus).Error; err != nil {
 		tx.Rollback()
 		return c.Status(500).JSON(fiber.Map{
@@ -817,11 +1041,20 @@ func ToggleTableStatus(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := service.RecordTableHistory(tx, service.NewTableHistory(models.TableActionStatus, before, table,
+		models.TableHistory{StaffID: utils.GetStaffID(c)})); err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{
+			"error": "เกิดข้อผิดพลาดในการบันทึกประวัติโต๊ะ",
+		})
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "เกิดข้อผิดพลาดในการบันทึกข้อมูล",
 		})
 	}
+	service.PublishTableChange(service.TableEventStatus, table.ID)
 
 	return c.JSON(fiber.Map{
 		"message":    "สลับสถานะสำเร็จ",
@@ -831,10 +1064,10 @@ func ToggleTableStatus(c *fiber.Ctx) error {
 }
 
 // @Summary ดึงรายการอาหารที่ต้องคิดเงินตาม UUID
-// @Description ดึงรายการอาหารที่มีสถานะ served และ pending สำหรับการคิดเงิน
+// @Description ดึงรายการอาหารที่มีสถานะ served และ pending ของทุกโต๊ะใน session สำหรับการคิดเงิน
 // @Accept json
 // @Produce json
-// @Param uuid path string true "UUID ของโต๊ะ"
+// @Param uuid path string true "token ของ session (UUID ใน QR Code)"
 // @Success 200 {object} map[string]interface{} "รายการอาหารที่ต้องคิดเงิน"
 // @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
 // @Failure 404 {object} map[string]interface{} "ไม่พบข้อมูล"
@@ -849,6 +1082,13 @@ func GetBillableItems(c *fiber.Ctx) error {
 		})
 	}
 
+	session, err := service.FindSessionByToken(db.DB, uuid)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "ไม่พบรายการอาหารที่ต้องคิดเงินสำหรับ UUID นี้",
+		})
+	}
+
 	// ดึงรายการอาหารที่ต้องคิดเงิน
 	var orderItems []models.OrderItem
 	if err := db.DB.Joins("Order").
@@ -856,7 +1096,7 @@ func GetBillableItems(c *fiber.Ctx) error {
 		Joins("MenuItem.Category").
 		Preload("Options.MenuOption").
 		Preload("PromotionUsage.Promotion"). // เพิ่ม Preload สำหรับโปรโมชั่น
-		Where("\"Order\".uuid = ? AND order_items.status IN ?", uuid, []string{"served", "pending"}).
+		Where("\"Order\".session_id = ? AND order_items.status IN ?", session.ID, []string{"served", "pending"}).
 		Find(&orderItems).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "ไม่สามารถดึงข้อมูลรายการอาหารได้",
@@ -870,12 +1110,14 @@ func GetBillableItems(c *fiber.Ctx) error {
 	}
 
 	var response struct {
-		UUID  string         `json:"uuid"`
-		Items []BillableItem `json:"items"`
-		Total float64        `json:"total"`
+		UUID      string         `json:"uuid"`
+		SessionID uint           `json:"session_id"`
+		Items     []BillableItem `json:"items"`
+		Total     float64        `json:"total"`
 	}
 
 	response.UUID = uuid
+	response.SessionID = session.ID
 	var total float64
 
 	// แปลงข้อมูลรายการอาหาร
@@ -960,6 +1202,7 @@ type PromotionInfo struct {
 // @Tags Reservation
 // @Accept json
 // @Produce json
+// @Param zone_id query integer false "เฉพาะโต๊ะในโซนนี้"
 // @Success 200 {array} ReservationResponse "รายการการจองทั้งหมด"
 // @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
 // @Router /api/table/reservations [get]
@@ -968,12 +1211,19 @@ func GetAllReservations(c *fiber.Ctx) error {
 	var reservations []struct {
 		models.TableReservation
 		TableName string `json:"table_name"`
+		ZoneID    *uint  `json:"zone_id"`
+		ZoneName  string `json:"zone_name"`
 	}
 
-	if err := db.DB.Table("table_reservations").
-		Select("table_reservations.*, tables.name as table_name").
+	query := db.DB.Table("table_reservations").
+		Select("table_reservations.*, tables.name as table_name, tables.zone_id, COALESCE(table_zones.name, '') as zone_name").
 		Joins("LEFT JOIN tables ON tables.id = table_reservations.table_id").
-		Order("reserved_for DESC").
+		Joins("LEFT JOIN table_zones ON table_zones.id = tables.zone_id")
+	if zoneID := c.QueryInt("zone_id", 0); zoneID > 0 {
+		query = query.Where("tables.zone_id = ?", zoneID)
+	}
+
+	if err := query.Order("reserved_for DESC").
 		Scan(&reservations).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "เกิดข้อผิดพลาดในการดึงข้อมูล",
@@ -995,6 +1245,7 @@ func GetFailedPrintJobs(c *fiber.Ctx) error {
 	if err := db.DB.Where("status = ?", "failed").
 		Preload("Order.Items.MenuItem").
 		Preload("Order.Items.Options.MenuOption.OptionGroup").
+		Preload("Order.Items.ComboUsage.Combo", models.WithDeletedCombo).
 		Preload("Receipt.Orders.Items.MenuItem").
 		Preload("Receipt.Orders.Items.Options.MenuOption").
 		Preload("Receipt.Discounts.DiscountType").
@@ -1048,7 +1299,7 @@ func preparePrintJobResponse(job models.PrintJob) PrintJobResponse {
 // @Accept json
 // @Produce json
 // @Param id path string true "ID ของโต๊ะ"
-// @Param uuid query string true "UUID ของ QR Code"
+// @Param uuid query string true "token ของ session (UUID ใน QR Code)"
 // @Success 200 {object} map[string]interface{} "ปิดโต๊ะสำเร็จ"
 // @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
 // @Failure 404 {object} map[string]interface{} "ไม่พบโต๊ะ"
@@ -1084,10 +1335,19 @@ func CloseTable(c *fiber.Ctx) error {
 		})
 	}
 
-	// ตรวจสอบรายการอาหารของโต๊ะ
+	// session ต้องเป็นรอบที่เปิดอยู่ของโต๊ะนี้ (ไม่สนเวลาหมดอายุของ QR Code)
+	session, err := service.FindOpenSession(tx, uuid, table.ID)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "ไม่พบรอบการใช้โต๊ะที่เปิดอยู่",
+		})
+	}
+
+	// ตรวจสอบรายการอาหารของทุกโต๊ะใน session
 	var orders []models.Order
-	if err := tx.Where("table_id = ? AND uuid = ? AND status != ?",
-		tableID, uuid, "cancelled").Find(&orders).Error; err != nil {
+	if err := tx.Where("session_id = ? AND status != ?",
+		session.ID, "cancelled").Find(&orders).Error; err != nil {
 		tx.Rollback()
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "ไม่สามารถตรวจสอบรายการอาหารได้",
@@ -1118,30 +1378,28 @@ func CloseTable(c *fiber.Ctx) error {
 		})
 	}
 
-	// ปิด QR Code ที่เชื่อมต่อกับโต๊ะ
-	if err := tx.Model(&models.QRCode{}).
-		Where("table_id = ? AND uuid = ? AND is_active = ?",
-			tableID, uuid, true).
-		Update("is_active", false).Error; err != nil {
+	// ปิด session พร้อม QR Code และคืนทุกโต๊ะใน session เป็นพร้อมใช้งาน
+	tableIDs, err := service.SessionTableIDs(tx, session.ID)
+	if err != nil {
 		tx.Rollback()
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "ไม่สามารถปิด QR Code ได้",
+			"error": "ไม่สามารถปิดโต๊ะได้",
 		})
 	}
-
-	// อัพเดทสถานะโต๊ะเป็นพร้อมใช้งาน
-	if err := tx.Model(&table).Update("status", "available").Error; err != nil {
+	if err := service.CloseTableSession(tx, session, utils.GetStaffID(c)); err != nil {
 		tx.Rollback()
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "ไม่สามารถอัพเดทสถานะโต๊ะได้",
+			"error": "ไม่สามารถปิดโต๊ะได้",
 		})
 	}
+	table.Status = "available"
 
 	if err := tx.Commit().Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "เกิดข้อผิดพลาดในการบันทึกข้อมูล",
 		})
 	}
+	service.PublishTableChange(service.TableEventClosed, tableIDs...)
 
 	return c.JSON(fiber.Map{
 		"message": "ปิดโต๊ะสำเร็จ",
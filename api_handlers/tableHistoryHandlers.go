@@ -0,0 +1,86 @@
+package api_handlers
+
+import (
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseHistoryTime อ่านเวลาจาก query รับทั้ง RFC3339 และ YYYY-MM-DD
+// ถ้าเป็นวันที่และ endOfDay = true จะคืนเวลาเริ่มต้นของวันถัดไป (ใช้เป็นขอบบนแบบไม่รวม)
+func parseHistoryTime(value string, endOfDay bool) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, true
+}
+
+// @Summary ดึงประวัติการเปลี่ยนแปลงของโต๊ะ
+// @Description ดึงประวัติการสร้าง/แก้ไข/รวม/ย้าย/แยก/จอง/เปิด/ปิดโต๊ะ เรียงจากล่าสุด
+// @Produce json
+// @Param table_id query integer false "เฉพาะโต๊ะนี้"
+// @Param session_id query integer false "เฉพาะรอบการใช้โต๊ะนี้"
+// @Param action query string false "เฉพาะการกระทำนี้ เช่น move, merge"
+// @Param from query string false "ตั้งแต่เวลา (RFC3339 หรือ YYYY-MM-DD)"
+// @Param to query string false "ถึงเวลา (RFC3339 หรือ YYYY-MM-DD รวมทั้งวัน)"
+// @Param limit query integer false "จำนวนรายการ (ค่าเริ่มต้น 100 สูงสุด 1000)"
+// @Success 200 {array} models.TableHistory "ประวัติของโต๊ะ"
+// @Failure 400 {object} map[string]interface{} "พารามิเตอร์ไม่ถูกต้อง"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
+// @Router /api/table/history [get]
+// @Tags Table
+func GetTableHistory(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 100)
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	query := db.DB.Model(&models.TableHistory{})
+	if tableID := c.QueryInt("table_id", 0); tableID > 0 {
+		query = query.Where("table_id = ?", tableID)
+	}
+	if sessionID := c.QueryInt("session_id", 0); sessionID > 0 {
+		query = query.Where("session_id = ?", sessionID)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if from := c.Query("from"); from != "" {
+		t, ok := parseHistoryTime(from, false)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid from, use RFC3339 or YYYY-MM-DD",
+			})
+		}
+		query = query.Where("created_at >= ?", t)
+	}
+	if to := c.Query("to"); to != "" {
+		t, ok := parseHistoryTime(to, true)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid to, use RFC3339 or YYYY-MM-DD",
+			})
+		}
+		query = query.Where("created_at < ?", t)
+	}
+
+	var history []models.TableHistory
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&history).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch table history",
+		})
+	}
+	return c.JSON(history)
+}
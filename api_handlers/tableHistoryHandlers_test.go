@@ -0,0 +1,138 @@
+package api_handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableHistory(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.Table{}, &models.TableZone{}, &models.TableHistory{}, &models.TableSession{},
+		&models.QRCode{}, &models.Order{}, &models.Printer{}, &models.PrintJob{})
+
+	app.Post("/api/table", Addtable)
+	app.Post("/api/table/mergeTable", MergeTables)
+	app.Post("/api/table/splitTable/:id", SplitTables)
+	app.Post("/api/table/moveTable", MoveTable)
+	app.Put("/api/table/setstatus/:id", ToggleTableStatus)
+	app.Get("/api/table/history", GetTableHistory)
+
+	history := func(query string) []models.TableHistory {
+		resp := sendJSON(t, app, "GET", "/api/table/history?"+query, nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var entries []models.TableHistory
+		json.NewDecoder(resp.Body).Decode(&entries)
+		return entries
+	}
+
+	var h1, h2, h3 models.Table
+
+	// กรณีทดสอบที่ 1: สร้างโต๊ะบันทึกประวัติ create
+	t.Run("Success - Create table is logged", func(t *testing.T) {
+		for i, table := range []*models.Table{&h1, &h2, &h3} {
+			resp := sendJSON(t, app, "POST", "/api/table", Table{Name: fmt.Sprintf("H%d", i+1), Capacity: 4})
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			json.NewDecoder(resp.Body).Decode(table)
+		}
+
+		entries := history(fmt.Sprintf("table_id=%d", h1.ID))
+		assert.Len(t, entries, 1)
+		assert.Equal(t, models.TableActionCreate, entries[0].Action)
+		assert.Equal(t, "", entries[0].FromStatus)
+		assert.Equal(t, "available", entries[0].ToStatus)
+	})
+
+	// กรณีทดสอบที่ 2: รวมและแยกโต๊ะ บันทึก group_id ก่อน/หลัง ของทุกโต๊ะ
+	t.Run("Success - Merge and split are logged", func(t *testing.T) {
+		resp := sendJSON(t, app, "POST", "/api/table/mergeTable", Req_merge{TableIDs: []uint{h1.ID, h2.ID}})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var merged map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&merged)
+		groupID := merged["group_id"].(string)
+
+		entries := history("action=merge")
+		assert.Len(t, entries, 2)
+		for _, entry := range entries {
+			assert.Nil(t, entry.FromGroupID)
+			assert.Equal(t, groupID, *entry.ToGroupID)
+			assert.Equal(t, h1.ID, *entry.RelatedTableID)
+		}
+
+		resp = sendJSON(t, app, "POST", "/api/table/splitTable/"+groupID, nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		entries = history(fmt.Sprintf("action=split&table_id=%d", h2.ID))
+		assert.Len(t, entries, 1)
+		assert.Equal(t, groupID, *entries[0].FromGroupID)
+		assert.Nil(t, entries[0].ToGroupID)
+	})
+
+	// กรณีทดสอบที่ 3: ย้ายโต๊ะบันทึกทั้งสองฝั่งพร้อมออเดอร์ที่ย้ายไปและ session
+	t.Run("Success - Move is logged with moved orders", func(t *testing.T) {
+		db.DB.Model(&models.Table{}).Where("id = ?", h1.ID).Update("status", "occupied")
+		session, err := service.OpenTableSession(db.DB, h1, 2, nil, nil)
+		assert.Nil(t, err)
+		order := models.Order{UUID: session.Token, TableID: int(h1.ID), Status: "pending", SessionID: &session.ID}
+		db.DB.Create(&order)
+
+		resp := sendJSON(t, app, "POST", "/api/table/moveTable", move_req{FromTableID: h1.ID, ToTableID: h3.ID})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		entries := history("action=move")
+		assert.Len(t, entries, 2)
+		byTable := map[uint]models.TableHistory{}
+		for _, entry := range entries {
+			byTable[entry.TableID] = entry
+			assert.Equal(t, []uint{order.ID}, entry.OrderIDs)
+			assert.Equal(t, session.ID, *entry.SessionID)
+		}
+		assert.Equal(t, "occupied", byTable[h1.ID].FromStatus)
+		assert.Equal(t, "available", byTable[h1.ID].ToStatus)
+		assert.Equal(t, h3.ID, *byTable[h1.ID].RelatedTableID)
+		assert.Equal(t, "available", byTable[h3.ID].FromStatus)
+		assert.Equal(t, "occupied", byTable[h3.ID].ToStatus)
+		assert.Equal(t, h1.ID, *byTable[h3.ID].RelatedTableID)
+
+		entries = history(fmt.Sprintf("session_id=%d", session.ID))
+		assert.Len(t, entries, 2)
+	})
+
+	// กรณีทดสอบที่ 4: สลับสถานะบันทึก status และเรียงจากล่าสุด
+	t.Run("Success - Status toggle is logged", func(t *testing.T) {
+		resp := sendJSON(t, app, "PUT", fmt.Sprintf("/api/table/setstatus/%d", h2.ID), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		entries := history(fmt.Sprintf("table_id=%d", h2.ID))
+		assert.Equal(t, models.TableActionStatus, entries[0].Action)
+		assert.Equal(t, "available", entries[0].FromStatus)
+		assert.Equal(t, "unavailable", entries[0].ToStatus)
+		assert.Nil(t, entries[0].StaffID)
+	})
+
+	// กรณีทดสอบที่ 5: กรองตามช่วงเวลา และรูปแบบเวลาที่ไม่ถูกต้อง
+	t.Run("Success - Filter by time range", func(t *testing.T) {
+		today := time.Now().Format("2006-01-02")
+		assert.NotEmpty(t, history("from="+today+"&to="+today))
+
+		tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+		assert.Empty(t, history("from="+tomorrow))
+
+		past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+		assert.Empty(t, history("to="+url.QueryEscape(past)))
+		assert.Len(t, history("limit=2"), 2)
+
+		resp := sendJSON(t, app, "GET", "/api/table/history?from=yesterday", nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
@@ -0,0 +1,173 @@
+package api_handlers
+
+import (
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	service "food-ordering-api/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// tableSessionDetail รอบการใช้โต๊ะพร้อมออเดอร์ทั้งหมดของรอบนั้น
+type tableSessionDetail struct {
+	models.TableSession
+	Orders []models.Order `json:"orders"`
+	Total  float64        `json:"total"` // ยอดรวมออเดอร์ที่ไม่ถูกยกเลิก
+}
+
+// getTableSessionFromParam โหลด session จาก :id พร้อมโต๊ะทั้งหมด
+func getTableSessionFromParam(c *fiber.Ctx) (*models.TableSession, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid session ID",
+		})
+	}
+
+	var session models.TableSession
+	if err := db.DB.Preload("Tables").First(&session, id).Error; err != nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Table session not found",
+		})
+	}
+	return &session, nil
+}
+
+// @Summary ดึงรายการรอบการใช้โต๊ะ
+// @Description ดึงรอบการใช้โต๊ะพร้อมโต๊ะที่ผูกอยู่ เรียงจากเปิดล่าสุด
+// @Produce json
+// @Param status query string false "สถานะ (ค่าเริ่มต้น open)" Enums(open, closed)
+// @Param table_id query integer false "เฉพาะรอบที่ใช้โต๊ะนี้"
+// @Param limit query integer false "จำนวนรายการ (ค่าเริ่มต้น 100)"
+// @Success 200 {array} models.TableSession "รอบการใช้โต๊ะ"
+// @Failure 400 {object} map[string]interface{} "พารามิเตอร์ไม่ถูกต้อง"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
+// @Router /api/table/sessions [get]
+// @Tags Table
+func GetTableSessions(c *fiber.Ctx) error {
+	status := c.Query("status", models.SessionOpen)
+	if status != models.SessionOpen && status != models.SessionClosed {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "status must be open or closed",
+		})
+	}
+	limit := c.QueryInt("limit", 100)
+	if limit <= 0 {
+		limit = 100
+	}
+
+	query := db.DB.Preload("Tables").Where("status = ?", status)
+	if tableID := c.QueryInt("table_id", 0); tableID > 0 {
+		query = query.Where("id IN (SELECT table_session_id FROM table_session_tables WHERE table_id = ?)", tableID)
+	}
+
+	var sessions []models.TableSession
+	if err := query.Order("opened_at DESC, id DESC").Limit(limit).Find(&sessions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch table sessions",
+		})
+	}
+	return c.JSON(sessions)
+}
+
+// @Summary ดึงรายละเอียดรอบการใช้โต๊ะ
+// @Description ดึงรอบการใช้โต๊ะพร้อมโต๊ะและออเดอร์ทั้งหมดของรอบนั้น
+// @Produce json
+// @Param id path integer true "ID ของ session"
+// @Success 200 {object} tableSessionDetail "รายละเอียดรอบการใช้โต๊ะ"
+// @Failure 404 {object} map[string]interface{} "ไม่พบรอบการใช้โต๊ะ"
+// @Router /api/table/sessions/{id} [get]
+// @Tags Table
+func GetTableSession(c *fiber.Ctx) error {
+	session, err := getTableSessionFromParam(c)
+	if session == nil {
+		return err
+	}
+
+	detail := tableSessionDetail{TableSession: *session}
+	if err := db.DB.Preload("Items.MenuItem").
+		Preload("Items.Options.MenuOption").
+		Where("session_id = ?", session.ID).
+		Order("created_at").
+		Find(&detail.Orders).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch session orders",
+		})
+	}
+	for _, order := range detail.Orders {
+		if order.Status != "cancelled" {
+			detail.Total += order.Total
+		}
+	}
+	return c.JSON(detail)
+}
+
+// @Summary แก้ไขรอบการใช้โต๊ะ
+// @Description แก้ไขจำนวนลูกค้าหรือพนักงานที่ดูแลของรอบที่ยังเปิดอยู่
+// @Accept json
+// @Produce json
+// @Param id path integer true "ID ของ session"
+// @Param request body models.UpdateTableSessionRequest true "ข้อมูลที่ต้องการแก้ไข"
+// @Success 200 {object} models.TableSession "รอบการใช้โต๊ะที่แก้ไขแล้ว"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 404 {object} map[string]interface{} "ไม่พบรอบการใช้โต๊ะ"
+// @Failure 422 {object} map[string]interface{} "รอบการใช้โต๊ะปิดไปแล้ว"
+// @Router /api/table/sessions/{id} [put]
+// @Tags Table
+func UpdateTableSession(c *fiber.Ctx) error {
+	session, err := getTableSessionFromParam(c)
+	if session == nil {
+		return err
+	}
+
+	var req models.UpdateTableSessionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+	if session.Status != models.SessionOpen {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": "Table session is already closed",
+		})
+	}
+
+	updates := map[string]interface{}{}
+	if req.GuestCount != nil {
+		if *req.GuestCount < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "guest_count must not be negative",
+			})
+		}
+		updates["guest_count"] = *req.GuestCount
+	}
+	if req.ServerID != nil {
+		if *req.ServerID == 0 {
+			updates["server_id"] = nil
+		} else {
+			var server models.Users
+			if err := db.DB.First(&server, *req.ServerID).Error; err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Server not found",
+				})
+			}
+			updates["server_id"] = *req.ServerID
+		}
+	}
+	if len(updates) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "No fields to update",
+		})
+	}
+
+	if err := db.DB.Model(&models.TableSession{}).Where("id = ?", session.ID).Updates(updates).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update table session",
+		})
+	}
+
+	var updated models.TableSession
+	db.DB.Preload("Tables").First(&updated, session.ID)
+	service.PublishTableChange(service.TableEventSession, session.TableID)
+	return c.JSON(updated)
+}
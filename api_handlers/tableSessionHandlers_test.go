@@ -0,0 +1,198 @@
+package api_handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableSessions(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.Table{}, &models.QRCode{}, &models.TableSession{}, &models.Order{},
+		&models.OrderItem{}, &models.OrderItemOption{}, &models.Receipt{}, &models.Users{}, &models.TableHistory{})
+
+	app.Get("/api/table/sessions", GetTableSessions)
+	app.Get("/api/table/sessions/:id", GetTableSession)
+	app.Put("/api/table/sessions/:id", UpdateTableSession)
+
+	group := "group-1"
+	t1 := models.Table{Name: "T1", Status: "occupied", GroupID: &group}
+	db.DB.Create(&t1)
+	t2 := models.Table{Name: "T2", Status: "occupied", GroupID: &group, ParentID: &t1.ID}
+	t3 := models.Table{Name: "T3", Status: "available"}
+	t4 := models.Table{Name: "T4", Status: "available"}
+	for _, table := range []*models.Table{&t2, &t3, &t4} {
+		db.DB.Create(table)
+	}
+
+	var session *models.TableSession
+
+	// กรณีทดสอบที่ 1: เปิดโต๊ะรวม session ผูกทุกโต๊ะในกลุ่มและใช้โต๊ะแม่เป็นโต๊ะหลัก
+	t.Run("Success - Open session for merged tables", func(t *testing.T) {
+		var err error
+		session, err = service.OpenTableSession(db.DB, t2, 4, nil, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, t1.ID, session.TableID)
+		assert.NotEmpty(t, session.Token)
+
+		ids, _ := service.SessionTableIDs(db.DB, session.ID)
+		assert.Equal(t, []uint{t1.ID, t2.ID}, ids)
+
+		_, err = service.OpenTableSession(db.DB, t1, 2, nil, nil)
+		assert.ErrorIs(t, err, service.ErrTableInSession)
+	})
+
+	// กรณีทดสอบที่ 2: token ใช้ได้กับโต๊ะใน session เท่านั้น และหมดอายุแล้วลูกค้าสั่งไม่ได้ แต่พนักงานยังปิดบิลได้
+	t.Run("Success - Resolve session token", func(t *testing.T) {
+		found, err := service.ResolveSessionToken(db.DB, session.Token, t2.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, session.ID, found.ID)
+
+		_, err = service.ResolveSessionToken(db.DB, session.Token, t3.ID)
+		assert.ErrorIs(t, err, service.ErrSessionNotFound)
+
+		db.DB.Model(session).Update("expires_at", time.Now().Add(-time.Minute))
+		_, err = service.ResolveSessionToken(db.DB, session.Token, t1.ID)
+		assert.ErrorIs(t, err, service.ErrSessionExpired)
+
+		_, err = service.FindOpenSession(db.DB, session.Token, t1.ID)
+		assert.Nil(t, err)
+	})
+
+	// กรณีทดสอบที่ 3: ย้ายโต๊ะ ออเดอร์และ QR Code ย้ายตาม session
+	t.Run("Success - Move session", func(t *testing.T) {
+		single, err := service.OpenTableSession(db.DB, t3, 2, nil, nil)
+		assert.Nil(t, err)
+		db.DB.Create(&models.QRCode{TableID: int(t3.ID), UUID: single.Token, IsActive: true, SessionID: &single.ID})
+		order := models.Order{UUID: single.Token, TableID: int(t3.ID), Status: "pending", SessionID: &single.ID}
+		db.DB.Create(&order)
+
+		movedOrderIDs, err := service.MoveTableSession(db.DB, single, t3.ID, t4.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, []uint{order.ID}, movedOrderIDs)
+
+		found, err := service.FindOpenSessionForTable(db.DB, t4.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, t4.ID, found.TableID)
+		_, err = service.FindOpenSessionForTable(db.DB, t3.ID)
+		assert.ErrorIs(t, err, service.ErrSessionNotFound)
+
+		var moved models.Order
+		db.DB.First(&moved, order.ID)
+		assert.Equal(t, int(t4.ID), moved.TableID)
+	})
+
+	// กรณีทดสอบที่ 4: แก้ไขจำนวนลูกค้าและพนักงานที่ดูแล
+	t.Run("Success - Update session", func(t *testing.T) {
+		server := models.Users{Username: "waiter", Password: "x", Name: "Waiter", Role: "staff"}
+		db.DB.Create(&server)
+
+		url := fmt.Sprintf("/api/table/sessions/%d", session.ID)
+		resp := sendJSON(t, app, "PUT", url, map[string]interface{}{"guest_count": 6, "server_id": server.ID})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var updated models.TableSession
+		json.NewDecoder(resp.Body).Decode(&updated)
+		assert.Equal(t, 6, updated.GuestCount)
+		assert.Equal(t, server.ID, *updated.ServerID)
+		assert.Len(t, updated.Tables, 2)
+
+		resp = sendJSON(t, app, "PUT", url, map[string]interface{}{"server_id": 9999})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp = sendJSON(t, app, "PUT", url, map[string]interface{}{"guest_count": -1})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = sendJSON(t, app, "GET", fmt.Sprintf("/api/table/sessions?table_id=%d", t2.ID), nil)
+		var sessions []models.TableSession
+		json.NewDecoder(resp.Body).Decode(&sessions)
+		assert.Len(t, sessions, 1)
+	})
+
+	// กรณีทดสอบที่ 5: ปิด session คืนทุกโต๊ะและปิด QR Code แล้วแก้ไขไม่ได้อีก
+	t.Run("Success - Close session", func(t *testing.T) {
+		db.DB.Create(&models.QRCode{TableID: int(t1.ID), UUID: session.Token, IsActive: true, SessionID: &session.ID})
+		assert.Nil(t, service.CloseTableSession(db.DB, session, nil))
+
+		var tables []models.Table
+		db.DB.Where("id IN ?", []uint{t1.ID, t2.ID}).Find(&tables)
+		for _, table := range tables {
+			assert.Equal(t, "available", table.Status)
+		}
+		var qr models.QRCode
+		db.DB.Where("session_id = ?", session.ID).First(&qr)
+		assert.False(t, qr.IsActive)
+
+		resp := sendJSON(t, app, "PUT", fmt.Sprintf("/api/table/sessions/%d", session.ID), map[string]interface{}{"guest_count": 2})
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 6: ย้ายข้อมูลเดิมที่ผูกกันด้วย UUID เป็น session
+	t.Run("Success - Migrate legacy UUIDs", func(t *testing.T) {
+		db.DB.Create(&models.QRCode{TableID: int(t3.ID), UUID: "legacy-open", IsActive: true, ExpiryAt: time.Now().Add(time.Hour)})
+		db.DB.Create(&models.Order{UUID: "legacy-open", TableID: int(t3.ID), Status: "pending"})
+		db.DB.Create(&models.Order{UUID: "legacy-orphan", TableID: int(t4.ID), Status: "served"})
+
+		assert.Nil(t, db.MigrateTableSessions())
+		assert.Nil(t, db.MigrateTableSessions()) // เรียกซ้ำได้
+
+		open, err := service.ResolveSessionToken(db.DB, "legacy-open", t3.ID)
+		assert.Nil(t, err)
+		var count int64
+		db.DB.Model(&models.Order{}).Where("session_id = ?", open.ID).Count(&count)
+		assert.Equal(t, int64(1), count)
+
+		orphan, err := service.FindSessionByToken(db.DB, "legacy-orphan")
+		assert.Nil(t, err)
+		assert.Equal(t, models.SessionClosed, orphan.Status)
+		ids, _ := service.SessionTableIDs(db.DB, orphan.ID)
+		assert.Equal(t, []uint{t4.ID}, ids)
+
+		db.DB.Model(&models.TableSession{}).Count(&count)
+		assert.Equal(t, int64(4), count)
+	})
+}
+
+func TestCloseMergedTable(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.Table{}, &models.QRCode{}, &models.TableSession{}, &models.Order{},
+		&models.OrderItem{}, &models.TableHistory{})
+
+	app.Post("/api/table/close/:id", CloseTable)
+
+	group := "group-1"
+	t1 := models.Table{Name: "T1", Status: "occupied", GroupID: &group}
+	db.DB.Create(&t1)
+	t2 := models.Table{Name: "T2", Status: "occupied", GroupID: &group, ParentID: &t1.ID}
+	db.DB.Create(&t2)
+
+	session, err := service.OpenTableSession(db.DB, t2, 4, nil, nil)
+	assert.Nil(t, err)
+
+	// กรณีทดสอบที่ 1: ปิดโต๊ะรวมแจ้ง event ของทุกโต๊ะใน session
+	t.Run("Success - Publish every table in session", func(t *testing.T) {
+		events, cancel := service.SubscribeTableEvents()
+		defer cancel()
+
+		req, _ := http.NewRequest(http.MethodPost,
+			fmt.Sprintf("/api/table/close/%d?uuid=%s", t1.ID, session.Token), nil)
+		resp, _ := app.Test(req)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		select {
+		case event := <-events:
+			assert.Equal(t, service.TableEventClosed, event.Reason)
+			assert.Equal(t, []uint{t1.ID, t2.ID}, event.TableIDs)
+		case <-time.After(time.Second):
+			t.Fatal("event not delivered")
+		}
+	})
+}
@@ -0,0 +1,389 @@
+package api_handlers
+
+import (
+	"errors"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	utils "food-ordering-api/utility"
+	"net/http"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// floorPlanZone โซนพร้อมโต๊ะทั้งหมดในโซน
+type floorPlanZone struct {
+	models.TableZone
+	Tables []models.Table `json:"tables"`
+}
+
+// floorPlan ผังร้านทั้งหมด โต๊ะที่ยังไม่ได้จัดเข้าโซนอยู่ใน unassigned
+type floorPlan struct {
+	Zones      []floorPlanZone `json:"zones"`
+	Unassigned []models.Table  `json:"unassigned"`
+}
+
+// validateZoneID ตรวจว่าโซนมีอยู่จริง
+func validateZoneID(tx *gorm.DB, zoneID uint) error {
+	var zone models.TableZone
+	if err := tx.First(&zone, zoneID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("ไม่พบโซนรหัส %d", zoneID)
+		}
+		return err
+	}
+	return nil
+}
+
+// getTableZoneFromParam โหลดโซนจาก :id
+func getTableZoneFromParam(c *fiber.Ctx) (*models.TableZone, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return nil, c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID ต้องเป็นตัวเลข",
+		})
+	}
+
+	var zone models.TableZone
+	if err := db.DB.First(&zone, id).Error; err != nil {
+		return nil, c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "ไม่พบโซน",
+		})
+	}
+	return &zone, nil
+}
+
+// zoneNameTaken ชื่อโซนซ้ำกับโซนอื่นหรือไม่ (excludeID = 0 คือไม่ยกเว้น)
+func zoneNameTaken(name string, excludeID uint) bool {
+	var count int64
+	db.DB.Model(&models.TableZone{}).Where("name = ? AND id <> ?", name, excludeID).Count(&count)
+	return count > 0
+}
+
+// @Summary ดึงรายการโซน
+// @Description ดึงโซนทั้งหมดของร้านเรียงตาม sort_order
+// @Produce json
+// @Param active query boolean false "เฉพาะโซนที่เปิดใช้งาน"
+// @Success 200 {array} models.TableZone "รายการโซน"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
+// @Router /api/table/zones [get]
+// @Tags Table
+func GetTableZones(c *fiber.Ctx) error {
+	query := db.DB.Order("sort_order, id")
+	if c.QueryBool("active") {
+		query = query.Where("is_active = ?", true)
+	}
+
+	var zones []models.TableZone
+	if err := query.Find(&zones).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "เกิดข้อผิดพลาดในการดึงข้อมูลโซน",
+		})
+	}
+	return c.JSON(zones)
+}
+
+// @Summary สร้างโซน
+// @Description สร้างโซนหรือห้องใหม่ เช่น ห้องแอร์ ระเบียง
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TableZoneRequest true "ข้อมูลโซน"
+// @Success 201 {object} models.TableZone "โซนที่สร้าง"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 409 {object} map[string]interface{} "ชื่อโซนซ้ำ"
+// @Router /api/table/zones [post]
+// @Tags Table
+func CreateTableZone(c *fiber.Ctx) error {
+	var req models.TableZoneRequest
+	if err := c.BodyParser(&req); err != nil || req.Name == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "กรุณาระบุชื่อโซน",
+		})
+	}
+	if req.Width < 0 || req.Height < 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "ขนาดผังต้องไม่ติดลบ",
+		})
+	}
+	if zoneNameTaken(req.Name, 0) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error": "มีโซนชื่อนี้อยู่แล้ว",
+		})
+	}
+
+	zone := models.TableZone{
+		Name:        req.Name,
+		Description: req.Description,
+		Color:       req.Color,
+		SortOrder:   req.SortOrder,
+		Width:       req.Width,
+		Height:      req.Height,
+		IsActive:    true,
+	}
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&zone).Error; err != nil {
+			return err
+		}
+		// IsActive มี default:true GORM จึงไม่บันทึกค่า false ตอนสร้าง
+		if req.IsActive != nil && !*req.IsActive {
+			return tx.Model(&zone).Update("is_active", false).Error
+		}
+		return nil
+	}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ไม่สามารถสร้างโซนได้",
+		})
+	}
+
+	db.DB.First(&zone, zone.ID)
+	return c.Status(http.StatusCreated).JSON(zone)
+}
+
+// @Summary แก้ไขโซน
+// @Description แก้ไขข้อมูลโซน
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID โซน"
+// @Param request body models.TableZoneRequest true "ข้อมูลโซน"
+// @Success 200 {object} models.TableZone "โซนที่แก้ไขแล้ว"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 404 {object} map[string]interface{} "ไม่พบโซน"
+// @Failure 409 {object} map[string]interface{} "ชื่อโซนซ้ำ"
+// @Router /api/table/zones/{id} [put]
+// @Tags Table
+func UpdateTableZone(c *fiber.Ctx) error {
+	zone, err := getTableZoneFromParam(c)
+	if zone == nil {
+		return err
+	}
+
+	var req models.TableZoneRequest
+	if err := c.BodyParser(&req); err != nil || req.Name == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "กรุณาระบุชื่อโซน",
+		})
+	}
+	if req.Width < 0 || req.Height < 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "ขนาดผังต้องไม่ติดลบ",
+		})
+	}
+	if zoneNameTaken(req.Name, zone.ID) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error": "มีโซนชื่อนี้อยู่แล้ว",
+		})
+	}
+
+	updates := map[string]interface{}{
+		"name":        req.Name,
+		"description": req.Description,
+		"color":       req.Color,
+		"sort_order":  req.SortOrder,
+		"width":       req.Width,
+		"height":      req.Height,
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+	if err := db.DB.Model(zone).Updates(updates).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ไม่สามารถแก้ไขโซนได้",
+		})
+	}
+
+	db.DB.First(zone, zone.ID)
+	service.PublishTableChange(service.TableEventLayout)
+	return c.JSON(zone)
+}
+
+// @Summary ลบโซน
+// @Description ลบโซน (soft delete) โต๊ะในโซนจะกลายเป็นโต๊ะที่ไม่อยู่ในโซนใด
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID โซน"
+// @Success 200 {object} map[string]interface{} "ลบโซนสำเร็จ"
+// @Failure 404 {object} map[string]interface{} "ไม่พบโซน"
+// @Router /api/table/zones/{id} [delete]
+// @Tags Table
+func DeleteTableZone(c *fiber.Ctx) error {
+	zone, err := getTableZoneFromParam(c)
+	if zone == nil {
+		return err
+	}
+
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var tableIDs []uint
+		if err := tx.Model(&models.Table{}).Where("zone_id = ?", zone.ID).Pluck("id", &tableIDs).Error; err != nil {
+			return err
+		}
+		change, err := service.BeginTableChange(tx, models.TableActionLayout, tableIDs...)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Table{}).Where("zone_id = ?", zone.ID).Update("zone_id", nil).Error; err != nil {
+			return err
+		}
+		change.Entry.StaffID = utils.GetStaffID(c)
+		change.Entry.Note = fmt.Sprintf("zone %s deleted", zone.Name)
+		if err := change.Record(tx); err != nil {
+			return err
+		}
+		return tx.Delete(zone).Error
+	}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "เกิดข้อผิดพลาดในการลบโซน",
+		})
+	}
+
+	service.PublishTableChange(service.TableEventLayout)
+	return c.JSON(fiber.Map{
+		"message": "ลบโซนสำเร็จ",
+	})
+}
+
+// @Summary ดึงผังร้าน
+// @Description ดึงโซนพร้อมตำแหน่ง ขนาด รูปทรงของโต๊ะในแต่ละโซน และโต๊ะที่ยังไม่ได้จัดเข้าโซน
+// @Produce json
+// @Param zone_id query integer false "เฉพาะโซนนี้"
+// @Success 200 {object} floorPlan "ผังร้าน"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
+// @Router /api/table/layout [get]
+// @Tags Table
+func GetFloorPlan(c *fiber.Ctx) error {
+	zoneID := c.QueryInt("zone_id", 0)
+
+	zoneQuery := db.DB.Order("sort_order, id")
+	tableQuery := db.DB.Order("id")
+	if zoneID > 0 {
+		zoneQuery = zoneQuery.Where("id = ?", zoneID)
+		tableQuery = tableQuery.Where("zone_id = ?", zoneID)
+	}
+
+	var zones []models.TableZone
+	var tables []models.Table
+	if err := zoneQuery.Find(&zones).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "เกิดข้อผิดพลาดในการดึงข้อมูลโซน",
+		})
+	}
+	if err := tableQuery.Find(&tables).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "เกิดข้อผิดพลาดในการดึงข้อมูลโต๊ะ",
+		})
+	}
+
+	plan := floorPlan{Zones: []floorPlanZone{}, Unassigned: []models.Table{}}
+	index := map[uint]int{}
+	for i, zone := range zones {
+		index[zone.ID] = i
+		plan.Zones = append(plan.Zones, floorPlanZone{TableZone: zone, Tables: []models.Table{}})
+	}
+	for _, table := range tables {
+		if table.ZoneID != nil {
+			if i, ok := index[*table.ZoneID]; ok {
+				plan.Zones[i].Tables = append(plan.Zones[i].Tables, table)
+				continue
+			}
+		}
+		plan.Unassigned = append(plan.Unassigned, table)
+	}
+	return c.JSON(plan)
+}
+
+// @Summary บันทึกผังโต๊ะ
+// @Description บันทึกโซน ตำแหน่ง ขนาด รูปทรง และองศาการหมุนของโต๊ะหลายตัวพร้อมกัน แก้ได้แม้โต๊ะกำลังใช้งานอยู่
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.SaveTableLayoutRequest true "ผังโต๊ะ"
+// @Success 200 {array} models.Table "โต๊ะที่บันทึกแล้ว"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการบันทึก"
+// @Router /api/table/layout [put]
+// @Tags Table
+func SaveTableLayout(c *fiber.Ctx) error {
+	var req models.SaveTableLayoutRequest
+	if err := c.BodyParser(&req); err != nil || len(req.Tables) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "ข้อมูลไม่ถูกต้อง",
+		})
+	}
+
+	var tableIDs []uint
+	var reqErr error
+	staffID := utils.GetStaffID(c)
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		for _, item := range req.Tables {
+			if slices.Contains(tableIDs, item.TableID) {
+				reqErr = fmt.Errorf("โต๊ะรหัส %d ถูกส่งมาซ้ำ", item.TableID)
+				return reqErr
+			}
+			tableIDs = append(tableIDs, item.TableID)
+
+			var table models.Table
+			if err := tx.First(&table, item.TableID).Error; err != nil {
+				reqErr = fmt.Errorf("ไม่พบโต๊ะรหัส %d", item.TableID)
+				return reqErr
+			}
+			if item.ZoneID != nil && *item.ZoneID == 0 {
+				item.ZoneID = nil
+			}
+			if item.ZoneID != nil {
+				if err := validateZoneID(tx, *item.ZoneID); err != nil {
+					reqErr = err
+					return reqErr
+				}
+			}
+			if item.Shape == "" {
+				item.Shape = models.TableShapeSquare
+			}
+			if !slices.Contains([]string{models.TableShapeSquare, models.TableShapeRectangle, models.TableShapeRound}, item.Shape) {
+				reqErr = fmt.Errorf("รูปทรงโต๊ะ %s ไม่ถูกต้อง", item.Shape)
+				return reqErr
+			}
+			if item.PosX < 0 || item.PosY < 0 || item.Width < 0 || item.Height < 0 {
+				reqErr = fmt.Errorf("ตำแหน่งและขนาดของโต๊ะรหัส %d ต้องไม่ติดลบ", item.TableID)
+				return reqErr
+			}
+
+			before := table
+			if err := tx.Model(&table).Updates(map[string]interface{}{
+				"zone_id":  item.ZoneID,
+				"pos_x":    item.PosX,
+				"pos_y":    item.PosY,
+				"width":    item.Width,
+				"height":   item.Height,
+				"shape":    item.Shape,
+				"rotation": (item.Rotation%360 + 360) % 360,
+			}).Error; err != nil {
+				return err
+			}
+			if err := service.RecordTableHistory(tx, service.NewTableHistory(models.TableActionLayout, before, table,
+				models.TableHistory{StaffID: staffID})); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if reqErr != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": reqErr.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "เกิดข้อผิดพลาดในการบันทึกผังโต๊ะ",
+		})
+	}
+
+	service.PublishTableChange(service.TableEventLayout, tableIDs...)
+
+	var tables []models.Table
+	db.DB.Where("id IN ?", tableIDs).Order("id").Find(&tables)
+	return c.JSON(tables)
+}
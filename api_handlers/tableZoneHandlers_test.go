@@ -0,0 +1,141 @@
+package api_handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableZonesAndLayout(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.Table{}, &models.TableZone{}, &models.TableReservation{}, &models.TableHistory{})
+
+	app.Get("/api/table/reservations", GetAllReservations)
+	app.Get("/api/table/zones", GetTableZones)
+	app.Post("/api/table/zones", CreateTableZone)
+	app.Put("/api/table/zones/:id", UpdateTableZone)
+	app.Delete("/api/table/zones/:id", DeleteTableZone)
+	app.Get("/api/table/layout", GetFloorPlan)
+	app.Put("/api/table/layout", SaveTableLayout)
+	app.Post("/api/table", Addtable)
+
+	var indoor, terrace models.TableZone
+	var a1, b1 models.Table
+
+	// กรณีทดสอบที่ 1: สร้างโซน ชื่อซ้ำไม่ได้ และเรียงตาม sort_order
+	t.Run("Success - Create zones", func(t *testing.T) {
+		resp := sendJSON(t, app, "POST", "/api/table/zones", models.TableZoneRequest{Name: "ระเบียง", SortOrder: 2, Width: 800, Height: 600})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		json.NewDecoder(resp.Body).Decode(&terrace)
+
+		resp = sendJSON(t, app, "POST", "/api/table/zones", models.TableZoneRequest{Name: "ห้องแอร์", SortOrder: 1})
+		json.NewDecoder(resp.Body).Decode(&indoor)
+
+		resp = sendJSON(t, app, "POST", "/api/table/zones", models.TableZoneRequest{Name: "ห้องแอร์"})
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		resp = sendJSON(t, app, "GET", "/api/table/zones", nil)
+		var zones []models.TableZone
+		json.NewDecoder(resp.Body).Decode(&zones)
+		assert.Equal(t, "ห้องแอร์", zones[0].Name)
+		assert.True(t, zones[0].IsActive)
+	})
+
+	// กรณีทดสอบที่ 2: สร้างโต๊ะในโซน และโซนที่ไม่มีอยู่ใช้ไม่ได้
+	t.Run("Success - Create table in zone", func(t *testing.T) {
+		resp := sendJSON(t, app, "POST", "/api/table", Table{Name: "A1", Capacity: 4, ZoneID: &indoor.ID})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		json.NewDecoder(resp.Body).Decode(&a1)
+		assert.Equal(t, indoor.ID, *a1.ZoneID)
+		assert.Equal(t, models.TableShapeSquare, a1.Shape)
+
+		missing := uint(9999)
+		resp = sendJSON(t, app, "POST", "/api/table", Table{Name: "X1", Capacity: 2, ZoneID: &missing})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = sendJSON(t, app, "POST", "/api/table", Table{Name: "B1", Capacity: 2})
+		json.NewDecoder(resp.Body).Decode(&b1)
+		assert.Nil(t, b1.ZoneID)
+	})
+
+	// กรณีทดสอบที่ 3: บันทึกผังโต๊ะ องศาการหมุนถูกปรับให้อยู่ใน 0-359 และผังแยกตามโซน
+	t.Run("Success - Save and get layout", func(t *testing.T) {
+		resp := sendJSON(t, app, "PUT", "/api/table/layout", models.SaveTableLayoutRequest{Tables: []models.TableLayoutItem{
+			{TableID: a1.ID, ZoneID: &indoor.ID, PosX: 10, PosY: 20, Width: 80, Height: 80, Shape: models.TableShapeRound, Rotation: -90},
+			{TableID: b1.ID, ZoneID: &terrace.ID, PosX: 100, PosY: 50, Width: 120, Height: 60, Shape: models.TableShapeRectangle},
+		}})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var tables []models.Table
+		json.NewDecoder(resp.Body).Decode(&tables)
+		assert.Equal(t, 270, tables[0].Rotation)
+		assert.Equal(t, models.TableShapeRound, tables[0].Shape)
+
+		resp = sendJSON(t, app, "GET", "/api/table/layout", nil)
+		var plan floorPlan
+		json.NewDecoder(resp.Body).Decode(&plan)
+		assert.Len(t, plan.Zones, 2)
+		assert.Equal(t, "A1", plan.Zones[0].Tables[0].Name)
+		assert.Equal(t, "B1", plan.Zones[1].Tables[0].Name)
+		assert.Empty(t, plan.Unassigned)
+
+		resp = sendJSON(t, app, "PUT", "/api/table/layout", models.SaveTableLayoutRequest{Tables: []models.TableLayoutItem{
+			{TableID: a1.ID, Shape: "hexagon"},
+		}})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 4: กรองการจองตามโซน
+	t.Run("Success - Reservations by zone", func(t *testing.T) {
+		db.DB.Create(&models.TableReservation{TableID: a1.ID, CustomerName: "สมชาย", PhoneNumber: "0800000000", GuestCount: 2})
+		db.DB.Create(&models.TableReservation{TableID: b1.ID, CustomerName: "สมหญิง", PhoneNumber: "0800000001", GuestCount: 2})
+
+		resp := sendJSON(t, app, "GET", fmt.Sprintf("/api/table/reservations?zone_id=%d", terrace.ID), nil)
+		var reservations []map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&reservations)
+		assert.Len(t, reservations, 1)
+		assert.Equal(t, "ระเบียง", reservations[0]["zone_name"])
+	})
+
+	// กรณีทดสอบที่ 5: ลบโซนแล้วโต๊ะในโซนไม่อยู่ในโซนใด
+	t.Run("Success - Delete zone", func(t *testing.T) {
+		resp := sendJSON(t, app, "DELETE", fmt.Sprintf("/api/table/zones/%d", terrace.ID), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = sendJSON(t, app, "GET", "/api/table/layout", nil)
+		var plan floorPlan
+		json.NewDecoder(resp.Body).Decode(&plan)
+		assert.Len(t, plan.Zones, 1)
+		assert.Len(t, plan.Unassigned, 1)
+		assert.Equal(t, "B1", plan.Unassigned[0].Name)
+	})
+
+	// กรณีทดสอบที่ 6: สร้างโซนแบบปิดใช้งาน และบันทึกผังด้วย zone_id 0 คือเอาโต๊ะออกจากโซน
+	t.Run("Success - Inactive zone and unassign in layout", func(t *testing.T) {
+		inactive := false
+		resp := sendJSON(t, app, "POST", "/api/table/zones", models.TableZoneRequest{Name: "ห้องส่วนตัว", SortOrder: 3, IsActive: &inactive})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		var private models.TableZone
+		json.NewDecoder(resp.Body).Decode(&private)
+		assert.False(t, private.IsActive)
+
+		var stored models.TableZone
+		db.DB.First(&stored, private.ID)
+		assert.False(t, stored.IsActive)
+
+		noZone := uint(0)
+		resp = sendJSON(t, app, "PUT", "/api/table/layout", models.SaveTableLayoutRequest{Tables: []models.TableLayoutItem{
+			{TableID: a1.ID, ZoneID: &noZone, PosX: 10, PosY: 20},
+		}})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var table models.Table
+		db.DB.First(&table, a1.ID)
+		assert.Nil(t, table.ZoneID)
+	})
+}
@@ -1,7 +1,14 @@
 package api_handlers
 
 import (
+	"food-ordering-api/models"
+	service "food-ordering-api/services"
 	"log"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -10,39 +17,336 @@ import (
 )
 
 type TableResponse struct {
-	ID        uint      `json:"ID"`
-	Name      string    `json:"Name"`
-	Capacity  int       `json:"Capacity"`
-	Status    string    `json:"Status"`
-	ParentID  *uint     `json:"ParentID"`
-	GroupID   *string   `json:"GroupID"`
-	UUID      string    `json:"UUID,omitempty"`
-	CreatedAt time.Time `json:"CreatedAt"`
-	UpdatedAt time.Time `json:"UpdatedAt"`
+	ID         uint      `json:"ID"`
+	Name       string    `json:"Name"`
+	Capacity   int       `json:"Capacity"`
+	Status     string    `json:"Status"`
+	ParentID   *uint     `json:"ParentID"`
+	GroupID    *string   `json:"GroupID"`
+	UUID       string    `json:"UUID,omitempty"`      // token ของ session ที่เปิดอยู่
+	SessionID  *uint     `json:"SessionID,omitempty"` // session ที่เปิดอยู่ของโต๊ะ
+	GuestCount int       `json:"GuestCount"`
+	ZoneID     *uint     `json:"ZoneID"`
+	ZoneName   string    `json:"ZoneName"`
+	PosX       float64   `json:"PosX"`
+	PosY       float64   `json:"PosY"`
+	Width      float64   `json:"Width"`
+	Height     float64   `json:"Height"`
+	Shape      string    `json:"Shape"`
+	Rotation   int       `json:"Rotation"`
+	CreatedAt  time.Time `json:"CreatedAt"`
+	UpdatedAt  time.Time `json:"UpdatedAt"`
 }
 
+const (
+	tableFeedHeartbeat = 15 * time.Second       // ส่ง heartbeat ให้ client รู้ว่ายังเชื่อมต่ออยู่และเทียบ version
+	tableFeedReconcile = 30 * time.Second       // โหลดสถานะทั้งหมดเป็นระยะ เผื่อมีการเปลี่ยนที่ไม่ได้ publish event
+	tableFeedDebounce  = 100 * time.Millisecond // รวม event ที่มาติดกันเป็นการโหลดครั้งเดียว
+)
+
+// tableSnapshot สถานะโต๊ะทั้งหมดและคิว walk-in ณ version หนึ่ง
+type tableSnapshot struct {
+	Version  uint64
+	Reasons  []string
+	Tables   []TableResponse
+	Waitlist []models.WaitlistEntry // เรียงตามลำดับคิว
+}
+
+// tableDiffMessage การเปลี่ยนแปลงจาก snapshot ก่อนหน้าของ client
+type tableDiffMessage struct {
+	Type    string          `json:"type"` // table_diff
+	Version uint64          `json:"version"`
+	Reasons []string        `json:"reasons"`
+	Updated []TableResponse `json:"updated"` // โต๊ะที่เพิ่มหรือเปลี่ยน (ข้อมูลทั้งแถว)
+	Removed []uint          `json:"removed"` // ID โต๊ะที่ถูกลบหรือย้ายออกจากโซนที่ดูอยู่
+}
+
+// loadTableResponses โหลดสถานะโต๊ะทั้งหมดพร้อมโซนและ session ที่เปิดอยู่
+func loadTableResponses(db *gorm.DB) ([]TableResponse, error) {
+	var tables []TableResponse
+	err := db.Table("tables").
+		Where("tables.deleted_at IS NULL").
+		Select("tables.*, COALESCE(table_zones.name, '') as zone_name, COALESCE(table_sessions.token, '') as uuid, table_sessions.id as session_id, COALESCE(table_sessions.guest_count, 0) as guest_count").
+		Joins("LEFT JOIN table_zones ON table_zones.id = tables.zone_id AND table_zones.deleted_at IS NULL").
+		Joins("LEFT JOIN table_session_tables ON table_session_tables.table_id = tables.id AND table_session_tables.table_session_id IN (SELECT id FROM table_sessions WHERE status = ?)", models.SessionOpen).
+		Joins("LEFT JOIN table_sessions ON table_sessions.id = table_session_tables.table_session_id").
+		Order("tables.id").
+		Scan(&tables).Error
+	return tables, err
+}
+
+// filterTablesByZone เฉพาะโต๊ะในโซน (zoneID = 0 คือทุกโต๊ะ)
+func filterTablesByZone(tables []TableResponse, zoneID uint) []TableResponse {
+	if zoneID == 0 {
+		return tables
+	}
+	filtered := []TableResponse{}
+	for _, table := range tables {
+		if table.ZoneID != nil && *table.ZoneID == zoneID {
+			filtered = append(filtered, table)
+		}
+	}
+	return filtered
+}
+
+// diffTables เทียบสถานะที่ client มีอยู่กับสถานะใหม่ คืนโต๊ะที่เพิ่ม/เปลี่ยน และ ID ที่หายไป
+func diffTables(prev map[uint]TableResponse, next []TableResponse) ([]TableResponse, []uint) {
+	updated := []TableResponse{}
+	removed := []uint{}
+	seen := make(map[uint]bool, len(next))
+	for _, table := range next {
+		seen[table.ID] = true
+		if old, ok := prev[table.ID]; !ok || !reflect.DeepEqual(old, table) {
+			updated = append(updated, table)
+		}
+	}
+	for id := range prev {
+		if !seen[id] {
+			removed = append(removed, id)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i] < removed[j] })
+	return updated, removed
+}
+
+// tableFeed รับ TableEvent จาก event bus โหลดสถานะโต๊ะครั้งเดียวต่อชุด event แล้วกระจายให้ทุก client
+// ทำให้จำนวนการ query ไม่ขึ้นกับจำนวนแท็บเล็ตที่เชื่อมต่อ
+type tableFeed struct {
+	db      *gorm.DB
+	mu      sync.Mutex
+	latest  *tableSnapshot
+	clients map[chan *tableSnapshot]struct{}
+}
+
+func newTableFeed(db *gorm.DB) *tableFeed {
+	return &tableFeed{db: db, clients: make(map[chan *tableSnapshot]struct{})}
+}
+
+// run วนรับ event ตลอดอายุของ process
+func (f *tableFeed) run() {
+	events, _ := service.SubscribeTableEvents()
+	reconcile := time.NewTicker(tableFeedReconcile)
+	defer reconcile.Stop()
+
+	for {
+		var reasons []string
+		select {
+		case event := <-events:
+			reasons = append(reasons, event.Reason)
+			// รวม event ที่ตามมาติดๆ กัน เช่น ชำระเงินแล้วปิดหลายโต๊ะ
+			timer := time.NewTimer(tableFeedDebounce)
+		collect:
+			for {
+				select {
+				case event := <-events:
+					if !slices.Contains(reasons, event.Reason) {
+						reasons = append(reasons, event.Reason)
+					}
+				case <-timer.C:
+					break collect
+				}
+			}
+		case <-reconcile.C:
+			reasons = []string{"reconcile"}
+		}
+
+		if _, err := f.refresh(reasons); err != nil {
+			log.Printf("Error querying tables: %v", err)
+		}
+	}
+}
+
+// refresh โหลดสถานะใหม่และส่งให้ทุก client
+func (f *tableFeed) refresh(reasons []string) (*tableSnapshot, error) {
+	tables, err := loadTableResponses(f.db)
+	if err != nil {
+		return nil, err
+	}
+	waitlist, err := loadWaitlist(f.db)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	version := uint64(1)
+	if f.latest != nil {
+		version = f.latest.Version
+		// reconcile ที่ไม่มีอะไรเปลี่ยนไม่ต้องขยับ version
+		if !reflect.DeepEqual(f.latest.Tables, tables) || !reflect.DeepEqual(f.latest.Waitlist, waitlist) {
+			version++
+		}
+	}
+	snapshot := &tableSnapshot{Version: version, Reasons: reasons, Tables: tables, Waitlist: waitlist}
+	f.latest = snapshot
+
+	for ch := range f.clients {
+		// ช่องมีที่ว่างตัวเดียว client ที่ตามไม่ทันได้เฉพาะ snapshot ล่าสุด
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- snapshot
+	}
+	return snapshot, nil
+}
+
+// current snapshot ล่าสุด โหลดใหม่ถ้ายังไม่เคยโหลด
+func (f *tableFeed) current() (*tableSnapshot, error) {
+	f.mu.Lock()
+	latest := f.latest
+	f.mu.Unlock()
+	if latest != nil {
+		return latest, nil
+	}
+	return f.refresh([]string{"snapshot"})
+}
+
+func (f *tableFeed) subscribe() (chan *tableSnapshot, func()) {
+	ch := make(chan *tableSnapshot, 1)
+	f.mu.Lock()
+	f.clients[ch] = struct{}{}
+	f.mu.Unlock()
+	return ch, func() {
+		f.mu.Lock()
+		delete(f.clients, ch)
+		f.mu.Unlock()
+	}
+}
+
+// TableWebSocketHandler ส่งสถานะโต๊ะแบบ event-driven แทนการ query ทุกวินาที
+// เมื่อเชื่อมต่อ (หรือเชื่อมต่อใหม่) ได้ {"type":"table_update","version":n,"data":[...]} เป็นสถานะทั้งหมด
+// จากนั้นได้ {"type":"table_diff","version":n,"updated":[...],"removed":[...]} เมื่อโต๊ะเปลี่ยน
+// คิว walk-in ส่งเป็น {"type":"waitlist_update","version":n,"data":[...]} (ทั้งคิวเรียงตามลำดับ) ตอนเชื่อมต่อและเมื่อคิวเปลี่ยน
+// และ {"type":"heartbeat","version":n} ทุก 15 วินาที ถ้า version ไม่ตรงกับที่มีให้ส่ง {"type":"resync"} เพื่อขอสถานะทั้งหมดใหม่
+// ?zone_id= ส่งเฉพาะโต๊ะในโซนนั้น
 func TableWebSocketHandler(db *gorm.DB) fiber.Handler {
+	feed := newTableFeed(db)
+	go feed.run()
+
 	return websocket.New(func(c *websocket.Conn) {
-		ticker := time.NewTicker(1 * time.Second)
-		defer ticker.Stop()
-
-		for range ticker.C {
-			var tables []TableResponse
-
-			if err := db.Table("tables").
-				Where("tables.deleted_at IS NULL").
-				Select("tables.*, COALESCE(qr_codes.uuid, '') as uuid").
-				Joins("LEFT JOIN qr_codes ON qr_codes.table_id = tables.id AND qr_codes.is_active = ? AND qr_codes.expiry_at > ?", true, time.Now()).
-				Scan(&tables).Error; err != nil {
-				log.Printf("Error querying tables: %v", err)
-				continue
+		zoneID, _ := strconv.Atoi(c.Query("zone_id"))
+
+		updates, unsubscribe := feed.subscribe()
+		defer unsubscribe()
+
+		// อ่านข้อความจาก client ใน goroutine แยก ส่วนการเขียนทำใน loop หลักที่เดียว
+		resync := make(chan struct{}, 1)
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			for {
+				var msg struct {
+					Type string `json:"type"`
+				}
+				if err := c.ReadJSON(&msg); err != nil {
+					return
+				}
+				if msg.Type == "resync" {
+					select {
+					case resync <- struct{}{}:
+					default:
+					}
+				}
 			}
+		}()
 
+		state := map[uint]TableResponse{}
+		var waitlist []models.WaitlistEntry
+		var version uint64
+
+		sendWaitlist := func(snapshot *tableSnapshot) error {
+			waitlist = snapshot.Waitlist
+			return c.WriteJSON(map[string]interface{}{
+				"type":    "waitlist_update",
+				"version": version,
+				"data":    waitlist,
+			})
+		}
+		sendSnapshot := func(snapshot *tableSnapshot) error {
+			tables := filterTablesByZone(snapshot.Tables, uint(zoneID))
+			state = make(map[uint]TableResponse, len(tables))
+			for _, table := range tables {
+				state[table.ID] = table
+			}
+			version = snapshot.Version
 			if err := c.WriteJSON(map[string]interface{}{
-				"type": "table_update",
-				"data": tables,
+				"type":    "table_update",
+				"version": version,
+				"data":    tables,
 			}); err != nil {
+				return err
+			}
+			return sendWaitlist(snapshot)
+		}
+
+		snapshot, err := feed.current()
+		if err != nil {
+			log.Printf("Error querying tables: %v", err)
+			return
+		}
+		if err := sendSnapshot(snapshot); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(tableFeedHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-closed:
 				return
+
+			case <-resync:
+				snapshot, err := feed.current()
+				if err != nil {
+					log.Printf("Error querying tables: %v", err)
+					continue
+				}
+				if err := sendSnapshot(snapshot); err != nil {
+					return
+				}
+
+			case snapshot := <-updates:
+				tables := filterTablesByZone(snapshot.Tables, uint(zoneID))
+				updated, removed := diffTables(state, tables)
+				waitlistChanged := !reflect.DeepEqual(waitlist, snapshot.Waitlist)
+				if len(updated) == 0 && len(removed) == 0 && !waitlistChanged {
+					continue
+				}
+				// version ที่ client ถืออยู่ขยับเฉพาะเมื่อได้รับข้อความ heartbeat จึงเทียบกับ client ได้ตรงๆ
+				version = snapshot.Version
+				if len(updated) > 0 || len(removed) > 0 {
+					for _, table := range updated {
+						state[table.ID] = table
+					}
+					for _, id := range removed {
+						delete(state, id)
+					}
+					if err := c.WriteJSON(tableDiffMessage{
+						Type:    "table_diff",
+						Version: version,
+						Reasons: snapshot.Reasons,
+						Updated: updated,
+						Removed: removed,
+					}); err != nil {
+						return
+					}
+				}
+				if waitlistChanged {
+					if err := sendWaitlist(snapshot); err != nil {
+						return
+					}
+				}
+
+			case <-heartbeat.C:
+				if err := c.WriteJSON(map[string]interface{}{
+					"type":    "heartbeat",
+					"version": version,
+				}); err != nil {
+					return
+				}
 			}
 		}
 	})
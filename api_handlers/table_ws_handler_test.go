@@ -0,0 +1,94 @@
+package api_handlers
+
+import (
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableEventBus(t *testing.T) {
+	events, cancel := service.SubscribeTableEvents()
+
+	service.PublishTableChange(service.TableEventMoved, 1, 2)
+	select {
+	case event := <-events:
+		assert.Equal(t, service.TableEventMoved, event.Reason)
+		assert.Equal(t, []uint{1, 2}, event.TableIDs)
+	case <-time.After(time.Second):
+		t.Fatal("event not delivered")
+	}
+
+	// ผู้รับที่ไม่อ่านต้องไม่ทำให้ผู้ publish ค้าง
+	for i := 0; i < 100; i++ {
+		service.PublishTableChange(service.TableEventStatus)
+	}
+
+	cancel()
+	cancel() // เรียกซ้ำได้
+	service.PublishTableChange(service.TableEventStatus)
+}
+
+func TestTableFeedDiff(t *testing.T) {
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.Table{}, &models.TableZone{}, &models.TableSession{}, &models.WaitlistEntry{})
+
+	zone := models.TableZone{Name: "ระเบียง"}
+	db.DB.Create(&zone)
+	a1 := models.Table{Name: "A1", Status: "available", ZoneID: &zone.ID}
+	a2 := models.Table{Name: "A2", Status: "available"}
+	db.DB.Create(&a1)
+	db.DB.Create(&a2)
+
+	feed := newTableFeed(db.DB)
+	updates, unsubscribe := feed.subscribe()
+	defer unsubscribe()
+
+	// กรณีทดสอบที่ 1: snapshot แรกมีทุกโต๊ะพร้อมชื่อโซน
+	snapshot, err := feed.current()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), snapshot.Version)
+	assert.Len(t, snapshot.Tables, 2)
+	assert.Equal(t, "ระเบียง", snapshot.Tables[0].ZoneName)
+	<-updates
+
+	state := map[uint]TableResponse{}
+	for _, table := range snapshot.Tables {
+		state[table.ID] = table
+	}
+
+	// กรณีทดสอบที่ 2: reconcile ที่ไม่มีอะไรเปลี่ยนไม่ขยับ version และไม่มี diff
+	snapshot, _ = feed.refresh([]string{"reconcile"})
+	assert.Equal(t, uint64(1), snapshot.Version)
+	updated, removed := diffTables(state, (<-updates).Tables)
+	assert.Empty(t, updated)
+	assert.Empty(t, removed)
+
+	// กรณีทดสอบที่ 3: เปิดโต๊ะแล้วได้ diff เฉพาะโต๊ะที่เปลี่ยนพร้อม session
+	session, err := service.OpenTableSession(db.DB, a2, 3, nil, nil)
+	assert.Nil(t, err)
+	db.DB.Model(&a2).Update("status", "occupied")
+	db.DB.Delete(&a1)
+
+	snapshot, _ = feed.refresh([]string{service.TableEventOpened})
+	assert.Equal(t, uint64(2), snapshot.Version)
+	updated, removed = diffTables(state, (<-updates).Tables)
+	assert.Len(t, updated, 1)
+	assert.Equal(t, "occupied", updated[0].Status)
+	assert.Equal(t, session.Token, updated[0].UUID)
+	assert.Equal(t, 3, updated[0].GuestCount)
+	assert.Equal(t, []uint{a1.ID}, removed)
+
+	// กรณีทดสอบที่ 4: กรองตามโซน
+	assert.Empty(t, filterTablesByZone(snapshot.Tables, zone.ID))
+	assert.Len(t, filterTablesByZone(snapshot.Tables, 0), 1)
+
+	// กรณีทดสอบที่ 5: คิว walk-in เปลี่ยนทำให้ version ขยับแม้โต๊ะไม่เปลี่ยน
+	db.DB.Create(&models.WaitlistEntry{TicketCode: "ABCDE", CustomerName: "สมชาย", PartySize: 2, Status: models.WaitlistWaiting})
+	snapshot, _ = feed.refresh([]string{service.TableEventWaitlist})
+	assert.Equal(t, uint64(3), snapshot.Version)
+	assert.Len(t, (<-updates).Waitlist, 1)
+}
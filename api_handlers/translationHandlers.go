@@ -0,0 +1,368 @@
+package api_handlers
+
+import (
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm/clause"
+)
+
+// translatableFields ฟิลด์ที่แปลได้ของแต่ละประเภทข้อมูล
+var translatableFields = map[string][]string{
+	models.TranslatableMenuItem:    {"name", "description"},
+	models.TranslatableCategory:    {"name"},
+	models.TranslatableOptionGroup: {"name"},
+	models.TranslatableMenuOption:  {"name"},
+	models.TranslatablePromotion:   {"name", "description"},
+	models.TranslatableDietaryTag:  {"name"},
+	models.TranslatableCombo:       {"name", "description"},
+	models.TranslatableComboSlot:   {"name"},
+}
+
+// normalizeLang แปลง language tag เป็นรหัสภาษาหลัก เช่น en-US -> en, zh-TW/ch -> zh (ว่างถ้าไม่ถูกต้อง)
+func normalizeLang(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	switch tag {
+	case "ch", "cn": // frontend เดิมใช้ ch แทนภาษาจีน
+		return models.LangChinese
+	}
+	if len(tag) < 2 || len(tag) > 3 {
+		return ""
+	}
+	for _, r := range tag {
+		if r < 'a' || r > 'z' {
+			return ""
+		}
+	}
+	return tag
+}
+
+// availableLanguages ภาษาที่ตอบได้ คือ th/en/zh และภาษาที่มีในตาราง translations
+func availableLanguages() map[string]bool {
+	langs := map[string]bool{models.LangThai: true, models.LangEnglish: true, models.LangChinese: true}
+	var extra []string
+	db.DB.Model(&models.Translation{}).Distinct().Pluck("lang", &extra)
+	for _, lang := range extra {
+		langs[lang] = true
+	}
+	return langs
+}
+
+// resolveLanguage เลือกภาษาจาก ?lang ก่อน แล้วจึงดู Accept-Language ตามค่า q
+// ทั้งสองทางต้องเป็นภาษาที่ระบบมีข้อมูล ไม่พบใช้ภาษาไทย
+func resolveLanguage(c *fiber.Ctx) string {
+	var available map[string]bool
+	isAvailable := func(lang string) bool {
+		if available == nil {
+			available = availableLanguages()
+		}
+		return available[lang]
+	}
+
+	if lang := normalizeLang(c.Query("lang")); lang != "" && isAvailable(lang) {
+		return lang
+	}
+
+	header := c.Get(fiber.HeaderAcceptLanguage)
+	if header == "" {
+		return models.LangThai
+	}
+
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if lang := normalizeLang(fields[0]); lang != "" && q > 0 {
+			candidates = append(candidates, candidate{lang: lang, q: q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, cand := range candidates {
+		if isAvailable(cand.lang) {
+			return cand.lang
+		}
+	}
+	return models.LangThai
+}
+
+// localizer เลือกข้อความตามภาษา: ตาราง translations -> คอลัมน์ของภาษานั้น -> ภาษาไทย
+type localizer struct {
+	lang   string
+	values map[string]string
+}
+
+func translationKey(entityType string, entityID uint, field string) string {
+	return entityType + ":" + strconv.FormatUint(uint64(entityID), 10) + ":" + field
+}
+
+// newLocalizer อ่านภาษาจาก request โหลดคำแปลของภาษานั้น และตั้ง header ให้ cache แยกตามภาษา
+func newLocalizer(c *fiber.Ctx) *localizer {
+	l := &localizer{lang: resolveLanguage(c), values: map[string]string{}}
+
+	var translations []models.Translation
+	db.DB.Where("lang = ?", l.lang).Find(&translations)
+	for _, t := range translations {
+		l.values[translationKey(t.EntityType, t.EntityID, t.Field)] = t.Value
+	}
+
+	c.Set(fiber.HeaderContentLanguage, l.lang)
+	c.Vary(fiber.HeaderAcceptLanguage)
+	return l
+}
+
+// text คืนค่าของฟิลด์ในภาษาที่เลือก values เรียงเป็น ไทย, อังกฤษ, จีน
+func (l *localizer) text(entityType string, entityID uint, field string, th, en, zh string) string {
+	if v := l.values[translationKey(entityType, entityID, field)]; v != "" {
+		return v
+	}
+	switch l.lang {
+	case models.LangEnglish:
+		if en != "" {
+			return en
+		}
+	case models.LangChinese:
+		if zh != "" {
+			return zh
+		}
+	}
+	return th
+}
+
+func (l *localizer) category(category *models.Category) {
+	category.Localized = &models.LocalizedText{
+		Lang: l.lang,
+		Name: l.text(models.TranslatableCategory, category.ID, "name", category.Name, category.NameEn, category.NameCh),
+	}
+}
+
+func (l *localizer) menuItem(item *models.MenuItem) {
+	item.Localized = &models.LocalizedText{
+		Lang:        l.lang,
+		Name:        l.text(models.TranslatableMenuItem, item.ID, "name", item.Name, item.NameEn, item.NameCh),
+		Description: l.text(models.TranslatableMenuItem, item.ID, "description", item.Description, item.DescriptionEn, item.DescriptionCh),
+	}
+	if item.Category.ID != 0 {
+		l.category(&item.Category)
+	}
+	for i := range item.Tags {
+		l.dietaryTag(&item.Tags[i])
+	}
+	for i := range item.OptionGroups {
+		group := &item.OptionGroups[i]
+		group.Localized = &models.LocalizedText{
+			Lang: l.lang,
+			Name: l.text(models.TranslatableOptionGroup, group.ID, "name", group.Name, group.NameEn, group.NameCh),
+		}
+		for j := range group.Options {
+			l.menuOption(&group.Options[j])
+		}
+	}
+}
+
+func (l *localizer) menuOption(option *models.MenuOption) {
+	option.Localized = &models.LocalizedText{
+		Lang: l.lang,
+		Name: l.text(models.TranslatableMenuOption, option.ID, "name", option.Name, option.NameEn, option.NameCh),
+	}
+	for i := range option.Tags {
+		l.dietaryTag(&option.Tags[i])
+	}
+}
+
+func (l *localizer) dietaryTag(tag *models.DietaryTag) {
+	tag.Localized = &models.LocalizedText{
+		Lang: l.lang,
+		Name: l.text(models.TranslatableDietaryTag, tag.ID, "name", tag.Name, tag.NameEn, tag.NameCh),
+	}
+}
+
+func (l *localizer) promotion(promotion *models.Promotion) {
+	promotion.Localized = &models.LocalizedText{
+		Lang:        l.lang,
+		Name:        l.text(models.TranslatablePromotion, promotion.ID, "name", promotion.Name, promotion.NameEn, promotion.NameCh),
+		Description: l.text(models.TranslatablePromotion, promotion.ID, "description", promotion.Description, promotion.DescriptionEn, promotion.DescriptionCh),
+	}
+	for i := range promotion.Items {
+		if promotion.Items[i].MenuItem.ID != 0 {
+			l.menuItem(&promotion.Items[i].MenuItem)
+		}
+	}
+}
+
+func (l *localizer) combo(combo *models.Combo) {
+	combo.Localized = &models.LocalizedText{
+		Lang:        l.lang,
+		Name:        l.text(models.TranslatableCombo, combo.ID, "name", combo.Name, combo.NameEn, combo.NameCh),
+		Description: l.text(models.TranslatableCombo, combo.ID, "description", combo.Description, combo.DescriptionEn, combo.DescriptionCh),
+	}
+	for i := range combo.Slots {
+		slot := &combo.Slots[i]
+		slot.Localized = &models.LocalizedText{
+			Lang: l.lang,
+			Name: l.text(models.TranslatableComboSlot, slot.ID, "name", slot.Name, slot.NameEn, slot.NameCh),
+		}
+		for j := range slot.Choices {
+			if slot.Choices[j].MenuItem.ID != 0 {
+				l.menuItem(&slot.Choices[j].MenuItem)
+			}
+		}
+	}
+}
+
+// @Summary ดูคำแปล
+// @Description ดึงคำแปลทั้งหมด กรองตามประเภทข้อมูล, ID และภาษาได้
+// @Produce json
+// @Security BearerAuth
+// @Param entity_type query string false "ประเภทข้อมูล" Enums(menu_item, category, option_group, menu_option, promotion, dietary_tag, combo, combo_slot)
+// @Param entity_id query integer false "ID ของข้อมูล"
+// @Param lang query string false "รหัสภาษา เช่น ja, ko"
+// @Success 200 {array} models.Translation "รายการคำแปล"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
+// @Router /api/translations [get]
+// @Tags translations
+func GetTranslations(c *fiber.Ctx) error {
+	query := db.DB.Order("entity_type, entity_id, field, lang")
+	if entityType := c.Query("entity_type"); entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if entityID := c.QueryInt("entity_id"); entityID > 0 {
+		query = query.Where("entity_id = ?", entityID)
+	}
+	if lang := normalizeLang(c.Query("lang")); lang != "" {
+		query = query.Where("lang = ?", lang)
+	}
+
+	var translations []models.Translation
+	if err := query.Find(&translations).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch translations",
+		})
+	}
+
+	return c.JSON(translations)
+}
+
+// @Summary เพิ่ม/แก้ไขคำแปล
+// @Description บันทึกคำแปลหลายรายการพร้อมกัน ถ้ามีคำแปลของฟิลด์และภาษาเดิมอยู่แล้วจะถูกแทนที่
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body []models.UpsertTranslationRequest true "รายการคำแปล"
+// @Success 200 {array} models.Translation "คำแปลที่บันทึกแล้ว"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการบันทึก"
+// @Router /api/translations [put]
+// @Tags translations
+func UpsertTranslations(c *fiber.Ctx) error {
+	var req []models.UpsertTranslationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input format",
+		})
+	}
+
+	if len(req) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "At least one translation is required",
+		})
+	}
+
+	translations := make([]models.Translation, 0, len(req))
+	for _, item := range req {
+		fields, ok := translatableFields[item.EntityType]
+		if !ok {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid entity type: " + item.EntityType,
+			})
+		}
+
+		if !slices.Contains(fields, item.Field) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid field for " + item.EntityType + ": " + item.Field,
+			})
+		}
+
+		lang := normalizeLang(item.Lang)
+		if lang == "" || item.EntityID == 0 || strings.TrimSpace(item.Value) == "" {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "entity_id, lang and value are required",
+			})
+		}
+
+		translations = append(translations, models.Translation{
+			EntityType: item.EntityType,
+			EntityID:   item.EntityID,
+			Field:      item.Field,
+			Lang:       lang,
+			Value:      strings.TrimSpace(item.Value),
+		})
+	}
+
+	if err := db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "entity_type"}, {Name: "entity_id"}, {Name: "field"}, {Name: "lang"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&translations).Error; err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save translations",
+		})
+	}
+
+	return c.JSON(translations)
+}
+
+// @Summary ลบคำแปล
+// @Description ลบคำแปลหนึ่งรายการ หลังลบจะกลับไปใช้ค่าในคอลัมน์หลักหรือภาษาไทย
+// @Produce json
+// @Security BearerAuth
+// @Param id path integer true "ID ของคำแปล"
+// @Success 200 {object} map[string]interface{} "ลบสำเร็จ"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 404 {object} map[string]interface{} "ไม่พบคำแปล"
+// @Router /api/translations/{id} [delete]
+// @Tags translations
+func DeleteTranslation(c *fiber.Ctx) error {
+	translationID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid translation ID format",
+		})
+	}
+
+	result := db.DB.Delete(&models.Translation{}, translationID)
+	if result.Error != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete translation",
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Translation not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Translation deleted successfully",
+	})
+}
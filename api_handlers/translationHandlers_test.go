@@ -0,0 +1,114 @@
+package api_handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeLang(t *testing.T) {
+	assert.Equal(t, "en", normalizeLang("en-US"))
+	assert.Equal(t, "zh", normalizeLang("zh_TW"))
+	assert.Equal(t, "zh", normalizeLang("ch"))
+	assert.Equal(t, "ja", normalizeLang(" JA "))
+	assert.Equal(t, "", normalizeLang("*"))
+	assert.Equal(t, "", normalizeLang("english"))
+}
+
+func TestLocalizedActiveMenu(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.Translation{})
+
+	app.Get("/api/menu/ActiveMenu", GetActiveMenu)
+	app.Put("/api/translations", UpsertTranslations)
+
+	menu := models.MenuItem{
+		Name: "ผัดไทย", NameEn: "Pad Thai", NameCh: "", Description: "เส้นจันท์", DescriptionEn: "Rice noodles",
+		CategoryID: 1, Price: 80,
+		OptionGroups: []models.OptionGroup{{
+			Name: "ท็อปปิ้ง", NameEn: "Topping", NameCh: "配料", MaxSelections: 1,
+			Options: []models.MenuOption{{Name: "ไข่ดาว", NameEn: "Fried egg", NameCh: "煎蛋", Price: 10}},
+		}},
+	}
+	db.DB.Create(&menu)
+
+	getMenu := func(query, acceptLanguage string) ([]models.MenuItem, *http.Response) {
+		req := httptest.NewRequest("GET", "/api/menu/ActiveMenu"+query, nil)
+		if acceptLanguage != "" {
+			req.Header.Set("Accept-Language", acceptLanguage)
+		}
+		resp, err := app.Test(req)
+		assert.Nil(t, err)
+		var items []models.MenuItem
+		json.NewDecoder(resp.Body).Decode(&items)
+		return items, resp
+	}
+
+	// กรณีทดสอบที่ 1: ไม่ระบุภาษา ใช้ภาษาไทย
+	t.Run("Success - Default Thai", func(t *testing.T) {
+		items, resp := getMenu("", "")
+		assert.Equal(t, "th", resp.Header.Get("Content-Language"))
+		assert.Equal(t, "ผัดไทย", items[0].Localized.Name)
+		assert.Equal(t, "Default Category", items[0].Category.Localized.Name)
+	})
+
+	// กรณีทดสอบที่ 2: Accept-Language ภาษาอังกฤษ
+	t.Run("Success - Accept-Language English", func(t *testing.T) {
+		items, resp := getMenu("", "fr-FR,en-US;q=0.8,th;q=0.5")
+		assert.Equal(t, "en", resp.Header.Get("Content-Language"))
+		assert.Equal(t, "Pad Thai", items[0].Localized.Name)
+		assert.Equal(t, "Rice noodles", items[0].Localized.Description)
+		assert.Equal(t, "Fried egg", items[0].OptionGroups[0].Options[0].Localized.Name)
+	})
+
+	// กรณีทดสอบที่ 3: ?lang มาก่อน header และค่าว่างย้อนกลับเป็นไทย
+	t.Run("Success - Query lang with fallback", func(t *testing.T) {
+		items, _ := getMenu("?lang=ch", "en")
+		assert.Equal(t, "zh", items[0].Localized.Lang)
+		assert.Equal(t, "ผัดไทย", items[0].Localized.Name)
+		assert.Equal(t, "配料", items[0].OptionGroups[0].Localized.Name)
+
+		// ภาษาที่ระบบไม่มีข้อมูล ใช้ Accept-Language แทน
+		items, resp := getMenu("?lang=fr", "en")
+		assert.Equal(t, "en", resp.Header.Get("Content-Language"))
+		assert.Equal(t, "Pad Thai", items[0].Localized.Name)
+
+		_, resp = getMenu("?lang=fr", "")
+		assert.Equal(t, "th", resp.Header.Get("Content-Language"))
+	})
+
+	// กรณีทดสอบที่ 4: ภาษาใหม่จากตาราง translations
+	t.Run("Success - Translation table language", func(t *testing.T) {
+		body, _ := json.Marshal([]models.UpsertTranslationRequest{
+			{EntityType: models.TranslatableMenuItem, EntityID: menu.ID, Field: "name", Lang: "ja", Value: "パッタイ"},
+		})
+		req := httptest.NewRequest("PUT", "/api/translations", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		items, resp := getMenu("", "ja-JP,en;q=0.5")
+		assert.Equal(t, "ja", resp.Header.Get("Content-Language"))
+		assert.Equal(t, "パッタイ", items[0].Localized.Name)
+		assert.Equal(t, "เส้นจันท์", items[0].Localized.Description)
+	})
+
+	// กรณีทดสอบที่ 5: ฟิลด์ที่แปลไม่ได้
+	t.Run("Failure - Invalid translation field", func(t *testing.T) {
+		body, _ := json.Marshal([]models.UpsertTranslationRequest{
+			{EntityType: models.TranslatableCategory, EntityID: 1, Field: "description", Lang: "ja", Value: "x"},
+		})
+		req := httptest.NewRequest("PUT", "/api/translations", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
@@ -0,0 +1,495 @@
+package api_handlers
+
+import (
+	"errors"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	utils "food-ordering-api/utility"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	waitlistMinutesPerParty = 15                                 // เวลารอโดยประมาณต่อคิวที่อยู่ข้างหน้า ใช้เมื่อพนักงานไม่ได้แจ้งเวลารอเอง
+	ticketCodeChars         = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // ไม่มี I O 0 1 ที่อ่านสับสนง่าย
+	waitlistTicketLength    = 5
+)
+
+// WaitlistQueueEntry คิวที่ยังรออยู่พร้อมลำดับและเวลารอที่เหลือโดยประมาณ
+type WaitlistQueueEntry struct {
+	models.WaitlistEntry
+	Position             int `json:"position"`
+	EstimatedWaitMinutes int `json:"estimated_wait_minutes"`
+}
+
+// WaitlistTicketStatus ข้อมูลคิวที่ลูกค้าดูเองได้จาก ticket code (ไม่มีชื่อและเบอร์โทร)
+type WaitlistTicketStatus struct {
+	TicketCode           string `json:"ticket_code"`
+	Status               string `json:"status"`
+	PartySize            int    `json:"party_size"`
+	Position             int    `json:"position"`      // 0 = ไม่ได้อยู่ในคิวแล้ว
+	PartiesAhead         int    `json:"parties_ahead"` // จำนวนคิวข้างหน้า
+	EstimatedWaitMinutes int    `json:"estimated_wait_minutes"`
+}
+
+// loadWaitlist คิวที่ยังรออยู่ของวันนี้ เรียงตามลำดับคิว (คิวที่ค้างจากวันก่อนไม่นับ)
+func loadWaitlist(tx *gorm.DB) ([]models.WaitlistEntry, error) {
+	now := time.Now()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	entries := []models.WaitlistEntry{}
+	err := tx.Where("status IN ? AND created_at >= ?", []string{models.WaitlistWaiting, models.WaitlistNotified}, startOfDay).
+		Order("created_at, id").Find(&entries).Error
+	return entries, err
+}
+
+// waitlistQueue ใส่ลำดับคิวและเวลารอที่เหลือ นับจากเวลาที่แจ้งลูกค้าลบด้วยเวลาที่รอไปแล้ว
+func waitlistQueue(entries []models.WaitlistEntry, now time.Time) []WaitlistQueueEntry {
+	queue := make([]WaitlistQueueEntry, 0, len(entries))
+	for i, entry := range entries {
+		remaining := entry.QuotedWaitMinutes - int(now.Sub(entry.CreatedAt).Minutes())
+		if remaining < 0 {
+			remaining = 0
+		}
+		queue = append(queue, WaitlistQueueEntry{
+			WaitlistEntry:        entry,
+			Position:             i + 1,
+			EstimatedWaitMinutes: remaining,
+		})
+	}
+	return queue
+}
+
+// newWaitlistTicketCode สุ่ม ticket code ที่ยังไม่ซ้ำกับคิวอื่น
+func newWaitlistTicketCode(tx *gorm.DB) (string, error) {
+	for attempt := 0; attempt < 10; attempt++ {
+		code := make([]byte, waitlistTicketLength)
+		for i := range code {
+			code[i] = ticketCodeChars[rand.Intn(len(ticketCodeChars))]
+		}
+
+		var count int64
+		if err := tx.Model(&models.WaitlistEntry{}).Where("ticket_code = ?", string(code)).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return string(code), nil
+		}
+	}
+	return "", errors.New("failed to generate unique ticket code")
+}
+
+// findWaitlistEntry คิวจาก ID ใน path
+func findWaitlistEntry(tx *gorm.DB, c *fiber.Ctx) (*models.WaitlistEntry, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	var entry models.WaitlistEntry
+	if err := tx.First(&entry, id).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func isWaitlistActive(entry *models.WaitlistEntry) bool {
+	return entry.Status == models.WaitlistWaiting || entry.Status == models.WaitlistNotified
+}
+
+// @Summary รับคิวลูกค้า walk-in
+// @Description เพิ่มลูกค้าเข้าคิวรอโต๊ะ ถ้าไม่ระบุเวลารอจะประเมินจากจำนวนคิวข้างหน้า คืน ticket code ให้ลูกค้าตรวจสอบคิวเอง
+// @Accept json
+// @Produce json
+// @Param request body models.WaitlistRequest true "ข้อมูลลูกค้า"
+// @Success 201 {object} WaitlistQueueEntry "รับคิวสำเร็จ"
+// @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการรับคิว"
+// @Router /api/waitlist [post]
+// @Tags Waitlist
+func AddWaitlistEntry(c *fiber.Ctx) error {
+	var req models.WaitlistRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	req.CustomerName = strings.TrimSpace(req.CustomerName)
+	if req.CustomerName == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "กรุณาระบุชื่อลูกค้า",
+		})
+	}
+	if req.PartySize <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "จำนวนลูกค้าต้องมากกว่า 0",
+		})
+	}
+	if req.QuotedWaitMinutes != nil && *req.QuotedWaitMinutes < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "เวลารอต้องไม่ติดลบ",
+		})
+	}
+
+	var entry models.WaitlistEntry
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		waiting, err := loadWaitlist(tx)
+		if err != nil {
+			return err
+		}
+		code, err := newWaitlistTicketCode(tx)
+		if err != nil {
+			return err
+		}
+
+		entry = models.WaitlistEntry{
+			TicketCode:        code,
+			CustomerName:      req.CustomerName,
+			PhoneNumber:       strings.TrimSpace(req.PhoneNumber),
+			PartySize:         req.PartySize,
+			Note:              req.Note,
+			Status:            models.WaitlistWaiting,
+			QuotedWaitMinutes: (len(waiting) + 1) * waitlistMinutesPerParty,
+			StaffID:           utils.GetStaffID(c),
+		}
+		if req.QuotedWaitMinutes != nil {
+			entry.QuotedWaitMinutes = *req.QuotedWaitMinutes
+		}
+		return tx.Create(&entry).Error
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to add waitlist entry",
+		})
+	}
+	service.PublishTableChange(service.TableEventWaitlist)
+
+	return c.Status(fiber.StatusCreated).JSON(waitlistEntryInQueue(db.DB, entry))
+}
+
+// waitlistEntryInQueue คิวพร้อมลำดับปัจจุบัน ถ้าไม่ได้อยู่ในคิวแล้ว position = 0
+func waitlistEntryInQueue(tx *gorm.DB, entry models.WaitlistEntry) WaitlistQueueEntry {
+	entries, err := loadWaitlist(tx)
+	if err == nil {
+		for _, queued := range waitlistQueue(entries, time.Now()) {
+			if queued.ID == entry.ID {
+				return queued
+			}
+		}
+	}
+	return WaitlistQueueEntry{WaitlistEntry: entry}
+}
+
+// @Summary ดูคิวที่รออยู่
+// @Description คิวที่ยังรอหรือแจ้งแล้วของวันนี้ เรียงตามลำดับคิว พร้อมเวลารอที่เหลือโดยประมาณ
+// @Produce json
+// @Success 200 {array} WaitlistQueueEntry "คิวที่รออยู่"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
+// @Router /api/waitlist [get]
+// @Tags Waitlist
+func GetWaitlist(c *fiber.Ctx) error {
+	entries, err := loadWaitlist(db.DB)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch waitlist",
+		})
+	}
+	return c.JSON(waitlistQueue(entries, time.Now()))
+}
+
+// @Summary แจ้งลูกค้าว่าโต๊ะพร้อม
+// @Description เปลี่ยนคิวเป็น notified และบันทึกเวลาที่แจ้ง เรียกซ้ำได้เมื่อแจ้งลูกค้าอีกครั้ง
+// @Produce json
+// @Param id path integer true "ID คิว"
+// @Success 200 {object} models.WaitlistEntry "แจ้งลูกค้าแล้ว"
+// @Failure 400 {object} map[string]interface{} "คิวนี้ไม่ได้รออยู่"
+// @Failure 404 {object} map[string]interface{} "ไม่พบคิว"
+// @Router /api/waitlist/{id}/notify [post]
+// @Tags Waitlist
+func NotifyWaitlistEntry(c *fiber.Ctx) error {
+	entry, err := findWaitlistEntry(db.DB, c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Waitlist entry not found",
+		})
+	}
+	if !isWaitlistActive(entry) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("คิวนี้อยู่ในสถานะ %s แล้ว", entry.Status),
+		})
+	}
+
+	now := time.Now()
+	if err := db.DB.Model(&models.WaitlistEntry{}).Where("id = ?", entry.ID).Updates(map[string]interface{}{
+		"status":      models.WaitlistNotified,
+		"notified_at": now,
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update waitlist entry",
+		})
+	}
+	entry.Status = models.WaitlistNotified
+	entry.NotifiedAt = &now
+	service.PublishTableChange(service.TableEventWaitlist)
+
+	return c.JSON(entry)
+}
+
+// @Summary แนะนำโต๊ะให้คิว
+// @Description โต๊ะที่ว่างตอนนี้และนั่งได้ครบจำนวนลูกค้าของคิวนี้ รวมโต๊ะรวมที่ว่างทั้งกลุ่มและโต๊ะที่ติดกันซึ่งรวมได้ เรียงจากเหมาะที่สุด
+// @Produce json
+// @Param id path integer true "ID คิว"
+// @Param zone_id query integer false "โซนที่ลูกค้าต้องการ"
+// @Success 200 {array} service.TableAllocation "โต๊ะที่แนะนำ"
+// @Failure 404 {object} map[string]interface{} "ไม่พบคิว"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการดึงข้อมูล"
+// @Router /api/waitlist/{id}/suggest [get]
+// @Tags Waitlist
+func SuggestWaitlistTables(c *fiber.Ctx) error {
+	entry, err := findWaitlistEntry(db.DB, c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Waitlist entry not found",
+		})
+	}
+
+	suggestions, err := service.AllocateTables(db.DB, service.AllocationRequest{
+		PartySize: entry.PartySize,
+		ZoneID:    queryZoneID(c),
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch tables",
+		})
+	}
+	return c.JSON(suggestions)
+}
+
+// @Summary พาคิวไปนั่งโต๊ะ
+// @Description เปิดโต๊ะ (หรือโต๊ะรวมทั้งกลุ่ม) ให้คิวนี้ สร้าง session ตามจำนวนลูกค้าและเปลี่ยนคิวเป็น seated
+// @Description พิมพ์ QR Code สำหรับสั่งอาหารได้ที่ /api/qr/reprint/{id} ด้วย token ของ session
+// @Accept json
+// @Produce json
+// @Param id path integer true "ID คิว"
+// @Param request body models.SeatWaitlistRequest true "โต๊ะที่จะนั่ง"
+// @Success 200 {object} map[string]interface{} "พาไปนั่งสำเร็จ"
+// @Failure 400 {object} map[string]interface{} "คิวหรือโต๊ะไม่พร้อม"
+// @Failure 404 {object} map[string]interface{} "ไม่พบคิวหรือโต๊ะ"
+// @Failure 500 {object} map[string]interface{} "เกิดข้อผิดพลาดในการเปิดโต๊ะ"
+// @Router /api/waitlist/{id}/seat [post]
+// @Tags Waitlist
+func SeatWaitlistEntry(c *fiber.Ctx) error {
+	var req models.SeatWaitlistRequest
+	if err := c.BodyParser(&req); err != nil || req.TableID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "กรุณาระบุโต๊ะ",
+		})
+	}
+
+	tx := db.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	entry, err := findWaitlistEntry(tx, c)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Waitlist entry not found",
+		})
+	}
+	if !isWaitlistActive(entry) {
+		tx.Rollback()
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("คิวนี้อยู่ในสถานะ %s แล้ว", entry.Status),
+		})
+	}
+
+	var table models.Table
+	if err := tx.First(&table, req.TableID).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Table not found",
+		})
+	}
+
+	// โต๊ะรวมต้องว่างทั้งกลุ่ม และนับที่นั่งรวมของทั้งกลุ่ม
+	var tables []models.Table
+	if hasGroupID(table.GroupID) {
+		if err := tx.Where("group_id = ?", *table.GroupID).Find(&tables).Error; err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch table group",
+			})
+		}
+	} else {
+		tables = []models.Table{table}
+	}
+	tableIDs := make([]uint, 0, len(tables))
+	capacity := 0
+	for _, t := range tables {
+		if t.Status != "available" {
+			tx.Rollback()
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("โต๊ะ %s ไม่ว่าง", t.Name),
+			})
+		}
+		tableIDs = append(tableIDs, t.ID)
+		capacity += t.Capacity
+	}
+	if capacity < entry.PartySize && !req.Force {
+		tx.Rollback()
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("โต๊ะนั่งได้ %d คน แต่ลูกค้ามี %d คน", capacity, entry.PartySize),
+		})
+	}
+
+	change, err := service.BeginTableChange(tx, models.TableActionOpen, tableIDs...)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to read table state",
+		})
+	}
+
+	if err := tx.Model(&models.Table{}).Where("id IN ?", tableIDs).Updates(map[string]interface{}{
+		"status":     "occupied",
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update table status",
+		})
+	}
+
+	session, err := service.OpenTableSession(tx, table, entry.PartySize, nil, nil)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, service.ErrTableInSession) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "โต๊ะนี้มีลูกค้าใช้งานอยู่",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to open table session",
+		})
+	}
+
+	change.Entry.SessionID = &session.ID
+	change.Entry.StaffID = utils.GetStaffID(c)
+	change.Entry.Note = fmt.Sprintf("waitlist %s", entry.TicketCode)
+	if err := change.Record(tx); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record table history",
+		})
+	}
+
+	now := time.Now()
+	if err := tx.Model(&models.WaitlistEntry{}).Where("id = ?", entry.ID).Updates(map[string]interface{}{
+		"status":     models.WaitlistSeated,
+		"seated_at":  now,
+		"table_id":   session.TableID,
+		"session_id": session.ID,
+	}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update waitlist entry",
+		})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to commit transaction",
+		})
+	}
+	service.PublishTableChange(service.TableEventOpened, tableIDs...)
+	service.PublishTableChange(service.TableEventWaitlist)
+
+	entry.Status = models.WaitlistSeated
+	entry.SeatedAt = &now
+	entry.TableID = &session.TableID
+	entry.SessionID = &session.ID
+	return c.JSON(fiber.Map{
+		"message":  "Party seated successfully",
+		"waitlist": entry,
+		"session":  session,
+	})
+}
+
+// @Summary ยกเลิกคิว
+// @Description ลูกค้ายกเลิกคิว หรือส่ง ?no_show=true เมื่อเรียกแล้วลูกค้าไม่มา
+// @Produce json
+// @Param id path integer true "ID คิว"
+// @Param no_show query boolean false "ลูกค้าไม่มา"
+// @Success 200 {object} models.WaitlistEntry "ยกเลิกคิวสำเร็จ"
+// @Failure 400 {object} map[string]interface{} "คิวนี้ไม่ได้รออยู่"
+// @Failure 404 {object} map[string]interface{} "ไม่พบคิว"
+// @Router /api/waitlist/{id}/cancel [post]
+// @Tags Waitlist
+func CancelWaitlistEntry(c *fiber.Ctx) error {
+	entry, err := findWaitlistEntry(db.DB, c)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Waitlist entry not found",
+		})
+	}
+	if !isWaitlistActive(entry) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("คิวนี้อยู่ในสถานะ %s แล้ว", entry.Status),
+		})
+	}
+
+	status := models.WaitlistCancelled
+	if c.QueryBool("no_show") {
+		status = models.WaitlistNoShow
+	}
+	if err := db.DB.Model(&models.WaitlistEntry{}).Where("id = ?", entry.ID).Update("status", status).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update waitlist entry",
+		})
+	}
+	entry.Status = status
+	service.PublishTableChange(service.TableEventWaitlist)
+
+	return c.JSON(entry)
+}
+
+// @Summary ตรวจสอบคิวด้วย ticket code
+// @Description สำหรับลูกค้าดูลำดับคิวและเวลารอโดยประมาณ ไม่ต้องเข้าสู่ระบบ
+// @Produce json
+// @Param code path string true "ticket code"
+// @Success 200 {object} WaitlistTicketStatus "สถานะคิว"
+// @Failure 404 {object} map[string]interface{} "ไม่พบคิว"
+// @Router /api/waitlist/ticket/{code} [get]
+// @Tags Waitlist
+func GetWaitlistTicket(c *fiber.Ctx) error {
+	code := strings.ToUpper(strings.TrimSpace(c.Params("code")))
+
+	var entry models.WaitlistEntry
+	if err := db.DB.Where("ticket_code = ?", code).First(&entry).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Ticket not found",
+		})
+	}
+
+	queued := waitlistEntryInQueue(db.DB, entry)
+	ticket := WaitlistTicketStatus{
+		TicketCode:           entry.TicketCode,
+		Status:               entry.Status,
+		PartySize:            entry.PartySize,
+		Position:             queued.Position,
+		EstimatedWaitMinutes: queued.EstimatedWaitMinutes,
+	}
+	if queued.Position > 0 {
+		ticket.PartiesAhead = queued.Position - 1
+	}
+	return c.JSON(ticket)
+}
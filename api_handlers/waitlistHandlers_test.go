@@ -0,0 +1,149 @@
+package api_handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"food-ordering-api/db"
+	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitlist(t *testing.T) {
+	app := fiber.New()
+	setupTestDB(t)
+	db.DB.AutoMigrate(&models.Table{}, &models.TableSession{}, &models.TableHistory{}, &models.WaitlistEntry{}, &models.TableReservation{})
+
+	app.Get("/api/waitlist/ticket/:code", GetWaitlistTicket)
+	app.Get("/api/waitlist", GetWaitlist)
+	app.Post("/api/waitlist", AddWaitlistEntry)
+	app.Post("/api/waitlist/:id/notify", NotifyWaitlistEntry)
+	app.Get("/api/waitlist/:id/suggest", SuggestWaitlistTables)
+	app.Post("/api/waitlist/:id/seat", SeatWaitlistEntry)
+	app.Post("/api/waitlist/:id/cancel", CancelWaitlistEntry)
+
+	add := func(name string, size int) WaitlistQueueEntry {
+		resp := sendJSON(t, app, "POST", "/api/waitlist", models.WaitlistRequest{CustomerName: name, PhoneNumber: "0812345678", PartySize: size})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		var entry WaitlistQueueEntry
+		json.NewDecoder(resp.Body).Decode(&entry)
+		return entry
+	}
+
+	// โต๊ะ 2 ที่ โต๊ะ 4 ที่ โต๊ะรวม 2+2 ที่ และโต๊ะ 6 ที่ที่มีลูกค้าอยู่
+	groupID := "g-1"
+	small := models.Table{Name: "A1", Capacity: 2, Status: "available"}
+	four := models.Table{Name: "A2", Capacity: 4, Status: "available"}
+	parent := models.Table{Name: "B1", Capacity: 2, Status: "available", GroupID: &groupID}
+	busy := models.Table{Name: "C1", Capacity: 6, Status: "occupied"}
+	for _, table := range []*models.Table{&small, &four, &parent, &busy} {
+		db.DB.Create(table)
+	}
+	child := models.Table{Name: "B2", Capacity: 2, Status: "available", GroupID: &groupID, ParentID: &parent.ID}
+	db.DB.Create(&child)
+
+	var first, second WaitlistQueueEntry
+
+	// กรณีทดสอบที่ 1: รับคิวได้ ticket code ลำดับคิว และเวลารอโดยประมาณ
+	t.Run("Success - Add parties to queue", func(t *testing.T) {
+		first = add("สมชาย", 4)
+		second = add("สมหญิง", 2)
+
+		assert.Len(t, first.TicketCode, waitlistTicketLength)
+		assert.Equal(t, 1, first.Position)
+		assert.Equal(t, waitlistMinutesPerParty, first.QuotedWaitMinutes)
+		assert.Equal(t, 2, second.Position)
+		assert.Equal(t, 2*waitlistMinutesPerParty, second.EstimatedWaitMinutes)
+
+		resp := sendJSON(t, app, "POST", "/api/waitlist", models.WaitlistRequest{CustomerName: "ไม่มีคน", PartySize: 0})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 2: แนะนำโต๊ะเดี่ยวและโต๊ะรวมที่นั่งพอ เรียงจากที่นั่งเหลือน้อยที่สุด
+	t.Run("Success - Suggest tables including merged groups", func(t *testing.T) {
+		resp := sendJSON(t, app, "GET", fmt.Sprintf("/api/waitlist/%d/suggest", first.ID), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var suggestions []service.TableAllocation
+		json.NewDecoder(resp.Body).Decode(&suggestions)
+		assert.Len(t, suggestions, 2)
+		assert.Equal(t, four.ID, suggestions[0].TableID)
+		assert.Equal(t, parent.ID, suggestions[1].TableID)
+		assert.Equal(t, []uint{parent.ID, child.ID}, suggestions[1].TableIDs)
+		assert.Equal(t, 4, suggestions[1].Capacity)
+		assert.Equal(t, "B1+B2", suggestions[1].Name)
+	})
+
+	// กรณีทดสอบที่ 3: แจ้งลูกค้าแล้วพาไปนั่งโต๊ะรวม เปิด session ทั้งกลุ่มและบันทึกประวัติ
+	t.Run("Success - Notify and seat on merged group", func(t *testing.T) {
+		resp := sendJSON(t, app, "POST", fmt.Sprintf("/api/waitlist/%d/notify", first.ID), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = sendJSON(t, app, "POST", fmt.Sprintf("/api/waitlist/%d/seat", first.ID), models.SeatWaitlistRequest{TableID: small.ID})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = sendJSON(t, app, "POST", fmt.Sprintf("/api/waitlist/%d/seat", first.ID), models.SeatWaitlistRequest{TableID: child.ID})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var seated models.WaitlistEntry
+		db.DB.First(&seated, first.ID)
+		assert.Equal(t, models.WaitlistSeated, seated.Status)
+		assert.NotNil(t, seated.NotifiedAt)
+		assert.Equal(t, parent.ID, *seated.TableID)
+
+		session, err := service.FindOpenSessionForTable(db.DB, child.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, *seated.SessionID, session.ID)
+		assert.Equal(t, 4, session.GuestCount)
+
+		var tables []models.Table
+		db.DB.Where("group_id = ?", groupID).Find(&tables)
+		for _, table := range tables {
+			assert.Equal(t, "occupied", table.Status)
+		}
+
+		var history int64
+		db.DB.Model(&models.TableHistory{}).Where("action = ? AND session_id = ?", models.TableActionOpen, session.ID).Count(&history)
+		assert.Equal(t, int64(2), history)
+
+		// นั่งซ้ำไม่ได้
+		resp = sendJSON(t, app, "POST", fmt.Sprintf("/api/waitlist/%d/seat", first.ID), models.SeatWaitlistRequest{TableID: four.ID})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 4: ลูกค้าตรวจสอบคิวด้วย ticket code ลำดับขยับเมื่อคิวข้างหน้าได้นั่ง
+	t.Run("Success - Public ticket lookup", func(t *testing.T) {
+		resp := sendJSON(t, app, "GET", "/api/waitlist/ticket/"+second.TicketCode, nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var ticket WaitlistTicketStatus
+		json.NewDecoder(resp.Body).Decode(&ticket)
+		assert.Equal(t, models.WaitlistWaiting, ticket.Status)
+		assert.Equal(t, 1, ticket.Position)
+		assert.Equal(t, 0, ticket.PartiesAhead)
+
+		resp = sendJSON(t, app, "GET", "/api/waitlist/ticket/ZZZZZ", nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	// กรณีทดสอบที่ 5: ลูกค้าไม่มา ออกจากคิว
+	t.Run("Success - Cancel as no-show", func(t *testing.T) {
+		resp := sendJSON(t, app, "POST", fmt.Sprintf("/api/waitlist/%d/cancel?no_show=true", second.ID), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = sendJSON(t, app, "GET", "/api/waitlist", nil)
+		var queue []WaitlistQueueEntry
+		json.NewDecoder(resp.Body).Decode(&queue)
+		assert.Empty(t, queue)
+
+		resp = sendJSON(t, app, "GET", "/api/waitlist/ticket/"+second.TicketCode, nil)
+		var ticket WaitlistTicketStatus
+		json.NewDecoder(resp.Body).Decode(&ticket)
+		assert.Equal(t, models.WaitlistNoShow, ticket.Status)
+		assert.Equal(t, 0, ticket.Position)
+	})
+}
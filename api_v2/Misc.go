@@ -89,11 +89,18 @@ func V2_prepareOrderPrintContent(job models.PrintJob) ([]byte, error) {
 			}
 		}
 
+		// รายการในชุดเดียวกันพิมพ์ติดกันใต้หัวข้อชุด
+		newItems = GroupKitchenItems(newItems)
+
 		if len(newItems) > 0 {
 			content.WriteString("\n~รายการใหม่\n")
 			content.WriteString("----\n")
 
 			for i, item := range newItems {
+				if header := ComboKitchenHeader(newItems, i); header != "" {
+					content.WriteString("~" + header + "\n")
+				}
+
 				// หมายเลขรายการและชื่ออาหาร
 				itemName := fmt.Sprintf("%d. %s", i+1, item.MenuItem.Name)
 
@@ -116,11 +123,19 @@ func V2_prepareOrderPrintContent(job models.PrintJob) ([]byte, error) {
 					content.WriteString(quantityText + "\n")
 				}
 
+				// เตือนสารก่อภูมิแพ้จากเมนูและตัวเลือกที่ลูกค้าเลือก
+				if allergens := orderItemAllergens(item); len(allergens) > 0 {
+					for _, line := range wrapItemName("!! สารก่อภูมิแพ้: "+strings.Join(allergens, ", "), 35) {
+						content.WriteString(line + "\n")
+					}
+				}
+
 				// ตัวเลือกเพิ่มเติม
 				if len(item.Options) > 0 {
 					content.WriteString("รายละเอียดเพิ่มเติม:\n")
-					for _, opt := range item.Options {
-						optName := "   • " + opt.MenuOption.OptionGroup.Name + ": " + opt.MenuOption.Name
+					for _, nested := range NestOrderItemOptions(item.Options) {
+						opt := nested.Option
+						optName := NestedOptionPrefix("   ", nested.Depth) + opt.MenuOption.OptionGroup.Name + ": " + opt.MenuOption.Name
 						// ใช้ wrapItemName สำหรับตัวเลือกด้วย
 						optLines := wrapItemName(optName, 35)
 						for _, line := range optLines {
@@ -197,6 +212,7 @@ func PrepareReceiptPrintContent(job models.PrintJob) ([]byte, error) {
 		fmt.Sprintf("เลขที่:                           %d", job.Receipt.ID),
 		fmt.Sprintf("โต๊ะที่:                          %s", job.Receipt.TableID),
 		fmt.Sprintf("พนักงาน:                      %s", job.Receipt.Staff.Name),
+		fmt.Sprintf("จำนวนลูกค้า:                    %d", job.Receipt.GuestCount),
 		fmt.Sprintf("วันที่:                         %s", time.Now().Format("02-01-2006")),
 		fmt.Sprintf("เวลาเข้า:                         %s", job.Receipt.CreatedAt.Format("15:04")),
 		fmt.Sprintf("เวลาออก:                         %s", time.Now().Format("15:04")),
@@ -228,8 +244,15 @@ func PrepareReceiptPrintContent(job models.PrintJob) ([]byte, error) {
 
 	// คำนวณยอดรวม
 	var totalItems int
+	var comboLines []ComboLine
 	for _, order := range job.Receipt.Orders {
-		for _, item := range order.Items {
+		regularItems, combos := GroupComboItems(order.Items)
+		comboLines = append(comboLines, combos...)
+		for _, combo := range combos {
+			totalItems += combo.Quantity
+		}
+
+		for _, item := range regularItems {
 			if item.Status != "cancelled" {
 				totalItems += item.Quantity
 				var optStrings []string
@@ -316,8 +339,9 @@ func PrepareReceiptPrintContent(job models.PrintJob) ([]byte, error) {
 		}
 
 		// ตัวเลือกเพิ่มเติม
-		for _, opt := range group.Options {
-			optName := "  • " + opt.MenuOption.Name
+		for _, nested := range NestOrderItemOptions(group.Options) {
+			opt := nested.Option
+			optName := NestedOptionPrefix("  ", nested.Depth) + opt.MenuOption.Name
 			optPrice := opt.Price * float64(opt.Quantity)
 
 			// ตัดข้อความตัวเลือกที่ยาวเกิน
@@ -343,6 +367,9 @@ func PrepareReceiptPrintContent(job models.PrintJob) ([]byte, error) {
 		}
 	}
 
+	// ชุดอาหารพิมพ์ต่อจากรายการปกติ
+	WriteComboLines(&content, comboLines)
+
 	content.WriteString(formatter.GetDivider() + "\n")
 
 	// สรุปยอด
@@ -1048,6 +1075,193 @@ func isPNG(data []byte) bool {
 	return len(data) > 8 && bytes.Equal(data[:8], pngSignature)
 }
 
+// orderItemAllergens รวมชื่อแท็กสารก่อภูมิแพ้ของเมนูและตัวเลือกที่เลือก (ไม่ซ้ำ เรียงตามที่พบ)
+func orderItemAllergens(item models.OrderItem) []string {
+	var names []string
+	seen := map[string]bool{}
+	add := func(tags []models.DietaryTag) {
+		for _, tag := range tags {
+			if tag.Type != models.TagTypeAllergen || seen[tag.Code] {
+				continue
+			}
+			seen[tag.Code] = true
+			names = append(names, tag.Name)
+		}
+	}
+
+	add(item.MenuItem.Tags)
+	for _, opt := range item.Options {
+		add(opt.MenuOption.Tags)
+	}
+	return names
+}
+
+// NestedOrderOption ตัวเลือกของรายการอาหารพร้อมระดับชั้น (0 = กลุ่มหลัก) ใช้ย่อหน้าตอนพิมพ์
+type NestedOrderOption struct {
+	Option models.OrderItemOption
+	Depth  int
+}
+
+// NestOrderItemOptions เรียงตัวเลือกให้ตัวเลือกของกลุ่มย่อยอยู่ต่อจากตัวเลือกแม่ที่ลูกค้าเลือก
+// ต้อง preload MenuOption.OptionGroup ไว้ ไม่งั้นทุกตัวเลือกจะอยู่ชั้นแรก
+func NestOrderItemOptions(options []models.OrderItemOption) []NestedOrderOption {
+	selected := map[uint]bool{}
+	for _, opt := range options {
+		selected[opt.MenuOptionID] = true
+	}
+
+	children := map[uint][]int{}
+	var roots []int
+	for i, opt := range options {
+		parent := opt.MenuOption.OptionGroup.ParentOptionID
+		if parent != nil && *parent != opt.MenuOptionID && selected[*parent] {
+			children[*parent] = append(children[*parent], i)
+		} else {
+			roots = append(roots, i)
+		}
+	}
+
+	result := make([]NestedOrderOption, 0, len(options))
+	visited := make([]bool, len(options))
+	var walk func(indexes []int, depth int)
+	walk = func(indexes []int, depth int) {
+		for _, i := range indexes {
+			if visited[i] {
+				continue
+			}
+			visited[i] = true
+			result = append(result, NestedOrderOption{Option: options[i], Depth: depth})
+			walk(children[options[i].MenuOptionID], depth+1)
+		}
+	}
+	walk(roots, 0)
+	// ตัวเลือกที่อ้างถึงกันเป็นวง (ข้อมูลผิดปกติ) ยังต้องพิมพ์ออกมา
+	for i, opt := range options {
+		if !visited[i] {
+			result = append(result, NestedOrderOption{Option: opt})
+		}
+	}
+	return result
+}
+
+// NestedOptionPrefix คำนำหน้าตัวเลือกตามระดับชั้น ตัวเลือกของกลุ่มย่อยย่อหน้าลึกขึ้นและใช้ "-" แทน "•"
+func NestedOptionPrefix(indent string, depth int) string {
+	if depth == 0 {
+		return indent + "• "
+	}
+	return strings.Repeat(indent, depth+1) + "- "
+}
+
+// ComboLine ชุดอาหารหนึ่งชุดที่สั่งบนใบเสร็จ/ใบรายการอาหาร พิมพ์เป็นบรรทัดเดียวพร้อมรายการในชุด
+type ComboLine struct {
+	Name     string
+	Quantity int
+	Price    float64 // ราคารวมของชุด (ส่วนแบ่งราคาของทุกรายการ + ตัวเลือกเสริม)
+	Items    []models.OrderItem
+}
+
+// comboName ชื่อชุดของรายการ ต้อง preload ComboUsage.Combo ไว้
+func comboName(item models.OrderItem) string {
+	if item.ComboUsage != nil && item.ComboUsage.Combo.Name != "" {
+		return item.ComboUsage.Combo.Name
+	}
+	return "ชุดอาหาร"
+}
+
+// GroupComboItems แยกรายการที่อยู่ในชุดออกมารวมเป็น ComboLine ตามลำดับที่สั่ง คืนรายการปกติที่เหลือและบรรทัดชุด
+// รายการในชุดที่ถูกยกเลิกไม่นับรวม ส่วนรายการปกติคืนไปทั้งหมดให้ผู้เรียกกรองเอง
+func GroupComboItems(items []models.OrderItem) ([]models.OrderItem, []ComboLine) {
+	var regular []models.OrderItem
+	var combos []ComboLine
+	index := map[uint]int{}
+	for _, item := range items {
+		if item.ComboUsageID == nil {
+			regular = append(regular, item)
+			continue
+		}
+		if item.Status == "cancelled" {
+			continue
+		}
+
+		price := item.Price * float64(item.Quantity)
+		for _, opt := range item.Options {
+			price += opt.Price * float64(opt.Quantity)
+		}
+
+		i, ok := index[*item.ComboUsageID]
+		if !ok {
+			i = len(combos)
+			index[*item.ComboUsageID] = i
+			combos = append(combos, ComboLine{Name: comboName(item), Quantity: item.Quantity})
+		}
+		combos[i].Price += price
+		combos[i].Items = append(combos[i].Items, item)
+	}
+	return regular, combos
+}
+
+// GroupKitchenItems เรียงรายการให้รายการในชุดเดียวกันอยู่ติดกัน (ที่ตำแหน่งของรายการแรกของชุด) ลำดับอื่นคงเดิม
+func GroupKitchenItems(items []models.OrderItem) []models.OrderItem {
+	result := make([]models.OrderItem, 0, len(items))
+	placed := map[uint]bool{}
+	for _, item := range items {
+		if item.ComboUsageID == nil {
+			result = append(result, item)
+			continue
+		}
+		if placed[*item.ComboUsageID] {
+			continue
+		}
+		placed[*item.ComboUsageID] = true
+		for _, other := range items {
+			if other.ComboUsageID != nil && *other.ComboUsageID == *item.ComboUsageID {
+				result = append(result, other)
+			}
+		}
+	}
+	return result
+}
+
+// ComboKitchenHeader หัวข้อชุดที่พิมพ์ก่อนรายการแรกของแต่ละชุดบนใบสั่งครัว คืน "" ถ้าไม่ต้องพิมพ์
+// items ต้องผ่าน GroupKitchenItems แล้ว
+func ComboKitchenHeader(items []models.OrderItem, i int) string {
+	item := items[i]
+	if item.ComboUsageID == nil {
+		return ""
+	}
+	if i > 0 && items[i-1].ComboUsageID != nil && *items[i-1].ComboUsageID == *item.ComboUsageID {
+		return ""
+	}
+	return fmt.Sprintf("[ชุด] %s x%d", comboName(item), item.Quantity)
+}
+
+// WriteComboLines พิมพ์บรรทัดชุดบนใบเสร็จ/ใบรายการอาหาร ราคารวมอยู่ที่บรรทัดชุด รายการในชุดพิมพ์ใต้ชุดโดยไม่มีราคา
+func WriteComboLines(content *bytes.Buffer, combos []ComboLine) {
+	for _, combo := range combos {
+		nameLines := wrapItemName(combo.Name, 35)
+		content.WriteString(fmt.Sprintf("%-35s ~~%5d **%12.2f\n", nameLines[0], combo.Quantity, combo.Price))
+		for i := 1; i < len(nameLines); i++ {
+			content.WriteString(fmt.Sprintf("%-35s ~~%5s **%12s\n", nameLines[i], "", ""))
+		}
+
+		for _, item := range combo.Items {
+			for _, line := range wrapItemName("  • "+item.MenuItem.Name, 35) {
+				content.WriteString(fmt.Sprintf("%-35s ~~%5s **%12s\n", line, "", ""))
+			}
+			for _, nested := range NestOrderItemOptions(item.Options) {
+				optName := NestedOptionPrefix("    ", nested.Depth+1) + nested.Option.MenuOption.Name
+				for _, line := range wrapItemName(optName, 35) {
+					content.WriteString(fmt.Sprintf("%-35s ~~%5s **%12s\n", line, "", ""))
+				}
+			}
+		}
+		// หมายเหตุของชุดบันทึกไว้ที่ทุกรายการในชุดเหมือนกัน พิมพ์ครั้งเดียว
+		if notes := combo.Items[0].Notes; notes != "" {
+			content.WriteString(fmt.Sprintf("   [หมายเหตุ: %s]\n", notes))
+		}
+	}
+}
+
 // เพิ่มฟังก์ชันใหม่สำหรับตัดข้อความที่ยาวเกิน
 func wrapItemName(name string, maxWidth int) []string {
 	var lines []string
@@ -1156,8 +1370,15 @@ func V2_prepareBillCheckPrintContent(orders []models.Order, tableIDs []string, d
 
 	// คำนวณยอดรวม
 	var totalItems int
+	var comboLines []ComboLine
 	for _, order := range orders {
-		for _, item := range order.Items {
+		regularItems, combos := GroupComboItems(order.Items)
+		comboLines = append(comboLines, combos...)
+		for _, combo := range combos {
+			totalItems += combo.Quantity
+		}
+
+		for _, item := range regularItems {
 			if item.Status != "cancelled" {
 				totalItems += item.Quantity
 				var optStrings []string
@@ -1244,8 +1465,9 @@ func V2_prepareBillCheckPrintContent(orders []models.Order, tableIDs []string, d
 		}
 
 		// ตัวเลือกเพิ่มเติม
-		for _, opt := range group.Options {
-			optName := "  • " + opt.MenuOption.Name
+		for _, nested := range NestOrderItemOptions(group.Options) {
+			opt := nested.Option
+			optName := NestedOptionPrefix("  ", nested.Depth) + opt.MenuOption.Name
 			optPrice := opt.Price * float64(opt.Quantity)
 
 			// ตัดข้อความตัวเลือกที่ยาวเกิน
@@ -1271,6 +1493,9 @@ func V2_prepareBillCheckPrintContent(orders []models.Order, tableIDs []string, d
 		}
 	}
 
+	// ชุดอาหารพิมพ์ต่อจากรายการปกติ
+	WriteComboLines(&content, comboLines)
+
 	content.WriteString(formatter.GetDivider() + "\n")
 
 	// สรุปยอด
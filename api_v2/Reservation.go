@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"food-ordering-api/db"
 	"food-ordering-api/models"
+	service "food-ordering-api/services"
+	utils "food-ordering-api/utility"
 	"image"
 	"image/png"
 	"net/http"
@@ -16,7 +18,6 @@ import (
 	"github.com/disintegration/imaging"
 	"github.com/fogleman/gg"
 	"github.com/gofiber/fiber/v2"
-	"github.com/google/uuid"
 	"github.com/skip2/go-qrcode"
 	"gorm.io/gorm"
 )
@@ -64,13 +65,40 @@ func CancelReservation(c *fiber.Ctx) error {
 		})
 	}
 
+	change, err := service.BeginTableChange(tx, models.TableActionUnreserve, reservation.TableID)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{
+			"error": "ไม่สามารถอ่านสถานะโต๊ะได้",
+		})
+	}
+
 	// ถ้าโต๊ะถูกกั้นไว้แล้ว (status = reserved) ให้คืนสถานะเป็น available
-	if err := tx.Model(&models.Table{}).
-		Where("id = ? AND status = ?", reservation.TableID, "reserved").
-		Update("status", "available").Error; err != nil {
+	// เว้นแต่การจองรอบอื่นกั้นโต๊ะอยู่ด้วย
+	held, err := service.TableHeldByReservation(tx, reservation.TableID, time.Now())
+	if err != nil {
 		tx.Rollback()
 		return c.Status(500).JSON(fiber.Map{
-			"error": "ไม่สามารถอัพเดทสถานะโต๊ะได้",
+			"error": "ไม่สามารถตรวจสอบการจองของโต๊ะได้",
+		})
+	}
+	if !held {
+		if err := tx.Model(&models.Table{}).
+			Where("id = ? AND status = ?", reservation.TableID, "reserved").
+			Update("status", "available").Error; err != nil {
+			tx.Rollback()
+			return c.Status(500).JSON(fiber.Map{
+				"error": "ไม่สามารถอัพเดทสถานะโต๊ะได้",
+			})
+		}
+	}
+
+	change.Entry.StaffID = utils.GetStaffID(c)
+	change.Entry.Note = fmt.Sprintf("reservation #%d cancelled", reservation.ID)
+	if err := change.Record(tx); err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{
+			"error": "ไม่สามารถบันทึกประวัติโต๊ะได้",
 		})
 	}
 
@@ -79,6 +107,7 @@ func CancelReservation(c *fiber.Ctx) error {
 			"error": "เกิดข้อผิดพลาดในการบันทึกข้อมูล",
 		})
 	}
+	service.PublishTableChange(service.TableEventReservation, reservation.TableID)
 
 	return c.JSON(fiber.Map{
 		"message": "ยกเลิกการจองสำเร็จ",
@@ -93,6 +122,7 @@ func CancelReservation(c *fiber.Ctx) error {
 // @Produce json
 // @Security BearerAuth
 // @Param id path integer true "ID โต๊ะ"
+// @Param guests query integer false "จำนวนลูกค้าที่มาจริง (ค่าเริ่มต้นตามที่จอง)"
 // @Success 200 {object} map[string]interface{} "เช็คอินสำเร็จ"
 // @Failure 400 {object} map[string]interface{} "ข้อมูลไม่ถูกต้อง"
 // @Failure 404 {object} map[string]interface{} "ไม่พบการจอง"
@@ -120,9 +150,10 @@ func CheckinReservedTable(c *fiber.Ctx) error {
 		}
 	}()
 
-	// ตรวจสอบว่ามีการจองที่ active อยู่หรือไม่
+	// ตรวจสอบว่ามีการจองที่ active อยู่หรือไม่ โต๊ะหนึ่งมีได้หลายรอบต่อวัน ใช้รอบที่ยังไม่หมดเวลาสายที่เร็วที่สุด
 	var reservation models.TableReservation
-	if err := tx.Where("table_id = ? AND status = ?", num, "active").First(&reservation).Error; err != nil {
+	if err := tx.Where("table_id = ? AND status = ? AND grace_period_until > ?", num, "active", time.Now()).
+		Order("reserved_for").First(&reservation).Error; err != nil {
 		tx.Rollback()
 		return c.Status(404).JSON(fiber.Map{
 			"error": "ไม่พบการจองที่ยังใช้งานอยู่สำหรับโต๊ะนี้",
@@ -144,10 +175,31 @@ func CheckinReservedTable(c *fiber.Ctx) error {
 		actualTableID = int(*table.ParentID)
 	}
 
+	// จำนวนลูกค้าที่มาจริงอาจไม่ตรงกับที่จองไว้ ส่ง ?guests= เพื่อแก้ไขได้
+	guestCount := c.QueryInt("guests", reservation.GuestCount)
+	if guestCount < 0 {
+		tx.Rollback()
+		return c.Status(400).JSON(fiber.Map{
+			"error": "จำนวนลูกค้าต้องไม่ติดลบ",
+		})
+	}
+
+	// เปิดรอบการใช้โต๊ะจากการจอง token ของ session คือ uuid ใน QR Code
+	session, err := service.OpenTableSession(tx, table, guestCount, nil, &reservation.ID)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, service.ErrTableInSession) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "โต๊ะนี้มีลูกค้าใช้งานอยู่",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to open table session",
+		})
+	}
+
 	// สร้าง QR Code
-	UUID := uuid.New().String()
-	expiryAt := time.Now().Add(2 * time.Hour)
-	url := fmt.Sprintf("http://localhost:5173/menu?tableID=%v&uuid=%v", tableID, UUID)
+	url := fmt.Sprintf("http://localhost:5173/menu?tableID=%v&uuid=%v", tableID, session.Token)
 
 	// สร้าง QR Code image
 	qrCode, err := qrcode.New(url, qrcode.Medium)
@@ -179,9 +231,10 @@ func CheckinReservedTable(c *fiber.Ctx) error {
 	// บันทึก QR Code
 	qrCodeRecord := models.QRCode{
 		TableID:   actualTableID,
-		UUID:      UUID,
+		UUID:      session.Token,
 		CreatedAt: time.Now(),
-		ExpiryAt:  expiryAt,
+		ExpiryAt:  session.ExpiresAt,
+		SessionID: &session.ID,
 		IsActive:  true,
 		Qr_Image:  finalQRCodeImage,
 	}
@@ -201,6 +254,14 @@ func CheckinReservedTable(c *fiber.Ctx) error {
 		})
 	}
 
+	change, err := service.BeginTableChange(tx, models.TableActionCheckin, table.ID)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{
+			"error": "ไม่สามารถอ่านสถานะโต๊ะได้",
+		})
+	}
+
 	// อัพเดทสถานะโต๊ะ
 	if err := tx.Model(&models.Table{}).Where("id = ?", tableID).Update("status", "occupied").Error; err != nil {
 		tx.Rollback()
@@ -209,6 +270,16 @@ func CheckinReservedTable(c *fiber.Ctx) error {
 		})
 	}
 
+	change.Entry.SessionID = &session.ID
+	change.Entry.StaffID = utils.GetStaffID(c)
+	change.Entry.Note = fmt.Sprintf("reservation #%d", reservation.ID)
+	if err := change.Record(tx); err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{
+			"error": "ไม่สามารถบันทึกประวัติโต๊ะได้",
+		})
+	}
+
 	// หา main printer
 	var mainPrinter models.Printer
 	if err := tx.Where("name = ?", "main").First(&mainPrinter).Error; err != nil {
@@ -239,6 +310,7 @@ func CheckinReservedTable(c *fiber.Ctx) error {
 			"error": "Failed to commit transaction",
 		})
 	}
+	service.PublishTableChange(service.TableEventOpened, table.ID)
 
 	// ส่ง QR Code image กลับไปแสดงผล
 	c.Set("Content-Type", "image/png")
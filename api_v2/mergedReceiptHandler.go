@@ -1,10 +1,13 @@
 package api_v2
 
 import (
+	"errors"
 	"fmt"
 	"food-ordering-api/db"
 	"food-ordering-api/models"
+	service "food-ordering-api/services"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -71,11 +74,25 @@ func CreateMergedReceipt(c *fiber.Ctx) error {
 		}
 	}
 
-	var activeQRs []models.QRCode
-	if err := db.DB.Where("table_id IN ? AND is_active = ?", req.TableIDs, true).Find(&activeQRs).Error; err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "ไม่สามารถตรวจสอบ QR codes ได้",
-		})
+	// session ที่เปิดอยู่ของทุกโต๊ะ (โต๊ะรวมหลายตัวใช้ session เดียวกัน)
+	var sessions []*models.TableSession
+	var sessionIDs []uint
+	guestCount := 0
+	for _, tableID := range req.TableIDs {
+		session, err := service.FindOpenSessionForTable(db.DB, tableID)
+		if errors.Is(err, service.ErrSessionNotFound) {
+			continue
+		}
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "ไม่สามารถตรวจสอบรอบการใช้โต๊ะได้",
+			})
+		}
+		if !slices.Contains(sessionIDs, session.ID) {
+			sessions = append(sessions, session)
+			sessionIDs = append(sessionIDs, session.ID)
+			guestCount += session.GuestCount
+		}
 	}
 
 	var staff models.Users
@@ -87,32 +104,34 @@ func CreateMergedReceipt(c *fiber.Ctx) error {
 
 	tx := db.DB.Begin()
 
-	// 1. ดึงออเดอร์ที่ยังไม่ได้ชำระจากทุกโต๊ะ
+	// 1. ดึงออเดอร์ที่ยังไม่ได้ชำระจากทุก session
 	var allOrders []models.Order
-	for _, tableID := range req.TableIDs {
-		var orders []models.Order
+	if len(sessionIDs) > 0 {
 		if err := tx.Preload("Items", "status != ?", "cancelled").
 			Preload("Items.MenuItem").
 			Preload("Items.Options.MenuOption").
-			Where("table_id = ? AND status NOT IN (?, ?) AND receipt_id IS NULL", //ไม่ต้องกลัวออเดอร์เก่าหรืออันที่มไ่เกียวข้องติดมาเพราะถ้าทำงานตามจริงออเดอร์ก่อนหน้าจะเป็น completed ไม่ก็ cancelled และออเดอร์ที่จ่ายตังแล้ส receipt_id จะไม่ว่าง
-				tableID, "completed", "cancelled").
-			Find(&orders).Error; err != nil {
+			Where("session_id IN ? AND status NOT IN (?, ?) AND receipt_id IS NULL",
+				sessionIDs, "completed", "cancelled").
+			Find(&allOrders).Error; err != nil {
 			tx.Rollback()
 			return c.Status(http.StatusNotFound).JSON(fiber.Map{
 				"error": "ไม่พบออเดอร์สำหรับโต๊ะบางโต๊ะ",
 			})
 		}
-		allOrders = append(allOrders, orders...)
 	}
 
-	if len(allOrders) == 0 && len(activeQRs) > 0 {
-		if err := tx.Model(&models.QRCode{}).Where("id IN ?", activeQRs).Update("is_active", false).Error; err != nil {
-			tx.Rollback()
-			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-				"error": "ไม่สามารถอัพเดทสถานะ QR codes ได้",
-			})
+	if len(allOrders) == 0 && len(sessions) > 0 {
+		for _, session := range sessions {
+			if err := service.CloseTableSession(tx, session, &req.StaffID); err != nil {
+				tx.Rollback()
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+					"error": "ไม่สามารถปิดรอบการใช้โต๊ะได้",
+				})
+			}
+		}
+		if tx.Commit().Error == nil {
+			service.PublishTableChange(service.TableEventClosed, req.TableIDs...)
 		}
-		tx.Commit()
 		return c.Status(http.StatusNotFound).JSON(fiber.Map{
 			"error": "ไม่พบออเดอร์ที่ยังไม่ได้ชำระ และได้ยกเลิก QR codes แล้ว",
 		})
@@ -128,6 +147,7 @@ func CreateMergedReceipt(c *fiber.Ctx) error {
 	receipt := models.Receipt{
 		UUID:          uuid.New().String(),
 		TableID:       tableIDsStr,
+		GuestCount:    guestCount,
 		SubTotal:      subTotal,
 		ServiceCharge: req.ServiceCharge, //ต้องเป็น VAT 7%
 		PaymentMethod: req.PaymentMethod,
@@ -246,32 +266,51 @@ func CreateMergedReceipt(c *fiber.Ctx) error {
 		}
 	}
 
-	// 8. อัพเดทสถานะโต๊ะ
-	for _, tableID := range req.TableIDs {
-		if err := tx.Model(&models.Table{}).
-			Where("id = ?", tableID).
-			Update("status", "available").Error; err != nil {
+	// 8. ปิดรอบการใช้โต๊ะ แล้วคืนโต๊ะที่เหลือ (ไม่มี session) เป็นพร้อมใช้งาน
+	staffID := &req.StaffID
+	for _, session := range sessions {
+		if err := service.CloseTableSession(tx, session, staffID); err != nil {
 			tx.Rollback()
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-				"error": "ไม่สามารถอัพเดทสถานะโต๊ะได้",
+				"error": "ไม่สามารถปิดรอบการใช้โต๊ะได้",
 			})
 		}
 	}
 
-	if err := tx.Model(&models.QRCode{}).
-		Where("table_id IN ? AND is_active = ?", req.TableIDs, true).
-		Update("is_active", false).Error; err != nil {
+	var remainingIDs []uint
+	if err := tx.Model(&models.Table{}).
+		Where("id IN ? AND status <> ?", req.TableIDs, "available").
+		Pluck("id", &remainingIDs).Error; err != nil {
 		tx.Rollback()
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "ไม่สามารถอัพเดทสถานะ QR code ได้",
+			"error": "ไม่สามารถอัพเดทสถานะโต๊ะได้",
 		})
 	}
+	if len(remainingIDs) > 0 {
+		change, err := service.BeginTableChange(tx, models.TableActionClose, remainingIDs...)
+		if err == nil {
+			err = tx.Model(&models.Table{}).
+				Where("id IN ?", remainingIDs).
+				Update("status", "available").Error
+		}
+		if err == nil {
+			change.Entry.StaffID = staffID
+			err = change.Record(tx)
+		}
+		if err != nil {
+			tx.Rollback()
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "ไม่สามารถอัพเดทสถานะโต๊ะได้",
+			})
+		}
+	}
 
 	if err := tx.Commit().Error; err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "ไม่สามารถบันทึกข้อมูลได้",
 		})
 	}
+	service.PublishTableChange(service.TableEventClosed, req.TableIDs...)
 
 	// 9. พิมพ์ใบเสร็จ
 	if err := PrintReceipt(receipt.ID); err != nil {
@@ -71,15 +71,19 @@ func GetPendingPrintJobs(c *fiber.Ctx) error {
 		Order("created_at ASC")
 
 	err = query.Preload("Order.Items.MenuItem").
+		Preload("Order.Items.MenuItem.Tags").
 		Preload("Order.Items", func(db *gorm.DB) *gorm.DB {
 			return db.Where("status = ?", "pending")
 		}).
 		Preload("Order.Items.Options.MenuOption.OptionGroup").
+		Preload("Order.Items.ComboUsage.Combo", models.WithDeletedCombo).
+		Preload("Order.Items.Options.MenuOption.Tags").
 		Preload("Receipt.Orders.Items", func(db *gorm.DB) *gorm.DB {
 			return db.Where("status != ?", "cancelled")
 		}).
 		Preload("Receipt.Orders.Items.MenuItem").
-		Preload("Receipt.Orders.Items.Options.MenuOption").
+		Preload("Receipt.Orders.Items.Options.MenuOption.OptionGroup").
+		Preload("Receipt.Orders.Items.ComboUsage.Combo", models.WithDeletedCombo).
 		Preload("Receipt.Discounts.DiscountType").
 		Preload("Receipt.Charges.ChargeType").
 		Find(&jobs).Error
@@ -169,9 +173,13 @@ func ReprintDocument(c *fiber.Ctx) error {
 	var originalJob models.PrintJob
 	if err := db.DB.Preload("Printer").
 		Preload("Order.Items.MenuItem").
+		Preload("Order.Items.MenuItem.Tags").
 		Preload("Order.Items.Options.MenuOption.OptionGroup").
+		Preload("Order.Items.ComboUsage.Combo", models.WithDeletedCombo).
+		Preload("Order.Items.Options.MenuOption.Tags").
 		Preload("Receipt.Orders.Items.MenuItem").
-		Preload("Receipt.Orders.Items.Options.MenuOption").
+		Preload("Receipt.Orders.Items.Options.MenuOption.OptionGroup").
+		Preload("Receipt.Orders.Items.ComboUsage.Combo", models.WithDeletedCombo).
 		Preload("Receipt.Discounts.DiscountType").
 		Preload("Receipt.Charges.ChargeType").
 		First(&originalJob, jobID).Error; err != nil {
@@ -252,8 +260,11 @@ func GetReprintableJobs(c *fiber.Ctx) error {
 		}).
 		Preload("Order.Items.MenuItem").
 		Preload("Order.Items.MenuItem.Category").
+		Preload("Order.Items.MenuItem.Tags").
 		Preload("Order.Items.Options.MenuOption").
 		Preload("Order.Items.Options.MenuOption.OptionGroup").
+		Preload("Order.Items.ComboUsage.Combo", models.WithDeletedCombo).
+		Preload("Order.Items.Options.MenuOption.Tags").
 		Preload("Receipt").
 		Preload("Receipt.Staff").
 		Preload("Receipt.Orders", func(db *gorm.DB) *gorm.DB {
@@ -266,6 +277,7 @@ func GetReprintableJobs(c *fiber.Ctx) error {
 		Preload("Receipt.Orders.Items.MenuItem.Category").
 		Preload("Receipt.Orders.Items.Options.MenuOption").
 		Preload("Receipt.Orders.Items.Options.MenuOption.OptionGroup").
+		Preload("Receipt.Orders.Items.ComboUsage.Combo", models.WithDeletedCombo).
 		Preload("Receipt.Discounts.DiscountType").
 		Preload("Receipt.Charges.ChargeType")
 
@@ -8,4 +8,20 @@ var (
 	DBName     = os.Getenv("DB_NAME")     // ค่าจาก environment variable DB_NAME
 	DBHost     = os.Getenv("DB_HOST")     // ค่าจาก environment variable DB_HOST
 	DBPort     = os.Getenv("DB_PORT")     // ค่าจาก environment variable DB_PORT
+
+	ImageStoreDriver = os.Getenv("IMAGE_STORE_DRIVER") // ที่เก็บรูป: local (ค่าเริ่มต้น)
+	ImageStoreDir    = os.Getenv("IMAGE_STORE_DIR")    // โฟลเดอร์เก็บรูปสำหรับ local store
+
+	RestaurantName   = os.Getenv("RESTAURANT_NAME")    // ชื่อร้านในข้อความถึงลูกค้า
+	NotifyDriver     = os.Getenv("NOTIFY_DRIVER")      // ช่องทางส่งข้อความถึงลูกค้า: log (ค่าเริ่มต้น), sms, line, email
+	NotifyLogFile    = os.Getenv("NOTIFY_LOG_FILE")    // ไฟล์ที่ log driver เขียนข้อความลง ค่าว่าง = แสดงใน log ของระบบ
+	SMSAPIURL        = os.Getenv("SMS_API_URL")        // endpoint ของ SMS gateway (รับ JSON to, sender, message)
+	SMSAPIKey        = os.Getenv("SMS_API_KEY")        // ส่งเป็น Bearer token
+	SMSSender        = os.Getenv("SMS_SENDER")         // ชื่อผู้ส่ง SMS
+	LineChannelToken = os.Getenv("LINE_CHANNEL_TOKEN") // channel access token ของ LINE Messaging API
+	SMTPHost         = os.Getenv("SMTP_HOST")
+	SMTPPort         = os.Getenv("SMTP_PORT")
+	SMTPUser         = os.Getenv("SMTP_USER")
+	SMTPPassword     = os.Getenv("SMTP_PASSWORD")
+	SMTPFrom         = os.Getenv("SMTP_FROM") // อีเมลผู้ส่ง
 )
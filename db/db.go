@@ -101,6 +101,7 @@ func migrate() {
 		&models.Users{},
 		&models.QRCode{},
 		&models.MenuItem{},
+		&models.StoredImage{},
 		&models.Category{},
 		&models.Table{},
 		&models.TableReservation{},
@@ -126,6 +127,24 @@ func migrate() {
 		&models.POSSession{},
 		&models.POSSessionLog{},
 		&models.POSVerificationAttempt{},
+		&models.MenuVersion{},
+		&models.MenuVersionItem{},
+		&models.MenuVersionOption{},
+		&models.MenuPriceHistory{},
+		&models.Translation{},
+		&models.DietaryTag{},
+		&models.MenuItemOptionPrice{},
+		&models.Combo{},
+		&models.ComboSlot{},
+		&models.ComboSlotChoice{},
+		&models.ComboUsage{},
+		&models.MenuBulkAudit{},
+		&models.MenuBulkAuditItem{},
+		&models.TableSession{},
+		&models.TableZone{},
+		&models.TableHistory{},
+		&models.WaitlistEntry{},
+		&models.ReservationNotification{},
 	)
 
 	if err != nil {
@@ -133,4 +152,23 @@ func migrate() {
 	} else {
 		log.Println("Database migration completed successfully")
 	}
+
+	seedDietaryTags()
+
+	if err := MigrateSharedOptionGroups(); err != nil {
+		log.Printf("Error migrating shared option groups: %v", err)
+	}
+
+	if err := MigrateTableSessions(); err != nil {
+		log.Printf("Error migrating table sessions: %v", err)
+	}
+}
+
+// seedDietaryTags สร้างแท็กสารก่อภูมิแพ้/โภชนาการเริ่มต้นที่ยังไม่มี (ไม่แก้แท็กที่ผู้จัดการแก้ไขไปแล้ว)
+func seedDietaryTags() {
+	for _, tag := range models.DefaultDietaryTags {
+		if err := DB.Where(models.DietaryTag{Code: tag.Code}).FirstOrCreate(&tag).Error; err != nil {
+			log.Printf("Error seeding dietary tag %s: %v", tag.Code, err)
+		}
+	}
 }
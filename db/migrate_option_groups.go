@@ -0,0 +1,129 @@
+package db
+
+import (
+	"fmt"
+	"food-ordering-api/models"
+	"log"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MigrateSharedOptionGroups ย้ายกลุ่มตัวเลือกแบบเดิม (หนึ่งกลุ่มต่อหนึ่งเมนูผ่าน option_groups.menu_item_id)
+// ไปใช้ตาราง menu_item_option_groups แล้วรวมกลุ่มที่เหมือนกันทุกอย่างให้เหลือกลุ่มเดียวที่ใช้ร่วมกัน
+// เรียกซ้ำได้ ถ้าไม่มีกลุ่มเก่าที่ยังไม่ย้ายจะไม่ทำอะไร
+func MigrateSharedOptionGroups() error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(`INSERT INTO menu_item_option_groups (menu_item_id, option_group_id)
+			SELECT menu_item_id, id FROM option_groups g
+			WHERE menu_item_id IS NOT NULL AND deleted_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM menu_item_option_groups l WHERE l.option_group_id = g.id)`)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		merged, err := mergeDuplicateOptionGroups(tx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Migrated %d option group links, merged %d duplicate option groups", result.RowsAffected, merged)
+		return nil
+	})
+}
+
+// optionSignature ค่าที่ใช้เทียบว่าตัวเลือกสองตัวเหมือนกัน
+func optionSignature(option models.MenuOption) string {
+	codes := make([]string, 0, len(option.Tags))
+	for _, tag := range option.Tags {
+		codes = append(codes, tag.Code)
+	}
+	sort.Strings(codes)
+	return fmt.Sprintf("%s|%s|%s|%.2f|%s", option.Name, option.NameEn, option.NameCh, option.Price, strings.Join(codes, ","))
+}
+
+// sortOptionsBySignature เรียงตัวเลือกตาม signature เพื่อจับคู่ตัวเลือกของกลุ่มที่ซ้ำกันตามลำดับ
+func sortOptionsBySignature(options []models.MenuOption) []string {
+	sort.SliceStable(options, func(i, j int) bool {
+		return optionSignature(options[i]) < optionSignature(options[j])
+	})
+	signatures := make([]string, len(options))
+	for i, option := range options {
+		signatures[i] = optionSignature(option)
+	}
+	return signatures
+}
+
+// mergeDuplicateOptionGroups รวมกลุ่มที่ชื่อ เงื่อนไข ตัวเลือกแม่ และตัวเลือกเหมือนกันทั้งหมด ให้ใช้กลุ่มที่ ID น้อยที่สุด
+// ย้ายเฉพาะการผูกที่ใช้งานอยู่ (เมนูที่ผูกกลุ่ม และกลุ่มย่อยของตัวเลือก) ไปที่กลุ่มหลัก
+// ออเดอร์และเวอร์ชันเมนูเก่ายังอ้างถึงตัวเลือกเดิม ไม่แก้ประวัติ
+func mergeDuplicateOptionGroups(tx *gorm.DB) (int, error) {
+	var groups []models.OptionGroup
+	if err := tx.Preload("Options.Tags").Order("id").Find(&groups).Error; err != nil {
+		return 0, err
+	}
+
+	canonical := map[string]models.OptionGroup{}
+	// ตัวเลือกของกลุ่มที่ถูกรวมแล้ว -> ตัวเลือกของกลุ่มหลัก กลุ่มย่อยที่อ่านมาก่อนรวมยังอ้างถึงตัวเลือกเดิม
+	replacedOptions := map[uint]uint{}
+	merged := 0
+	for _, group := range groups {
+		// กลุ่มย่อยต้องรวมกับกลุ่มย่อยของตัวเลือกแม่เดียวกันเท่านั้น ไม่รวมกับกลุ่มระดับบนสุด
+		parent := "-"
+		if group.ParentOptionID != nil {
+			parentID := *group.ParentOptionID
+			if replaced, ok := replacedOptions[parentID]; ok {
+				parentID = replaced
+			}
+			parent = fmt.Sprint(parentID)
+		}
+		signatures := sortOptionsBySignature(group.Options)
+		key := fmt.Sprintf("%s|%s|%s|%d|%t|%s|%s", group.Name, group.NameEn, group.NameCh,
+			group.MaxSelections, group.IsRequired, parent, strings.Join(signatures, ";"))
+
+		target, ok := canonical[key]
+		if !ok {
+			canonical[key] = group
+			continue
+		}
+
+		for i, option := range group.Options {
+			replacedOptions[option.ID] = target.Options[i].ID
+			if err := tx.Model(&models.OptionGroup{}).
+				Where("parent_option_id = ?", option.ID).
+				Update("parent_option_id", target.Options[i].ID).Error; err != nil {
+				return 0, err
+			}
+		}
+
+		var menuItemIDs []uint
+		if err := tx.Table("menu_item_option_groups").
+			Where("option_group_id = ?", group.ID).
+			Pluck("menu_item_id", &menuItemIDs).Error; err != nil {
+			return 0, err
+		}
+		for _, menuItemID := range menuItemIDs {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Table("menu_item_option_groups").
+				Create(map[string]interface{}{"menu_item_id": menuItemID, "option_group_id": target.ID}).Error; err != nil {
+				return 0, err
+			}
+		}
+		if err := tx.Exec("DELETE FROM menu_item_option_groups WHERE option_group_id = ?", group.ID).Error; err != nil {
+			return 0, err
+		}
+
+		if err := tx.Where("group_id = ?", group.ID).Delete(&models.MenuOption{}).Error; err != nil {
+			return 0, err
+		}
+		if err := tx.Delete(&models.OptionGroup{}, group.ID).Error; err != nil {
+			return 0, err
+		}
+		merged++
+	}
+	return merged, nil
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"food-ordering-api/models"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// MigrateTableSessions สร้าง TableSession จาก UUID แบบเดิมที่คัดลอกอยู่ใน qr_codes, orders และ receipts
+// QR Code ที่ยังใช้งานอยู่กลายเป็น session ที่เปิดอยู่ ที่เหลือเป็น session ที่ปิดแล้ว
+// เรียกซ้ำได้ แถวที่ผูก session แล้วจะไม่ถูกแตะ
+func MigrateTableSessions() error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		created := tx.Exec(`INSERT INTO table_sessions
+			(token, table_id, status, guest_count, expires_at, opened_at, closed_at, created_at, updated_at)
+			SELECT q.uuid, q.table_id,
+				CASE WHEN q.is_active THEN ? ELSE ? END, 0, q.expiry_at, q.created_at,
+				CASE WHEN q.is_active THEN NULL
+					ELSE COALESCE((SELECT MAX(o.updated_at) FROM orders o WHERE o.uuid = q.uuid), q.expiry_at) END,
+				q.created_at, q.created_at
+			FROM qr_codes q
+			WHERE q.session_id IS NULL AND NOT EXISTS (SELECT 1 FROM table_sessions s WHERE s.token = q.uuid)`,
+			models.SessionOpen, models.SessionClosed)
+		if created.Error != nil {
+			return created.Error
+		}
+
+		// ออเดอร์ที่ UUID ไม่มี QR Code แล้ว (เช่น ลบ QR Code ทิ้งไป) ให้เป็น session ที่ปิดแล้ว
+		orphans := tx.Exec(`INSERT INTO table_sessions
+			(token, table_id, status, guest_count, expires_at, opened_at, closed_at, created_at, updated_at)
+			SELECT o.uuid, MIN(o.table_id), ?, 0, MIN(o.created_at), MIN(o.created_at), MAX(o.updated_at),
+				MIN(o.created_at), MAX(o.updated_at)
+			FROM orders o
+			WHERE o.session_id IS NULL AND o.uuid <> ''
+				AND NOT EXISTS (SELECT 1 FROM table_sessions s WHERE s.token = o.uuid)
+			GROUP BY o.uuid`, models.SessionClosed)
+		if orphans.Error != nil {
+			return orphans.Error
+		}
+
+		for _, table := range []string{"qr_codes", "orders", "receipts"} {
+			if err := tx.Exec(`UPDATE ` + table + ` SET session_id =
+				(SELECT s.id FROM table_sessions s WHERE s.token = ` + table + `.uuid)
+				WHERE session_id IS NULL`).Error; err != nil {
+				return err
+			}
+		}
+
+		// ผูกโต๊ะกับ session: โต๊ะหลัก, โต๊ะในกลุ่มของ session ที่เปิดอยู่ และโต๊ะที่ออเดอร์ถูกสั่ง
+		links := []string{
+			`SELECT s.id AS session_id, s.table_id AS table_id FROM table_sessions s
+				WHERE s.table_id IN (SELECT id FROM tables)`,
+			`SELECT s.id AS session_id, t.id AS table_id FROM table_sessions s
+				JOIN tables p ON p.id = s.table_id
+				JOIN tables t ON t.group_id = p.group_id
+				WHERE s.status = '` + models.SessionOpen + `' AND p.group_id IS NOT NULL AND p.group_id <> ''`,
+			`SELECT DISTINCT o.session_id AS session_id, o.table_id AS table_id FROM orders o
+				WHERE o.session_id IS NOT NULL AND o.table_id IN (SELECT id FROM tables)`,
+		}
+		for _, link := range links {
+			if err := tx.Exec(`INSERT INTO table_session_tables (table_session_id, table_id)
+				SELECT l.session_id, l.table_id FROM (` + link + `) l
+				WHERE NOT EXISTS (SELECT 1 FROM table_session_tables e
+					WHERE e.table_session_id = l.session_id AND e.table_id = l.table_id)`).Error; err != nil {
+				return err
+			}
+		}
+
+		if created.RowsAffected+orphans.RowsAffected > 0 {
+			log.Printf("Migrated %d table sessions from QR codes and %d from orders", created.RowsAffected, orphans.RowsAffected)
+		}
+		return nil
+	})
+}
@@ -41,6 +41,9 @@ import (
 func main() {
 	db.InitDatabase()
 	utils.InitAPIKeys()
+	service.InitImageStore()
+	service.InitNotifier()
+	service.MigrateLegacyImages(db.DB)
 
 	// printerIP := "192.168.1.100" // เปลี่ยนเป็น IP จริงของเครื่องพิมพ์
 	// port := 9100
@@ -73,9 +76,13 @@ func main() {
 
 	go api_handlers.ManageTableReservationStatus()
 	go api_handlers.AutoManageReservations()
+	go api_handlers.ManageReservationNotifications()
+	go api_handlers.PublishScheduledMenuVersions()
 
 	// app.Get("/swagger/*", swagger.HandlerDefault)
 
+	app.Get("/images/:id", api_handlers.GetImage)
+
 	app.Get("/ws/tables", utils.WebSocketAPIKeyMiddleware("websocket_table"), api_handlers.TableWebSocketHandler(db.DB))
 
 	// app.Use("/ws/printer", api_handlers.HandlePrinterWebSocket)
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Combo - ชุดอาหารที่ประกอบด้วยหลายช่อง (slot) เช่น จานหลัก 1 + เครื่องเคียง 1 + เครื่องดื่ม 1
+// ราคาชุด = Price + ค่าอัปเกรดของรายการที่เลือกในแต่ละช่อง
+type Combo struct {
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	Name          string         `gorm:"not null" json:"name"`
+	NameEn        string         `json:"name_en"`
+	NameCh        string         `json:"name_ch"`
+	Description   string         `json:"description"`
+	DescriptionEn string         `json:"description_en"`
+	DescriptionCh string         `json:"description_ch"`
+	Price         float64        `gorm:"not null" json:"price"` // ราคาเริ่มต้นของชุด (เลือกรายการที่ไม่มีค่าอัปเกรด)
+	IsActive      bool           `gorm:"not null;default:true" json:"is_active"`
+	Slots         []ComboSlot    `gorm:"foreignKey:ComboID" json:"slots"`
+	Localized     *LocalizedText `gorm:"-" json:"localized,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"-" swaggerignore:"true"`
+}
+
+// WithDeletedCombo ใช้กับ Preload("...ComboUsage.Combo") ให้ออเดอร์และใบเสร็จเก่ายังแสดงชื่อชุดที่ถูกลบไปแล้ว
+func WithDeletedCombo(tx *gorm.DB) *gorm.DB {
+	return tx.Unscoped()
+}
+
+// ComboSlot - ช่องในชุด ลูกค้าเลือกรายการจาก Choices ได้ MinSelections ถึง MaxSelections รายการ
+type ComboSlot struct {
+	ID            uint              `gorm:"primaryKey" json:"id"`
+	ComboID       uint              `gorm:"not null;index" json:"combo_id"`
+	Name          string            `gorm:"not null" json:"name"` // เช่น "จานหลัก", "เครื่องดื่ม"
+	NameEn        string            `json:"name_en"`
+	NameCh        string            `json:"name_ch"`
+	SortOrder     int               `gorm:"not null;default:0" json:"sort_order"`
+	MinSelections int               `gorm:"not null;default:1" json:"min_selections"`
+	MaxSelections int               `gorm:"not null;default:1" json:"max_selections"`
+	Choices       []ComboSlotChoice `gorm:"foreignKey:SlotID" json:"choices"`
+	Localized     *LocalizedText    `gorm:"-" json:"localized,omitempty"`
+}
+
+// ComboSlotChoice - เมนูที่เลือกได้ในช่อง พร้อมค่าอัปเกรด เช่น เปลี่ยนน้ำเปล่าเป็นชาไทย +20 บาท
+type ComboSlotChoice struct {
+	ID         uint     `gorm:"primaryKey" json:"id"`
+	SlotID     uint     `gorm:"not null;index" json:"slot_id"`
+	MenuItemID uint     `gorm:"not null;index" json:"menu_item_id"`
+	MenuItem   MenuItem `gorm:"foreignKey:MenuItemID" json:"menu_item"`
+	Upcharge   float64  `gorm:"not null;default:0" json:"upcharge"`
+	IsDefault  bool     `gorm:"not null;default:false" json:"is_default"` // รายการมาตรฐานของช่อง (แสดงเป็นตัวเลือกแรก)
+}
+
+// ComboUsage - ชุดที่สั่งในออเดอร์ OrderItem ของแต่ละรายการในชุดอ้างถึงแถวนี้
+type ComboUsage struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	ComboID   uint           `gorm:"not null;index" json:"combo_id"`
+	Combo     Combo          `gorm:"foreignKey:ComboID" json:"combo"`
+	OrderID   uint           `gorm:"not null;index" json:"order_id"`
+	Quantity  int            `gorm:"not null;default:1" json:"quantity"`
+	UnitPrice float64        `gorm:"not null" json:"unit_price"` // ราคาชุดต่อชุดรวมค่าอัปเกรด (ไม่รวมตัวเลือกเสริม)
+	CreatedAt time.Time      `json:"created_at"`
+	DeletedAt gorm.DeletedAt `json:"-" swaggerignore:"true"`
+}
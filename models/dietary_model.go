@@ -0,0 +1,41 @@
+package models
+
+// ประเภทของแท็ก
+const (
+	TagTypeAllergen = "allergen" // สารก่อภูมิแพ้ พิมพ์เตือนในใบครัว
+	TagTypeDietary  = "dietary"  // ข้อมูลโภชนาการ/ศาสนา เช่น ฮาลาล มังสวิรัติ
+)
+
+// ระดับความเผ็ดสูงสุดของ SpicyLevel (0 = ไม่เผ็ด)
+const MaxSpicyLevel = 5
+
+// DietaryTag - แท็กสารก่อภูมิแพ้และข้อมูลโภชนาการ ผูกกับ MenuItem และ MenuOption
+// Code ใช้อ้างอิงใน query (exclude_allergens=peanut) และในไฟล์นำเข้า/ส่งออก
+type DietaryTag struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Code      string         `gorm:"not null;uniqueIndex" json:"code"`
+	Type      string         `gorm:"not null;index" json:"type"`
+	Name      string         `gorm:"not null" json:"name"`
+	NameEn    string         `gorm:"not null" json:"name_en"`
+	NameCh    string         `gorm:"not null" json:"name_ch"`
+	Localized *LocalizedText `gorm:"-" json:"localized,omitempty"`
+}
+
+// DefaultDietaryTags แท็กเริ่มต้นที่สร้างให้ตอน migrate (ผู้จัดการเพิ่มเองได้ภายหลัง)
+var DefaultDietaryTags = []DietaryTag{
+	{Code: "peanut", Type: TagTypeAllergen, Name: "ถั่วลิสง", NameEn: "Peanut", NameCh: "花生"},
+	{Code: "tree_nut", Type: TagTypeAllergen, Name: "ถั่วเปลือกแข็ง", NameEn: "Tree nuts", NameCh: "坚果"},
+	{Code: "shellfish", Type: TagTypeAllergen, Name: "กุ้ง/ปู/หอย", NameEn: "Shellfish", NameCh: "贝类"},
+	{Code: "fish", Type: TagTypeAllergen, Name: "ปลา", NameEn: "Fish", NameCh: "鱼"},
+	{Code: "egg", Type: TagTypeAllergen, Name: "ไข่", NameEn: "Egg", NameCh: "鸡蛋"},
+	{Code: "milk", Type: TagTypeAllergen, Name: "นม", NameEn: "Milk", NameCh: "牛奶"},
+	{Code: "soy", Type: TagTypeAllergen, Name: "ถั่วเหลือง", NameEn: "Soy", NameCh: "大豆"},
+	{Code: "wheat", Type: TagTypeAllergen, Name: "แป้งสาลี", NameEn: "Wheat", NameCh: "小麦"},
+	{Code: "sesame", Type: TagTypeAllergen, Name: "งา", NameEn: "Sesame", NameCh: "芝麻"},
+	{Code: "pork", Type: TagTypeDietary, Name: "หมู", NameEn: "Pork", NameCh: "猪肉"},
+	{Code: "beef", Type: TagTypeDietary, Name: "เนื้อวัว", NameEn: "Beef", NameCh: "牛肉"},
+	{Code: "halal", Type: TagTypeDietary, Name: "ฮาลาล", NameEn: "Halal", NameCh: "清真"},
+	{Code: "vegetarian", Type: TagTypeDietary, Name: "มังสวิรัติ", NameEn: "Vegetarian", NameCh: "素食"},
+	{Code: "vegan", Type: TagTypeDietary, Name: "วีแกน", NameEn: "Vegan", NameCh: "纯素"},
+	{Code: "gluten_free", Type: TagTypeDietary, Name: "ไม่มีกลูเตน", NameEn: "Gluten-free", NameCh: "无麸质"},
+}
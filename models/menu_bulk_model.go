@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// การแก้ไขเมนูแบบกลุ่มที่รองรับ
+const (
+	BulkSetPrice       = "set_price"     // ตั้งราคาเป็น amount
+	BulkAdjustPrice    = "adjust_price"  // ปรับราคาเพิ่ม/ลดตาม amount (บาท) หรือ percent
+	BulkAvailability   = "availability"  // ตั้งพร้อมขาย/หมด (ไม่ส่ง value = สลับสถานะของแต่ละเมนู)
+	BulkMoveCategory   = "move_category" // ย้ายไปหมวดหมู่ category_id
+	BulkRecommendation = "recommended"   // ตั้งเมนูแนะนำ (ไม่ส่ง value = สลับสถานะของแต่ละเมนู)
+)
+
+// MenuBulkAudit - บันทึกการแก้ไขเมนูแบบกลุ่มแต่ละครั้ง
+type MenuBulkAudit struct {
+	ID        uint                `gorm:"primaryKey" json:"id"`
+	Action    string              `gorm:"not null;index" json:"action"`
+	Request   string              `gorm:"type:text" json:"request"` // JSON ของคำขอ (ตัวกรองและค่าที่ใช้)
+	Note      string              `json:"note"`
+	ItemCount int                 `gorm:"not null" json:"item_count"`
+	CreatedBy *uint               `json:"created_by"`
+	Items     []MenuBulkAuditItem `gorm:"foreignKey:AuditID" json:"items,omitempty"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+// MenuBulkAuditItem - ค่าก่อนและหลังของเมนูหนึ่งรายการในการแก้ไขแบบกลุ่ม
+type MenuBulkAuditItem struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	AuditID    uint   `gorm:"not null;index" json:"audit_id"`
+	MenuItemID uint   `gorm:"not null;index" json:"menu_item_id"`
+	Name       string `json:"name"`
+	Field      string `gorm:"not null" json:"field"`
+	OldValue   string `json:"old_value"`
+	NewValue   string `json:"new_value"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// สถานะของเวอร์ชันเมนู
+const (
+	MenuVersionDraft     = "draft"     // กำลังแก้ไข ยังไม่มีผลกับเมนูจริง
+	MenuVersionScheduled = "scheduled" // รอ worker เผยแพร่ตาม PublishAt
+	MenuVersionPublished = "published" // เวอร์ชันที่ใช้งานอยู่ (มีได้ทีละเวอร์ชัน)
+	MenuVersionArchived  = "archived"  // เคยเผยแพร่แล้ว เก็บไว้สำหรับ rollback
+)
+
+// MenuVersion - ชุดข้อมูลเมนูทั้งร้าน (snapshot) ที่แก้ไขเป็น draft แล้วเผยแพร่ทีเดียว
+// เพื่อไม่ให้ราคาเปลี่ยนกลางรอบบริการ
+type MenuVersion struct {
+	ID          uint                `gorm:"primaryKey" json:"id"`
+	Name        string              `gorm:"not null" json:"name"`
+	Note        string              `json:"note"`
+	Status      string              `gorm:"not null;default:'draft';index" json:"status"`
+	BasedOnID   *uint               `json:"based_on_id"`             // เวอร์ชันต้นทางที่คัดลอกมา (nil = คัดลอกจากเมนูจริง)
+	PublishAt   *time.Time          `gorm:"index" json:"publish_at"` // เวลาที่ตั้งให้เผยแพร่
+	PublishedAt *time.Time          `json:"published_at"`            // เวลาที่เผยแพร่จริงครั้งล่าสุด
+	CreatedBy   *uint               `json:"created_by"`
+	Items       []MenuVersionItem   `gorm:"foreignKey:MenuVersionID" json:"items,omitempty"`
+	Options     []MenuVersionOption `gorm:"foreignKey:MenuVersionID" json:"options,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+// MenuVersionItem - ข้อมูลเมนูหนึ่งรายการในเวอร์ชัน
+type MenuVersionItem struct {
+	ID            uint   `gorm:"primaryKey" json:"id"`
+	MenuVersionID uint   `gorm:"not null;uniqueIndex:idx_menu_version_item" json:"menu_version_id"`
+	MenuItemID    uint   `gorm:"not null;uniqueIndex:idx_menu_version_item" json:"menu_item_id"`
+	Name          string `gorm:"not null" json:"name"`
+	NameEn        string `gorm:"not null" json:"name_en"`
+	NameCh        string `gorm:"not null" json:"name_ch"`
+	Description   string `gorm:"type:varchar(255)" json:"description"`
+	DescriptionEn string `gorm:"type:varchar(255)" json:"description_en"`
+	DescriptionCh string `gorm:"type:varchar(255)" json:"description_ch"`
+	CategoryID    uint   `gorm:"not null" json:"category_id"`
+	Price         int16  `gorm:"not null" json:"price"`
+	IsAvailable   bool   `gorm:"not null" json:"is_available"`
+}
+
+// MenuVersionOption - ราคาตัวเลือกเสริมในเวอร์ชัน
+type MenuVersionOption struct {
+	ID            uint    `gorm:"primaryKey" json:"id"`
+	MenuVersionID uint    `gorm:"not null;uniqueIndex:idx_menu_version_option" json:"menu_version_id"`
+	MenuOptionID  uint    `gorm:"not null;uniqueIndex:idx_menu_version_option" json:"menu_option_id"`
+	MenuItemID    uint    `gorm:"not null" json:"menu_item_id"`
+	Name          string  `gorm:"not null" json:"name"`
+	Price         float64 `gorm:"not null" json:"price"`
+}
+
+// MenuPriceHistory - ช่วงเวลาที่เมนูใช้ราคาแต่ละราคา ใช้สำหรับรายงานย้อนหลัง
+// EffectiveTo เป็น nil คือราคาปัจจุบัน
+type MenuPriceHistory struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	MenuItemID    uint       `gorm:"not null;index" json:"menu_item_id"`
+	Price         int16      `gorm:"not null" json:"price"`
+	EffectiveFrom time.Time  `gorm:"not null" json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to"`
+	MenuVersionID *uint      `json:"menu_version_id"` // nil = แก้ราคาตรงผ่าน UpdateMenuItem
+	CreatedAt     time.Time  `json:"created_at"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type CreateUserRequest struct {
 	Username string   `json:"username" binding:"required,min=3"`
 	Password string   `json:"password" binding:"required,min=6"`
@@ -8,7 +10,14 @@ type CreateUserRequest struct {
 }
 
 type CreateCategoryRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name      string `json:"name" binding:"required"`
+	NameEn    string `json:"nameEn"`
+	NameCh    string `json:"nameCh"`
+	ParentID  *uint  `json:"parent_id"` // หมวดหมู่แม่ (ไม่ส่ง = ระดับบนสุด)
+	SortOrder int    `json:"sort_order"`
+	Icon      string `json:"icon"`
+	ShowOnQR  *bool  `json:"show_on_qr"`  // ไม่ส่ง = แสดง
+	ShowOnPOS *bool  `json:"show_on_pos"` // ไม่ส่ง = แสดง
 }
 
 // CreateMenuRequest - รับข้อมูลสำหรับสร้างเมนูใหม่พร้อม options
@@ -30,17 +39,221 @@ type MenuItemRequest struct {
 }
 
 type OptionRequest struct {
-	Name   string  `json:"name" binding:"required"`
-	NameEn string  `json:"name_en" binding:"required"`
-	NameCh string  `json:"name_ch" binding:"required"`
-	Price  float64 `json:"price"`
+	Name        string               `json:"name" binding:"required"`
+	NameEn      string               `json:"name_en" binding:"required"`
+	NameCh      string               `json:"name_ch" binding:"required"`
+	Price       float64              `json:"price"`
+	ChildGroups []OptionGroupRequest `json:"child_groups,omitempty"` // กลุ่มย่อยที่ต้องเลือกต่อเมื่อเลือกตัวเลือกนี้
 }
 
 type OptionGroupRequest struct {
-	Name          string          `json:"name" binding:"required"`
-	NameEn        string          `json:"name_en" binding:"required"`
-	NameCh        string          `json:"name_ch" binding:"required"`
-	MaxSelections int             `json:"MaxSelections"`
-	IsRequired    bool            `json:"is_required"`
-	Options       []OptionRequest `json:"options"`
+	Name           string          `json:"name" binding:"required"`
+	NameEn         string          `json:"name_en" binding:"required"`
+	NameCh         string          `json:"name_ch" binding:"required"`
+	MaxSelections  int             `json:"MaxSelections"`
+	IsRequired     bool            `json:"is_required"`
+	Options        []OptionRequest `json:"options"`
+	ParentOptionID *uint           `json:"parent_option_id,omitempty"` // ใส่เมื่อเป็นกลุ่มย่อยของตัวเลือกที่มีอยู่แล้ว
+}
+
+type CreateMenuVersionRequest struct {
+	Name      string `json:"name" binding:"required"`
+	Note      string `json:"note"`
+	BasedOnID *uint  `json:"based_on_id"` // คัดลอกจากเวอร์ชันเดิม ถ้าไม่ระบุจะคัดลอกจากเมนูที่ใช้อยู่
+}
+
+// UpdateMenuVersionItemRequest - ฟิลด์ที่ไม่ส่งมา (nil) จะคงค่าเดิมใน draft
+type UpdateMenuVersionItemRequest struct {
+	Name          *string          `json:"name"`
+	NameEn        *string          `json:"name_en"`
+	NameCh        *string          `json:"name_ch"`
+	Description   *string          `json:"description"`
+	DescriptionEn *string          `json:"description_en"`
+	DescriptionCh *string          `json:"description_ch"`
+	CategoryID    *uint            `json:"category_id"`
+	Price         *int16           `json:"price"`
+	IsAvailable   *bool            `json:"is_available"`
+	OptionPrices  map[uint]float64 `json:"option_prices"` // menu_option_id -> ราคาใหม่
+}
+
+type PublishMenuVersionRequest struct {
+	PublishAt *time.Time `json:"publish_at"` // ไม่ระบุหรือเป็นเวลาที่ผ่านมาแล้ว = เผยแพร่ทันที
+}
+
+// UpsertTranslationRequest - คำแปลหนึ่งฟิลด์ (field: name หรือ description)
+type UpsertTranslationRequest struct {
+	EntityType string `json:"entity_type" binding:"required"`
+	EntityID   uint   `json:"entity_id" binding:"required"`
+	Field      string `json:"field" binding:"required"`
+	Lang       string `json:"lang" binding:"required"`
+	Value      string `json:"value" binding:"required"`
+}
+
+type DietaryTagRequest struct {
+	Code   string `json:"code" binding:"required"`
+	Type   string `json:"type" binding:"required"` // allergen, dietary
+	Name   string `json:"name" binding:"required"`
+	NameEn string `json:"name_en"`
+	NameCh string `json:"name_ch"`
+}
+
+// SetMenuTagsRequest - แทนที่แท็กทั้งหมดของเมนู/ตัวเลือกด้วยรายการนี้ (ส่ง [] เพื่อล้าง)
+type SetMenuTagsRequest struct {
+	Tags       []string `json:"tags"`        // code ของแท็ก เช่น ["peanut", "vegan"]
+	SpicyLevel *int     `json:"spicy_level"` // ใช้กับเมนูเท่านั้น ไม่ส่ง = คงค่าเดิม
+}
+
+// OptionPriceOverrideRequest - ราคาตัวเลือกเฉพาะเมนู (price เป็น null = กลับไปใช้ราคากลาง)
+type OptionPriceOverrideRequest struct {
+	MenuOptionID uint     `json:"menu_option_id" binding:"required"`
+	Price        *float64 `json:"price"`
+}
+
+type SetOptionPricesRequest struct {
+	Prices []OptionPriceOverrideRequest `json:"prices" binding:"required"`
+}
+
+// ComboRequest - สร้าง/แก้ไขชุดอาหาร (แก้ไขจะแทนที่ช่องทั้งหมดด้วย slots ที่ส่งมา)
+type ComboRequest struct {
+	Name          string             `json:"name" binding:"required"`
+	NameEn        string             `json:"name_en"`
+	NameCh        string             `json:"name_ch"`
+	Description   string             `json:"description"`
+	DescriptionEn string             `json:"description_en"`
+	DescriptionCh string             `json:"description_ch"`
+	Price         float64            `json:"price"`
+	IsActive      *bool              `json:"is_active"` // ไม่ส่ง = เปิดใช้งาน (ตอนสร้าง) หรือคงค่าเดิม (ตอนแก้ไข)
+	Slots         []ComboSlotRequest `json:"slots" binding:"required"`
+}
+
+type ComboSlotRequest struct {
+	Name          string               `json:"name" binding:"required"`
+	NameEn        string               `json:"name_en"`
+	NameCh        string               `json:"name_ch"`
+	MinSelections *int                 `json:"min_selections"` // ไม่ส่ง = 1
+	MaxSelections *int                 `json:"max_selections"` // ไม่ส่ง = เท่ากับ min_selections (อย่างน้อย 1)
+	Choices       []ComboChoiceRequest `json:"choices" binding:"required"`
+}
+
+type ComboChoiceRequest struct {
+	MenuItemID uint    `json:"menu_item_id" binding:"required"`
+	Upcharge   float64 `json:"upcharge"` // ค่าอัปเกรดเมื่อเลือกรายการนี้ เช่น 20
+	IsDefault  bool    `json:"is_default"`
+}
+
+// ReorderMenuRequest - จัดลำดับเมนูในแต่ละหมวดหมู่ เมนูที่ไม่ได้ส่งมาจะต่อท้ายตามลำดับเดิม
+type ReorderMenuRequest struct {
+	Categories []CategoryMenuOrder `json:"categories" binding:"required"`
+}
+
+type CategoryMenuOrder struct {
+	CategoryID  uint   `json:"category_id" binding:"required"`
+	MenuItemIDs []uint `json:"menu_item_ids" binding:"required"` // ID เมนูเรียงตามลำดับที่ต้องการ
+}
+
+// BulkMenuRequest - แก้ไขเมนูหลายรายการพร้อมกัน ส่ง preview = true เพื่อดูผลก่อนโดยไม่บันทึก
+type BulkMenuRequest struct {
+	Filter     BulkMenuFilter `json:"filter"`
+	Action     string         `json:"action" binding:"required"` // set_price, adjust_price, availability, move_category, recommended
+	Amount     *float64       `json:"amount"`                    // set_price: ราคาใหม่, adjust_price: บาทที่เพิ่ม (ติดลบ = ลด)
+	Percent    *float64       `json:"percent"`                   // adjust_price: เปอร์เซ็นต์ที่เพิ่ม เช่น 5 = +5% (ปัดเป็นบาท)
+	Value      *bool          `json:"value"`                     // availability/recommended: ไม่ส่ง = สลับสถานะ
+	CategoryID *uint          `json:"category_id"`               // move_category: หมวดหมู่ปลายทาง
+	Preview    bool           `json:"preview"`
+	Note       string         `json:"note"`
+}
+
+// BulkMenuFilter - เลือกเมนูที่จะแก้ไข ตัวกรองที่ส่งมาต้องตรงทุกข้อ ต้องส่งอย่างน้อยหนึ่งข้อหรือ all = true
+type BulkMenuFilter struct {
+	CategoryIDs []uint   `json:"category_ids"` // รวมหมวดหมู่ย่อย
+	MenuItemIDs []uint   `json:"menu_item_ids"`
+	Tags        []string `json:"tags"` // code ของแท็ก มีแท็กใดแท็กหนึ่ง
+	All         bool     `json:"all"`
+}
+
+// UpdateTableSessionRequest - แก้ไขข้อมูลรอบการใช้โต๊ะที่เปิดอยู่ ส่งเฉพาะฟิลด์ที่ต้องการแก้
+type UpdateTableSessionRequest struct {
+	GuestCount *int  `json:"guest_count"`
+	ServerID   *uint `json:"server_id"` // ส่ง 0 เพื่อยกเลิกพนักงานที่ดูแล
+}
+
+// TableZoneRequest - สร้าง/แก้ไขโซนของร้าน
+type TableZoneRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	Description string  `json:"description"`
+	Color       string  `json:"color"`
+	SortOrder   int     `json:"sort_order"`
+	Width       float64 `json:"width"`
+	Height      float64 `json:"height"`
+	IsActive    *bool   `json:"is_active"` // ไม่ส่ง = เปิดใช้งาน (ตอนสร้าง) หรือคงเดิม (ตอนแก้ไข)
+}
+
+// SaveTableLayoutRequest - บันทึกผังโต๊ะหลายตัวพร้อมกัน ค่าของแต่ละโต๊ะแทนที่ค่าเดิมทั้งหมด
+type SaveTableLayoutRequest struct {
+	Tables []TableLayoutItem `json:"tables" binding:"required"`
+}
+
+type TableLayoutItem struct {
+	TableID  uint    `json:"table_id" binding:"required"`
+	ZoneID   *uint   `json:"zone_id"` // null หรือ 0 = ไม่อยู่ในโซนใด
+	PosX     float64 `json:"pos_x"`
+	PosY     float64 `json:"pos_y"`
+	Width    float64 `json:"width"`
+	Height   float64 `json:"height"`
+	Shape    string  `json:"shape"` // square, rectangle, round (ค่าเริ่มต้น square)
+	Rotation int     `json:"rotation"`
+}
+
+// TransferOrderItemsRequest - ย้ายรายการอาหารบางรายการหรือบางจำนวนไปยังโต๊ะที่เปิดอยู่อีกโต๊ะ
+type TransferOrderItemsRequest struct {
+	FromTableID uint                `json:"from_table_id" binding:"required"`
+	ToTableID   uint                `json:"to_table_id" binding:"required"`
+	Items       []TransferOrderItem `json:"items" binding:"required"`
+	Note        string              `json:"note"`
+}
+
+type TransferOrderItem struct {
+	OrderItemID uint `json:"order_item_id" binding:"required"`
+	Quantity    int  `json:"quantity"` // ไม่ส่งหรือ 0 = ย้ายทั้งหมด
+}
+
+// WaitlistRequest - รับคิวลูกค้า walk-in
+type WaitlistRequest struct {
+	CustomerName      string `json:"customer_name" binding:"required"`
+	PhoneNumber       string `json:"phone_number"`
+	PartySize         int    `json:"party_size" binding:"required"`
+	Note              string `json:"note"`
+	QuotedWaitMinutes *int   `json:"quoted_wait_minutes"` // ไม่ส่ง = ประเมินจากจำนวนคิวที่รออยู่
+}
+
+// SeatWaitlistRequest - พาลูกค้าในคิวไปนั่งโต๊ะ
+type SeatWaitlistRequest struct {
+	TableID uint `json:"table_id" binding:"required"`
+	Force   bool `json:"force"` // นั่งได้แม้ที่นั่งไม่พอ (เช่น เสริมเก้าอี้)
+}
+
+// BookingRequest - ลูกค้าจองโต๊ะเอง ระบบเลือกโต๊ะให้
+type BookingRequest struct {
+	CustomerName string    `json:"customer_name" binding:"required"`
+	PhoneNumber  string    `json:"phone_number" binding:"required"`
+	GuestCount   int       `json:"guest_count" binding:"required"`
+	ReservedFor  time.Time `json:"reserved_for" binding:"required"` // ต้องเป็นเวลาจาก /api/booking/availability
+	ZoneID       *uint     `json:"zone_id"`                         // โซนที่ต้องการ (ถ้ามี)
+	Note         string    `json:"note"`
+	Email        string    `json:"email"`        // รับข้อความยืนยันและเตือนทางอีเมล (ถ้ามี)
+	LineUserID   string    `json:"line_user_id"` // รับข้อความทาง LINE (ถ้ามี)
+	Language     string    `json:"language"`     // ภาษาของข้อความ th (ค่าเริ่มต้น), en
+}
+
+// UpdateBookingRequest - ลูกค้าแก้ไขการจองของตัวเอง ส่งเฉพาะค่าที่ต้องการเปลี่ยน
+type UpdateBookingRequest struct {
+	PhoneNumber string     `json:"phone_number" binding:"required"` // เบอร์ที่ใช้จอง เพื่อยืนยันตัวตน
+	GuestCount  *int       `json:"guest_count"`
+	ReservedFor *time.Time `json:"reserved_for"`
+	Note        *string    `json:"note"`
+}
+
+// CancelBookingRequest - ลูกค้ายกเลิกการจองของตัวเอง
+type CancelBookingRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required"` // เบอร์ที่ใช้จอง เพื่อยืนยันตัวตน
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // -----
@@ -57,52 +58,93 @@ func (r UserRole) IsValid() bool {
 }
 
 type MenuItem struct {
-	ID            uint          `gorm:"primaryKey"`
-	Name          string        `gorm:"not null"`
-	NameEn        string        `gorm:"not null"`
-	NameCh        string        `gorm:"not null"`
-	Description   string        `gorm:"type:varchar(255)"`
-	DescriptionEn string        `gorm:"type:varchar(255)"`
-	DescriptionCh string        `gorm:"type:varchar(255)"`
-	Image         []byte        `gorm:"type:bytea"`            // ฟิลด์ Image เป็น type bytea
-	CategoryID    uint          `gorm:"not null"`              // foreign key ที่เชื่อมกับ Category
-	Category      Category      `gorm:"foreignKey:CategoryID"` // ลิงก์ไปยังตาราง Category
-	Price         int16         `gorm:"not null"`
-	OptionGroups  []OptionGroup `gorm:"foreignKey:MenuItemID"`
-	Is_available  bool          `gorm:"not null;default:true"` //พร้อมขายหรือไม่
-	IsRecommended bool          `gorm:"not null;default:false"`
+	ID            uint           `gorm:"primaryKey"`
+	Name          string         `gorm:"not null"`
+	NameEn        string         `gorm:"not null"`
+	NameCh        string         `gorm:"not null"`
+	Description   string         `gorm:"type:varchar(255)"`
+	DescriptionEn string         `gorm:"type:varchar(255)"`
+	DescriptionCh string         `gorm:"type:varchar(255)"`
+	Image         []byte         `gorm:"type:bytea" json:"-"`   // รูปแบบเก่า ย้ายไปเก็บใน image store แล้ว
+	ImageID       *uint          `gorm:"index"`                 // รูปเมนูที่เก็บใน image store
+	ImageURL      string         `gorm:"-"`                     // URL สำหรับดึงรูป (คำนวณตอนโหลดข้อมูล)
+	CategoryID    uint           `gorm:"not null"`              // foreign key ที่เชื่อมกับ Category
+	Category      Category       `gorm:"foreignKey:CategoryID"` // ลิงก์ไปยังตาราง Category
+	Price         int16          `gorm:"not null"`
+	OptionGroups  []OptionGroup  `gorm:"many2many:menu_item_option_groups"` // กลุ่มตัวเลือกใช้ร่วมกันได้หลายเมนู
+	Is_available  bool           `gorm:"not null;default:true"`             //พร้อมขายหรือไม่
+	IsRecommended bool           `gorm:"not null;default:false"`
+	IsPinned      bool           `gorm:"not null;default:false" json:"is_pinned"` // ปักหมุดไว้บนสุดของหมวดหมู่
+	DisplayOrder  int            `gorm:"not null;default:0" json:"display_order"` // ลำดับในหมวดหมู่ น้อยขึ้นก่อน
+	SKU           *string        `gorm:"uniqueIndex"`                             // รหัสเมนูสำหรับจับคู่ตอนนำเข้าไฟล์ (ไม่บังคับ)
+	SpicyLevel    int            `gorm:"not null;default:0"`                      // ระดับความเผ็ด 0-5
+	Tags          []DietaryTag   `gorm:"many2many:menu_item_tags"`                // สารก่อภูมิแพ้และข้อมูลโภชนาการ
+	Localized     *LocalizedText `gorm:"-" json:"localized,omitempty"`            // ชื่อ/คำอธิบายตามภาษาที่ขอ (มีเฉพาะ API ที่รองรับหลายภาษา)
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	DeletedAt     gorm.DeletedAt `json:"-" swaggerignore:"true"` //เอาไว้ทำ softdelete จะได้ restore ง่ายๆ
 }
 
+// AfterFind เติม ImageURL จาก ImageID เพื่อให้ response ส่ง URL แทนข้อมูลรูป
+func (m *MenuItem) AfterFind(tx *gorm.DB) error {
+	m.ImageURL = ImageURL(m.ImageID)
+	return nil
+}
+
 type MenuOption struct {
-	ID          uint        `gorm:"primaryKey"`
-	GroupID     uint        `gorm:"not null"`
-	OptionGroup OptionGroup `gorm:"foreignKey:GroupID" json:"-"`
-	Name        string      `gorm:"not null"`
-	NameEn      string      `gorm:"not null"`
-	NameCh      string      `gorm:"not null"`
-	Price       float64     `gorm:"not null"`
+	ID          uint           `gorm:"primaryKey"`
+	GroupID     uint           `gorm:"not null"`
+	OptionGroup OptionGroup    `gorm:"foreignKey:GroupID" json:"-"`
+	Name        string         `gorm:"not null"`
+	NameEn      string         `gorm:"not null"`
+	NameCh      string         `gorm:"not null"`
+	Price       float64        `gorm:"not null"`
+	BasePrice   *float64       `gorm:"-" json:"base_price,omitempty"` // ราคากลางของตัวเลือก (มีเฉพาะเมื่อเมนูนี้กำหนดราคาเฉพาะไว้)
+	Tags        []DietaryTag   `gorm:"many2many:menu_option_tags"`    // เช่น ท็อปปิ้งกุ้งมี shellfish
+	Localized   *LocalizedText `gorm:"-" json:"localized,omitempty"`
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	DeletedAt   gorm.DeletedAt `json:"-" swaggerignore:"true"` //เอาไว้ทำ softdelete จะได้ restore ง่ายๆ
 }
 
 // กรณีที่อาหารอาจมีหลายตัวเลือกแต่เลือกได้แค่ == MaxSelections ที่กำหนด เช่น น้ำซุปเลือกได้แค่ 1 จาก 5
+// กลุ่มหนึ่งผูกกับหลายเมนูได้ผ่านตาราง menu_item_option_groups (เช่น "ความเผ็ด" ใช้ร่วมกันทุกเมนู)
 type OptionGroup struct {
-	ID            uint         `gorm:"primaryKey"`
-	MenuItemID    uint         `gorm:"not null"`
-	MenuItem      MenuItem     `gorm:"foreignKey:MenuItemID" json:"-"`
-	Name          string       `gorm:"not null"` // เช่น "น้ำซุป", "ความเผ็ด"
-	NameEn        string       `gorm:"not null"`
-	NameCh        string       `gorm:"not null"`
-	MaxSelections int          `gorm:"not null;default:1"` // จำนวนที่เลือกได้
-	IsRequired    bool         `gorm:"not null;default:false"`
-	Options       []MenuOption `gorm:"foreignKey:GroupID"`
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	DeletedAt     gorm.DeletedAt `json:"-" swaggerignore:"true"` //เอาไว้ทำ softdelete จะได้ restore ง่ายๆ
+	ID             uint           `gorm:"primaryKey"`
+	MenuItemID     *uint          `gorm:"index"` // เมนูที่สร้างกลุ่มนี้ (nil = สร้างจากคลังกลุ่มตัวเลือก) ใช้ผูกเมนูอัตโนมัติตอนสร้าง
+	MenuItem       MenuItem       `gorm:"foreignKey:MenuItemID" json:"-"`
+	ParentOptionID *uint          `gorm:"index" json:"parent_option_id,omitempty"` // กลุ่มย่อยที่แสดงเมื่อเลือกตัวเลือกนี้ เช่น เลือก "ก๋วยเตี๋ยวน้ำ" แล้วต้องเลือก "เส้น"
+	Name           string         `gorm:"not null"`                                // เช่น "น้ำซุป", "ความเผ็ด"
+	NameEn         string         `gorm:"not null"`
+	NameCh         string         `gorm:"not null"`
+	MaxSelections  int            `gorm:"not null;default:1"` // จำนวนที่เลือกได้
+	IsRequired     bool           `gorm:"not null;default:false"`
+	Options        []MenuOption   `gorm:"foreignKey:GroupID"`
+	Localized      *LocalizedText `gorm:"-" json:"localized,omitempty"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `json:"-" swaggerignore:"true"` //เอาไว้ทำ softdelete จะได้ restore ง่ายๆ
+}
+
+// AfterCreate ผูกกลุ่มที่สร้างจากหน้าเมนูเข้ากับเมนูนั้นทันที
+func (g *OptionGroup) AfterCreate(tx *gorm.DB) error {
+	if g.MenuItemID == nil {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Table("menu_item_option_groups").
+		Create(map[string]interface{}{"menu_item_id": *g.MenuItemID, "option_group_id": g.ID}).Error
+}
+
+// MenuItemOptionPrice - ราคาตัวเลือกเฉพาะเมนู ใช้แทน MenuOption.Price เมื่อกลุ่มใช้ร่วมกันหลายเมนู
+// เช่น "ไข่ดาว" ราคากลาง 10 บาท แต่ในเมนูข้าวผัดคิด 5 บาท
+type MenuItemOptionPrice struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	MenuItemID   uint      `gorm:"not null;uniqueIndex:idx_menu_item_option_price" json:"menu_item_id"`
+	MenuOptionID uint      `gorm:"not null;uniqueIndex:idx_menu_item_option_price" json:"menu_option_id"`
+	Price        float64   `gorm:"not null" json:"price"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 type Category struct {
@@ -110,9 +152,43 @@ type Category struct {
 	Name      string         `gorm:"not null"`
 	NameEn    string         `gorm:"not null"`
 	NameCh    string         `gorm:"not null"`
+	ParentID  *uint          `gorm:"index" json:"parent_id,omitempty"`         // หมวดหมู่แม่ (nil = ระดับบนสุด)
+	SortOrder int            `gorm:"not null;default:0" json:"sort_order"`     // ลำดับการแสดงในระดับเดียวกัน น้อยขึ้นก่อน
+	Icon      string         `json:"icon"`                                     // ชื่อไอคอนหรือ URL ของไอคอน
+	ShowOnQR  bool           `gorm:"not null;default:true" json:"show_on_qr"`  // แสดงในเมนูลูกค้า (สแกน QR)
+	ShowOnPOS bool           `gorm:"not null;default:true" json:"show_on_pos"` // แสดงในหน้า POS ของพนักงาน
+	Children  []Category     `gorm:"-" json:"children,omitempty"`
+	Localized *LocalizedText `gorm:"-" json:"localized,omitempty"`
 	DeletedAt gorm.DeletedAt `json:"-" swaggerignore:"true"`
 }
 
+// ช่องทางที่แสดงเมนู ใช้กับ Category.ShowOnQR / ShowOnPOS
+const (
+	ChannelQR  = "qr"
+	ChannelPOS = "pos"
+)
+
+// StoredImage - ข้อมูลรูปภาพที่เก็บไว้นอกฐานข้อมูล (ไฟล์จริงอยู่ใน image store)
+type StoredImage struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	StorageKey  string    `gorm:"not null;uniqueIndex" json:"-"` // prefix ของไฟล์ใน store เช่น images/2025/01/<uuid>
+	ContentType string    `gorm:"not null" json:"content_type"`
+	Extension   string    `gorm:"not null" json:"-"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	Size        int64     `json:"size"`
+	Checksum    string    `gorm:"not null" json:"checksum"` // sha256 ของไฟล์ต้นฉบับ ใช้เป็น ETag
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ImageURL สร้าง URL สาธารณะของรูปจาก ID (ว่างถ้าไม่มีรูป)
+func ImageURL(imageID *uint) string {
+	if imageID == nil {
+		return ""
+	}
+	return fmt.Sprintf("/images/%d", *imageID)
+}
+
 type Table struct {
 	ID        uint   `gorm:"primaryKey"`
 	Name      string `gorm:"not null"`
@@ -120,22 +196,54 @@ type Table struct {
 	Status    string `gorm:"not null;default:'available'"` // available, reserved, occupied, unavailable
 	ParentID  *uint
 	GroupID   *string // ID กลุ่มสำหรับโต๊ะที่รวมกัน
+	ZoneID    *uint   `gorm:"index"`              // โซนที่โต๊ะตั้งอยู่
+	PosX      float64 `gorm:"not null;default:0"` // ตำแหน่งมุมซ้ายบนบนผังของโซน
+	PosY      float64 `gorm:"not null;default:0"`
+	Width     float64 `gorm:"not null;default:0"` // ขนาดบนผัง
+	Height    float64 `gorm:"not null;default:0"`
+	Shape     string  `gorm:"not null;default:'square'"` // square, rectangle, round
+	Rotation  int     `gorm:"not null;default:0"`        // องศาการหมุน 0-359
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt `json:"-" swaggerignore:"true"`
 }
 
+// ที่มาของการจอง
+const (
+	ReservationSourcePOS    = "pos"    // พนักงานจองให้
+	ReservationSourceOnline = "online" // ลูกค้าจองเองผ่าน /api/booking
+)
+
+// สถานะเงินมัดจำของการจอง
+const (
+	DepositNone      = "none"      // ไม่ต้องมัดจำ
+	DepositPending   = "pending"   // รอชำระ
+	DepositPaid      = "paid"      // ชำระแล้ว
+	DepositRefunded  = "refunded"  // คืนเงินแล้ว
+	DepositForfeited = "forfeited" // ริบเพราะลูกค้าไม่มา
+)
+
 type TableReservation struct {
-	ID               uint      `gorm:"primaryKey"`
-	TableID          uint      `gorm:"not null"`                   // Foreign key to Table
-	Table            Table     `gorm:"foreignKey:TableID"`         // Relation to Table
-	CustomerName     string    `gorm:"type:varchar(100);not null"` // ชื่อลูกค้าที่จอง
-	PhoneNumber      string    `gorm:"type:varchar(20);not null"`  // เบอร์โทรศัพท์
-	GuestCount       int       `gorm:"not null"`                   // จำนวนลูกค้า
-	ReservedFor      time.Time `gorm:"not null"`                   // เวลาที่ลูกค้าจะมาใช้บริการ
-	TableBlockedFrom time.Time `json:"table_blocked_from"`         // เวลาที่เริ่มกั้นโต๊ะ
-	GracePeriodUntil time.Time `json:"grace_period_until"`         // เวลาสิ้นสุด grace period
-	Status           string    `gorm:"not null;default:'active'"`  // active, cancelled, completed
+	ID               uint       `gorm:"primaryKey"`
+	TableID          uint       `gorm:"not null"`                                                    // Foreign key to Table
+	Table            Table      `gorm:"foreignKey:TableID"`                                          // Relation to Table
+	ConfirmationCode *string    `gorm:"type:varchar(12);uniqueIndex" json:"confirmation_code"`       // รหัสยืนยันให้ลูกค้าดู/แก้ไข/ยกเลิกการจองเอง
+	Source           string     `gorm:"type:varchar(10);not null;default:'pos'" json:"source"`       // pos, online
+	CustomerName     string     `gorm:"type:varchar(100);not null"`                                  // ชื่อลูกค้าที่จอง
+	PhoneNumber      string     `gorm:"type:varchar(20);not null"`                                   // เบอร์โทรศัพท์
+	Email            string     `gorm:"type:varchar(255)" json:"email"`                              // สำหรับส่งข้อความทางอีเมล
+	LineUserID       string     `gorm:"type:varchar(64)" json:"line_user_id"`                        // สำหรับส่งข้อความทาง LINE
+	Language         string     `gorm:"type:varchar(2);not null;default:'th'" json:"language"`       // ภาษาของข้อความถึงลูกค้า th, en
+	GuestCount       int        `gorm:"not null"`                                                    // จำนวนลูกค้า
+	Note             string     `gorm:"type:text" json:"note"`                                       // หมายเหตุจากลูกค้า เช่น ขอเก้าอี้เด็ก
+	ReservedFor      time.Time  `gorm:"not null"`                                                    // เวลาที่ลูกค้าจะมาใช้บริการ
+	TableBlockedFrom time.Time  `json:"table_blocked_from"`                                          // เวลาที่เริ่มกั้นโต๊ะ
+	GracePeriodUntil time.Time  `json:"grace_period_until"`                                          // เวลาสิ้นสุด grace period
+	DepositAmount    float64    `gorm:"type:decimal(10,2);not null;default:0" json:"deposit_amount"` // เงินมัดจำตามกฎการจองตอนที่จอง
+	DepositStatus    string     `gorm:"type:varchar(20);not null;default:'none'" json:"deposit_status"`
+	NoShowFee        float64    `gorm:"type:decimal(10,2);not null;default:0" json:"no_show_fee"` // ค่าปรับเมื่อไม่มาตามนโยบายตอนที่จอง
+	Status           string     `gorm:"not null;default:'active'"`                                // active, cancelled, completed, no_show, expired
+	CancelledAt      *time.Time `json:"cancelled_at"`
 	CreatedAt        time.Time
 	UpdatedAt        time.Time
 	DeletedAt        gorm.DeletedAt `json:"-" swaggerignore:"true"`
@@ -143,25 +251,56 @@ type TableReservation struct {
 
 type ReservationRules struct {
 	ID                    uint      `gorm:"primaryKey" json:"id"`
-	GracePeriodMinutes    int       `json:"grace_period_minutes"`    // เวลาที่ยอมให้สายได้ (นาที)
-	PreReservationMinutes int       `json:"pre_reservation_minutes"` // เวลาที่จะกั้นโต๊ะก่อนถึงเวลาจอง (นาที)
-	IsActive              bool      `json:"is_active"`               // ใช้งาน Rule นี้อยู่หรือไม่
+	GracePeriodMinutes    int       `json:"grace_period_minutes"`                                            // เวลาที่ยอมให้สายได้ (นาที)
+	PreReservationMinutes int       `json:"pre_reservation_minutes"`                                         // เวลาที่จะกั้นโต๊ะก่อนถึงเวลาจอง (นาที)
+	FirstSlotTime         string    `gorm:"type:varchar(5);not null;default:'11:00'" json:"first_slot_time"` // เวลาแรกที่รับจองของแต่ละวัน (HH:MM)
+	LastSlotTime          string    `gorm:"type:varchar(5);not null;default:'21:00'" json:"last_slot_time"`  // เวลาสุดท้ายที่รับจอง (HH:MM)
+	SlotIntervalMinutes   int       `gorm:"not null;default:30" json:"slot_interval_minutes"`                // ช่วงห่างของเวลาที่ให้เลือกจอง (นาที)
+	DiningDurationMinutes int       `gorm:"not null;default:0" json:"dining_duration_minutes"`               // เวลาที่คาดว่าลูกค้าหนึ่งกลุ่มใช้โต๊ะ (นาที) 0 = ใช้เวลานั่งเฉลี่ยจากประวัติ
+	DepositPerGuest       float64   `gorm:"type:decimal(10,2);not null;default:0" json:"deposit_per_guest"`  // เงินมัดจำต่อคน 0 = ไม่ต้องมัดจำ
+	NoShowFee             float64   `gorm:"type:decimal(10,2);not null;default:0" json:"no_show_fee"`        // ค่าปรับเมื่อลูกค้าไม่มา
+	CancelCutoffMinutes   int       `gorm:"not null;default:0" json:"cancel_cutoff_minutes"`                 // ลูกค้าแก้ไข/ยกเลิกเองได้ถึงกี่นาทีก่อนเวลาจอง
+	ReminderHoursBefore   int       `gorm:"not null;default:0" json:"reminder_hours_before"`                 // ส่งข้อความเตือนก่อนเวลาจองกี่ชั่วโมง 0 = ไม่เตือน
+	IsActive              bool      `json:"is_active"`                                                       // ใช้งาน Rule นี้อยู่หรือไม่
 	CreatedAt             time.Time `json:"created_at"`
 	UpdatedAt             time.Time `json:"updated_at"`
 }
 
-// TableHistory - เก็บประวัติการย้าย/รวมโต๊ะ
-// type TableHistory struct {
-// 	ID          uint   `gorm:"primaryKey"`
-// 	TableID     uint   `gorm:"not null"`
-// 	Action      string `gorm:"not null"` // move, merge, split
-// 	FromStatus  string `gorm:"not null"`
-// 	ToStatus    string `gorm:"not null"`
-// 	FromGroupID *string
-// 	ToGroupID   *string
-// 	StaffID     uint `gorm:"not null"`
-// 	CreatedAt   time.Time
-// }
+// การกระทำที่บันทึกใน TableHistory
+const (
+	TableActionCreate    = "create"
+	TableActionUpdate    = "update"
+	TableActionDelete    = "delete"
+	TableActionMerge     = "merge"
+	TableActionMove      = "move"
+	TableActionSplit     = "split"
+	TableActionReserve   = "reserve"
+	TableActionUnreserve = "unreserve"
+	TableActionStatus    = "status"
+	TableActionLayout    = "layout"   // ย้ายโซน/ตำแหน่งในผังร้าน
+	TableActionTransfer  = "transfer" // ย้ายรายการอาหารบางส่วนระหว่างโต๊ะ
+	TableActionOpen      = "open"     // เปิดโต๊ะ/สร้าง QR Code
+	TableActionCheckin   = "checkin"  // เช็คอินการจอง
+	TableActionClose     = "close"    // ชำระเงินหรือปิดโต๊ะ
+	TableActionRelease   = "release"  // ระบบคืนโต๊ะที่จองไว้ (ลูกค้าไม่มา/หมดเวลา)
+)
+
+// TableHistory - เก็บประวัติการเปลี่ยนแปลงของโต๊ะ (ย้าย/รวม/แยก/เปลี่ยนสถานะ) หนึ่งแถวต่อโต๊ะต่อการกระทำ
+type TableHistory struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	TableID        uint      `gorm:"not null;index" json:"table_id"`
+	Action         string    `gorm:"not null;index" json:"action"` // move, merge, split, ...
+	FromStatus     string    `json:"from_status"`
+	ToStatus       string    `json:"to_status"`
+	FromGroupID    *string   `json:"from_group_id"`
+	ToGroupID      *string   `json:"to_group_id"`
+	RelatedTableID *uint     `json:"related_table_id"` // โต๊ะอีกฝั่ง เช่น โต๊ะปลายทางของการย้าย
+	SessionID      *uint     `gorm:"index" json:"session_id"`
+	OrderIDs       []uint    `gorm:"serializer:json;type:text" json:"order_ids"` // ออเดอร์ที่ย้ายไปพร้อมกัน
+	StaffID        *uint     `gorm:"index" json:"staff_id"`                      // ว่าง = ทำโดยระบบ
+	Note           string    `json:"note"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+}
 
 type QRCode struct {
 	ID        uint      `gorm:"primaryKey"`
@@ -171,6 +310,7 @@ type QRCode struct {
 	Qr_Image  []byte    `gorm:"type:bytea"`
 	ExpiryAt  time.Time `json:"expiry_at" db:"expiry_at"`
 	IsActive  bool      `gorm:"not null;default:true;index"`
+	SessionID *uint     `gorm:"index" json:"session_id"` // รอบการใช้โต๊ะของ QR Code นี้
 }
 
 // FE-4 การจัดการออเดอร์
@@ -185,6 +325,7 @@ type Order struct {
 	Items     []OrderItem
 	ReceiptID *uint   `gorm:"index"`
 	Receipt   Receipt `gorm:"foreignKey:ReceiptID"`
+	SessionID *uint   `gorm:"index"` // รอบการใช้โต๊ะที่สั่งออเดอร์นี้ (UUID คือ token ของ session)
 }
 
 // FE-4 การจัดการออเดอร์
@@ -204,6 +345,8 @@ type OrderItem struct {
 	UpdatedAt        time.Time
 	PromotionUsageID *uint           `gorm:"index"`                       // เพิ่มฟิลด์ใหม่
 	PromotionUsage   *PromotionUsage `gorm:"foreignKey:PromotionUsageID"` // เพิ่มความสัมพันธ์
+	ComboUsageID     *uint           `gorm:"index"`                       // รายการนี้เป็นส่วนหนึ่งของชุด (Price คือส่วนแบ่งราคาชุด)
+	ComboUsage       *ComboUsage     `gorm:"foreignKey:ComboUsageID"`
 }
 
 // FE-4 การจัดการออเดอร์
@@ -364,6 +507,8 @@ type Receipt struct {
 	// Orders        []Order `gorm:"foreignKey:ReceiptID"`
 	Orders        []Order `gorm:"foreignKey:ReceiptID"`
 	OrderID       *uint   `gorm:"index"`
+	SessionID     *uint   `gorm:"index"`              // รอบการใช้โต๊ะที่ชำระ (ใบเสร็จรวมหลายโต๊ะไม่มีค่า)
+	GuestCount    int     `gorm:"not null;default:0"` // จำนวนลูกค้า (covers) ณ ตอนชำระเงิน
 	SubTotal      float64 // ยอดรวมทุก order
 	DiscountTotal float64
 	ChargeTotal   float64
@@ -19,7 +19,10 @@ type Promotion struct {
 	IsActive      bool            `gorm:"not null;default:true"`
 	Price         float64         `gorm:"not null"` // เพิ่มฟิลด์ราคา
 	Items         []PromotionItem `gorm:"foreignKey:PromotionID"`
-	Image         []byte          `gorm:"type:bytea"` // รูปโปร
+	Image         []byte          `gorm:"type:bytea" json:"-"` // รูปโปรแบบเก่า ย้ายไป image store แล้ว
+	ImageID       *uint           `gorm:"index"`               // รูปโปรที่เก็บใน image store
+	ImageURL      string          `gorm:"-"`
+	Localized     *LocalizedText  `gorm:"-" json:"localized,omitempty"`
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	DeletedAt     gorm.DeletedAt `json:"-" swaggerignore:"true"`
@@ -28,6 +31,12 @@ type Promotion struct {
 	TotalItems    int            `gorm:"not null"` // จำนวนรายการทั้งหมดในโปรโมชั่น
 }
 
+// AfterFind เติม ImageURL จาก ImageID
+func (p *Promotion) AfterFind(tx *gorm.DB) error {
+	p.ImageURL = ImageURL(p.ImageID)
+	return nil
+}
+
 type PromotionItem struct {
 	ID          uint      `gorm:"primaryKey"`
 	PromotionID uint      `gorm:"not null;index"`
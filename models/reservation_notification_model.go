@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// ประเภทข้อความที่ส่งถึงลูกค้าที่จองโต๊ะ
+const (
+	NotificationConfirmation = "confirmation" // ยืนยันการจอง
+	NotificationReminder     = "reminder"     // เตือนก่อนถึงเวลาจอง
+	NotificationNoShow       = "no_show"      // ติดตามเมื่อลูกค้าไม่มา
+)
+
+// สถานะการส่งข้อความ
+const (
+	NotificationPending = "pending" // รอส่ง
+	NotificationSent    = "sent"    // ส่งสำเร็จ
+	NotificationFailed  = "failed"  // ส่งไม่สำเร็จครบจำนวนครั้งแล้ว
+	NotificationSkipped = "skipped" // ไม่มีผู้รับสำหรับช่องทางนี้ เช่น ไม่มี LINE user ID
+)
+
+// ReservationNotification - ประวัติการส่งข้อความของการจองแต่ละรายการ
+// สร้างเป็น pending ใน transaction เดียวกับการจอง แล้ว ManageReservationNotifications ส่งภายหลัง
+type ReservationNotification struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	ReservationID uint       `gorm:"not null;index" json:"reservation_id"`
+	Kind          string     `gorm:"type:varchar(20);not null" json:"kind"`
+	Channel       string     `gorm:"type:varchar(10)" json:"channel"` // sms, line, email, log (ช่องทางที่ใช้ตอนส่ง)
+	Recipient     string     `gorm:"type:varchar(255)" json:"recipient"`
+	Language      string     `gorm:"type:varchar(2)" json:"language"`
+	Subject       string     `gorm:"type:varchar(255)" json:"subject"`
+	Body          string     `gorm:"type:text" json:"body"`
+	Status        string     `gorm:"type:varchar(10);not null;default:'pending';index" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	Error         string     `gorm:"type:text" json:"error"`
+	ProviderRef   string     `gorm:"type:varchar(255)" json:"provider_ref"` // message ID จากผู้ให้บริการ (ถ้ามี)
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// สถานะของ TableSession
+const (
+	SessionOpen   = "open"
+	SessionClosed = "closed"
+)
+
+// TableSession - การใช้โต๊ะหนึ่งรอบตั้งแต่เปิดโต๊ะ (สร้าง QR Code) จนชำระเงินหรือปิดโต๊ะ
+// ออเดอร์ ใบเสร็จ และ QR Code ของรอบนั้นอ้างถึง session นี้
+type TableSession struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Token         string     `gorm:"not null;uniqueIndex" json:"token"`                      // token ที่อยู่ใน QR Code (เดิมคือ QRCode.UUID)
+	TableID       uint       `gorm:"not null;index" json:"table_id"`                         // โต๊ะหลัก (โต๊ะแม่ถ้าเป็นโต๊ะรวม)
+	Tables        []Table    `gorm:"many2many:table_session_tables" json:"tables,omitempty"` // ทุกโต๊ะที่ลูกค้ากลุ่มนี้ใช้
+	Status        string     `gorm:"not null;default:'open';index" json:"status"`
+	GuestCount    int        `gorm:"not null;default:0" json:"guest_count"`
+	ServerID      *uint      `gorm:"index" json:"server_id"`      // พนักงานที่ดูแลโต๊ะ
+	ReservationID *uint      `gorm:"index" json:"reservation_id"` // การจองที่เช็คอินเข้ามา (ถ้ามี)
+	ExpiresAt     time.Time  `json:"expires_at"`                  // QR Code ใช้สั่งอาหารได้ถึงเวลานี้
+	OpenedAt      time.Time  `gorm:"not null" json:"opened_at"`
+	ClosedAt      *time.Time `json:"closed_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// รูปทรงโต๊ะบนผังร้าน
+const (
+	TableShapeSquare    = "square"
+	TableShapeRectangle = "rectangle"
+	TableShapeRound     = "round"
+)
+
+// TableZone - โซนหรือห้องในร้าน เช่น ห้องแอร์ ระเบียง ชั้น 2 แต่ละโซนมีผังโต๊ะของตัวเอง
+type TableZone struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	Name        string         `gorm:"not null" json:"name"`
+	Description string         `json:"description"`
+	Color       string         `json:"color"`                                // สีที่ใช้แสดงโซนบน POS เช่น #4caf50
+	SortOrder   int            `gorm:"not null;default:0" json:"sort_order"` // ลำดับแท็บโซนบน POS
+	Width       float64        `gorm:"not null;default:0" json:"width"`      // ขนาดพื้นที่ผังของโซน (หน่วยเดียวกับตำแหน่งโต๊ะ)
+	Height      float64        `gorm:"not null;default:0" json:"height"`
+	IsActive    bool           `gorm:"not null;default:true" json:"is_active"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-" swaggerignore:"true"`
+}
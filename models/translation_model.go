@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// ภาษาที่มีคอลัมน์ในตารางหลักอยู่แล้ว ภาษาอื่นเก็บในตาราง translations อย่างเดียว
+const (
+	LangThai    = "th" // ภาษาหลัก ใช้เป็น fallback เสมอ
+	LangEnglish = "en"
+	LangChinese = "zh"
+)
+
+// ประเภทข้อมูลที่แปลได้ (EntityType ใน Translation)
+const (
+	TranslatableMenuItem    = "menu_item"
+	TranslatableCategory    = "category"
+	TranslatableOptionGroup = "option_group"
+	TranslatableMenuOption  = "menu_option"
+	TranslatablePromotion   = "promotion"
+	TranslatableDietaryTag  = "dietary_tag"
+	TranslatableCombo       = "combo"
+	TranslatableComboSlot   = "combo_slot"
+)
+
+// Translation - คำแปลของฟิลด์หนึ่งในภาษาหนึ่ง
+// เพิ่มภาษาใหม่ได้โดยเพิ่มแถว ไม่ต้องเพิ่มคอลัมน์ และใช้แทนค่าในคอลัมน์ NameEn/NameCh ได้ถ้ามีแถวของภาษานั้น
+type Translation struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	EntityType string    `gorm:"not null;uniqueIndex:idx_translation_key" json:"entity_type"`
+	EntityID   uint      `gorm:"not null;uniqueIndex:idx_translation_key" json:"entity_id"`
+	Field      string    `gorm:"not null;uniqueIndex:idx_translation_key" json:"field"` // name, description
+	Lang       string    `gorm:"not null;uniqueIndex:idx_translation_key;index" json:"lang"`
+	Value      string    `gorm:"not null" json:"value"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// LocalizedText - ชื่อ/คำอธิบายที่เลือกตามภาษาที่ client ขอ (ไม่บันทึกลงฐานข้อมูล)
+type LocalizedText struct {
+	Lang        string `json:"lang"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// สถานะของคิว walk-in
+const (
+	WaitlistWaiting   = "waiting"   // รอคิว
+	WaitlistNotified  = "notified"  // แจ้งลูกค้าแล้วว่าโต๊ะพร้อม
+	WaitlistSeated    = "seated"    // พาไปนั่งแล้ว
+	WaitlistCancelled = "cancelled" // ลูกค้ายกเลิก
+	WaitlistNoShow    = "no_show"   // เรียกแล้วไม่มา
+)
+
+// WaitlistEntry - ลูกค้า walk-in ที่รอโต๊ะตอนร้านเต็ม ลูกค้าใช้ TicketCode ตรวจสอบลำดับคิวเองได้
+type WaitlistEntry struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	TicketCode        string     `gorm:"type:varchar(10);not null;uniqueIndex" json:"ticket_code"`
+	CustomerName      string     `gorm:"type:varchar(100);not null" json:"customer_name"`
+	PhoneNumber       string     `gorm:"type:varchar(20)" json:"phone_number"`
+	PartySize         int        `gorm:"not null" json:"party_size"`
+	Note              string     `json:"note"`
+	Status            string     `gorm:"not null;default:'waiting';index" json:"status"`
+	QuotedWaitMinutes int        `gorm:"not null;default:0" json:"quoted_wait_minutes"` // เวลารอที่แจ้งลูกค้าตอนรับคิว
+	NotifiedAt        *time.Time `json:"notified_at"`
+	SeatedAt          *time.Time `json:"seated_at"`
+	TableID           *uint      `gorm:"index" json:"table_id"`   // โต๊ะที่ได้นั่ง (โต๊ะหลักถ้าเป็นโต๊ะรวม)
+	SessionID         *uint      `gorm:"index" json:"session_id"` // รอบการใช้โต๊ะที่เปิดตอนพาไปนั่ง
+	StaffID           *uint      `json:"staff_id"`                // พนักงานที่รับคิว
+	CreatedAt         time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
@@ -851,10 +851,10 @@ const MenuManagement = () => {
                           </span>
                         </td>
                         <td className="px-6 py-4">
-                          {menu.Image ? (
+                          {menu.ImageURL ? (
                             <div className="group relative">
                               <img
-                                src={`data:image/png;base64,${menu.Image}`}
+                                src={`${import.meta.env.VITE_APP_API_URL}${menu.ImageURL}?size=thumb`}
                                 alt={menu.Name}
                                 className="w-32 h-32 object-cover rounded-xl shadow-md transition-transform transform hover:scale-105"
                               />
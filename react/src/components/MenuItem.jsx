@@ -135,9 +135,9 @@ const MenuItem = ({ item, language, isPremium }) => {
 
       {/* รูปอาหาร */}
       <div className="relative aspect-[4/3] bg-gradient-to-b from-[#3D3038] to-[#2A2530]">
-        {item.Image ? (
+        {item.ImageURL ? (
           <img
-            src={`data:image/png;base64,${item.Image}`}
+            src={`${import.meta.env.VITE_APP_API_URL}${item.ImageURL}?size=thumb`}
             alt={item.Name}
             className="w-full h-full object-cover opacity-95"
             onClick={togglePopup}
@@ -223,9 +223,9 @@ const MenuItem = ({ item, language, isPremium }) => {
                 </svg>
               </button>
 
-              {item.Image ? (
+              {item.ImageURL ? (
                 <img
-                  src={`data:image/png;base64,${item.Image}`}
+                  src={`${import.meta.env.VITE_APP_API_URL}${item.ImageURL}?size=medium`}
                   alt={item.Name || 'Menu item'}
                   className="w-full aspect-video object-cover"
                 />
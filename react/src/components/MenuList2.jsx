@@ -235,7 +235,7 @@ export default function MenuList({ language }) {
                 <div key={promotion.ID} className="relative">
                   <img
                     className="w-full h-full object-cover"
-                    src={`data:image/png;base64,${promotion.Image}`}
+                    src={`${import.meta.env.VITE_APP_API_URL}${promotion.ImageURL}?size=medium`}
                     alt={promotion.Name}
                     onClick={() => handlePromotionClick(promotion)}
                   />
@@ -326,7 +326,7 @@ export default function MenuList({ language }) {
               </button>
 
               <img
-                src={`data:image/png;base64,${displayItem.Image}`}
+                src={`${import.meta.env.VITE_APP_API_URL}${displayItem.ImageURL}?size=medium`}
                 alt={displayItem.Name}
                 className="w-full aspect-video object-cover"
               />
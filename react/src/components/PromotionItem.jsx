@@ -41,7 +41,7 @@ const PromotionItem = ({ item, promotion }) => {
         ID: selectedPromotion.ID,
         Name: selectedPromotion.Name,
         Price: selectedPromotion.Price,
-        Image: selectedPromotion.Image,
+        ImageURL: selectedPromotion.ImageURL,
         quantity: quantity,
         note: note,
         isPromotion: true,
@@ -71,9 +71,9 @@ const PromotionItem = ({ item, promotion }) => {
             <div key={displayItem.ID} className="relative">
               <div className="bg-white border border-gold rounded-md shadow-md">
                 {/* แสดงรูปภาพเมนูหรือโปรโมชัน */}
-                {displayItem.Image ? (
+                {displayItem.ImageURL ? (
                   <img
-                    src={`data:image/png;base64,${displayItem.Image}`}
+                    src={`${import.meta.env.VITE_APP_API_URL}${displayItem.ImageURL}?size=medium`}
                     alt={displayItem.Name}
                     className="w-full h-full object-cover"
                     onClick={togglePopup} // เมื่อคลิกจะแสดง Popup
@@ -138,9 +138,9 @@ const PromotionItem = ({ item, promotion }) => {
                 </svg>
               </button>
 
-              {displayItem.Image ? (
+              {displayItem.ImageURL ? (
                 <img
-                  src={`data:image/png;base64,${displayItem.Image}`}
+                  src={`${import.meta.env.VITE_APP_API_URL}${displayItem.ImageURL}?size=medium`}
                   alt={displayItem.Name || 'Item'}
                   className="w-full aspect-video object-cover"
                 />
@@ -683,9 +683,9 @@ const Promotions = () => {
             >
               {/* Promotion Image */}
               <div className="relative h-48 bg-gray-100 rounded-t-lg overflow-hidden">
-                {promotion.Image ? (
+                {promotion.ImageURL ? (
                   <img
-                    src={`data:image/png;base64,${promotion.Image}`}
+                    src={`${import.meta.env.VITE_APP_API_URL}${promotion.ImageURL}?size=thumb`}
                     alt={promotion.Name}
                     className="w-full h-full object-cover"
                   />
@@ -7,6 +7,7 @@ import (
 	utils "food-ordering-api/utility"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/gofiber/websocket/v2"
 )
 
@@ -62,15 +63,22 @@ func SetupRoutes(app *fiber.App) {
 	{
 		// เมนูพื้นฐาน
 		menu.Post("/import", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.ImportMenuFromExcel)
+		menu.Get("/export", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.ExportMenuToExcel)
 		menu.Post("/", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.CreateMenuItemHandler)
 		menu.Get("/ActiveMenu", api_handlers.GetActiveMenu) // สำหรับดึงเมนูที่เปิดใช้งาน
+		menu.Get("/search", api_handlers.SearchMenu)        // ค้นหาเมนูที่เปิดขาย
 		menu.Get("/", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.GetMenu)
+		menu.Put("/reorder", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.ReorderMenuItems)
+		menu.Post("/bulk", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.BulkUpdateMenu)
+		menu.Get("/bulk/audits", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.GetMenuBulkAudits)
 		menu.Put("/:id", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.UpdateMenuItem)
 		menu.Put("/image/:id", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.UpdateMenuImage)
 		menu.Delete("/:id", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.SoftDelete_Menu)
 
 		menu.Get("/recommended", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.GetRecommendedMenuItems)
+		menu.Get("/best-sellers", api_handlers.GetBestSellers)
 		menu.Put("/:id/recommend", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.ToggleMenuItemRecommendation)
+		menu.Put("/:id/pin", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.ToggleMenuItemPin)
 
 		// Option Groups
 		menu.Get("/option-groups/:id", api_handlers.GetOptionByid)
@@ -91,6 +99,47 @@ func SetupRoutes(app *fiber.App) {
 		menu.Post("/restore-option/:id", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.RestoreOption)
 
 		menu.Put("/status/:id", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.UpdateMenuStatus)
+
+		// คลังกลุ่มตัวเลือกที่ใช้ร่วมกันหลายเมนู และราคาตัวเลือกเฉพาะเมนู
+		menu.Get("/modifier-groups", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.GetModifierGroups)
+		menu.Post("/modifier-groups", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.CreateModifierGroup)
+		menu.Put("/:id/option-groups/:group_id", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.LinkOptionGroup)
+		menu.Delete("/:id/option-groups/:group_id", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.UnlinkOptionGroup)
+		menu.Put("/:id/option-prices", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.SetMenuOptionPrices)
+
+		// แท็กสารก่อภูมิแพ้/โภชนาการ และระดับความเผ็ด
+		menu.Put("/:id/tags", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.SetMenuItemTags)
+		menu.Put("/options/:id/tags", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.SetMenuOptionTags)
+
+		// Menu Versions - แก้ไขเป็น draft แล้วเผยแพร่/ตั้งเวลา/ย้อนกลับ
+		menu.Get("/:id/price-history", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.GetMenuPriceHistory)
+		versions := menu.Group("/versions", utils.AuthRequired(), utils.RoleRequired(models.RoleManager))
+		{
+			versions.Post("/", api_handlers.CreateMenuVersion)
+			versions.Get("/", api_handlers.GetMenuVersions)
+			versions.Get("/:id", api_handlers.GetMenuVersion)
+			versions.Get("/:id/diff", api_handlers.GetMenuVersionDiff)
+			versions.Put("/:id/items/:menu_item_id", api_handlers.UpdateMenuVersionItem)
+			versions.Post("/:id/publish", api_handlers.PublishMenuVersion)
+			versions.Post("/:id/unschedule", api_handlers.UnscheduleMenuVersion)
+			versions.Post("/:id/rollback", api_handlers.RollbackMenuVersion)
+		}
+	}
+
+	// คำแปลสำหรับภาษาเพิ่มเติม (นอกจาก th/en/zh ที่มีคอลัมน์อยู่แล้ว)
+	translations := api.Group("/translations", utils.AuthRequired(), utils.RoleRequired(models.RoleManager))
+	{
+		translations.Get("/", api_handlers.GetTranslations)
+		translations.Put("/", api_handlers.UpsertTranslations)
+		translations.Delete("/:id", api_handlers.DeleteTranslation)
+	}
+
+	dietaryTags := api.Group("/dietary-tags")
+	{
+		dietaryTags.Get("/", api_handlers.GetDietaryTags)
+		dietaryTags.Post("/", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.CreateDietaryTag)
+		dietaryTags.Put("/:id", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.UpdateDietaryTag)
+		dietaryTags.Delete("/:id", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.DeleteDietaryTag)
 	}
 
 	promotion := api.Group("/promotions")
@@ -107,6 +156,16 @@ func SetupRoutes(app *fiber.App) {
 		promotion.Post("/:id/items", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.AddPromotionItems)
 	}
 
+	combo := api.Group("/combos")
+	{
+		combo.Post("/", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.CreateCombo)
+		combo.Get("/", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.GetCombos)
+		combo.Get("/Active", api_handlers.GetActiveCombos) // สำหรับหน้าสั่งอาหารของลูกค้า
+		combo.Get("/:id", api_handlers.GetComboByID)
+		combo.Put("/:id", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.UpdateCombo)
+		combo.Delete("/:id", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.DeleteCombo)
+	}
+
 	// Category Management Routes - ต้องการการยืนยันตัวตน และต้องเป็น manager
 	// categories := api.Group("/categories", utils.AuthRequired(), utils.RoleRequired(models.RoleManager))
 	categories := api.Group("/categories")
@@ -124,6 +183,7 @@ func SetupRoutes(app *fiber.App) {
 	{
 		orders.Post("/", api_handlers.CreateOrder) // สำหรับสั่งอาหาร
 		orders.Post("/items/cancel", utils.AuthRequired(), api_handlers.CancelOrderItem)
+		orders.Post("/items/transfer", utils.POSAuthRequired(), api_handlers.TransferOrderItems)
 		orders.Put("/status/:id", utils.AuthRequired(), api_handlers.UpdateOrderStatus) //สั่งอาหารa
 		orders.Post("/items/serve/:id", utils.AuthRequired(), api_handlers.ServeOrderItem)
 		orders.Get("/active", utils.POSAuthRequired(), api_handlers.GetActiveOrders)
@@ -141,17 +201,54 @@ func SetupRoutes(app *fiber.App) {
 	table := api.Group("/table")
 	{
 		table.Get("/reservations", utils.POSAuthRequired(), api_handlers.GetAllReservations)
+		table.Get("/reservations/:id/notifications", utils.POSAuthRequired(), api_handlers.GetReservationNotifications)
 		table.Get("/billable/:uuid", utils.POSAuthRequired(), api_handlers.GetBillableItems)
+		// ผังร้าน ประวัติ และการเสนอโต๊ะต้องลงทะเบียนก่อน /:id ไม่เช่นนั้น PUT /layout จะเข้า UpdateTable
+		table.Get("/zones", utils.POSAuthRequired(), api_handlers.GetTableZones)
+		table.Post("/zones", utils.POSAuthRequired(), api_handlers.CreateTableZone)
+		table.Put("/zones/:id", utils.POSAuthRequired(), api_handlers.UpdateTableZone)
+		table.Delete("/zones/:id", utils.POSAuthRequired(), api_handlers.DeleteTableZone)
+		table.Get("/layout", utils.POSAuthRequired(), api_handlers.GetFloorPlan)
+		table.Put("/layout", utils.POSAuthRequired(), api_handlers.SaveTableLayout)
+		table.Get("/history", utils.POSAuthRequired(), api_handlers.GetTableHistory)
+		table.Get("/allocate", utils.POSAuthRequired(), api_handlers.GetTableAllocation)
 		table.Post("/", utils.POSAuthRequired(), api_handlers.Addtable)
 		table.Delete("/:id", utils.POSAuthRequired(), api_handlers.DeleteTable)
 		table.Put("/:id", utils.POSAuthRequired(), api_handlers.UpdateTable)
 		table.Post("/mergeTable", utils.POSAuthRequired(), api_handlers.MergeTables)
 		table.Post("/moveTable", utils.POSAuthRequired(), api_handlers.MoveTable)
 		table.Post("/splitTable/:id", utils.POSAuthRequired(), api_handlers.SplitTables)
+		table.Post("/reservedTable/auto", utils.POSAuthRequired(), api_handlers.AutoReserveTable)
 		table.Post("/reservedTable/:id", utils.POSAuthRequired(), api_handlers.ReservedTable)
 		table.Post("/unreservedTable/:id", utils.POSAuthRequired(), api_handlers.UnreservedTable)
 		table.Put("/setstatus/:id", utils.POSAuthRequired(), api_handlers.ToggleTableStatus)
 		table.Post("/close/:id", utils.POSAuthRequired(), api_handlers.CloseTable)
+		table.Get("/sessions", utils.POSAuthRequired(), api_handlers.GetTableSessions)
+		table.Get("/sessions/:id", utils.POSAuthRequired(), api_handlers.GetTableSession)
+		table.Put("/sessions/:id", utils.POSAuthRequired(), api_handlers.UpdateTableSession)
+	}
+
+	// หน้าจองโต๊ะของลูกค้า ไม่ต้องเข้าสู่ระบบ (แยกจาก /reservation ที่ต้องเป็นผู้จัดการ)
+	// จำกัดจำนวนครั้งต่อ IP การจองและการใช้รหัสยืนยันจำกัดเข้มกว่าเพื่อกันการเดารหัส
+	booking := api.Group("/booking", limiter.New(utils.BookingRateLimit))
+	{
+		bookingLimit := limiter.New(utils.BookingActionRateLimit)
+		booking.Get("/availability", api_handlers.GetReservationAvailability)
+		booking.Post("/", bookingLimit, api_handlers.CreateBooking)
+		booking.Get("/:code", bookingLimit, api_handlers.GetBooking)
+		booking.Put("/:code", bookingLimit, api_handlers.UpdateBooking)
+		booking.Post("/:code/cancel", bookingLimit, api_handlers.CancelBooking)
+	}
+
+	waitlist := api.Group("/waitlist")
+	{
+		waitlist.Get("/ticket/:code", api_handlers.GetWaitlistTicket) // ลูกค้าตรวจสอบคิวเอง ไม่ต้องเข้าสู่ระบบ
+		waitlist.Get("/", utils.POSAuthRequired(), api_handlers.GetWaitlist)
+		waitlist.Post("/", utils.POSAuthRequired(), api_handlers.AddWaitlistEntry)
+		waitlist.Post("/:id/notify", utils.POSAuthRequired(), api_handlers.NotifyWaitlistEntry)
+		waitlist.Get("/:id/suggest", utils.POSAuthRequired(), api_handlers.SuggestWaitlistTables)
+		waitlist.Post("/:id/seat", utils.POSAuthRequired(), api_handlers.SeatWaitlistEntry)
+		waitlist.Post("/:id/cancel", utils.POSAuthRequired(), api_handlers.CancelWaitlistEntry)
 	}
 
 	// QR Code Management Routes
@@ -211,6 +308,12 @@ func SetupRoutes(app *fiber.App) {
 			chargeTypes.Delete("/:id", utils.AuthRequired(), utils.RoleRequired(models.RoleManager), api_handlers.DeleteChargeType) // ลบ/ปิดใช้งาน
 		}
 
+		// รายงานสำหรับผู้จัดการ
+		reports := api.Group("/reports", utils.AuthRequired(), utils.RoleRequired(models.RoleManager))
+		{
+			reports.Get("/covers", api_handlers.GetCoverReport) // ยอดเฉลี่ยต่อลูกค้าและการหมุนเวียนโต๊ะ
+		}
+
 		reservation := api.Group("/reservation", utils.AuthRequired(), utils.RoleRequired(models.RoleManager))
 		{
 			reservation.Get("/rules/active", api_handlers.GetActiveReservationRule)
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"food-ordering-api/config"
+	"food-ordering-api/models"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrImageNotFound ใช้เมื่อไม่พบไฟล์ใน store
+var ErrImageNotFound = errors.New("image not found")
+
+// ImageStore - ที่เก็บไฟล์รูปแบบ key/object (ออกแบบให้ตรงกับ S3 เพื่อสลับ backend ได้)
+type ImageStore interface {
+	Put(key string, data []byte, contentType string) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+}
+
+// LocalImageStore เก็บไฟล์ลง filesystem โดยใช้ key เป็น path ย่อยใต้ BaseDir
+type LocalImageStore struct {
+	BaseDir string
+}
+
+// path แปลง key เป็น path จริง โดย Clean จาก root กัน key ที่มี .. หลุดออกนอก BaseDir
+func (s *LocalImageStore) path(key string) (string, error) {
+	if key == "" {
+		return "", errors.New("empty image key")
+	}
+	return filepath.Join(s.BaseDir, filepath.Clean("/"+key)), nil
+}
+
+func (s *LocalImageStore) Put(key string, data []byte, contentType string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	// เขียนไฟล์ชั่วคราวก่อนแล้วค่อย rename กันไฟล์ขาดครึ่งถ้ามีคนอ่านพร้อมกัน
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+func (s *LocalImageStore) Get(key string) ([]byte, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrImageNotFound
+	}
+	return data, err
+}
+
+func (s *LocalImageStore) Delete(key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Images คือ store ที่ใช้งานอยู่ ตั้งค่าตอนเริ่มระบบผ่าน InitImageStore
+var Images ImageStore
+
+// InitImageStore เลือก backend ตาม IMAGE_STORE_DRIVER (ตอนนี้รองรับ local)
+func InitImageStore() {
+	switch config.ImageStoreDriver {
+	case "", "local":
+		dir := config.ImageStoreDir
+		if dir == "" {
+			dir = "./uploads"
+		}
+		Images = &LocalImageStore{BaseDir: dir}
+		log.Printf("Image store: local (%s)", dir)
+	default:
+		log.Fatalf("Unsupported IMAGE_STORE_DRIVER: %s", config.ImageStoreDriver)
+	}
+}
+
+// ImageVariant ขนาดรูปที่สร้างไว้ล่วงหน้า
+type ImageVariant struct {
+	Name  string
+	Width int // 0 = ต้นฉบับ
+}
+
+var ImageVariants = map[string]ImageVariant{
+	"original": {Name: "original", Width: 0},
+	"medium":   {Name: "medium", Width: 800},
+	"thumb":    {Name: "thumb", Width: 240},
+}
+
+// ImageVariantKey path ของไฟล์แต่ละขนาดใน store
+func ImageVariantKey(img models.StoredImage, variant string) string {
+	return fmt.Sprintf("%s/%s%s", img.StorageKey, variant, img.Extension)
+}
+
+// SaveImage ถอดรหัสรูป สร้าง thumbnail ทุกขนาด เก็บลง store แล้วบันทึก StoredImage ใน tx
+// ไฟล์ถูกเขียนก่อน tx commit ถ้าผู้เรียก rollback หรือ commit ไม่สำเร็จต้องเรียก DiscardImageFiles
+func SaveImage(tx *gorm.DB, data []byte) (*models.StoredImage, error) {
+	if Images == nil {
+		return nil, errors.New("image store is not initialized")
+	}
+
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	var ext, contentType string
+	var encFormat imaging.Format
+	switch format {
+	case "png":
+		ext, contentType, encFormat = ".png", "image/png", imaging.PNG
+	case "jpeg":
+		ext, contentType, encFormat = ".jpg", "image/jpeg", imaging.JPEG
+	default:
+		return nil, fmt.Errorf("unsupported image format: %s", format)
+	}
+
+	sum := sha256.Sum256(data)
+	bounds := src.Bounds()
+	record := models.StoredImage{
+		StorageKey:  fmt.Sprintf("images/%s/%s", time.Now().Format("2006/01"), uuid.New().String()),
+		ContentType: contentType,
+		Extension:   ext,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		Size:        int64(len(data)),
+		Checksum:    hex.EncodeToString(sum[:]),
+	}
+
+	written := []string{}
+	cleanup := func() {
+		for _, key := range written {
+			Images.Delete(key)
+		}
+	}
+
+	for name, variant := range ImageVariants {
+		content := data
+		if variant.Width > 0 && bounds.Dx() > variant.Width {
+			resized := imaging.Resize(src, variant.Width, 0, imaging.Lanczos)
+			var buf bytes.Buffer
+			if err := imaging.Encode(&buf, resized, encFormat, imaging.JPEGQuality(85)); err != nil {
+				cleanup()
+				return nil, fmt.Errorf("failed to encode %s variant: %v", name, err)
+			}
+			content = buf.Bytes()
+		}
+
+		key := ImageVariantKey(record, name)
+		if err := Images.Put(key, content, contentType); err != nil {
+			cleanup()
+			return nil, fmt.Errorf("failed to store %s variant: %v", name, err)
+		}
+		written = append(written, key)
+	}
+
+	if err := tx.Create(&record).Error; err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// DeleteImage ลบไฟล์ทุกขนาดและ record ของรูป (ใช้ตอนเปลี่ยนรูปใหม่)
+func DeleteImage(tx *gorm.DB, imageID uint) error {
+	var img models.StoredImage
+	if err := tx.First(&img, imageID).Error; err != nil {
+		return err
+	}
+	if err := tx.Delete(&img).Error; err != nil {
+		return err
+	}
+	DiscardImageFiles(img)
+	return nil
+}
+
+// DiscardImageFiles ลบไฟล์ทุกขนาดของรูปออกจาก store โดยไม่แตะ record
+// ใช้เก็บกวาดไฟล์ของ SaveImage เมื่อ transaction ไม่สำเร็จ
+func DiscardImageFiles(img models.StoredImage) {
+	for name := range ImageVariants {
+		if err := Images.Delete(ImageVariantKey(img, name)); err != nil {
+			log.Printf("Failed to delete image file %s: %v", ImageVariantKey(img, name), err)
+		}
+	}
+}
+
+// MigrateLegacyImages ย้ายรูปที่เก็บเป็น bytea ใน menu_items/promotions ออกไปไว้ใน image store
+// ทำซ้ำได้ปลอดภัย เพราะจะย้ายเฉพาะแถวที่ยังมี image และยังไม่มี image_id
+func MigrateLegacyImages(database *gorm.DB) {
+	migrate := func(model interface{}, table string) {
+		var rows []struct {
+			ID    uint
+			Image []byte
+		}
+		if err := database.Table(table).
+			Select("id, image").
+			Where("image IS NOT NULL AND image_id IS NULL").
+			Find(&rows).Error; err != nil {
+			log.Printf("Failed to load legacy images from %s: %v", table, err)
+			return
+		}
+
+		moved := 0
+		for _, row := range rows {
+			if len(row.Image) == 0 {
+				continue
+			}
+			var img *models.StoredImage
+			err := database.Transaction(func(tx *gorm.DB) error {
+				var err error
+				img, err = SaveImage(tx, row.Image)
+				if err != nil {
+					return err
+				}
+				return tx.Unscoped().Model(model).Where("id = ?", row.ID).
+					Updates(map[string]interface{}{
+						"image_id": img.ID,
+						"image":    nil,
+					}).Error
+			})
+			if err != nil {
+				if img != nil {
+					DiscardImageFiles(*img)
+				}
+				log.Printf("Failed to migrate image of %s id=%d: %v", table, row.ID, err)
+				continue
+			}
+			moved++
+		}
+
+		if moved > 0 {
+			log.Printf("Migrated %d legacy images from %s", moved, table)
+		}
+	}
+
+	migrate(&models.MenuItem{}, "menu_items")
+	migrate(&models.Promotion{}, "promotions")
+}
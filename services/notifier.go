@@ -0,0 +1,218 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"food-ordering-api/config"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ช่องทางส่งข้อความ
+const (
+	ChannelLog   = "log"
+	ChannelSMS   = "sms"
+	ChannelLine  = "line"
+	ChannelEmail = "email"
+)
+
+// NotificationMessage ข้อความหนึ่งฉบับถึงผู้รับหนึ่งคน
+type NotificationMessage struct {
+	To      string // เบอร์โทร LINE user ID หรืออีเมล ตามช่องทาง
+	Subject string // ใช้กับอีเมล ช่องทางอื่นไม่แสดง
+	Body    string
+}
+
+// Notifier - ผู้ให้บริการส่งข้อความถึงลูกค้า (สลับ backend ได้ผ่าน NOTIFY_DRIVER)
+type Notifier interface {
+	Channel() string
+	// Send ส่งข้อความ คืน message ID จากผู้ให้บริการ (ถ้ามี)
+	Send(msg NotificationMessage) (string, error)
+}
+
+var notifyHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// LogNotifier ไม่ส่งจริง เขียนข้อความเป็น JSON บรรทัดละฉบับลงไฟล์ หรือ log ของระบบถ้าไม่ระบุไฟล์ ใช้ตอนพัฒนาและทดสอบ
+type LogNotifier struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (n *LogNotifier) Channel() string { return ChannelLog }
+
+func (n *LogNotifier) Send(msg NotificationMessage) (string, error) {
+	ref := fmt.Sprintf("log-%d", time.Now().UnixNano())
+	line, err := json.Marshal(map[string]string{
+		"ref":     ref,
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"body":    msg.Body,
+	})
+	if err != nil {
+		return "", err
+	}
+	if n.Path == "" {
+		log.Printf("Notification: %s", line)
+		return ref, nil
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f, err := os.OpenFile(n.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return "", err
+	}
+	return ref, nil
+}
+
+// SMSNotifier ส่ง SMS ผ่าน HTTP gateway ที่รับ JSON {to, sender, message} และ Bearer API key
+type SMSNotifier struct {
+	APIURL string
+	APIKey string
+	Sender string
+}
+
+func (n *SMSNotifier) Channel() string { return ChannelSMS }
+
+func (n *SMSNotifier) Send(msg NotificationMessage) (string, error) {
+	var result struct {
+		ID string `json:"id"`
+	}
+	err := postNotificationJSON(n.APIURL, n.APIKey, map[string]string{
+		"to":      msg.To,
+		"sender":  n.Sender,
+		"message": msg.Body,
+	}, &result, nil)
+	return result.ID, err
+}
+
+// LineNotifier ส่งข้อความ push ผ่าน LINE Messaging API ถึงผู้ใช้ที่เพิ่มร้านเป็นเพื่อนแล้ว
+type LineNotifier struct {
+	ChannelToken string
+	APIURL       string // ค่าว่าง = https://api.line.me/v2/bot/message/push
+}
+
+func (n *LineNotifier) Channel() string { return ChannelLine }
+
+func (n *LineNotifier) Send(msg NotificationMessage) (string, error) {
+	url := n.APIURL
+	if url == "" {
+		url = "https://api.line.me/v2/bot/message/push"
+	}
+	text := msg.Body
+	if msg.Subject != "" {
+		text = msg.Subject + "\n" + msg.Body
+	}
+	header := http.Header{}
+	err := postNotificationJSON(url, n.ChannelToken, map[string]interface{}{
+		"to":       msg.To,
+		"messages": []map[string]string{{"type": "text", "text": text}},
+	}, nil, header)
+	return header.Get("X-Line-Request-Id"), err
+}
+
+// EmailNotifier ส่งอีเมลข้อความล้วน (UTF-8) ผ่าน SMTP
+type EmailNotifier struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (n *EmailNotifier) Channel() string { return ChannelEmail }
+
+func (n *EmailNotifier) Send(msg NotificationMessage) (string, error) {
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+	body := strings.Join([]string{
+		"From: " + n.From,
+		"To: " + msg.To,
+		"Subject: " + mime.QEncoding.Encode("UTF-8", msg.Subject),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		msg.Body,
+	}, "\r\n")
+	return "", smtp.SendMail(n.Host+":"+n.Port, auth, n.From, []string{msg.To}, []byte(body))
+}
+
+// postNotificationJSON POST JSON พร้อม Bearer token ถือว่าล้มเหลวถ้าได้ status ที่ไม่ใช่ 2xx
+// result (ถ้ามี) รับ JSON ที่ตอบกลับ และ header (ถ้ามี) รับ header ที่ตอบกลับ
+func postNotificationJSON(url, token string, payload interface{}, result interface{}, header http.Header) error {
+	if url == "" {
+		return errors.New("notification provider URL is not configured")
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := notifyHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("provider responded %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if header != nil {
+		for key, values := range resp.Header {
+			header[key] = values
+		}
+	}
+	if result != nil && len(data) > 0 {
+		json.Unmarshal(data, result)
+	}
+	return nil
+}
+
+// Notifications คือผู้ให้บริการส่งข้อความที่ใช้งานอยู่ ตั้งค่าตอนเริ่มระบบผ่าน InitNotifier
+var Notifications Notifier
+
+// InitNotifier เลือกผู้ให้บริการตาม NOTIFY_DRIVER (log, sms, line, email)
+func InitNotifier() {
+	switch config.NotifyDriver {
+	case "", ChannelLog:
+		Notifications = &LogNotifier{Path: config.NotifyLogFile}
+	case ChannelSMS:
+		Notifications = &SMSNotifier{APIURL: config.SMSAPIURL, APIKey: config.SMSAPIKey, Sender: config.SMSSender}
+	case ChannelLine:
+		Notifications = &LineNotifier{ChannelToken: config.LineChannelToken}
+	case ChannelEmail:
+		port := config.SMTPPort
+		if port == "" {
+			port = "587"
+		}
+		Notifications = &EmailNotifier{
+			Host: config.SMTPHost, Port: port, Username: config.SMTPUser, Password: config.SMTPPassword, From: config.SMTPFrom,
+		}
+	default:
+		log.Fatalf("Unsupported NOTIFY_DRIVER: %s", config.NotifyDriver)
+	}
+	log.Printf("Notifier: %s", Notifications.Channel())
+}
@@ -2,9 +2,11 @@ package service
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"food-ordering-api/db"
 	"food-ordering-api/models"
+	utils "food-ordering-api/utility"
 	"image"
 	"image/png"
 	"log"
@@ -16,7 +18,6 @@ import (
 	"github.com/disintegration/imaging"
 	"github.com/fogleman/gg"
 	"github.com/gofiber/fiber/v2"
-	"github.com/google/uuid"
 	"github.com/skip2/go-qrcode"
 	"gorm.io/gorm"
 )
@@ -79,12 +80,13 @@ func updateTableStatus(tx *gorm.DB, table models.Table, tableID int) error {
 // @Description เข้าสู่โต๊ะนั้นๆ ซึ่ง api เส้นนี้ไม่จำเป็นต้องถูกใช้งานโดยตรงเพราะ url ของแต่ละโต๊ะจะสามารถเข้าได้ผ่าน qr_code เท่านั้นจากฟังก์ชัน
 // @Produce json
 // @Param id path integer true "ID โต๊ะ"
-// @Success 200 {object} models.QRCode "รายละเอียดของตาราง qr_code"
+// @Param guests query integer false "จำนวนลูกค้า"
+// @Param server_id query integer false "ID พนักงานที่ดูแลโต๊ะ"
+// @Success 200 {object} models.QRCode "รายละเอียดของตาราง qr_code (ID ของ session อยู่ใน header X-Session-ID)"
 // @Failure 400 {object} map[string]interface{} "เกิดข้อผิดพลาดจาก action ที่ไม่ถูกต้อง"
 // @Router /api/qr/{id} [get]
 // @Tags Qr_code
 func HandleQRCodeRequest(c *fiber.Ctx) error {
-	UUID := uuid.New().String()
 	tableID := c.Params("id")
 	num, err := strconv.Atoi(tableID)
 	if err != nil {
@@ -112,13 +114,17 @@ func HandleQRCodeRequest(c *fiber.Ctx) error {
 		})
 	}
 
-	var existingQR models.QRCode
-	result := db.DB.Where("tableID = ? AND is_active = ?", actualTableID, true).First(&existingQR)
-	if result.Error == nil {
+	guestCount := c.QueryInt("guests", 0)
+	if guestCount < 0 {
 		return c.Status(400).JSON(fiber.Map{
-			"error": "table_id นี้มีคิวอาร์กำลังใช้งานอยู่",
+			"error": "จำนวนลูกค้าต้องไม่ติดลบ",
 		})
 	}
+	var serverID *uint
+	if id := c.QueryInt("server_id", 0); id > 0 {
+		server := uint(id)
+		serverID = &server
+	}
 
 	tx := db.DB.Begin()
 	defer func() {
@@ -127,6 +133,21 @@ func HandleQRCodeRequest(c *fiber.Ctx) error {
 		}
 	}()
 
+	groupIDs, err := GroupTableIDs(tx, table)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch table group",
+		})
+	}
+	change, err := BeginTableChange(tx, models.TableActionOpen, groupIDs...)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to read table state",
+		})
+	}
+
 	// อัพเดทสถานะโต๊ะ
 	if err := updateTableStatus(tx, table, actualTableID); err != nil {
 		tx.Rollback()
@@ -135,9 +156,31 @@ func HandleQRCodeRequest(c *fiber.Ctx) error {
 		})
 	}
 
+	// เปิดรอบการใช้โต๊ะ token ของ session คือ uuid ใน QR Code
+	session, err := OpenTableSession(tx, table, guestCount, serverID, nil)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, ErrTableInSession) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "table_id นี้มีคิวอาร์กำลังใช้งานอยู่",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to open table session",
+		})
+	}
+
+	change.Entry.SessionID = &session.ID
+	change.Entry.StaffID = utils.GetStaffID(c)
+	if err := change.Record(tx); err != nil {
+		tx.Rollback()
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to record table history",
+		})
+	}
+
 	// สร้าง QR Code
-	expiryAt := time.Now().Add(2 * time.Hour)
-	url := fmt.Sprintf("http://localhost:5173/menu?tableID=%v&uuid=%v", tableID, UUID)
+	url := fmt.Sprintf("http://localhost:5173/menu?tableID=%v&uuid=%v", tableID, session.Token)
 
 	// สร้าง QR Code image
 	qrCode, err := qrcode.New(url, qrcode.Medium)
@@ -171,11 +214,12 @@ func HandleQRCodeRequest(c *fiber.Ctx) error {
 	// บันทึก QR Code ในฐานข้อมูล
 	qrCodeRecord := models.QRCode{
 		TableID:   actualTableID,
-		UUID:      UUID,
+		UUID:      session.Token,
 		CreatedAt: time.Now(),
-		ExpiryAt:  expiryAt,
+		ExpiryAt:  session.ExpiresAt,
 		IsActive:  true,
 		Qr_Image:  finalQRCodeImage,
+		SessionID: &session.ID,
 	}
 
 	if err := tx.Create(&qrCodeRecord).Error; err != nil {
@@ -242,8 +286,10 @@ func HandleQRCodeRequest(c *fiber.Ctx) error {
 			"error": "Failed to commit transaction",
 		})
 	}
+	PublishTableChange(TableEventOpened, groupIDs...)
 
 	// ส่ง QR Code image กลับไปแสดงผล
+	c.Set("X-Session-ID", strconv.FormatUint(uint64(session.ID), 10))
 	c.Set("Content-Type", "image/png")
 	c.Set("Content-Disposition", "inline")
 	return c.Send(finalQRCodeImage)
@@ -418,15 +464,8 @@ func HandleQRCodeReprint(c *fiber.Ctx) error {
 		})
 	}
 
-	actualTableID := num
-	if hasGroupID(table.GroupID) && table.ParentID != nil {
-		actualTableID = int(*table.ParentID)
-	}
-
-	// ตรวจสอบว่า UUID ตรงกับที่มีในระบบ
-	var existingQR models.QRCode
-	if err := db.DB.Where("table_id = ? AND uuid = ? AND is_active = ?",
-		actualTableID, reqBody.UUID, true).First(&existingQR).Error; err != nil {
+	// ตรวจสอบว่า UUID เป็น token ของ session ที่เปิดอยู่ของโต๊ะนี้
+	if _, err := FindOpenSession(db.DB, reqBody.UUID, table.ID); err != nil {
 		return c.Status(400).JSON(fiber.Map{
 			"error": "Invalid UUID for this table",
 		})
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"food-ordering-api/config"
+	"food-ordering-api/models"
+	"strings"
+	"text/template"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxNotificationAttempts ส่งข้อความที่ล้มเหลวซ้ำได้ไม่เกินกี่ครั้ง
+const maxNotificationAttempts = 3
+
+// notificationTemplate หัวเรื่องและเนื้อความของข้อความหนึ่งภาษา
+type notificationTemplate struct {
+	Subject string
+	Body    string
+}
+
+// reservationTemplates ข้อความแต่ละประเภทแยกตามภาษา (th, en)
+var reservationTemplates = map[string]map[string]notificationTemplate{
+	models.NotificationConfirmation: {
+		"th": {
+			Subject: "ยืนยันการจองโต๊ะ {{.Restaurant}}",
+			Body: "คุณ{{.Name}} จองโต๊ะ{{if .Table}} {{.Table}}{{end}} สำหรับ {{.Guests}} ท่าน วันที่ {{.Date}} เวลา {{.Time}} น. เรียบร้อยแล้ว" +
+				" รหัสยืนยัน {{.Code}}{{if .Deposit}} กรุณาชำระมัดจำ {{.Deposit}} บาท{{end}}" +
+				"{{if .NoShowFee}} หากไม่มาตามนัดมีค่าปรับ {{.NoShowFee}} บาท{{end}}",
+		},
+		"en": {
+			Subject: "Your table at {{.Restaurant}} is confirmed",
+			Body: "Hi {{.Name}}, your table{{if .Table}} {{.Table}}{{end}} for {{.Guests}} on {{.Date}} at {{.Time}} is confirmed." +
+				" Confirmation code: {{.Code}}.{{if .Deposit}} Please pay a deposit of {{.Deposit}} THB.{{end}}" +
+				"{{if .NoShowFee}} A no-show fee of {{.NoShowFee}} THB applies.{{end}}",
+		},
+	},
+	models.NotificationReminder: {
+		"th": {
+			Subject: "เตือนการจองโต๊ะ {{.Restaurant}}",
+			Body:    "คุณ{{.Name}} อย่าลืมการจองโต๊ะสำหรับ {{.Guests}} ท่าน วันที่ {{.Date}} เวลา {{.Time}} น. รหัสยืนยัน {{.Code}} หากไม่สะดวกกรุณายกเลิกล่วงหน้า",
+		},
+		"en": {
+			Subject: "Reminder: your table at {{.Restaurant}}",
+			Body:    "Hi {{.Name}}, see you on {{.Date}} at {{.Time}} for {{.Guests}}. Confirmation code: {{.Code}}. If your plans change, please cancel in advance.",
+		},
+	},
+	models.NotificationNoShow: {
+		"th": {
+			Subject: "เราคิดถึงคุณที่ {{.Restaurant}}",
+			Body: "คุณ{{.Name}} เราไม่พบคุณตามการจองวันที่ {{.Date}} เวลา {{.Time}} น. (รหัส {{.Code}}) การจองถูกยกเลิกแล้ว" +
+				"{{if .NoShowFee}} ตามนโยบายมีค่าปรับ {{.NoShowFee}} บาท{{end}} หวังว่าจะได้ต้อนรับในโอกาสหน้า",
+		},
+		"en": {
+			Subject: "We missed you at {{.Restaurant}}",
+			Body: "Hi {{.Name}}, we didn't see you for your booking on {{.Date}} at {{.Time}} (code {{.Code}}), so it has been released." +
+				"{{if .NoShowFee}} A no-show fee of {{.NoShowFee}} THB applies per our policy.{{end}} We hope to welcome you another time.",
+		},
+	},
+}
+
+// reservationTemplateData ค่าที่ใช้ในข้อความ
+type reservationTemplateData struct {
+	Restaurant string
+	Name       string
+	Code       string
+	Table      string
+	Guests     int
+	Date       string
+	Time       string
+	Deposit    string // ค่าว่าง = ไม่ต้องมัดจำ
+	NoShowFee  string // ค่าว่าง = ไม่มีค่าปรับ
+}
+
+// formatBaht แสดงจำนวนเงิน ค่าว่างถ้าไม่มี
+func formatBaht(amount float64) string {
+	if amount <= 0 {
+		return ""
+	}
+	if amount == float64(int64(amount)) {
+		return fmt.Sprintf("%d", int64(amount))
+	}
+	return fmt.Sprintf("%.2f", amount)
+}
+
+// RenderReservationNotification สร้างหัวเรื่องและเนื้อความตามประเภทและภาษาของการจอง (ภาษาที่ไม่รองรับใช้ภาษาไทย)
+func RenderReservationNotification(kind string, reservation models.TableReservation) (string, string, error) {
+	templates, ok := reservationTemplates[kind]
+	if !ok {
+		return "", "", fmt.Errorf("unknown notification kind: %s", kind)
+	}
+	language := reservation.Language
+	if _, ok := templates[language]; !ok {
+		language = "th"
+	}
+
+	restaurant := config.RestaurantName
+	if restaurant == "" {
+		restaurant = "ร้านอาหาร"
+		if language == "en" {
+			restaurant = "our restaurant"
+		}
+	}
+	reservedFor := reservation.ReservedFor.In(time.Local)
+	data := reservationTemplateData{
+		Restaurant: restaurant,
+		Name:       reservation.CustomerName,
+		Table:      reservation.Table.Name,
+		Guests:     reservation.GuestCount,
+		Date:       reservedFor.Format("02/01/2006"),
+		Time:       reservedFor.Format("15:04"),
+		Deposit:    formatBaht(reservation.DepositAmount),
+		NoShowFee:  formatBaht(reservation.NoShowFee),
+	}
+	if language == "en" {
+		data.Date = reservedFor.Format("Mon 2 Jan 2006")
+	}
+	if reservation.ConfirmationCode != nil {
+		data.Code = *reservation.ConfirmationCode
+	}
+
+	render := func(text string) (string, error) {
+		tmpl, err := template.New(kind).Parse(text)
+		if err != nil {
+			return "", err
+		}
+		var out strings.Builder
+		if err := tmpl.Execute(&out, data); err != nil {
+			return "", err
+		}
+		return out.String(), nil
+	}
+	subject, err := render(templates[language].Subject)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := render(templates[language].Body)
+	return subject, body, err
+}
+
+// QueueReservationNotification บันทึกข้อความที่รอส่งของการจอง เรียกใน transaction เดียวกับการเปลี่ยนแปลงการจอง
+func QueueReservationNotification(tx *gorm.DB, reservationID uint, kind string) error {
+	return tx.Create(&models.ReservationNotification{
+		ReservationID: reservationID,
+		Kind:          kind,
+		Status:        models.NotificationPending,
+	}).Error
+}
+
+// reservationRecipient ผู้รับของการจองตามช่องทาง ค่าว่างถ้าลูกค้าไม่ได้ให้ข้อมูลช่องทางนั้น
+func reservationRecipient(channel string, reservation models.TableReservation) string {
+	switch channel {
+	case ChannelLine:
+		return reservation.LineUserID
+	case ChannelEmail:
+		return reservation.Email
+	default:
+		return reservation.PhoneNumber
+	}
+}
+
+// DeliverPendingNotifications ส่งข้อความที่รอส่งผ่าน notifier และบันทึกผลการส่งของแต่ละฉบับ
+// ข้อความที่ล้มเหลวจะส่งซ้ำในรอบถัดไปจนครบ maxNotificationAttempts คืนจำนวนข้อความที่ส่งสำเร็จ
+func DeliverPendingNotifications(tx *gorm.DB, notifier Notifier) (int, error) {
+	if notifier == nil {
+		return 0, errors.New("notifier is not initialized")
+	}
+
+	var pending []models.ReservationNotification
+	if err := tx.Where("status = ?", models.NotificationPending).Order("id").Limit(100).Find(&pending).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, notification := range pending {
+		updates := map[string]interface{}{
+			"channel":  notifier.Channel(),
+			"attempts": notification.Attempts + 1,
+		}
+
+		var reservation models.TableReservation
+		err := tx.Unscoped().Preload("Table").First(&reservation, notification.ReservationID).Error
+		var subject, body string
+		if err == nil {
+			subject, body, err = RenderReservationNotification(notification.Kind, reservation)
+		}
+		recipient := reservationRecipient(notifier.Channel(), reservation)
+		updates["recipient"] = recipient
+		updates["language"] = reservation.Language
+		updates["subject"] = subject
+		updates["body"] = body
+
+		switch {
+		case err != nil:
+			// การจองหายหรือข้อความสร้างไม่ได้ ส่งซ้ำก็ไม่สำเร็จ
+			updates["status"] = models.NotificationFailed
+			updates["error"] = err.Error()
+		case recipient == "":
+			updates["status"] = models.NotificationSkipped
+			updates["error"] = fmt.Sprintf("no %s recipient", notifier.Channel())
+		default:
+			ref, err := notifier.Send(NotificationMessage{To: recipient, Subject: subject, Body: body})
+			if err != nil {
+				updates["error"] = err.Error()
+				if notification.Attempts+1 >= maxNotificationAttempts {
+					updates["status"] = models.NotificationFailed
+				}
+				break
+			}
+			now := time.Now()
+			updates["status"] = models.NotificationSent
+			updates["provider_ref"] = ref
+			updates["error"] = ""
+			updates["sent_at"] = &now
+			sent++
+		}
+
+		if err := tx.Model(&models.ReservationNotification{}).Where("id = ?", notification.ID).Updates(updates).Error; err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
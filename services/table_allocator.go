@@ -0,0 +1,329 @@
+package service
+
+import (
+	"food-ordering-api/models"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultDiningDuration เวลาที่ลูกค้าหนึ่งกลุ่มใช้โต๊ะโดยประมาณ ใช้หาว่าโต๊ะว่างพอสำหรับมื้อนั้นหรือไม่
+const DefaultDiningDuration = 90 * time.Minute
+
+const (
+	maxMergeTables = 3 // รวมโต๊ะใหม่ได้ไม่เกินกี่ตัว
+
+	// คะแนนของข้อเสนอ ยิ่งน้อยยิ่งดี
+	scoreEmptySeat = 1   // ต่อที่นั่งที่เหลือว่าง
+	scoreMerge     = 3   // ต่อโต๊ะที่ต้องย้ายมารวมเพิ่ม
+	scoreOtherZone = 10  // ไม่อยู่ในโซนที่ลูกค้าต้องการ
+	scoreOversized = 100 // ที่นั่งเหลือมากเกินไป เช่น ลูกค้า 2 คนนั่งโต๊ะ 8 ที่
+)
+
+// AllocationRequest เงื่อนไขการหาโต๊ะ
+type AllocationRequest struct {
+	PartySize int
+	At        time.Time     // เวลาที่ลูกค้าจะนั่ง ค่าว่าง = ตอนนี้
+	Duration  time.Duration // เวลาที่ใช้โต๊ะ ค่าว่าง = DefaultDiningDuration
+	Lead      time.Duration // ต้องว่างก่อน At นานเท่าไร (เวลากั้นโต๊ะก่อนเวลาจอง)
+	ZoneID    *uint         // โซนที่ลูกค้าต้องการ (ถ้ามี)
+	Limit     int           // จำนวนข้อเสนอสูงสุด 0 = ทั้งหมด
+}
+
+// span ช่วงเวลาที่ลูกค้ากลุ่มนี้ต้องใช้โต๊ะ
+func (req AllocationRequest) span(now time.Time) (time.Time, time.Time, time.Duration) {
+	duration := req.Duration
+	if duration <= 0 {
+		duration = DefaultDiningDuration
+	}
+	at := req.At
+	if at.IsZero() || at.Before(now) {
+		at = now
+	}
+	start := at.Add(-req.Lead)
+	if start.Before(now) {
+		start = now
+	}
+	return start, at.Add(duration), duration
+}
+
+// TableAllocation ข้อเสนอโต๊ะหนึ่งตัว โต๊ะรวมที่มีอยู่แล้ว หรือโต๊ะที่ต้องนำมารวมกันใหม่
+type TableAllocation struct {
+	TableID    uint    `json:"table_id"`  // โต๊ะหลัก (โต๊ะแม่ถ้าเป็นโต๊ะรวม)
+	TableIDs   []uint  `json:"table_ids"` // ทุกโต๊ะที่ลูกค้าจะได้ใช้
+	Name       string  `json:"name"`
+	Capacity   int     `json:"capacity"`
+	ZoneID     *uint   `json:"zone_id"`
+	GroupID    *string `json:"group_id"`    // กลุ่มของโต๊ะรวมที่มีอยู่แล้ว
+	NeedsMerge bool    `json:"needs_merge"` // ต้องรวมโต๊ะ table_ids ก่อนใช้
+	Oversized  bool    `json:"oversized"`   // ที่นั่งเหลือมากเกินไป ใช้เมื่อไม่มีโต๊ะที่เหมาะกว่า
+	Score      int     `json:"score"`
+}
+
+// maxEmptySeats ที่นั่งว่างที่ยอมให้เหลือได้ก่อนถือว่าโต๊ะใหญ่เกินไป
+func maxEmptySeats(partySize int) int {
+	if partySize/2 > 2 {
+		return partySize / 2
+	}
+	return 2
+}
+
+// TableState โต๊ะ การจอง และโต๊ะที่มีลูกค้าอยู่ ณ เวลาที่โหลด ใช้หาโต๊ะหลายช่วงเวลาโดยไม่ต้อง query ซ้ำ
+type TableState struct {
+	now          time.Time
+	tables       []models.Table
+	reservations []models.TableReservation
+	busy         map[uint]bool // โต๊ะที่มี session เปิดอยู่
+}
+
+// LoadTableState โหลดสถานะโต๊ะและการจองที่อาจคาบเกี่ยวช่วง from ถึง to
+func LoadTableState(tx *gorm.DB, from, to time.Time) (*TableState, error) {
+	state := &TableState{now: time.Now(), busy: map[uint]bool{}}
+	if err := tx.Order("id").Find(&state.tables).Error; err != nil {
+		return nil, err
+	}
+
+	// ลูกค้าที่จองก่อน from อาจยังนั่งอยู่ จึงย้อนไปหนึ่งวัน
+	if err := tx.Where("status = ? AND table_blocked_from < ? AND reserved_for > ?", "active", to, from.Add(-24*time.Hour)).
+		Find(&state.reservations).Error; err != nil {
+		return nil, err
+	}
+
+	var ids []uint
+	if err := tx.Table("table_session_tables").
+		Where("table_session_id IN (SELECT id FROM table_sessions WHERE status = ?)", models.SessionOpen).
+		Pluck("table_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		state.busy[id] = true
+	}
+	return state, nil
+}
+
+// AllocateTables เสนอโต๊ะที่เหมาะกับลูกค้ากลุ่มหนึ่ง เรียงจากดีที่สุด
+func AllocateTables(tx *gorm.DB, req AllocationRequest) ([]TableAllocation, error) {
+	start, end, _ := req.span(time.Now())
+	state, err := LoadTableState(tx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return state.Allocate(req), nil
+}
+
+// Allocate เสนอโต๊ะจากสถานะที่โหลดไว้ เรียงจากดีที่สุด
+// โต๊ะต้องไม่ติดการจองในช่วงเวลาที่ลูกค้าใช้โต๊ะ และถ้าลูกค้ามาภายในรอบการนั่งของตอนนี้ โต๊ะต้องว่างอยู่ตอนนี้ด้วย
+func (state *TableState) Allocate(req AllocationRequest) []TableAllocation {
+	start, end, duration := req.span(state.now)
+
+	// การจองกั้นโต๊ะตั้งแต่ table_blocked_from จนลูกค้ากลุ่มนั้นกินเสร็จ
+	blocked := map[uint]bool{}
+	for _, r := range state.reservations {
+		if r.TableBlockedFrom.Before(end) && ReservationUntil(r, duration).After(start) {
+			blocked[r.TableID] = true
+		}
+	}
+
+	// ลูกค้าที่นั่งอยู่ตอนนี้ยังไม่ลุกก่อนเวลานี้ จึงต้องดูสถานะปัจจุบันด้วย
+	occupancyMatters := start.Before(state.now.Add(duration))
+
+	isFree := func(table models.Table) bool {
+		if table.Status == "unavailable" || blocked[table.ID] {
+			return false
+		}
+		if occupancyMatters {
+			return table.Status == "available" && !state.busy[table.ID]
+		}
+		return true
+	}
+
+	var singles []models.Table
+	groups := map[string][]models.Table{}
+	var groupOrder []string
+	for _, table := range state.tables {
+		if hasGroupID(table.GroupID) {
+			if _, ok := groups[*table.GroupID]; !ok {
+				groupOrder = append(groupOrder, *table.GroupID)
+			}
+			groups[*table.GroupID] = append(groups[*table.GroupID], table)
+			continue
+		}
+		if isFree(table) {
+			singles = append(singles, table)
+		}
+	}
+
+	allocations := []TableAllocation{}
+	consider := func(members []models.Table, groupID *string, needsMerge bool) {
+		allocation := newTableAllocation(members, groupID, needsMerge)
+		if allocation.Capacity < req.PartySize {
+			return
+		}
+		empty := allocation.Capacity - req.PartySize
+		allocation.Score = empty * scoreEmptySeat
+		if needsMerge {
+			allocation.Score += (len(members) - 1) * scoreMerge
+		}
+		if req.ZoneID != nil && (allocation.ZoneID == nil || *allocation.ZoneID != *req.ZoneID) {
+			allocation.Score += scoreOtherZone
+		}
+		if empty > maxEmptySeats(req.PartySize) {
+			allocation.Oversized = true
+			allocation.Score += scoreOversized
+		}
+		allocations = append(allocations, allocation)
+	}
+
+	for _, table := range singles {
+		consider([]models.Table{table}, nil, false)
+	}
+
+	// โต๊ะรวมที่มีอยู่แล้วใช้ได้เมื่อว่างทั้งกลุ่ม
+	for _, groupID := range groupOrder {
+		members := groups[groupID]
+		free := true
+		for _, table := range members {
+			if !isFree(table) {
+				free = false
+				break
+			}
+		}
+		if free {
+			id := groupID
+			consider(members, &id, false)
+		}
+	}
+
+	// รวมโต๊ะเดี่ยวที่ติดกันในโซนเดียวกัน เฉพาะชุดที่เอาโต๊ะตัวใดออกแล้วจะนั่งไม่พอ
+	for _, combo := range mergeableCombinations(singles, req.PartySize) {
+		consider(combo, nil, true)
+	}
+
+	sort.SliceStable(allocations, func(i, j int) bool {
+		a, b := allocations[i], allocations[j]
+		if a.Score != b.Score {
+			return a.Score < b.Score
+		}
+		if len(a.TableIDs) != len(b.TableIDs) {
+			return len(a.TableIDs) < len(b.TableIDs)
+		}
+		return a.TableID < b.TableID
+	})
+	if req.Limit > 0 && len(allocations) > req.Limit {
+		allocations = allocations[:req.Limit]
+	}
+	return allocations
+}
+
+// newTableAllocation รวมข้อมูลโต๊ะหลายตัวเป็นข้อเสนอเดียว โต๊ะหลักคือโต๊ะแม่ของกลุ่มหรือโต๊ะแรก
+func newTableAllocation(members []models.Table, groupID *string, needsMerge bool) TableAllocation {
+	allocation := TableAllocation{
+		TableID:    members[0].ID,
+		ZoneID:     members[0].ZoneID,
+		GroupID:    groupID,
+		NeedsMerge: needsMerge,
+	}
+	names := make([]string, 0, len(members))
+	for _, table := range members {
+		if groupID != nil && table.ParentID == nil {
+			allocation.TableID = table.ID
+			allocation.ZoneID = table.ZoneID
+		}
+		allocation.TableIDs = append(allocation.TableIDs, table.ID)
+		allocation.Capacity += table.Capacity
+		names = append(names, table.Name)
+	}
+	allocation.Name = strings.Join(names, "+")
+	return allocation
+}
+
+// mergeableCombinations ชุดโต๊ะ 2 ถึง maxMergeTables ตัวที่ติดกันและนั่งได้พอดี (ไม่มีโต๊ะเกินความจำเป็น)
+func mergeableCombinations(tables []models.Table, partySize int) [][]models.Table {
+	var combos [][]models.Table
+	var walk func(start int, picked []models.Table, capacity int)
+	walk = func(start int, picked []models.Table, capacity int) {
+		if len(picked) >= 2 && capacity >= partySize {
+			if isMinimalCombination(picked, capacity, partySize) && isConnected(picked) {
+				combos = append(combos, append([]models.Table(nil), picked...))
+			}
+			return
+		}
+		if len(picked) == maxMergeTables {
+			return
+		}
+		for i := start; i < len(tables); i++ {
+			if len(picked) > 0 && !sameZone(picked[0], tables[i]) {
+				continue
+			}
+			walk(i+1, append(picked, tables[i]), capacity+tables[i].Capacity)
+		}
+	}
+	walk(0, nil, 0)
+	return combos
+}
+
+func isMinimalCombination(tables []models.Table, capacity, partySize int) bool {
+	for _, table := range tables {
+		if capacity-table.Capacity >= partySize {
+			return false
+		}
+	}
+	return true
+}
+
+// isConnected โต๊ะทุกตัวในชุดเชื่อมถึงกันผ่านโต๊ะที่ติดกัน
+func isConnected(tables []models.Table) bool {
+	reached := map[int]bool{0: true}
+	queue := []int{0}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for i := range tables {
+			if !reached[i] && tablesAdjacent(tables[current], tables[i]) {
+				reached[i] = true
+				queue = append(queue, i)
+			}
+		}
+	}
+	return len(reached) == len(tables)
+}
+
+func sameZone(a, b models.Table) bool {
+	if a.ZoneID == nil || b.ZoneID == nil {
+		return a.ZoneID == nil && b.ZoneID == nil
+	}
+	return *a.ZoneID == *b.ZoneID
+}
+
+// tablesAdjacent โต๊ะสองตัวติดกันบนผังร้าน (ห่างกันไม่เกินครึ่งหนึ่งของด้านที่สั้นที่สุด)
+// ถ้าโต๊ะยังไม่ได้จัดผัง ถือว่าโต๊ะในโซนเดียวกันรวมกันได้
+func tablesAdjacent(a, b models.Table) bool {
+	if !sameZone(a, b) {
+		return false
+	}
+	if a.Width <= 0 || a.Height <= 0 || b.Width <= 0 || b.Height <= 0 {
+		return true
+	}
+	tolerance := min(a.Width, a.Height, b.Width, b.Height) / 2
+	gapX := max(a.PosX, b.PosX) - min(a.PosX+a.Width, b.PosX+b.Width)
+	gapY := max(a.PosY, b.PosY) - min(a.PosY+a.Height, b.PosY+b.Height)
+	return gapX <= tolerance && gapY <= tolerance
+}
+
+// Bookable จองได้ทันที การจองผูกกับโต๊ะเดียวและตรวจที่นั่งกับการจองซ้อนของโต๊ะนั้นเท่านั้น
+// จึงต้องเป็นโต๊ะเดี่ยว (ไม่ต้องรวมโต๊ะใหม่และไม่ใช่โต๊ะรวมที่มีอยู่แล้ว) และไม่ใหญ่เกินไป
+func (allocation TableAllocation) Bookable() bool {
+	return !allocation.NeedsMerge && allocation.GroupID == nil && !allocation.Oversized
+}
+
+// BookableAllocation ข้อเสนอแรกที่จองได้ทันที
+func BookableAllocation(allocations []TableAllocation) *TableAllocation {
+	for i := range allocations {
+		if allocations[i].Bookable() {
+			return &allocations[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// เหตุผลของการเปลี่ยนแปลงโต๊ะ ส่งต่อให้หน้าจอ POS ในข้อความ table_diff
+const (
+	TableEventCreated     = "created"
+	TableEventUpdated     = "updated"
+	TableEventDeleted     = "deleted"
+	TableEventMerged      = "merged"
+	TableEventMoved       = "moved"
+	TableEventSplit       = "split"
+	TableEventReserved    = "reserved"
+	TableEventStatus      = "status"
+	TableEventOpened      = "opened" // เปิดโต๊ะ/สร้าง QR Code
+	TableEventClosed      = "closed" // ชำระเงินหรือปิดโต๊ะ
+	TableEventSession     = "session"
+	TableEventLayout      = "layout"
+	TableEventReservation = "reservation"
+	TableEventWaitlist    = "waitlist" // คิว walk-in เปลี่ยน (ไม่ได้เปลี่ยนโต๊ะ)
+)
+
+// TableEvent การเปลี่ยนแปลงของโต๊ะที่ต้องแจ้งหน้าจอที่แสดงสถานะโต๊ะ
+type TableEvent struct {
+	Seq      uint64
+	Reason   string
+	TableIDs []uint // โต๊ะที่เปลี่ยน ว่าง = ไม่ระบุ (เช่น งานเบื้องหลังที่แก้หลายโต๊ะ)
+	At       time.Time
+}
+
+// tableEventBus ส่ง TableEvent ภายใน process ไปยังผู้รับทุกราย
+type tableEventBus struct {
+	mu   sync.Mutex
+	seq  uint64
+	subs map[chan TableEvent]struct{}
+}
+
+var tableEvents = &tableEventBus{subs: make(map[chan TableEvent]struct{})}
+
+// SubscribeTableEvents รับ TableEvent ที่ publish หลังจากนี้ เรียก cancel เมื่อเลิกใช้
+func SubscribeTableEvents() (<-chan TableEvent, func()) {
+	ch := make(chan TableEvent, 32)
+
+	tableEvents.mu.Lock()
+	tableEvents.subs[ch] = struct{}{}
+	tableEvents.mu.Unlock()
+
+	cancel := func() {
+		tableEvents.mu.Lock()
+		if _, ok := tableEvents.subs[ch]; ok {
+			delete(tableEvents.subs, ch)
+			close(ch)
+		}
+		tableEvents.mu.Unlock()
+	}
+	return ch, cancel
+}
+
+// PublishTableChange แจ้งว่าโต๊ะเปลี่ยนแปลง เรียกหลัง commit แล้วเท่านั้น ผู้รับจะได้อ่านข้อมูลล่าสุด
+// ไม่บล็อกผู้เรียก ถ้าผู้รับตามไม่ทัน event จะถูกทิ้ง (ผู้รับโหลดสถานะทั้งหมดใหม่ทุกครั้งอยู่แล้ว)
+func PublishTableChange(reason string, tableIDs ...uint) {
+	tableEvents.mu.Lock()
+	defer tableEvents.mu.Unlock()
+
+	tableEvents.seq++
+	event := TableEvent{
+		Seq:      tableEvents.seq,
+		Reason:   reason,
+		TableIDs: tableIDs,
+		At:       time.Now(),
+	}
+	for ch := range tableEvents.subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"food-ordering-api/models"
+
+	"gorm.io/gorm"
+)
+
+// TableChange เก็บสถานะโต๊ะก่อนแก้ไข เพื่อบันทึก TableHistory หลังแก้ไขเสร็จ (ภายใน transaction เดียวกัน)
+type TableChange struct {
+	action string
+	before map[uint]models.Table
+	order  []uint
+	// Entry ค่าที่ใส่ให้ทุกแถวของประวัติ เช่น StaffID SessionID OrderIDs RelatedTableID Note
+	Entry models.TableHistory
+}
+
+// BeginTableChange อ่านสถานะปัจจุบันของโต๊ะที่กำลังจะถูกแก้ไข เรียกก่อนอัปเดตโต๊ะ
+func BeginTableChange(tx *gorm.DB, action string, tableIDs ...uint) (*TableChange, error) {
+	change := &TableChange{action: action, before: map[uint]models.Table{}}
+	if len(tableIDs) == 0 {
+		return change, nil
+	}
+
+	var tables []models.Table
+	if err := tx.Unscoped().Where("id IN ?", tableIDs).Find(&tables).Error; err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		if _, ok := change.before[table.ID]; !ok {
+			change.order = append(change.order, table.ID)
+		}
+		change.before[table.ID] = table
+	}
+	return change, nil
+}
+
+// Record บันทึกประวัติหนึ่งแถวต่อโต๊ะ โดยเทียบสถานะก่อน/หลังแก้ไข เรียกหลังอัปเดตโต๊ะใน transaction เดียวกัน
+func (change *TableChange) Record(tx *gorm.DB) error {
+	if len(change.order) == 0 {
+		return nil
+	}
+
+	// Unscoped เพื่อให้บันทึกโต๊ะที่เพิ่งถูกลบได้
+	var tables []models.Table
+	if err := tx.Unscoped().Where("id IN ?", change.order).Find(&tables).Error; err != nil {
+		return err
+	}
+	after := make(map[uint]models.Table, len(tables))
+	for _, table := range tables {
+		after[table.ID] = table
+	}
+
+	entries := make([]models.TableHistory, 0, len(change.order))
+	for _, id := range change.order {
+		entries = append(entries, NewTableHistory(change.action, change.before[id], after[id], change.Entry))
+	}
+	return RecordTableHistory(tx, entries...)
+}
+
+// NewTableHistory สร้างประวัติของโต๊ะหนึ่งตัวจากสถานะก่อน/หลัง ค่าอื่นๆ คัดลอกจาก base
+func NewTableHistory(action string, before, after models.Table, base models.TableHistory) models.TableHistory {
+	entry := base
+	entry.ID = 0
+	entry.Action = action
+	entry.TableID = after.ID
+	if entry.TableID == 0 {
+		entry.TableID = before.ID
+	}
+	entry.FromStatus = before.Status
+	entry.ToStatus = after.Status
+	entry.FromGroupID = groupIDOrNil(before.GroupID)
+	entry.ToGroupID = groupIDOrNil(after.GroupID)
+	return entry
+}
+
+// RecordTableHistory บันทึกประวัติของโต๊ะ
+func RecordTableHistory(tx *gorm.DB, entries ...models.TableHistory) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return tx.Create(&entries).Error
+}
+
+// GroupTableIDs ID ของทุกโต๊ะในกลุ่มของโต๊ะรวม หรือเฉพาะโต๊ะนั้นถ้าไม่ได้รวมโต๊ะ
+func GroupTableIDs(tx *gorm.DB, table models.Table) ([]uint, error) {
+	if !hasGroupID(table.GroupID) {
+		return []uint{table.ID}, nil
+	}
+	var ids []uint
+	err := tx.Model(&models.Table{}).Where("group_id = ?", *table.GroupID).Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+func groupIDOrNil(groupID *string) *string {
+	if !hasGroupID(groupID) {
+		return nil
+	}
+	id := *groupID
+	return &id
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"errors"
+	"food-ordering-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReservationUntil เวลาที่การจองคืนโต๊ะ คือเมื่อลูกค้ากินเสร็จ หรือเมื่อหมดเวลาสายถ้านานกว่า
+func ReservationUntil(reservation models.TableReservation, dining time.Duration) time.Time {
+	until := reservation.ReservedFor.Add(dining)
+	if reservation.GracePeriodUntil.After(until) {
+		return reservation.GracePeriodUntil
+	}
+	return until
+}
+
+// FindReservationConflict การจองที่ active ของโต๊ะที่ใช้โต๊ะทับช่วง [from, to)
+// การจองแต่ละรายการใช้โต๊ะตั้งแต่ table_blocked_from ถึง ReservationUntil คืน nil ถ้าไม่ทับ
+// excludeID คือการจองที่กำลังแก้ไข ไม่นับว่าทับกับตัวเอง (0 = ไม่มี)
+func FindReservationConflict(tx *gorm.DB, tableID uint, from, to time.Time, dining time.Duration, excludeID uint) (*models.TableReservation, error) {
+	var reservation models.TableReservation
+	err := tx.Where(`table_id = ? AND status = 'active' AND id <> ? AND table_blocked_from < ?
+			AND (reserved_for > ? OR grace_period_until > ?)`,
+		tableID, excludeID, to, from.Add(-dining), from).
+		Order("reserved_for").
+		First(&reservation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+// TableHeldByReservation มีการจองที่ active กั้นโต๊ะไว้ ณ เวลา at หรือไม่
+// (ตั้งแต่ table_blocked_from จนหมดเวลาสาย) ใช้ตัดสินว่าโต๊ะควรอยู่ในสถานะ reserved
+func TableHeldByReservation(tx *gorm.DB, tableID uint, at time.Time) (bool, error) {
+	var count int64
+	err := tx.Model(&models.TableReservation{}).
+		Where("table_id = ? AND status = 'active' AND table_blocked_from <= ? AND grace_period_until > ?", tableID, at, at).
+		Count(&count).Error
+	return count > 0, err
+}
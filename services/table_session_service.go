@@ -0,0 +1,238 @@
+package service
+
+import (
+	"errors"
+	"food-ordering-api/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SessionTokenTTL อายุของ token ใน QR Code นับจากเปิดโต๊ะ
+const SessionTokenTTL = 2 * time.Hour
+
+const (
+	diningSampleDays    = 30 // ใช้ session ที่ปิดแล้วย้อนหลังกี่วันหาเวลานั่งเฉลี่ย
+	diningSampleMinimum = 10 // ถ้ามีน้อยกว่านี้ใช้ DefaultDiningDuration
+)
+
+var (
+	ErrSessionNotFound = errors.New("table session not found")
+	ErrSessionExpired  = errors.New("table session expired")
+	ErrTableInSession  = errors.New("table already has an open session")
+)
+
+// sessionTablesQuery เงื่อนไข session ที่ผูกกับโต๊ะ
+const sessionTablesQuery = "id IN (SELECT table_session_id FROM table_session_tables WHERE table_id = ?)"
+
+// OpenTableSession เปิด session ใหม่ให้โต๊ะ ถ้าเป็นโต๊ะรวมจะผูกทุกโต๊ะในกลุ่มและใช้โต๊ะแม่เป็นโต๊ะหลัก
+func OpenTableSession(tx *gorm.DB, table models.Table, guestCount int, serverID, reservationID *uint) (*models.TableSession, error) {
+	mainTableID := table.ID
+	tables := []models.Table{table}
+	if hasGroupID(table.GroupID) {
+		if table.ParentID != nil {
+			mainTableID = *table.ParentID
+		}
+		if err := tx.Where("group_id = ?", *table.GroupID).Find(&tables).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	for _, t := range tables {
+		if _, err := FindOpenSessionForTable(tx, t.ID); err == nil {
+			return nil, ErrTableInSession
+		} else if !errors.Is(err, ErrSessionNotFound) {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	session := models.TableSession{
+		Token:         uuid.New().String(),
+		TableID:       mainTableID,
+		Tables:        tables,
+		Status:        models.SessionOpen,
+		GuestCount:    guestCount,
+		ServerID:      serverID,
+		ReservationID: reservationID,
+		ExpiresAt:     now.Add(SessionTokenTTL),
+		OpenedAt:      now,
+	}
+	if err := tx.Omit("Tables.*").Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindOpenSessionForTable session ที่เปิดอยู่ของโต๊ะ (รวมโต๊ะลูกของโต๊ะรวม)
+func FindOpenSessionForTable(tx *gorm.DB, tableID uint) (*models.TableSession, error) {
+	var session models.TableSession
+	err := tx.Where("status = ?", models.SessionOpen).Where(sessionTablesQuery, tableID).
+		Order("id DESC").First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindSessionByToken session จาก token ใน QR Code ไม่สนสถานะ
+func FindSessionByToken(tx *gorm.DB, token string) (*models.TableSession, error) {
+	var session models.TableSession
+	err := tx.Where("token = ?", token).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindOpenSession session ที่เปิดอยู่จาก token ใน QR Code ไม่สนเวลาหมดอายุ (ใช้ฝั่งพนักงาน เช่น ชำระเงิน ปิดโต๊ะ)
+// tableID ต้องเป็นโต๊ะหนึ่งใน session (ส่ง 0 เพื่อข้ามการตรวจ)
+func FindOpenSession(tx *gorm.DB, token string, tableID uint) (*models.TableSession, error) {
+	if token == "" {
+		return nil, ErrSessionNotFound
+	}
+	query := tx.Where("token = ? AND status = ?", token, models.SessionOpen)
+	if tableID != 0 {
+		query = query.Where(sessionTablesQuery, tableID)
+	}
+
+	var session models.TableSession
+	if err := query.First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ResolveSessionToken เหมือน FindOpenSession แต่ token ต้องยังไม่หมดอายุ (ใช้ตอนลูกค้าสั่งอาหาร)
+func ResolveSessionToken(tx *gorm.DB, token string, tableID uint) (*models.TableSession, error) {
+	session, err := FindOpenSession(tx, token, tableID)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrSessionExpired
+	}
+	return session, nil
+}
+
+// SessionTableIDs ID ของทุกโต๊ะใน session
+func SessionTableIDs(tx *gorm.DB, sessionID uint) ([]uint, error) {
+	var ids []uint
+	err := tx.Table("table_session_tables").Where("table_session_id = ?", sessionID).
+		Order("table_id").Pluck("table_id", &ids).Error
+	return ids, err
+}
+
+// MoveTableSession ย้าย session จากโต๊ะหนึ่งไปอีกโต๊ะ รวมถึง QR Code และออเดอร์ที่สั่งจากโต๊ะเดิม
+// คืน ID ของออเดอร์ที่ถูกย้าย
+func MoveTableSession(tx *gorm.DB, session *models.TableSession, fromTableID, toTableID uint) ([]uint, error) {
+	if err := tx.Exec("DELETE FROM table_session_tables WHERE table_session_id = ? AND table_id = ?",
+		session.ID, fromTableID).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Exec("INSERT INTO table_session_tables (table_session_id, table_id) VALUES (?, ?)",
+		session.ID, toTableID).Error; err != nil {
+		return nil, err
+	}
+	if session.TableID == fromTableID {
+		// ไม่ใช้ Model(session) เพราะ GORM จะบันทึก Tables ที่โหลดไว้กลับเข้าตารางเชื่อม
+		if err := tx.Model(&models.TableSession{}).Where("id = ?", session.ID).
+			Update("table_id", toTableID).Error; err != nil {
+			return nil, err
+		}
+		session.TableID = toTableID
+	}
+	if err := tx.Model(&models.QRCode{}).Where("session_id = ? AND table_id = ?", session.ID, fromTableID).
+		Update("table_id", toTableID).Error; err != nil {
+		return nil, err
+	}
+
+	var orderIDs []uint
+	if err := tx.Model(&models.Order{}).Where("session_id = ? AND table_id = ?", session.ID, fromTableID).
+		Order("id").Pluck("id", &orderIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(orderIDs) == 0 {
+		return orderIDs, nil
+	}
+	return orderIDs, tx.Model(&models.Order{}).Where("id IN ?", orderIDs).
+		Update("table_id", toTableID).Error
+}
+
+// CloseTableSession ปิด session ปิด QR Code ของ session และคืนทุกโต๊ะใน session เป็นพร้อมใช้งาน
+// บันทึกประวัติการปิดโต๊ะในชื่อ staffID (nil = ระบบ)
+func CloseTableSession(tx *gorm.DB, session *models.TableSession, staffID *uint) error {
+	now := time.Now()
+	if err := tx.Model(&models.TableSession{}).Where("id = ?", session.ID).Updates(map[string]interface{}{
+		"status":    models.SessionClosed,
+		"closed_at": now,
+	}).Error; err != nil {
+		return err
+	}
+	session.Status = models.SessionClosed
+	session.ClosedAt = &now
+	if err := tx.Model(&models.QRCode{}).Where("session_id = ?", session.ID).
+		Update("is_active", false).Error; err != nil {
+		return err
+	}
+
+	tableIDs, err := SessionTableIDs(tx, session.ID)
+	if err != nil {
+		return err
+	}
+	if len(tableIDs) == 0 {
+		return nil
+	}
+
+	change, err := BeginTableChange(tx, models.TableActionClose, tableIDs...)
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Table{}).Where("id IN ?", tableIDs).
+		Updates(map[string]interface{}{
+			"status":     "available",
+			"updated_at": now,
+		}).Error; err != nil {
+		return err
+	}
+	change.Entry.SessionID = &session.ID
+	change.Entry.StaffID = staffID
+	return change.Record(tx)
+}
+
+// AverageDiningDuration เวลาที่ลูกค้าใช้โต๊ะโดยเฉลี่ยจาก session ที่ปิดแล้วใน 30 วันที่ผ่านมา ปัดขึ้นทีละ 5 นาที
+// ถ้าข้อมูลยังน้อยเกินไปใช้ DefaultDiningDuration
+func AverageDiningDuration(tx *gorm.DB) (time.Duration, error) {
+	var sessions []models.TableSession
+	if err := tx.Select("opened_at, closed_at").
+		Where("status = ? AND closed_at IS NOT NULL AND opened_at >= ?", models.SessionClosed, time.Now().AddDate(0, 0, -diningSampleDays)).
+		Find(&sessions).Error; err != nil {
+		return 0, err
+	}
+
+	var total time.Duration
+	count := 0
+	for _, session := range sessions {
+		if d := session.ClosedAt.Sub(session.OpenedAt); d > 0 {
+			total += d
+			count++
+		}
+	}
+	if count < diningSampleMinimum {
+		return DefaultDiningDuration, nil
+	}
+
+	step := 5 * time.Minute
+	average := total / time.Duration(count)
+	return (average + step - 1) / step * step, nil
+}
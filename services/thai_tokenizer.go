@@ -0,0 +1,166 @@
+package service
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// ThaiTokenizer ตัดคำข้อความผสมไทย/อังกฤษ/จีน สำหรับค้นหา
+// ภาษาไทยตัดคำด้วยพจนานุกรม (maximal matching: คำที่ไม่รู้จักน้อยที่สุด แล้วจำนวนคำน้อยที่สุด)
+// ภาษาอังกฤษ/ตัวเลขตัดตามช่องว่างและเครื่องหมาย ภาษาจีนแยกทีละตัวอักษร
+type ThaiTokenizer struct {
+	words  map[string]bool
+	maxLen int // ความยาวคำที่ยาวที่สุดในพจนานุกรม (นับเป็น rune)
+}
+
+// NewThaiTokenizer สร้างตัวตัดคำจากรายการคำ (ข้ามคำที่ว่างหรือมีช่องว่าง)
+func NewThaiTokenizer(words []string) *ThaiTokenizer {
+	t := &ThaiTokenizer{words: make(map[string]bool, len(words))}
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if word == "" || strings.ContainsAny(word, " \t") {
+			continue
+		}
+		t.words[word] = true
+		if n := len([]rune(word)); n > t.maxLen {
+			t.maxLen = n
+		}
+	}
+	return t
+}
+
+// LoadThaiTokenizer อ่านพจนานุกรมจากไฟล์ (หนึ่งคำต่อบรรทัด เช่น dict/lexitron.txt)
+func LoadThaiTokenizer(path string) (*ThaiTokenizer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		words = append(words, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewThaiTokenizer(words), nil
+}
+
+// isThai ตรวจว่าเป็นอักษรไทย (รวมสระและวรรณยุกต์)
+func isThai(r rune) bool {
+	return r >= 0x0E00 && r <= 0x0E7F
+}
+
+// Tokenize ตัดข้อความเป็นคำตัวพิมพ์เล็ก ไม่มีเครื่องหมายวรรคตอน
+func (t *ThaiTokenizer) Tokenize(text string) []string {
+	var tokens []string
+	var latin, thai []rune
+
+	flushLatin := func() {
+		if len(latin) > 0 {
+			tokens = append(tokens, string(latin))
+			latin = latin[:0]
+		}
+	}
+	flushThai := func() {
+		if len(thai) > 0 {
+			tokens = append(tokens, t.segmentThai(thai)...)
+			thai = thai[:0]
+		}
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isThai(r):
+			flushLatin()
+			thai = append(thai, r)
+		case unicode.Is(unicode.Han, r):
+			flushLatin()
+			flushThai()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushThai()
+			latin = append(latin, r)
+		default:
+			flushLatin()
+			flushThai()
+		}
+	}
+	flushLatin()
+	flushThai()
+
+	return tokens
+}
+
+// segmentThai ตัดคำภาษาไทยหนึ่งช่วง ตัวอักษรที่ไม่อยู่ในคำใดจะรวมเป็นคำเดียวกันถ้าติดกัน
+func (t *ThaiTokenizer) segmentThai(text []rune) []string {
+	n := len(text)
+	if t.maxLen == 0 {
+		return []string{string(text)}
+	}
+
+	type state struct {
+		unknown int // จำนวนตัวอักษรที่ไม่อยู่ในพจนานุกรม
+		count   int // จำนวนคำ
+		prev    int // ตำแหน่งเริ่มของคำสุดท้าย
+		known   bool
+	}
+	best := make([]*state, n+1)
+	best[0] = &state{}
+	better := func(a, b *state) bool {
+		return b == nil || a.unknown < b.unknown || (a.unknown == b.unknown && a.count < b.count)
+	}
+
+	for i := 0; i < n; i++ {
+		if best[i] == nil {
+			continue
+		}
+		// ข้ามตัวอักษรที่ไม่รู้จักทีละตัว
+		skip := &state{unknown: best[i].unknown + 1, count: best[i].count + 1, prev: i}
+		if better(skip, best[i+1]) {
+			best[i+1] = skip
+		}
+		for j := i + 1; j <= n && j-i <= t.maxLen; j++ {
+			if !t.words[string(text[i:j])] {
+				continue
+			}
+			word := &state{unknown: best[i].unknown, count: best[i].count + 1, prev: i, known: true}
+			if better(word, best[j]) {
+				best[j] = word
+			}
+		}
+	}
+
+	// ย้อนกลับเพื่อสร้างรายการคำ แล้วรวมตัวอักษรที่ไม่รู้จักที่อยู่ติดกัน
+	var spans [][2]int
+	var known []bool
+	for end := n; end > 0; end = best[end].prev {
+		spans = append(spans, [2]int{best[end].prev, end})
+		known = append(known, best[end].known)
+	}
+
+	var tokens []string
+	unknownStart := -1
+	for k := len(spans) - 1; k >= 0; k-- {
+		span := spans[k]
+		if !known[k] {
+			if unknownStart < 0 {
+				unknownStart = span[0]
+			}
+			continue
+		}
+		if unknownStart >= 0 {
+			tokens = append(tokens, string(text[unknownStart:span[0]]))
+			unknownStart = -1
+		}
+		tokens = append(tokens, string(text[span[0]:span[1]]))
+	}
+	if unknownStart >= 0 {
+		tokens = append(tokens, string(text[unknownStart:]))
+	}
+	return tokens
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -360,6 +361,22 @@ func GetUserFromPOSToken(c *fiber.Ctx) (*jwt.MapClaims, error) {
 	return claims, nil
 }
 
+// GetStaffID ID ของพนักงานที่ทำรายการ จาก token ของ POS หรือ token ผู้ใช้ คืน nil ถ้าไม่มีหรือ token ไม่ถูกต้อง
+func GetStaffID(c *fiber.Ctx) *uint {
+	claims, err := GetUserFromPOSToken(c)
+	if err != nil {
+		if claims, err = GetUserFromToken(c); err != nil {
+			return nil
+		}
+	}
+	userID, ok := (*claims)["user_id"].(float64)
+	if !ok {
+		return nil
+	}
+	id := uint(userID)
+	return &id
+}
+
 // POSAuthRequired middleware สำหรับตรวจสอบการ authentication ของ POS
 func POSAuthRequired() fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -387,3 +404,24 @@ func POSAuthRequired() fiber.Handler {
 		return c.Next()
 	}
 }
+
+// rateLimitReached ตอบ 429 เป็น JSON แบบเดียวกับ error อื่นของ API
+func rateLimitReached(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error": "เรียกใช้บ่อยเกินไป กรุณาลองใหม่ภายหลัง",
+	})
+}
+
+// BookingRateLimit จำกัดจำนวน request ต่อ IP ของ API จองโต๊ะสาธารณะทั้งกลุ่ม
+var BookingRateLimit = limiter.Config{
+	Max:          60,
+	Expiration:   time.Minute,
+	LimitReached: rateLimitReached,
+}
+
+// BookingActionRateLimit จำกัดการจองและการใช้รหัสยืนยันเข้มกว่า เพื่อกันการเดารหัส
+var BookingActionRateLimit = limiter.Config{
+	Max:          10,
+	Expiration:   time.Minute,
+	LimitReached: rateLimitReached,
+}